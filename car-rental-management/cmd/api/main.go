@@ -4,6 +4,7 @@ import (
 	// --- ตรวจสอบและแก้ไข Import ให้ถูกต้อง ---
 	"car-rental-management/internal/config"
 	"car-rental-management/internal/router" // <--- แก้ไขตรงนี้: ลบชื่อเล่น "handlers" ออก
+	"car-rental-management/internal/services"
 
 	// --- -------------------------------- ---
 	"log"
@@ -17,6 +18,8 @@ func main() {
 
 	config.ConnectDB()
 	log.Println("Database connection established.")
+	services.StartDeletedRecordPurge()
+	services.StartLoyaltyPointExpiry()
 
 	// --- ตรวจสอบการเรียกใช้ ---
 	r := router.SetupRouter() // <--- เรียกใช้ package router โดยตรง (ถูกต้องแล้ว)
@@ -0,0 +1,34 @@
+// Command reencrypt-pii brings encrypted customer data and documents up to date with the PII
+// keyring. It encrypts values stored before encryption was enabled, fills in their blind indexes,
+// and rewraps data keys still wrapped with a key other than active_key. It is safe to run while the
+// API is serving, and to run again if interrupted.
+//
+//	PII_KEYRING_FILE=/etc/car-rental/pii-keys.json go run ./cmd/reencrypt-pii
+//
+// Once it reports no failures and no skipped rows, keys other than active_key can be removed from
+// the keyring.
+package main
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/services"
+	"log"
+)
+
+func main() {
+	config.ConnectDB()
+	defer config.DB.Close()
+
+	result, err := services.ReencryptPII()
+	if err != nil {
+		log.Fatalf("❌ Re-encryption stopped after %d customers: %v", result.CustomersScanned, err)
+	}
+	log.Printf("✅ Scanned %d customers, updated %d; scanned %d documents, updated %d; %d values could not be re-encrypted; %d rows skipped",
+		result.CustomersScanned, result.CustomersUpdated, result.DocumentsScanned, result.DocumentsUpdated, result.Failed, result.Skipped)
+	if result.Failed > 0 {
+		log.Fatalf("❌ Some values are under keys missing from the keyring or are corrupt; keep the old keys until they are resolved")
+	}
+	if result.Skipped > 0 {
+		log.Fatalf("❌ %d rows changed while they were being re-encrypted and may still be under old keys; rerun before removing old keys", result.Skipped)
+	}
+}
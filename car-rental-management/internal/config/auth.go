@@ -0,0 +1,56 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// TokenLifetimes reads how long access and refresh tokens stay valid from
+// ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL (Go durations, e.g. "15m", "720h").
+func TokenLifetimes() (access, refresh time.Duration) {
+	return durationFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL), durationFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("⚠️ Invalid %s '%s', using default %v", key, value, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// AppBaseURL is the frontend address used to build links in emails (APP_BASE_URL).
+func AppBaseURL() string {
+	if url := os.Getenv("APP_BASE_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return "http://localhost:3000"
+}
+
+// RequireEmailVerification reports whether customers must verify their email
+// before booking (REQUIRE_EMAIL_VERIFICATION=true).
+func RequireEmailVerification() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// defaultDeletedRecordRetention is the five years the Accounting Act and the Revenue Code require
+// accounting documents and their supporting records to be kept.
+const defaultDeletedRecordRetention = 5 * 365 * 24 * time.Hour
+
+// DeletedRecordRetention is how long soft-deleted records are kept before they are purged
+// for good (DELETED_RECORD_RETENTION, a Go duration such as "43800h").
+func DeletedRecordRetention() time.Duration {
+	return durationFromEnv("DELETED_RECORD_RETENTION", defaultDeletedRecordRetention)
+}
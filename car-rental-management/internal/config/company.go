@@ -0,0 +1,34 @@
+package config
+
+import "os"
+
+// CompanyDetails is the seller information printed on tax invoices and receipts.
+type CompanyDetails struct {
+	Name    string
+	Address string
+	TaxID   string // 13-digit Thai taxpayer identification number
+	Phone   string
+}
+
+// Company reads the company details from the environment (COMPANY_NAME,
+// COMPANY_ADDRESS, COMPANY_TAX_ID, COMPANY_PHONE).
+func Company() CompanyDetails {
+	details := CompanyDetails{
+		Name:    os.Getenv("COMPANY_NAME"),
+		Address: os.Getenv("COMPANY_ADDRESS"),
+		TaxID:   os.Getenv("COMPANY_TAX_ID"),
+		Phone:   os.Getenv("COMPANY_PHONE"),
+	}
+	if details.Name == "" {
+		details.Name = "Car Rental Co., Ltd."
+	}
+	return details
+}
+
+// InvoiceFontFiles returns the TrueType fonts invoices are set in
+// (INVOICE_FONT_FILE, and INVOICE_FONT_BOLD_FILE for headings). Thai text needs
+// a font with Thai glyphs, such as Sarabun; with none set invoices fall back to
+// Helvetica, which prints Thai as '?'.
+func InvoiceFontFiles() (regular, bold string) {
+	return os.Getenv("INVOICE_FONT_FILE"), os.Getenv("INVOICE_FONT_BOLD_FILE")
+}
@@ -11,7 +11,6 @@ import (
 )
 
 var DB *sqlx.DB
-var JwtSecret string
 
 func ConnectDB() {
 
@@ -26,13 +25,8 @@ func ConnectDB() {
 		log.Println("⚠️ DATABASE_URL environment variable not set. Using default:", dsn)
 	}
 
-	JwtSecret = os.Getenv("JWT_SECRET")
-	if JwtSecret == "" {
-		JwtSecret = "a_very_secret_dev_key_change_in_production_12345!"
-		log.Println("🚨 CRITICAL WARNING: JWT_SECRET environment variable not set. Using default secret (INSECURE FOR PRODUCTION!). Change this immediately.")
-	} else if len(JwtSecret) < 32 {
-		log.Println("🚨 SECURITY WARNING: JWT_SECRET is set but seems short. Ensure it is a strong, long, random secret.")
-	}
+	LoadJWTKeys()
+	LoadPIIKeyring()
 
 	log.Println("🔍 Connecting to database...")
 	var dbErr error
@@ -135,7 +129,7 @@ func initializeSchema() {
 				name VARCHAR(100) NOT NULL,
 				email VARCHAR(100) UNIQUE NOT NULL,
 				password VARCHAR(255) NOT NULL,
-				role VARCHAR(50) NOT NULL CHECK (role IN ('admin', 'manager')),
+				role VARCHAR(50) NOT NULL, -- References roles(name), added by the "roles" schema
 				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
 				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 			);
@@ -217,11 +211,134 @@ func initializeSchema() {
 				FOREIGN KEY (rental_id) REFERENCES rentals(id) ON DELETE CASCADE,
 				FOREIGN KEY (recorded_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
 			);
+			ALTER TABLE payments ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'THB';
+			ALTER TABLE payments ADD COLUMN IF NOT EXISTS charged_amount DECIMAL(12,2);
+			ALTER TABLE payments ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18,8) NOT NULL DEFAULT 1 CHECK (exchange_rate > 0);
+			UPDATE payments SET charged_amount = amount WHERE charged_amount IS NULL;
 			CREATE INDEX IF NOT EXISTS idx_payments_rental_id ON payments(rental_id);
             CREATE INDEX IF NOT EXISTS idx_payments_status ON payments(payment_status);
 			DROP TRIGGER IF EXISTS update_payments_updated_at ON payments;
 			CREATE TRIGGER update_payments_updated_at BEFORE UPDATE ON payments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 		`,
+		"invoice_sequences": `
+			CREATE TABLE IF NOT EXISTS invoice_sequences (
+				branch_id INT NOT NULL,
+				year INT NOT NULL,
+				kind VARCHAR(20) NOT NULL CHECK (kind IN ('tax_invoice', 'receipt', 'corporate')),
+				last_number INT NOT NULL CHECK (last_number > 0),
+				PRIMARY KEY (branch_id, year, kind)
+			);
+			ALTER TABLE invoice_sequences DROP CONSTRAINT IF EXISTS invoice_sequences_kind_check;
+			ALTER TABLE invoice_sequences ADD CONSTRAINT invoice_sequences_kind_check CHECK (kind IN ('tax_invoice', 'receipt', 'corporate'));
+		`,
+		"invoices": `
+			CREATE TABLE IF NOT EXISTS invoices (
+				id SERIAL PRIMARY KEY,
+				rental_id INT NOT NULL,
+				kind VARCHAR(20) NOT NULL CHECK (kind IN ('tax_invoice', 'receipt')),
+				branch_id INT NOT NULL,
+				year INT NOT NULL,
+				sequence_number INT NOT NULL,
+				invoice_number VARCHAR(50) NOT NULL UNIQUE,
+				net_amount DECIMAL(12,2) NOT NULL,
+				vat_amount DECIMAL(12,2) NOT NULL,
+				total_amount DECIMAL(12,2) NOT NULL,
+				issued_by_employee_id INT,
+				issued_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (rental_id, kind),
+				UNIQUE (branch_id, year, kind, sequence_number)
+			);
+			-- Issued invoices are legal documents: no foreign keys so they survive deletes, and they cannot be changed.
+			DROP TRIGGER IF EXISTS invoices_append_only ON invoices;
+			CREATE TRIGGER invoices_append_only BEFORE UPDATE OR DELETE ON invoices FOR EACH ROW EXECUTE FUNCTION prevent_ledger_mutation();
+		`,
+		"corporate_accounts": `
+			CREATE TABLE IF NOT EXISTS corporate_accounts (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(150) NOT NULL UNIQUE,
+				tax_id VARCHAR(20),
+				billing_email VARCHAR(100) NOT NULL,
+				billing_address TEXT,
+				payment_terms_days INT NOT NULL DEFAULT 30 CHECK (payment_terms_days >= 0),
+				is_active BOOLEAN NOT NULL DEFAULT TRUE,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			DROP TRIGGER IF EXISTS update_corporate_accounts_updated_at ON corporate_accounts;
+			CREATE TRIGGER update_corporate_accounts_updated_at BEFORE UPDATE ON corporate_accounts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+			CREATE TABLE IF NOT EXISTS corporate_account_members (
+				account_id INT NOT NULL,
+				customer_id INT NOT NULL,
+				monthly_spending_limit DECIMAL(12,2) CHECK (monthly_spending_limit IS NULL OR monthly_spending_limit > 0), -- NULL = no limit
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (account_id, customer_id),
+				FOREIGN KEY (account_id) REFERENCES corporate_accounts(id) ON DELETE CASCADE,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS idx_corporate_account_members_customer_id ON corporate_account_members(customer_id);
+
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS corporate_account_id INT REFERENCES corporate_accounts(id) ON DELETE RESTRICT;
+			CREATE INDEX IF NOT EXISTS idx_rentals_corporate_account_id ON rentals(corporate_account_id);
+
+			CREATE TABLE IF NOT EXISTS corporate_invoices (
+				id SERIAL PRIMARY KEY,
+				account_id INT NOT NULL,
+				invoice_number VARCHAR(50) NOT NULL UNIQUE,
+				period_start DATE NOT NULL,
+				period_end DATE NOT NULL,
+				net_amount DECIMAL(12,2) NOT NULL,
+				vat_amount DECIMAL(12,2) NOT NULL,
+				total_amount DECIMAL(12,2) NOT NULL,
+				amount_paid DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (amount_paid >= 0 AND amount_paid <= total_amount),
+				status VARCHAR(20) NOT NULL DEFAULT 'Issued' CHECK (status IN ('Issued', 'Partially Paid', 'Paid')),
+				issued_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				due_date DATE NOT NULL,
+				issued_by_employee_id INT,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (account_id, period_start),
+				FOREIGN KEY (account_id) REFERENCES corporate_accounts(id) ON DELETE RESTRICT,
+				FOREIGN KEY (issued_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_corporate_invoices_status ON corporate_invoices(status);
+			DROP TRIGGER IF EXISTS update_corporate_invoices_updated_at ON corporate_invoices;
+			CREATE TRIGGER update_corporate_invoices_updated_at BEFORE UPDATE ON corporate_invoices FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+			CREATE TABLE IF NOT EXISTS corporate_invoice_items (
+				id SERIAL PRIMARY KEY,
+				invoice_id INT NOT NULL,
+				rental_id INT NOT NULL UNIQUE, -- A rental is billed on exactly one invoice
+				description TEXT NOT NULL,
+				net_amount DECIMAL(12,2) NOT NULL,
+				vat_amount DECIMAL(12,2) NOT NULL,
+				total_amount DECIMAL(12,2) NOT NULL,
+				FOREIGN KEY (invoice_id) REFERENCES corporate_invoices(id) ON DELETE RESTRICT,
+				FOREIGN KEY (rental_id) REFERENCES rentals(id) ON DELETE RESTRICT
+			);
+			CREATE INDEX IF NOT EXISTS idx_corporate_invoice_items_invoice_id ON corporate_invoice_items(invoice_id);
+
+			CREATE TABLE IF NOT EXISTS corporate_invoice_payments (
+				id SERIAL PRIMARY KEY,
+				invoice_id INT NOT NULL,
+				amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+				payment_method VARCHAR(50) NOT NULL,
+				reference VARCHAR(100),
+				recorded_by_employee_id INT,
+				paid_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (invoice_id) REFERENCES corporate_invoices(id) ON DELETE RESTRICT,
+				FOREIGN KEY (recorded_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_corporate_invoice_payments_invoice_id ON corporate_invoice_payments(invoice_id);
+		`,
+		"exchange_rates": `
+			CREATE TABLE IF NOT EXISTS exchange_rates (
+				currency CHAR(3) PRIMARY KEY CHECK (currency ~ '^[A-Z]{3}$'),
+				rate_to_base DECIMAL(18,8) NOT NULL CHECK (rate_to_base > 0), -- Units of base currency (THB) per 1 unit
+				updated_by_employee_id INT,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (updated_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+		`,
 		"reviews": `
 			CREATE TABLE IF NOT EXISTS reviews (
 				id SERIAL PRIMARY KEY,
@@ -239,9 +356,648 @@ func initializeSchema() {
 			DROP TRIGGER IF EXISTS update_reviews_updated_at ON reviews;
 			CREATE TRIGGER update_reviews_updated_at BEFORE UPDATE ON reviews FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 		`,
+		"ledger_accounts": `
+			CREATE TABLE IF NOT EXISTS ledger_accounts (
+				code VARCHAR(50) PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				type VARCHAR(20) NOT NULL CHECK (type IN ('asset', 'liability', 'revenue', 'contra_revenue'))
+			);
+			INSERT INTO ledger_accounts (code, name, type) VALUES
+				('cash', 'Cash and Bank', 'asset'),
+				('customer_receivable', 'Customer Receivable', 'asset'),
+				('deposits_held', 'Customer Deposits Held', 'liability'),
+				('vat_payable', 'VAT Payable', 'liability'),
+				('rental_revenue', 'Rental Revenue', 'revenue'),
+				('refunds', 'Refunds', 'contra_revenue')
+			ON CONFLICT (code) DO NOTHING;
+		`,
+		"refresh_tokens": `
+			-- Only a SHA-256 hash of each refresh token is stored. Rotating a token marks it used and
+			-- issues a successor in the same family; presenting a used token revokes the whole family.
+			CREATE TABLE IF NOT EXISTS refresh_tokens (
+				id SERIAL PRIMARY KEY,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				family_id VARCHAR(64) NOT NULL,
+				user_type VARCHAR(20) NOT NULL CHECK (user_type IN ('employee', 'customer')),
+				employee_id INT,
+				customer_id INT,
+				access_jti VARCHAR(64) NOT NULL, -- Access token issued alongside, revoked with the family
+				access_expires_at TIMESTAMPTZ NOT NULL,
+				issued_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMPTZ NOT NULL,
+				used_at TIMESTAMPTZ,
+				revoked_at TIMESTAMPTZ,
+				CONSTRAINT check_refresh_token_owner CHECK ((employee_id IS NULL) <> (customer_id IS NULL)),
+				FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+			CREATE INDEX IF NOT EXISTS idx_refresh_tokens_employee_id ON refresh_tokens(employee_id);
+			CREATE INDEX IF NOT EXISTS idx_refresh_tokens_customer_id ON refresh_tokens(customer_id);
+
+			-- Revoked access tokens by jti, kept until they would have expired anyway.
+			CREATE TABLE IF NOT EXISTS revoked_tokens (
+				jti VARCHAR(64) PRIMARY KEY,
+				expires_at TIMESTAMPTZ NOT NULL,
+				revoked_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+		`,
+		"one_time_tokens": `
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+			ALTER TABLE employees ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+			-- Single-use password reset and email verification tokens, stored as SHA-256 hashes.
+			CREATE TABLE IF NOT EXISTS one_time_tokens (
+				id SERIAL PRIMARY KEY,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				purpose VARCHAR(30) NOT NULL CHECK (purpose IN ('password_reset', 'email_verification', 'mfa_challenge', 'mfa_enrollment')),
+				user_type VARCHAR(20) NOT NULL CHECK (user_type IN ('employee', 'customer')),
+				employee_id INT,
+				customer_id INT,
+				email VARCHAR(100) NOT NULL, -- Address the token was sent to
+				expires_at TIMESTAMPTZ NOT NULL,
+				used_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				CONSTRAINT check_one_time_token_owner CHECK ((employee_id IS NULL) <> (customer_id IS NULL)),
+				FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS idx_one_time_tokens_employee_id ON one_time_tokens(employee_id);
+			CREATE INDEX IF NOT EXISTS idx_one_time_tokens_customer_id ON one_time_tokens(customer_id);
+			ALTER TABLE one_time_tokens DROP CONSTRAINT IF EXISTS one_time_tokens_purpose_check;
+			ALTER TABLE one_time_tokens ADD CONSTRAINT one_time_tokens_purpose_check CHECK (purpose IN ('password_reset', 'email_verification', 'mfa_challenge', 'mfa_enrollment'));
+			ALTER TABLE one_time_tokens ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0; -- Failed MFA codes against a challenge
+		`,
+		"employee_mfa": `
+			CREATE TABLE IF NOT EXISTS employee_mfa (
+				employee_id INT PRIMARY KEY,
+				secret VARCHAR(64) NOT NULL, -- Base32 TOTP secret
+				confirmed_at TIMESTAMPTZ, -- NULL while enrollment is pending
+				last_used_step BIGINT, -- TOTP time step of the last accepted code, to block replays
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE
+			);
+
+			CREATE TABLE IF NOT EXISTS employee_recovery_codes (
+				id SERIAL PRIMARY KEY,
+				employee_id INT NOT NULL,
+				code_hash CHAR(64) NOT NULL,
+				used_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (employee_id, code_hash),
+				FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE
+			);
+
+			-- Roles listed here with required = TRUE must complete MFA enrollment before they can log in.
+			CREATE TABLE IF NOT EXISTS mfa_policies (
+				role VARCHAR(50) PRIMARY KEY,
+				required BOOLEAN NOT NULL DEFAULT FALSE,
+				updated_by_employee_id INT,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (updated_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+		`,
+		"login_attempts": `
+			-- Every password login, successful or not, keyed by the normalised email whether or not an account exists.
+			CREATE TABLE IF NOT EXISTS login_attempts (
+				id BIGSERIAL PRIMARY KEY,
+				user_type VARCHAR(20) NOT NULL CHECK (user_type IN ('employee', 'customer')),
+				email VARCHAR(254) NOT NULL,
+				ip_address VARCHAR(45) NOT NULL,
+				success BOOLEAN NOT NULL,
+				failure_reason VARCHAR(50), -- bad_credentials, account_locked, ip_throttled, oidc_rejected, account_suspended
+				attempted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts(user_type, email, attempted_at);
+			CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts(ip_address, attempted_at);
+
+			CREATE TABLE IF NOT EXISTS account_lockouts (
+				user_type VARCHAR(20) NOT NULL,
+				email VARCHAR(254) NOT NULL,
+				failed_count INT NOT NULL DEFAULT 0, -- Consecutive failures since the last success or unlock
+				locked_until TIMESTAMPTZ,
+				last_failed_at TIMESTAMPTZ,
+				PRIMARY KEY (user_type, email)
+			);
+		`,
+		"roles": `
+			CREATE TABLE IF NOT EXISTS roles (
+				name VARCHAR(50) PRIMARY KEY,
+				description TEXT NOT NULL DEFAULT '',
+				is_system BOOLEAN NOT NULL DEFAULT FALSE, -- System roles cannot be edited or deleted
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			DROP TRIGGER IF EXISTS update_roles_updated_at ON roles;
+			CREATE TRIGGER update_roles_updated_at BEFORE UPDATE ON roles FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+			CREATE TABLE IF NOT EXISTS permissions (
+				code VARCHAR(100) PRIMARY KEY,
+				description TEXT NOT NULL DEFAULT ''
+			);
+
+			CREATE TABLE IF NOT EXISTS role_permissions (
+				role VARCHAR(50) NOT NULL,
+				permission VARCHAR(100) NOT NULL,
+				PRIMARY KEY (role, permission),
+				FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE,
+				FOREIGN KEY (permission) REFERENCES permissions(code) ON DELETE CASCADE
+			);
+
+			INSERT INTO roles (name, description, is_system) VALUES
+				('admin', 'Full access, including user, role and security management', TRUE),
+				('manager', 'Day-to-day branch operations and reporting', FALSE)
+			ON CONFLICT (name) DO NOTHING;
+
+			-- The permission catalogue. manager_default grants a permission to the manager role when it
+			-- first appears, so later edits an admin makes to the manager role are not undone on restart.
+			WITH catalogue (code, description, manager_default) AS (VALUES
+				('branches:manage', 'Create, update and delete branches', TRUE),
+				('cars:manage', 'Create, update and delete cars', TRUE),
+				('customers:view', 'View customer records', TRUE),
+				('customers:update', 'Edit customer records', TRUE),
+				('customers:delete', 'Delete customers', TRUE),
+				('rentals:view', 'List all rentals', TRUE),
+				('rentals:confirm', 'Confirm booked rentals', TRUE),
+				('rentals:activate', 'Hand over cars and activate rentals', TRUE),
+				('rentals:return', 'Check in returned cars', TRUE),
+				('rentals:cancel', 'Cancel rentals on behalf of customers', TRUE),
+				('rentals:delete', 'Delete rentals', TRUE),
+				('payments:view', 'View payments', TRUE),
+				('payments:record', 'Record payments', TRUE),
+				('payments:refund', 'Refund payments', TRUE),
+				('payments:verify', 'Verify uploaded payment slips', TRUE),
+				('ledger:view', 'View ledger postings and the trial balance', TRUE),
+				('dashboard:view', 'View the dashboard', TRUE),
+				('reports:view', 'View revenue and performance reports', TRUE),
+				('corporate:view', 'View corporate accounts and invoices', TRUE),
+				('corporate:manage', 'Manage corporate accounts and their members', TRUE),
+				('corporate:invoice', 'Run corporate invoicing and record invoice payments', TRUE),
+				('reviews:moderate', 'View and delete any review', TRUE),
+				('users:manage', 'Manage employee accounts and their sessions', FALSE),
+				('roles:manage', 'Manage roles and their permissions', FALSE),
+				('security:manage', 'Manage MFA policies, login attempts and lockouts', FALSE),
+				('exchange_rates:manage', 'Set and import exchange rates', FALSE),
+				('branches:all', 'Act on every branch, not only the ones the employee is assigned to', FALSE),
+				('api_keys:manage', 'Issue and revoke API keys for integrations', FALSE),
+				('audit:view', 'View the audit log of staff actions', FALSE),
+				('deleted_records:manage', 'View and restore deleted cars, customers, branches, employees and rentals', FALSE),
+				('customers:erase', 'Process customer requests to erase their personal data', FALSE),
+				('customer_documents:review', 'View and approve customer driving licences and identity documents', TRUE),
+				('customers:flag', 'Flag and blacklist customers, and lift their flags', TRUE),
+				('customer_notes:write', 'Add, pin, edit and delete notes on customers and rentals', TRUE),
+				('customer_notes:restricted', 'Read and write restricted customer notes', TRUE),
+				('customers:merge', 'Merge duplicate customer accounts', FALSE),
+				('loyalty:manage', 'Add or remove loyalty points by hand', FALSE)
+			),
+			new_permissions AS (
+				INSERT INTO permissions (code, description) SELECT code, description FROM catalogue
+				ON CONFLICT (code) DO UPDATE SET description = EXCLUDED.description
+				RETURNING code, (xmax = 0) AS inserted
+			)
+			INSERT INTO role_permissions (role, permission)
+			SELECT 'manager', c.code FROM catalogue c
+			JOIN new_permissions n ON n.code = c.code AND n.inserted
+			WHERE c.manager_default AND EXISTS (SELECT 1 FROM roles WHERE name = 'manager')
+			ON CONFLICT DO NOTHING;
+
+			-- admin always holds every permission.
+			INSERT INTO role_permissions (role, permission) SELECT 'admin', code FROM permissions ON CONFLICT DO NOTHING;
+
+			-- Roles used to be a fixed CHECK list; they are now rows in roles.
+			ALTER TABLE employees DROP CONSTRAINT IF EXISTS employees_role_check;
+			INSERT INTO roles (name) SELECT DISTINCT role FROM employees ON CONFLICT (name) DO NOTHING;
+			ALTER TABLE employees DROP CONSTRAINT IF EXISTS fk_employees_role;
+			ALTER TABLE employees ADD CONSTRAINT fk_employees_role FOREIGN KEY (role) REFERENCES roles(name) ON DELETE RESTRICT;
+			DELETE FROM mfa_policies WHERE role NOT IN (SELECT name FROM roles);
+			ALTER TABLE mfa_policies DROP CONSTRAINT IF EXISTS fk_mfa_policies_role;
+			ALTER TABLE mfa_policies ADD CONSTRAINT fk_mfa_policies_role FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE;
+		`,
+		"employee_branches": `
+			-- Employees without branches:all only see and act on the branches they are assigned to.
+			DO $$
+			BEGIN
+				IF to_regclass('employee_branches') IS NULL THEN
+					CREATE TABLE employee_branches (
+						employee_id INT NOT NULL,
+						branch_id INT NOT NULL,
+						PRIMARY KEY (employee_id, branch_id),
+						FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE,
+						FOREIGN KEY (branch_id) REFERENCES branches(id) ON DELETE CASCADE
+					);
+					-- Until now every employee could work on every branch; keep existing staff that way.
+					INSERT INTO employee_branches (employee_id, branch_id) SELECT e.id, b.id FROM employees e CROSS JOIN branches b;
+				END IF;
+			END $$;
+			CREATE INDEX IF NOT EXISTS idx_employee_branches_branch_id ON employee_branches(branch_id);
+		`,
+		"oidc": `
+			-- Employees signing in through the identity provider are matched on issuer and subject.
+			ALTER TABLE employees ADD COLUMN IF NOT EXISTS oidc_issuer VARCHAR(255);
+			ALTER TABLE employees ADD COLUMN IF NOT EXISTS oidc_subject VARCHAR(255);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_employees_oidc_identity ON employees(oidc_issuer, oidc_subject) WHERE oidc_subject IS NOT NULL;
+
+			-- Pending authorization code logins: the state (hashed), PKCE verifier and nonce.
+			CREATE TABLE IF NOT EXISTS oidc_login_states (
+				state_hash CHAR(64) PRIMARY KEY,
+				code_verifier VARCHAR(128) NOT NULL,
+				nonce VARCHAR(128) NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires_at ON oidc_login_states(expires_at);
+		`,
+		"api_keys": `
+			-- Keys for machine-to-machine callers, sent as X-API-Key. Only a SHA-256 hash of the key is
+			-- stored; key_prefix is the non-secret start of the key, shown in lists to tell keys apart.
+			CREATE TABLE IF NOT EXISTS api_keys (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				key_prefix VARCHAR(20) NOT NULL UNIQUE,
+				key_hash CHAR(64) NOT NULL,
+				all_branches BOOLEAN NOT NULL DEFAULT TRUE, -- FALSE limits the key to its api_key_branches rows
+				created_by_employee_id INT,
+				expires_at TIMESTAMPTZ,
+				last_used_at TIMESTAMPTZ,
+				last_used_ip VARCHAR(45),
+				revoked_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (created_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+
+			CREATE TABLE IF NOT EXISTS api_key_permissions (
+				api_key_id INT NOT NULL,
+				permission VARCHAR(100) NOT NULL,
+				PRIMARY KEY (api_key_id, permission),
+				FOREIGN KEY (api_key_id) REFERENCES api_keys(id) ON DELETE CASCADE,
+				FOREIGN KEY (permission) REFERENCES permissions(code) ON DELETE CASCADE
+			);
+
+			CREATE TABLE IF NOT EXISTS api_key_branches (
+				api_key_id INT NOT NULL,
+				branch_id INT NOT NULL,
+				PRIMARY KEY (api_key_id, branch_id),
+				FOREIGN KEY (api_key_id) REFERENCES api_keys(id) ON DELETE CASCADE,
+				FOREIGN KEY (branch_id) REFERENCES branches(id) ON DELETE CASCADE
+			);
+		`,
+		"sessions": `
+			-- One row per login (refresh token family), so users can see and end their sessions.
+			-- id is the family_id of the session's refresh tokens and the sid claim of its access tokens.
+			CREATE TABLE IF NOT EXISTS sessions (
+				id VARCHAR(64) PRIMARY KEY,
+				user_type VARCHAR(20) NOT NULL CHECK (user_type IN ('employee', 'customer')),
+				employee_id INT,
+				customer_id INT,
+				user_agent VARCHAR(512) NOT NULL DEFAULT '',
+				ip_address VARCHAR(45) NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMPTZ NOT NULL, -- Moves forward with every refresh
+				revoked_at TIMESTAMPTZ,
+				CONSTRAINT check_session_owner CHECK ((employee_id IS NULL) <> (customer_id IS NULL)),
+				FOREIGN KEY (employee_id) REFERENCES employees(id) ON DELETE CASCADE,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS idx_sessions_employee_id ON sessions(employee_id);
+			CREATE INDEX IF NOT EXISTS idx_sessions_customer_id ON sessions(customer_id);
+		`,
+		"audit_log": `
+			-- Append-only record of staff and integration changes. Actor and entity ids are not
+			-- foreign keys so entries outlive the rows they describe.
+			CREATE TABLE IF NOT EXISTS audit_log (
+				id BIGSERIAL PRIMARY KEY,
+				actor_type VARCHAR(20) NOT NULL CHECK (actor_type IN ('employee', 'integration')),
+				actor_employee_id INT,
+				actor_api_key_id INT,
+				actor_email VARCHAR(255),
+				action VARCHAR(100) NOT NULL,
+				entity_type VARCHAR(50) NOT NULL,
+				entity_id VARCHAR(100),
+				before_data JSONB,
+				after_data JSONB,
+				http_method VARCHAR(10) NOT NULL,
+				http_path VARCHAR(255) NOT NULL,
+				status_code INT NOT NULL,
+				ip_address VARCHAR(45) NOT NULL DEFAULT '',
+				request_id VARCHAR(64) NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+			CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+			CREATE INDEX IF NOT EXISTS idx_audit_log_actor_employee_id ON audit_log(actor_employee_id);
+			CREATE INDEX IF NOT EXISTS idx_audit_log_request_id ON audit_log(request_id);
+
+			-- The one exception: erasing a customer masks their personal data in the recorded
+			-- states of entries written before it was masked on the way in. The transaction sets
+			-- car_rental.audit_erasure, and nothing but before_data and after_data may change.
+			CREATE OR REPLACE FUNCTION prevent_audit_log_mutation()
+			RETURNS TRIGGER AS $$
+			BEGIN
+			   IF TG_OP = 'UPDATE' AND current_setting('car_rental.audit_erasure', true) = 'on'
+			      AND to_jsonb(NEW) - 'before_data' - 'after_data' = to_jsonb(OLD) - 'before_data' - 'after_data' THEN
+			      RETURN NEW;
+			   END IF;
+			   RAISE EXCEPTION 'audit log rows are append-only (% on %)', TG_OP, TG_TABLE_NAME;
+			END;
+			$$ language 'plpgsql';
+			DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+			CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log FOR EACH ROW EXECUTE FUNCTION prevent_audit_log_mutation();
+		`,
+		"soft_delete": `
+			-- Deleting these keeps the row with deleted_at set; the retention job removes it for good later.
+			ALTER TABLE branches ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+			ALTER TABLE employees ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+			ALTER TABLE cars ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+			CREATE INDEX IF NOT EXISTS idx_branches_deleted_at ON branches(deleted_at) WHERE deleted_at IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_employees_deleted_at ON employees(deleted_at) WHERE deleted_at IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_customers_deleted_at ON customers(deleted_at) WHERE deleted_at IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_cars_deleted_at ON cars(deleted_at) WHERE deleted_at IS NOT NULL;
+			CREATE INDEX IF NOT EXISTS idx_rentals_deleted_at ON rentals(deleted_at) WHERE deleted_at IS NOT NULL;
+		`,
+		"customer_erasure": `
+			-- Erasing a customer anonymises their row in place, so rentals and payments stay intact for accounting.
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS erased_at TIMESTAMPTZ;
+
+			-- A customer's request to have their personal data erased, completed or rejected by staff.
+			CREATE TABLE IF NOT EXISTS customer_erasure_requests (
+				id SERIAL PRIMARY KEY,
+				customer_id INT NOT NULL,
+				status VARCHAR(20) NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending', 'Completed', 'Rejected')),
+				reason TEXT NOT NULL DEFAULT '',
+				requested_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				processed_at TIMESTAMPTZ,
+				processed_by_employee_id INT,
+				rejection_reason TEXT,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+				FOREIGN KEY (processed_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_erasure_requests_one_pending ON customer_erasure_requests(customer_id) WHERE status = 'Pending';
+			CREATE INDEX IF NOT EXISTS idx_customer_erasure_requests_status ON customer_erasure_requests(status, requested_at);
+		`,
+		"pii_encryption": `
+			-- email and phone may hold ciphertext, which is longer than the plaintext. The *_bidx columns
+			-- are keyed hashes of the normalised plaintext, so encrypted values can still be looked up;
+			-- they are NULL for rows written without a keyring.
+			ALTER TABLE customers ALTER COLUMN email TYPE TEXT;
+			ALTER TABLE customers ALTER COLUMN phone TYPE TEXT;
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_bidx VARCHAR(64);
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS phone_bidx VARCHAR(64);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_email_bidx ON customers(email_bidx);
+			CREATE INDEX IF NOT EXISTS idx_customers_phone_bidx ON customers(phone_bidx);
+		`,
+		"customer_documents": `
+			-- Driving licences and identity documents uploaded by customers. The files live under
+			-- ./private/customer_documents, outside the public uploads directory.
+			CREATE TABLE IF NOT EXISTS customer_documents (
+				id SERIAL PRIMARY KEY,
+				customer_id INT NOT NULL,
+				document_type VARCHAR(20) NOT NULL CHECK (document_type IN ('licence_front', 'licence_back', 'id_document')),
+				file_name VARCHAR(255) NOT NULL,
+				content_type VARCHAR(50) NOT NULL,
+				status VARCHAR(20) NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending', 'Approved', 'Rejected', 'Superseded')),
+				expires_on DATE,
+				document_number TEXT, -- Encrypted like customers.email
+				reviewed_by_employee_id INT,
+				reviewed_at TIMESTAMPTZ,
+				rejection_reason TEXT,
+				uploaded_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+				FOREIGN KEY (reviewed_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_customer_documents_customer ON customer_documents(customer_id, document_type, status);
+			CREATE INDEX IF NOT EXISTS idx_customer_documents_status ON customer_documents(status, uploaded_at);
+		`,
+		"customer_risk_flags": `
+			-- Warnings about customers (watch) and bans (blacklist). A flag is active while lifted_at is
+			-- NULL and expires_at is NULL or still ahead.
+			CREATE TABLE IF NOT EXISTS customer_risk_flags (
+				id SERIAL PRIMARY KEY,
+				customer_id INT NOT NULL,
+				flag_type VARCHAR(20) NOT NULL CHECK (flag_type IN ('watch', 'blacklist')),
+				category VARCHAR(30) NOT NULL CHECK (category IN ('vehicle_damage', 'payment_fraud', 'unpaid_balance', 'abusive_behaviour', 'other')),
+				reason TEXT NOT NULL,
+				set_by_employee_id INT,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMPTZ,
+				lifted_at TIMESTAMPTZ,
+				lifted_by_employee_id INT,
+				lift_reason TEXT,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+				FOREIGN KEY (set_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL,
+				FOREIGN KEY (lifted_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_customer_risk_flags_customer ON customer_risk_flags(customer_id, flag_type) WHERE lifted_at IS NULL;
+		`,
+		"customer_notes": `
+			-- Staff notes on a customer, optionally about one of their rentals. Restricted notes are only
+			-- shown to their author and to holders of customer_notes:restricted.
+			CREATE TABLE IF NOT EXISTS customer_notes (
+				id SERIAL PRIMARY KEY,
+				customer_id INT NOT NULL,
+				rental_id INT,
+				author_employee_id INT,
+				body TEXT NOT NULL,
+				visibility VARCHAR(20) NOT NULL DEFAULT 'staff' CHECK (visibility IN ('staff', 'restricted')),
+				pinned BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+				FOREIGN KEY (rental_id) REFERENCES rentals(id) ON DELETE CASCADE,
+				FOREIGN KEY (author_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+			DROP TRIGGER IF EXISTS update_customer_notes_updated_at ON customer_notes;
+			CREATE TRIGGER update_customer_notes_updated_at BEFORE UPDATE ON customer_notes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+			CREATE INDEX IF NOT EXISTS idx_customer_notes_customer ON customer_notes(customer_id, created_at);
+			CREATE INDEX IF NOT EXISTS idx_customer_notes_rental ON customer_notes(rental_id) WHERE rental_id IS NOT NULL;
+
+			-- Every rental status change, wherever in the code it happens, for customer timelines.
+			-- Rentals created before this table existed only have changes from then on.
+			CREATE TABLE IF NOT EXISTS rental_status_history (
+				id BIGSERIAL PRIMARY KEY,
+				rental_id INT NOT NULL,
+				from_status VARCHAR(50), -- NULL for the status a rental was created with
+				to_status VARCHAR(50) NOT NULL,
+				changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (rental_id) REFERENCES rentals(id) ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS idx_rental_status_history_rental ON rental_status_history(rental_id, changed_at);
+
+			CREATE OR REPLACE FUNCTION record_rental_status_change()
+			RETURNS TRIGGER AS $$
+			BEGIN
+			   IF TG_OP = 'INSERT' THEN
+			      INSERT INTO rental_status_history (rental_id, to_status) VALUES (NEW.id, NEW.status);
+			   ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+			      INSERT INTO rental_status_history (rental_id, from_status, to_status) VALUES (NEW.id, OLD.status, NEW.status);
+			   END IF;
+			   RETURN NEW;
+			END;
+			$$ language 'plpgsql';
+			DROP TRIGGER IF EXISTS rentals_status_history ON rentals;
+			CREATE TRIGGER rentals_status_history AFTER INSERT OR UPDATE OF status ON rentals FOR EACH ROW EXECUTE FUNCTION record_rental_status_change();
+		`,
+		"customer_merge": `
+			-- A customer merged into another is soft-deleted and points at the account that absorbed
+			-- their rentals, so it is not restored or merged again by mistake.
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS merged_into_customer_id INT REFERENCES customers(id) ON DELETE SET NULL;
+			-- Plaintext rows (written without a keyring) are looked up by their normalised email.
+			CREATE INDEX IF NOT EXISTS idx_customers_email_normalised ON customers(LOWER(TRIM(email))) WHERE email_bidx IS NULL;
+		`,
+		"loyalty": `
+			-- Tiers go by the points a customer earned in the last 12 months. Their benefits apply to
+			-- personal bookings, not ones billed to a corporate account.
+			CREATE TABLE IF NOT EXISTS loyalty_tiers (
+				code VARCHAR(20) PRIMARY KEY,
+				name VARCHAR(50) NOT NULL,
+				min_points INT NOT NULL UNIQUE CHECK (min_points >= 0),
+				discount_percent INT NOT NULL DEFAULT 0 CHECK (discount_percent BETWEEN 0 AND 100),
+				free_upgrade BOOLEAN NOT NULL DEFAULT FALSE
+			);
+			INSERT INTO loyalty_tiers (code, name, min_points, discount_percent, free_upgrade) VALUES
+				('member', 'Member', 0, 0, FALSE),
+				('silver', 'Silver', 1000, 5, FALSE),
+				('gold', 'Gold', 5000, 10, TRUE)
+			ON CONFLICT (code) DO NOTHING;
+
+			-- The points ledger; a customer's balance is the sum of points. Rows adding points (earn,
+			-- reinstate and positive adjust) are lots: remaining is what is left of one, spent soonest
+			-- expiring first, and an expire row takes the rest once expires_at passes.
+			CREATE TABLE IF NOT EXISTS loyalty_transactions (
+				id BIGSERIAL PRIMARY KEY,
+				customer_id INT NOT NULL,
+				rental_id INT,
+				entry_type VARCHAR(20) NOT NULL CHECK (entry_type IN ('earn', 'redeem', 'reinstate', 'reverse', 'expire', 'adjust')),
+				points INT NOT NULL CHECK (points <> 0),
+				remaining INT NOT NULL DEFAULT 0 CHECK (remaining >= 0 AND remaining <= GREATEST(points, 0)),
+				expires_at TIMESTAMPTZ,
+				description TEXT NOT NULL,
+				created_by_employee_id INT,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+				FOREIGN KEY (rental_id) REFERENCES rentals(id) ON DELETE SET NULL,
+				FOREIGN KEY (created_by_employee_id) REFERENCES employees(id) ON DELETE SET NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_customer ON loyalty_transactions(customer_id, created_at);
+			CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_lots ON loyalty_transactions(customer_id, expires_at) WHERE remaining > 0;
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_transactions_once_per_rental ON loyalty_transactions(rental_id, entry_type) WHERE entry_type IN ('earn', 'reinstate');
+
+			-- What loyalty took off a rental's net price, fixed at booking.
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS loyalty_tier VARCHAR(20);
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS loyalty_discount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (loyalty_discount >= 0);
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS points_redeemed INT NOT NULL DEFAULT 0 CHECK (points_redeemed >= 0);
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS points_discount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (points_discount >= 0);
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS loyalty_free_upgrade BOOLEAN NOT NULL DEFAULT FALSE;
+		`,
+		"referrals": `
+			-- Codes are handed out at registration, or when a customer first asks for theirs.
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS referral_code VARCHAR(16);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_referral_code ON customers(referral_code);
+
+			-- One row per customer who registered with a code. It stays pending until the referee's
+			-- first rental is returned, when the referrer is credited; a failed fraud check rejects it.
+			-- The registration address and browser are kept for the device check and for review.
+			CREATE TABLE IF NOT EXISTS referrals (
+				id SERIAL PRIMARY KEY,
+				referrer_customer_id INT NOT NULL,
+				referee_customer_id INT NOT NULL UNIQUE,
+				referral_code VARCHAR(16) NOT NULL,
+				status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'converted', 'rejected')),
+				rejection_reason TEXT,
+				registration_ip VARCHAR(45) NOT NULL DEFAULT '',
+				registration_user_agent VARCHAR(512) NOT NULL DEFAULT '',
+				converted_rental_id INT,
+				reward_points INT NOT NULL DEFAULT 0,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				converted_at TIMESTAMPTZ,
+				FOREIGN KEY (referrer_customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+				FOREIGN KEY (referee_customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+				FOREIGN KEY (converted_rental_id) REFERENCES rentals(id) ON DELETE SET NULL,
+				CONSTRAINT check_referral_not_self CHECK (referrer_customer_id <> referee_customer_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_referrals_referrer ON referrals(referrer_customer_id);
+			CREATE INDEX IF NOT EXISTS idx_referrals_created_at ON referrals(created_at);
+
+			-- The referee's first-rental discount, off the net price like the loyalty ones.
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS referral_discount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (referral_discount >= 0);
+
+			-- The referrer's credit is paid in loyalty points.
+			ALTER TABLE loyalty_transactions DROP CONSTRAINT IF EXISTS loyalty_transactions_entry_type_check;
+			ALTER TABLE loyalty_transactions ADD CONSTRAINT loyalty_transactions_entry_type_check
+				CHECK (entry_type IN ('earn', 'redeem', 'reinstate', 'reverse', 'expire', 'adjust', 'referral'));
+		`,
+		"customer_billing": `
+			-- Printed as the buyer on a customer's own tax invoices; rentals billed to a corporate
+			-- account use the account's. tax_id is encrypted like the contact details.
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS billing_address TEXT;
+			ALTER TABLE customers ADD COLUMN IF NOT EXISTS tax_id TEXT;
+		`,
+		"booked_prices": `
+			-- A rental's price as booked: the car's daily rate at the time and the net (after
+			-- discounts), VAT and total. Rentals booked before these columns existed are priced at
+			-- the car's current rate, the same way billableRentalDays and vatOnNet would.
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS booked_price_per_day DECIMAL(10,2);
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS booked_net DECIMAL(12,2);
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS booked_vat DECIMAL(12,2);
+			ALTER TABLE rentals ADD COLUMN IF NOT EXISTS booked_total DECIMAL(12,2);
+			UPDATE rentals r SET booked_price_per_day = c.price_per_day,
+				booked_net = GREATEST(c.price_per_day * GREATEST(CEIL(EXTRACT(EPOCH FROM r.dropoff_datetime - r.pickup_datetime) / 86400), 1)
+					- r.loyalty_discount - r.points_discount - r.referral_discount, 0)
+				FROM cars c WHERE c.id = r.car_id AND r.booked_net IS NULL;
+			UPDATE rentals SET booked_vat = ROUND(booked_net * 7 / 100, 2) WHERE booked_vat IS NULL;
+			UPDATE rentals SET booked_total = booked_net + booked_vat WHERE booked_total IS NULL;
+			ALTER TABLE rentals ALTER COLUMN booked_price_per_day SET NOT NULL;
+			ALTER TABLE rentals ALTER COLUMN booked_net SET NOT NULL;
+			ALTER TABLE rentals ALTER COLUMN booked_vat SET NOT NULL;
+			ALTER TABLE rentals ALTER COLUMN booked_total SET NOT NULL;
+		`,
+		"ledger_transactions": `
+			-- rental_id/payment_id/created_by_employee_id are deliberately not foreign keys:
+			-- ledger history must survive deletion of the rows it refers to.
+			CREATE TABLE IF NOT EXISTS ledger_transactions (
+				id SERIAL PRIMARY KEY,
+				rental_id INT,
+				payment_id INT,
+				kind VARCHAR(30) NOT NULL,
+				description TEXT NOT NULL DEFAULT '',
+				created_by_employee_id INT,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_ledger_transactions_rental_id ON ledger_transactions(rental_id);
+			CREATE INDEX IF NOT EXISTS idx_ledger_transactions_created_at ON ledger_transactions(created_at);
+		`,
+		"ledger_entries": `
+			CREATE TABLE IF NOT EXISTS ledger_entries (
+				id SERIAL PRIMARY KEY,
+				transaction_id INT NOT NULL,
+				account_code VARCHAR(50) NOT NULL,
+				debit DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (debit >= 0),
+				credit DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (credit >= 0),
+				CONSTRAINT check_ledger_entry_one_side CHECK ((debit = 0) <> (credit = 0)),
+				FOREIGN KEY (transaction_id) REFERENCES ledger_transactions(id) ON DELETE RESTRICT,
+				FOREIGN KEY (account_code) REFERENCES ledger_accounts(code) ON DELETE RESTRICT
+			);
+			CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+			CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_code ON ledger_entries(account_code);
+
+			-- The ledger is append-only: corrections are posted as new reversing transactions.
+			CREATE OR REPLACE FUNCTION prevent_ledger_mutation()
+			RETURNS TRIGGER AS $$
+			BEGIN
+			   RAISE EXCEPTION 'ledger rows are append-only (% on %)', TG_OP, TG_TABLE_NAME;
+			END;
+			$$ language 'plpgsql';
+			DROP TRIGGER IF EXISTS ledger_transactions_append_only ON ledger_transactions;
+			CREATE TRIGGER ledger_transactions_append_only BEFORE UPDATE OR DELETE ON ledger_transactions FOR EACH ROW EXECUTE FUNCTION prevent_ledger_mutation();
+			DROP TRIGGER IF EXISTS ledger_entries_append_only ON ledger_entries;
+			CREATE TRIGGER ledger_entries_append_only BEFORE UPDATE OR DELETE ON ledger_entries FOR EACH ROW EXECUTE FUNCTION prevent_ledger_mutation();
+		`,
 	}
 
-	tableOrder := []string{"branches", "employees", "customers", "cars", "rentals", "payments", "reviews"}
+	tableOrder := []string{"branches", "employees", "customers", "cars", "rentals", "payments", "exchange_rates", "reviews", "ledger_accounts", "ledger_transactions", "ledger_entries", "invoice_sequences", "invoices", "corporate_accounts", "refresh_tokens", "one_time_tokens", "employee_mfa", "login_attempts", "roles", "employee_branches", "oidc", "api_keys", "sessions", "audit_log", "soft_delete", "customer_erasure", "pii_encryption", "customer_documents", "customer_risk_flags", "customer_notes", "customer_merge", "loyalty", "referrals", "customer_billing", "booked_prices"}
 
 	for _, tableName := range tableOrder {
 		sqlStmt := schemas[tableName]
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// JWTKey is a key tokens are signed or verified with. Private is only set for the signing key.
+type JWTKey struct {
+	KID       string
+	Algorithm string // "RS256" or "EdDSA"
+	Public    crypto.PublicKey
+	Private   crypto.Signer
+}
+
+// JWK is the public half of a JWTKey as published in the JWKS document (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KID       string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+var (
+	signingKey       *JWTKey
+	verificationKeys = map[string]*JWTKey{}
+)
+
+// LoadJWTKeys reads the token signing key from the PEM file named by JWT_SIGNING_KEY_FILE
+// (an RSA or Ed25519 private key) and any further verification keys from the comma-separated
+// PEM files in JWT_VERIFICATION_KEY_FILES. To rotate without logging anyone out, add the new
+// key's public half to JWT_VERIFICATION_KEY_FILES everywhere, then make it the signing key and
+// keep the old key as a verification key until the longest access token lifetime has passed.
+// Key IDs are derived from the public key, so every instance agrees on them.
+func LoadJWTKeys() {
+	if os.Getenv("JWT_SECRET") != "" {
+		log.Println("⚠️ JWT_SECRET is set but no longer used: tokens are signed with JWT_SIGNING_KEY_FILE. Tokens signed with the old secret are rejected.")
+	}
+
+	signingKey = nil
+	verificationKeys = map[string]*JWTKey{}
+
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		key, err := loadJWTKeyFile(path)
+		if err != nil {
+			log.Fatalf("❌ Failed to load JWT signing key: %v", err)
+		}
+		if key.Private == nil {
+			log.Fatalf("❌ JWT_SIGNING_KEY_FILE %s holds a public key; a private key is required", path)
+		}
+		signingKey = key
+	} else {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			log.Fatalf("❌ Failed to generate development JWT key: %v", err)
+		}
+		signingKey = newJWTKey(private.Public(), private)
+		log.Println("🚨 CRITICAL WARNING: JWT_SIGNING_KEY_FILE not set. Using a temporary key: tokens stop working on restart and are not accepted by other instances. Set it in production.")
+	}
+	verificationKeys[signingKey.KID] = signingKey
+	log.Printf("🔑 Signing tokens with %s key %s", signingKey.Algorithm, signingKey.KID)
+
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := loadJWTKeyFile(path)
+		if err != nil {
+			log.Fatalf("❌ Failed to load JWT verification key: %v", err)
+		}
+		key.Private = nil // Only ever used to verify
+		if _, exists := verificationKeys[key.KID]; !exists {
+			verificationKeys[key.KID] = key
+			log.Printf("🔑 Accepting tokens signed with %s key %s", key.Algorithm, key.KID)
+		}
+	}
+}
+
+// JWTSigningKey is the key new tokens are signed with.
+func JWTSigningKey() *JWTKey {
+	return signingKey
+}
+
+// JWTVerificationKey returns the key with the given ID, or false if tokens signed with it are not accepted.
+func JWTVerificationKey(kid string) (*JWTKey, bool) {
+	key, ok := verificationKeys[kid]
+	return key, ok
+}
+
+// JWKS returns the public keys tokens are verified with, signing key first.
+func JWKS() []JWK {
+	if signingKey == nil {
+		return []JWK{}
+	}
+	keys := []JWK{signingKey.JWK()}
+	for kid, key := range verificationKeys {
+		if kid != signingKey.KID {
+			keys = append(keys, key.JWK())
+		}
+	}
+	return keys
+}
+
+// JWK returns the public key in JWK form.
+func (k *JWTKey) JWK() JWK {
+	jwk := JWK{KID: k.KID, Use: "sig", Algorithm: k.Algorithm}
+	switch public := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// loadJWTKeyFile parses the first PEM block of path as a PKCS#8 or PKCS#1 private key or a PKIX public key.
+func loadJWTKeyFile(path string) (*JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s contains no PEM data", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", path)
+		}
+		return newJWTKey(&key.PublicKey, key), nil
+	case ed25519.PrivateKey:
+		return newJWTKey(key.Public(), key), nil
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", path)
+		}
+		return newJWTKey(key, nil), nil
+	case ed25519.PublicKey:
+		return newJWTKey(key, nil), nil
+	}
+	return nil, errors.New(path + ": only RSA and Ed25519 keys are supported")
+}
+
+// newJWTKey picks the algorithm for the key type and derives the key ID from the public key.
+func newJWTKey(public crypto.PublicKey, private crypto.Signer) *JWTKey {
+	key := &JWTKey{Public: public, Private: private, Algorithm: "EdDSA"}
+	if _, ok := public.(*rsa.PublicKey); ok {
+		key.Algorithm = "RS256"
+	}
+	der, _ := x509.MarshalPKIXPublicKey(public)
+	sum := sha256.Sum256(der)
+	key.KID = base64.RawURLEncoding.EncodeToString(sum[:12])
+	return key
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+// GroupRole maps an identity provider group to an employee role.
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// OIDCSettings configures employee login through an OpenID Connect identity provider.
+type OIDCSettings struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string // Optional: PKCE alone is enough for a public client
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	GroupRoles   []GroupRole // In priority order: the first group the employee is in decides the role
+	DefaultRole  string      // Role for employees in none of the mapped groups; empty refuses them
+	JITProvision bool        // Create employees on their first login
+}
+
+// Enabled reports whether OIDC login is configured.
+func (s OIDCSettings) Enabled() bool {
+	return s.IssuerURL != "" && s.ClientID != ""
+}
+
+// OIDC reads the OIDC settings from the environment:
+//
+//	OIDC_ISSUER_URL, OIDC_CLIENT_ID   required to enable OIDC login
+//	OIDC_CLIENT_SECRET               for confidential clients
+//	OIDC_REDIRECT_URL                default APP_BASE_URL + "/auth/oidc/callback"
+//	OIDC_SCOPES                      space separated, default "openid email profile groups"
+//	OIDC_GROUPS_CLAIM                ID token claim listing the user's groups, default "groups"
+//	OIDC_GROUP_ROLES                 e.g. "rental-admins=admin,rental-staff=manager"
+//	OIDC_DEFAULT_ROLE                role for users in none of those groups
+//	OIDC_JIT_PROVISIONING            "false" to only let existing employees in
+func OIDC() OIDCSettings {
+	settings := OIDCSettings{
+		IssuerURL:    strings.TrimRight(os.Getenv("OIDC_ISSUER_URL"), "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+		DefaultRole:  strings.TrimSpace(os.Getenv("OIDC_DEFAULT_ROLE")),
+		JITProvision: os.Getenv("OIDC_JIT_PROVISIONING") != "false",
+	}
+	if settings.RedirectURL == "" {
+		settings.RedirectURL = AppBaseURL() + "/auth/oidc/callback"
+	}
+	if len(settings.Scopes) == 0 {
+		settings.Scopes = []string{"openid", "email", "profile", "groups"}
+	}
+	if settings.GroupsClaim == "" {
+		settings.GroupsClaim = "groups"
+	}
+	for _, pair := range strings.Split(os.Getenv("OIDC_GROUP_ROLES"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(group) == "" || strings.TrimSpace(role) == "" {
+			log.Printf("⚠️ Ignoring malformed OIDC_GROUP_ROLES entry '%s' (expected group=role)", pair)
+			continue
+		}
+		settings.GroupRoles = append(settings.GroupRoles, GroupRole{Group: strings.TrimSpace(group), Role: strings.TrimSpace(role)})
+	}
+	return settings
+}
+
+// EmployeePasswordLoginEnabled reports whether employees may still sign in with a password.
+// Set EMPLOYEE_PASSWORD_LOGIN=disabled once everyone signs in through the identity provider.
+func EmployeePasswordLoginEnabled() bool {
+	return os.Getenv("EMPLOYEE_PASSWORD_LOGIN") != "disabled"
+}
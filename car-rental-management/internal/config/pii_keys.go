@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// PIIKeyring holds the keys personal data is encrypted with. Each value is encrypted under its own
+// data key, which is wrapped with a key-encryption key (KEK) from Keys; Active names the KEK new
+// values are wrapped with. BlindIndexKey keys the HMACs that let encrypted columns be searched.
+type PIIKeyring struct {
+	Active        string
+	Keys          map[string][]byte
+	BlindIndexKey []byte
+}
+
+// piiKeyringFile is the JSON layout of PII_KEYRING_FILE. Keys are base64-encoded 32-byte values:
+//
+//	{"active_key": "2026-01", "keys": {"2025-06": "...", "2026-01": "..."}, "blind_index_key": "..."}
+type piiKeyringFile struct {
+	ActiveKey     string            `json:"active_key"`
+	Keys          map[string]string `json:"keys"`
+	BlindIndexKey string            `json:"blind_index_key"`
+}
+
+var piiKeyring *PIIKeyring
+
+// LoadPIIKeyring reads the keyring from the file named by PII_KEYRING_FILE. To rotate, add a new
+// key, make it active_key, restart, then run cmd/reencrypt-pii; old keys can be removed once it
+// reports no failures and no skipped rows. The blind index key cannot be rotated this way, since every
+// index would have to be recomputed from the plaintext. Without a keyring, personal data is
+// stored unencrypted.
+func LoadPIIKeyring() {
+	piiKeyring = nil
+	path := os.Getenv("PII_KEYRING_FILE")
+	if path == "" {
+		log.Println("🚨 CRITICAL WARNING: PII_KEYRING_FILE not set. Customer personal data is stored unencrypted. Set it in production.")
+		return
+	}
+	keyring, err := loadPIIKeyringFile(path)
+	if err != nil {
+		log.Fatalf("❌ Failed to load PII keyring: %v", err)
+	}
+	piiKeyring = keyring
+	log.Printf("🔐 Encrypting personal data with key %s (%d keys in keyring)", keyring.Active, len(keyring.Keys))
+}
+
+// PIIKeys returns the loaded keyring, or nil when personal data is not encrypted.
+func PIIKeys() *PIIKeyring {
+	return piiKeyring
+}
+
+func loadPIIKeyringFile(path string) (*PIIKeyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var file piiKeyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	keyring := &PIIKeyring{Active: file.ActiveKey, Keys: map[string][]byte{}}
+	for id, encoded := range file.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%s: key IDs must be non-empty and must not contain ':'", path)
+		}
+		if keyring.Keys[id], err = decodePIIKey(encoded); err != nil {
+			return nil, fmt.Errorf("%s: key %s: %w", path, id, err)
+		}
+	}
+	if _, ok := keyring.Keys[keyring.Active]; !ok {
+		return nil, fmt.Errorf("%s: active_key %q is not in keys", path, keyring.Active)
+	}
+	if keyring.BlindIndexKey, err = decodePIIKey(file.BlindIndexKey); err != nil {
+		return nil, fmt.Errorf("%s: blind_index_key: %w", path, err)
+	}
+	return keyring, nil
+}
+
+func decodePIIKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyErrorStatus maps API key service errors to HTTP status codes.
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUnknownPermission), errors.Is(err, services.ErrAPIKeyExpiryInPast),
+		errors.Is(err, services.ErrAPIKeyBranchesRequired), err.Error() == "one or more branches do not exist",
+		err.Error() == "API key name cannot be empty":
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrAPIKeyPermissionDenied), errors.Is(err, services.ErrAPIKeyPermissionNotAllowed),
+		errors.Is(err, services.ErrBranchForbidden):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// respondAPIKeyError writes err with its mapped status, hiding internal error details.
+func respondAPIKeyError(c *gin.Context, err error, fallback string) {
+	statusCode := apiKeyErrorStatus(err)
+	if statusCode == http.StatusInternalServerError {
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(statusCode, gin.H{"error": fallback})
+		return
+	}
+	c.JSON(statusCode, gin.H{"error": err.Error()})
+}
+
+// HandleGetAPIKeys handles GET /api-keys
+func HandleGetAPIKeys(c *gin.Context) {
+	keys, err := services.GetAPIKeys()
+	if err != nil {
+		respondAPIKeyError(c, err, "Failed to fetch API keys")
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// HandleCreateAPIKey handles POST /api-keys. The key is only ever shown in this response.
+func HandleCreateAPIKey(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("employee_id")
+	employeeID, ok := employeeIDInterface.(int)
+	if !exists || !ok || employeeID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Employee authentication required"})
+		return
+	}
+	var input models.CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	role, _ := c.Get("user_role")
+	roleName, _ := role.(string)
+
+	created, err := services.CreateAPIKey(input, employeeID, roleName, scope)
+	if err != nil {
+		respondAPIKeyError(c, err, "Failed to create API key")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// HandleRevokeAPIKey handles DELETE /api-keys/:id
+func HandleRevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+	if err := services.RevokeAPIKey(id); err != nil {
+		respondAPIKeyError(c, err, "Failed to revoke API key")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setAuditChange describes the change a staff handler made for middleware.AuditStaffActions.
+// Fields left empty are filled in from the route.
+func setAuditChange(c *gin.Context, change services.AuditChange) {
+	c.Set("audit_change", change)
+}
+
+// auditSnapshot returns value for use as an audit before/after state, or nil if it could not be
+// loaded, so that a failed lookup never blocks the action being audited.
+func auditSnapshot[T any](value T, err error) interface{} {
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// HandleGetAuditLog handles GET /audit?actor_employee_id=&actor_api_key_id=&action=&entity_type=&entity_id=&request_id=&from=&to=&page=&limit=
+// from and to are RFC 3339 timestamps.
+func HandleGetAuditLog(c *gin.Context) {
+	filters := models.AuditLogFilters{
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		RequestID:  c.Query("request_id"),
+	}
+	filters.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	filters.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	for param, target := range map[string]**int{"actor_employee_id": &filters.ActorEmployeeID, "actor_api_key_id": &filters.ActorAPIKeyID} {
+		if value := c.Query(param); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil || id <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			*target = &id
+		}
+	}
+	for param, target := range map[string]**time.Time{"from": &filters.From, "to": &filters.To} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + ", expected an RFC 3339 timestamp"})
+				return
+			}
+			*target = &parsed
+		}
+	}
+
+	entries, err := services.GetAuditLog(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -3,8 +3,12 @@ package handlers
 import (
 	"car-rental-management/internal/models"
 	"car-rental-management/internal/services"
+	"errors"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -50,14 +54,171 @@ func LoginEmployee(c *gin.Context) {
 	}
 
 	log.Printf("🔑 Login attempt for employee: %s", credentials.Email)
-	token, err := services.AuthenticateEmployee(credentials.Email, credentials.Password)
+	result, err := services.AuthenticateEmployee(credentials.Email, credentials.Password, clientInfo(c))
 	if err != nil {
 		log.Printf("❌ Employee authentication failed for %s: %v", credentials.Email, err)
+		if respondLoginThrottled(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrPasswordLoginDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		// Return generic error for security
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	log.Printf("✅ Successful login for employee: %s", credentials.Email)
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	log.Printf("✅ Password accepted for employee: %s", credentials.Email)
+	c.JSON(http.StatusOK, result)
+}
+
+// clientInfo describes the caller for the session record a login or refresh creates.
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// respondLoginThrottled answers a refused login with 429 and Retry-After. It reports whether err was a refusal.
+func respondLoginThrottled(c *gin.Context, err error) bool {
+	var throttled *services.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": throttled.Error(), "retry_after": retryAfter})
+	return true
+}
+
+// RefreshToken handles POST /auth/refresh for employees and customers
+func RefreshToken(c *gin.Context) {
+	var input models.RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	tokens, err := services.RefreshTokens(input.RefreshToken, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Token refresh failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout handles POST /auth/logout. It ends the current session, revoking the presented access
+// token and every refresh token from the same login.
+func Logout(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	userType, userID, ok := currentSessionUser(c)
+	if !ok {
+		return
+	}
+	jti := c.GetString("token_jti")
+	expiresAt := c.GetTime("token_expires_at")
+
+	if err := services.Logout(userType, userID, c.GetString("session_id"), jti, expiresAt, input.RefreshToken); err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Logout failed for %s %d: %v", userType, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// ForgotPassword handles POST /auth/password/forgot. The response is the same whether
+// or not the address is registered.
+func ForgotPassword(c *gin.Context) {
+	var input models.ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if input.UserType == "" {
+		input.UserType = "customer"
+	}
+
+	if err := services.RequestPasswordReset(input.UserType, input.Email); err != nil {
+		log.Printf("❌ Password reset request failed for %s: %v", input.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password reset request"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a password reset link has been sent."})
+}
+
+// ResetPassword handles POST /auth/password/reset
+func ResetPassword(c *gin.Context) {
+	var input models.ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if err := services.ResetPassword(input.Token, input.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidOneTimeToken) || strings.Contains(err.Error(), "password must be") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Password reset failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset. Please log in with your new password."})
+}
+
+// VerifyEmail handles POST /auth/email/verify
+func VerifyEmail(c *gin.Context) {
+	var input models.VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if err := services.VerifyEmail(input.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidOneTimeToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Email verification failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+}
+
+// ResendVerificationEmail handles POST /auth/email/resend
+func ResendVerificationEmail(c *gin.Context) {
+	var input models.ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if input.UserType == "" {
+		input.UserType = "customer"
+	}
+
+	if err := services.RequestEmailVerification(input.UserType, input.Email); err != nil {
+		log.Printf("❌ Verification email request failed for %s: %v", input.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "If an unverified account exists for this email, a verification link has been sent."})
 }
@@ -19,6 +19,13 @@ func CreateBranch(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
 		return
 	}
+	// An employee limited to some branches could not work on a new one, so only unrestricted staff create them.
+	if scope, ok := currentBranchScope(c); !ok {
+		return
+	} else if !scope.All {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Creating branches requires access to all branches"})
+		return
+	}
 	createdBranch, err := services.CreateBranch(branch)
 	if err != nil {
 		log.Printf("❌ Handler: Error creating branch: %v", err)
@@ -31,12 +38,17 @@ func CreateBranch(c *gin.Context) {
 		}
 		return
 	}
+	setAuditChange(c, services.AuditChange{EntityID: strconv.Itoa(createdBranch.ID), After: createdBranch})
 	c.JSON(http.StatusCreated, createdBranch)
 }
 
 // GetBranches handles GET /branches (public route)
 func GetBranches(c *gin.Context) {
-	branches, err := services.GetBranches()
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+	branches, err := services.GetBranches(withDeleted)
 	if err != nil {
 		log.Printf("❌ Handler: Error getting branches: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get branches"})
@@ -58,7 +70,15 @@ func GetBranchByID(c *gin.Context) {
 		return
 	}
 
-	branch, err := services.GetBranchByID(id)
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+	getBranch := services.GetBranchByID
+	if withDeleted {
+		getBranch = services.GetBranchByIDIncludingDeleted
+	}
+	branch, err := getBranch(id)
 	if err != nil {
 		if errors.Is(err, errors.New("branch not found")) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -90,7 +110,11 @@ func UpdateBranch(c *gin.Context) {
 		return
 	}
 	branch.ID = id // Set ID from URL param
+	if !authorizeBranch(c, id) {
+		return
+	}
 
+	before := auditSnapshot(services.GetBranchByID(id))
 	updatedBranch, err := services.UpdateBranch(branch)
 	if err != nil {
 		log.Printf("❌ Handler: Error updating branch %d: %v", id, err)
@@ -105,6 +129,7 @@ func UpdateBranch(c *gin.Context) {
 		}
 		return
 	}
+	setAuditChange(c, services.AuditChange{Before: before, After: updatedBranch})
 	c.JSON(http.StatusOK, updatedBranch)
 }
 
@@ -121,6 +146,10 @@ func DeleteBranch(c *gin.Context) {
 		return
 	}
 
+	if !authorizeBranch(c, id) {
+		return
+	}
+	before := auditSnapshot(services.GetBranchByID(id))
 	err = services.DeleteBranch(id)
 	if err != nil {
 		log.Printf("❌ Handler: Error deleting branch %d: %v", id, err)
@@ -133,5 +162,6 @@ func DeleteBranch(c *gin.Context) {
 		}
 		return
 	}
+	setAuditChange(c, services.AuditChange{Before: before})
 	c.JSON(http.StatusOK, gin.H{"message": "Branch deleted successfully"})
 }
@@ -18,6 +18,9 @@ func AddCar(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
 		return
 	}
+	if !authorizeBranch(c, car.BranchID) {
+		return
+	}
 
 	createdCar, err := services.AddCar(car)
 	if err != nil {
@@ -31,6 +34,7 @@ func AddCar(c *gin.Context) {
 		}
 		return
 	}
+	setAuditChange(c, services.AuditChange{EntityID: strconv.Itoa(createdCar.ID), After: createdCar})
 	c.JSON(http.StatusCreated, createdCar)
 }
 
@@ -69,7 +73,7 @@ func GetCars(c *gin.Context) {
 		}
 	}
 	if minPriceStr := c.Query("min_price"); minPriceStr != "" {
-		if minPrice, err := strconv.ParseFloat(minPriceStr, 64); err == nil {
+		if minPrice, err := models.ParseMoney(minPriceStr, models.BaseCurrency); err == nil {
 			filters.MinPrice = &minPrice
 		} else {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_price filter"})
@@ -77,7 +81,7 @@ func GetCars(c *gin.Context) {
 		}
 	}
 	if maxPriceStr := c.Query("max_price"); maxPriceStr != "" {
-		if maxPrice, err := strconv.ParseFloat(maxPriceStr, 64); err == nil {
+		if maxPrice, err := models.ParseMoney(maxPriceStr, models.BaseCurrency); err == nil {
 			filters.MaxPrice = &maxPrice
 		} else {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_price filter"})
@@ -93,12 +97,23 @@ func GetCars(c *gin.Context) {
 		}
 	}
 
+	var ok bool
+	if filters.IncludeDeleted, ok = includeDeleted(c); !ok {
+		return
+	}
+
 	paginatedResponse, err := services.GetCarsPaginated(filters)
 	if err != nil {
 		log.Println("Error fetching cars (paginated):", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cars"})
 		return
 	}
+	if currency := c.Query("currency"); currency != "" {
+		if err := services.ApplyDisplayCurrency(paginatedResponse.Cars, currency); err != nil {
+			respondDisplayCurrencyError(c, err)
+			return
+		}
+	}
 	c.JSON(http.StatusOK, paginatedResponse)
 }
 
@@ -109,7 +124,15 @@ func GetCarByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid car ID"})
 		return
 	}
-	car, err := services.GetCarByID(id)
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+	getCar := services.GetCarByID
+	if withDeleted {
+		getCar = services.GetCarByIDIncludingDeleted
+	}
+	car, err := getCar(id)
 	if err != nil {
 		if errors.Is(err, errors.New("car not found")) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -119,6 +142,14 @@ func GetCarByID(c *gin.Context) {
 		}
 		return
 	}
+	if currency := c.Query("currency"); currency != "" {
+		cars := []models.Car{car}
+		if err := services.ApplyDisplayCurrency(cars, currency); err != nil {
+			respondDisplayCurrencyError(c, err)
+			return
+		}
+		car = cars[0]
+	}
 	c.JSON(http.StatusOK, car)
 }
 
@@ -135,7 +166,12 @@ func UpdateCar(c *gin.Context) {
 		return
 	}
 	car.ID = id
+	// Both the car's current branch and the one it is moving to must be the employee's.
+	if !authorizeCarBranch(c, id) || !authorizeBranch(c, car.BranchID) {
+		return
+	}
 
+	before := auditSnapshot(services.GetCarByID(id))
 	updatedCar, err := services.UpdateCar(car)
 	if err != nil {
 		log.Println("Error updating car:", err)
@@ -148,6 +184,7 @@ func UpdateCar(c *gin.Context) {
 		}
 		return
 	}
+	setAuditChange(c, services.AuditChange{Before: before, After: updatedCar})
 	c.JSON(http.StatusOK, updatedCar)
 }
 
@@ -158,6 +195,10 @@ func DeleteCar(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid car ID"})
 		return
 	}
+	if !authorizeCarBranch(c, id) {
+		return
+	}
+	before := auditSnapshot(services.GetCarByID(id))
 	err = services.DeleteCar(id)
 	if err != nil {
 		log.Println("Error deleting car:", err)
@@ -170,5 +211,6 @@ func DeleteCar(c *gin.Context) {
 		}
 		return
 	}
+	setAuditChange(c, services.AuditChange{Before: before})
 	c.JSON(http.StatusOK, gin.H{"message": "Car deleted successfully"})
 }
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corporateErrorStatus maps corporate account service errors to HTTP status codes.
+func corporateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCorporateAccountNotFound), errors.Is(err, services.ErrCorporateInvoiceNotFound),
+		errors.Is(err, services.ErrNotCorporateMember), strings.Contains(err.Error(), "customer not found"):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidInvoicePeriod), errors.Is(err, services.ErrInvoicePeriodNotFinished),
+		errors.Is(err, services.ErrInvoicePaymentExceedsDue), strings.Contains(err.Error(), "cannot be empty"),
+		strings.Contains(err.Error(), "must be greater than zero"), strings.Contains(err.Error(), "invalid customer ID"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "already exists"):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// respondCorporateError writes err with its mapped status, hiding internal error details.
+func respondCorporateError(c *gin.Context, err error, fallback string) {
+	statusCode := corporateErrorStatus(err)
+	if statusCode == http.StatusInternalServerError {
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(statusCode, gin.H{"error": fallback})
+		return
+	}
+	c.JSON(statusCode, gin.H{"error": err.Error()})
+}
+
+// HandleGetCorporateAccounts handles GET /corporate-accounts
+func HandleGetCorporateAccounts(c *gin.Context) {
+	accounts, err := services.GetCorporateAccounts()
+	if err != nil {
+		respondCorporateError(c, err, "Failed to fetch corporate accounts")
+		return
+	}
+	c.JSON(http.StatusOK, accounts)
+}
+
+// HandleGetCorporateAccountByID handles GET /corporate-accounts/:id
+func HandleGetCorporateAccountByID(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid corporate account ID"})
+		return
+	}
+	account, err := services.GetCorporateAccountByID(accountID)
+	if err != nil {
+		respondCorporateError(c, err, "Failed to fetch corporate account")
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// HandleCreateCorporateAccount handles POST /corporate-accounts
+func HandleCreateCorporateAccount(c *gin.Context) {
+	var input models.CorporateAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	account, err := services.CreateCorporateAccount(input)
+	if err != nil {
+		respondCorporateError(c, err, "Failed to create corporate account")
+		return
+	}
+	c.JSON(http.StatusCreated, account)
+}
+
+// HandleUpdateCorporateAccount handles PUT /corporate-accounts/:id
+func HandleUpdateCorporateAccount(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid corporate account ID"})
+		return
+	}
+	var input models.CorporateAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	account, err := services.UpdateCorporateAccount(accountID, input)
+	if err != nil {
+		respondCorporateError(c, err, "Failed to update corporate account")
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// HandleGetCorporateAccountMembers handles GET /corporate-accounts/:id/members
+func HandleGetCorporateAccountMembers(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid corporate account ID"})
+		return
+	}
+	members, err := services.GetCorporateAccountMembers(accountID)
+	if err != nil {
+		respondCorporateError(c, err, "Failed to fetch corporate account members")
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// HandleSetCorporateAccountMember handles POST /corporate-accounts/:id/members
+func HandleSetCorporateAccountMember(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid corporate account ID"})
+		return
+	}
+	var input models.CorporateMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	member, err := services.SetCorporateAccountMember(accountID, input)
+	if err != nil {
+		respondCorporateError(c, err, "Failed to save corporate account member")
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// HandleRemoveCorporateAccountMember handles DELETE /corporate-accounts/:id/members/:customerId
+func HandleRemoveCorporateAccountMember(c *gin.Context) {
+	accountID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || accountID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid corporate account ID"})
+		return
+	}
+	customerID, err := strconv.Atoi(c.Param("customerId"))
+	if err != nil || customerID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+	if err := services.RemoveCorporateAccountMember(accountID, customerID); err != nil {
+		respondCorporateError(c, err, "Failed to remove corporate account member")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed from corporate account"})
+}
+
+// HandleGetMyCorporateAccounts handles GET /me/corporate-accounts
+func HandleGetMyCorporateAccounts(c *gin.Context) {
+	customerIDInterface, exists := c.Get("customer_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Customer authentication required"})
+		return
+	}
+	customerID, ok := customerIDInterface.(int)
+	if !ok || customerID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication data"})
+		return
+	}
+	memberships, err := services.GetMyCorporateMemberships(customerID)
+	if err != nil {
+		respondCorporateError(c, err, "Failed to fetch corporate accounts")
+		return
+	}
+	c.JSON(http.StatusOK, memberships)
+}
+
+// HandleRunCorporateInvoicing handles POST /corporate-invoices/run
+func HandleRunCorporateInvoicing(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Employee authentication required"})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(int)
+	if !ok || employeeID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid employee authentication data"})
+		return
+	}
+	var input models.CorporateInvoiceRunInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	invoices, err := services.RunCorporateInvoicing(input.Period, input.AccountID, employeeID)
+	if err != nil {
+		statusCode := corporateErrorStatus(err)
+		if statusCode == http.StatusInternalServerError {
+			log.Printf("❌ Handler: Corporate invoice run for %s failed: %v", input.Period, err)
+			// Accounts invoiced before the failure keep their invoices; report them alongside the error.
+			c.JSON(statusCode, gin.H{"error": "Corporate invoice run did not complete", "invoices": invoices})
+			return
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"period": input.Period, "invoices_issued": len(invoices), "invoices": invoices})
+}
+
+// HandleGetCorporateInvoices handles GET /corporate-invoices and GET /corporate-accounts/:id/invoices
+// Optional query parameter: status (Issued, Partially Paid, Paid or Overdue)
+func HandleGetCorporateInvoices(c *gin.Context) {
+	var accountID *int
+	if idStr := c.Param("id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid corporate account ID"})
+			return
+		}
+		accountID = &id
+	}
+	var status *string
+	if statusStr := c.Query("status"); statusStr != "" {
+		switch statusStr {
+		case "Issued", "Partially Paid", "Paid", "Overdue":
+			status = &statusStr
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status filter"})
+			return
+		}
+	}
+	invoices, err := services.GetCorporateInvoices(accountID, status)
+	if err != nil {
+		respondCorporateError(c, err, "Failed to fetch corporate invoices")
+		return
+	}
+	c.JSON(http.StatusOK, invoices)
+}
+
+// HandleGetCorporateInvoiceByID handles GET /corporate-invoices/:id
+func HandleGetCorporateInvoiceByID(c *gin.Context) {
+	invoiceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || invoiceID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+	invoice, err := services.GetCorporateInvoiceByID(invoiceID)
+	if err != nil {
+		respondCorporateError(c, err, "Failed to fetch corporate invoice")
+		return
+	}
+	c.JSON(http.StatusOK, invoice)
+}
+
+// HandleRecordCorporateInvoicePayment handles POST /corporate-invoices/:id/payments
+func HandleRecordCorporateInvoicePayment(c *gin.Context) {
+	invoiceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || invoiceID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+	employeeIDInterface, exists := c.Get("employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Employee authentication required"})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(int)
+	if !ok || employeeID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid employee authentication data"})
+		return
+	}
+	var input models.CorporateInvoicePaymentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if err := services.RecordCorporateInvoicePayment(invoiceID, employeeID, input); err != nil {
+		respondCorporateError(c, err, "Failed to record invoice payment")
+		return
+	}
+	invoice, err := services.GetCorporateInvoiceByID(invoiceID)
+	if err != nil {
+		respondCorporateError(c, err, "Payment recorded but failed to fetch invoice")
+		return
+	}
+	c.JSON(http.StatusCreated, invoice)
+}
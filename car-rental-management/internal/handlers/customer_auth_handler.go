@@ -3,6 +3,7 @@ package handlers
 import (
 	"car-rental-management/internal/models"
 	"car-rental-management/internal/services"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -25,7 +26,7 @@ func RegisterCustomer(c *gin.Context) {
 	log.Printf("📝 Registration attempt for customer: %s", input.Email)
 
 	// Call service with the input struct
-	registeredCustomer, err := services.RegisterCustomer(input)
+	registeredCustomer, err := services.RegisterCustomer(input, clientInfo(c))
 	if err != nil {
 		log.Println("❌ Customer registration failed:", err)
 		statusCode := http.StatusInternalServerError // Default
@@ -71,24 +72,26 @@ func LoginCustomer(c *gin.Context) {
 	log.Printf("🔑 Login attempt for customer: %s", credentials.Email)
 
 	// Authenticate customer using the service
-	token, err := services.AuthenticateCustomer(credentials.Email, credentials.Password)
+	tokens, err := services.AuthenticateCustomer(credentials.Email, credentials.Password, clientInfo(c))
 	if err != nil {
 		log.Printf("❌ Customer authentication failed for %s: %v", credentials.Email, err)
-		// Check for specific error types if needed, otherwise return general invalid credentials
-		// The service already returns "invalid email or password" for not found or wrong password.
-		// Check for potential wrapped DB errors from fetching?
-		if strings.Contains(err.Error(), "error fetching customer data") {
-			// Internal server error if DB fetch failed
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed due to an internal error"})
-		} else {
-			// Primarily "invalid email or password" or "authentication failed" (token signing)
+		if respondLoginThrottled(c, err) {
+			return
+		}
+		// The service returns the same error for an unknown email and a wrong password.
+		if errors.Is(err, services.ErrInvalidCredentials) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrCustomerBlacklisted) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			// Database or token signing failure
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed due to an internal error"})
 		}
 		return
 	}
 
 	log.Printf("✅ Successful login for customer: %s", credentials.Email)
 
-	// Return the generated JWT token
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	// Return the generated access and refresh tokens
+	c.JSON(http.StatusOK, tokens)
 }
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxCustomerDocumentSize = 5 * 1024 * 1024
+
+// respondCustomerDocumentError maps document errors to HTTP statuses.
+func respondCustomerDocumentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrDocumentNotFound), err.Error() == "customer not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDocumentNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidDocument), errors.Is(err, services.ErrInvalidDocumentFile), errors.Is(err, services.ErrDocumentExpired),
+		err.Error() == "expires_on must be a date in YYYY-MM-DD format":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// auditableDocument strips the document number, which must not be copied into the audit log.
+func auditableDocument(document models.CustomerDocument, err error) interface{} {
+	document.DocumentNumber = nil
+	return auditSnapshot(document, err)
+}
+
+// serveCustomerDocument sends a document's file. It is never cached, as it is an identity document.
+func serveCustomerDocument(c *gin.Context, document models.CustomerDocument) {
+	data, err := services.ReadCustomerDocumentFile(document)
+	if err != nil {
+		respondCustomerDocumentError(c, err, "Failed to read document")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="document-%d"`, document.ID))
+	c.Data(http.StatusOK, document.ContentType, data)
+}
+
+// HandleUploadMyDocument handles POST /me/documents, a multipart form with document_type
+// (licence_front, licence_back or id_document) and file (JPEG, PNG or PDF, at most 5MB).
+func HandleUploadMyDocument(c *gin.Context) {
+	customerID, ok := currentCustomerID(c)
+	if !ok {
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Document file is required: " + err.Error()})
+		return
+	}
+	if file.Size > maxCustomerDocumentSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File size exceeds 5MB limit."})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxCustomerDocumentSize+1))
+	if err != nil || len(data) > maxCustomerDocumentSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	// The type is taken from the content, not the file name or the client's Content-Type.
+	document, err := services.UploadCustomerDocument(customerID, c.PostForm("document_type"), http.DetectContentType(data), data)
+	if err != nil {
+		respondCustomerDocumentError(c, err, "Failed to upload document")
+		return
+	}
+	c.JSON(http.StatusCreated, document)
+}
+
+// HandleGetMyDocuments handles GET /me/documents
+func HandleGetMyDocuments(c *gin.Context) {
+	customerID, ok := currentCustomerID(c)
+	if !ok {
+		return
+	}
+	documents, err := services.GetCustomerDocuments(customerID, "")
+	if err != nil {
+		respondCustomerDocumentError(c, err, "Failed to fetch documents")
+		return
+	}
+	c.JSON(http.StatusOK, documents)
+}
+
+// HandleGetMyDocumentFile handles GET /me/documents/:id/file
+func HandleGetMyDocumentFile(c *gin.Context) {
+	customerID, ok := currentCustomerID(c)
+	if !ok {
+		return
+	}
+	documentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || documentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+	document, err := services.GetCustomerDocumentByID(documentID)
+	if err == nil && document.CustomerID != customerID {
+		err = services.ErrDocumentNotFound // Do not reveal other customers' documents exist
+	}
+	if err != nil {
+		respondCustomerDocumentError(c, err, "Failed to fetch document")
+		return
+	}
+	serveCustomerDocument(c, document)
+}
+
+// HandleGetCustomerDocuments handles GET /customer-documents?status=&customer_id=
+func HandleGetCustomerDocuments(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", "Pending", "Approved", "Rejected", "Superseded":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status filter (Pending, Approved, Rejected or Superseded)"})
+		return
+	}
+	customerID := 0
+	if customerIDStr := c.Query("customer_id"); customerIDStr != "" {
+		id, err := strconv.Atoi(customerIDStr)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer_id"})
+			return
+		}
+		customerID = id
+	}
+	documents, err := services.GetCustomerDocuments(customerID, status)
+	if err != nil {
+		respondCustomerDocumentError(c, err, "Failed to fetch documents")
+		return
+	}
+	c.JSON(http.StatusOK, documents)
+}
+
+// customerDocumentAction reads the :id of a document and the employee reviewing it,
+// which is nil for an integration.
+func customerDocumentAction(c *gin.Context) (documentID int, employeeID *int, ok bool) {
+	documentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || documentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return 0, nil, false
+	}
+	return documentID, actingEmployeeID(c), true
+}
+
+// HandleGetCustomerDocumentFile handles GET /customer-documents/:id/file
+func HandleGetCustomerDocumentFile(c *gin.Context) {
+	documentID, _, ok := customerDocumentAction(c)
+	if !ok {
+		return
+	}
+	document, err := services.GetCustomerDocumentByID(documentID)
+	if err != nil {
+		respondCustomerDocumentError(c, err, "Failed to fetch document")
+		return
+	}
+	serveCustomerDocument(c, document)
+}
+
+// HandleApproveCustomerDocument handles POST /customer-documents/:id/approve
+func HandleApproveCustomerDocument(c *gin.Context) {
+	documentID, employeeID, ok := customerDocumentAction(c)
+	if !ok {
+		return
+	}
+	var input models.ApproveCustomerDocumentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	before := auditableDocument(services.GetCustomerDocumentByID(documentID))
+	document, err := services.ApproveCustomerDocument(documentID, employeeID, input)
+	if err != nil {
+		respondCustomerDocumentError(c, err, "Failed to approve document")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before, After: auditableDocument(document, nil)})
+	c.JSON(http.StatusOK, document)
+}
+
+// HandleRejectCustomerDocument handles POST /customer-documents/:id/reject
+func HandleRejectCustomerDocument(c *gin.Context) {
+	documentID, employeeID, ok := customerDocumentAction(c)
+	if !ok {
+		return
+	}
+	var input models.RejectCustomerDocumentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	before := auditableDocument(services.GetCustomerDocumentByID(documentID))
+	document, err := services.RejectCustomerDocument(documentID, employeeID, input.Reason)
+	if err != nil {
+		respondCustomerDocumentError(c, err, "Failed to reject document")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before, After: auditableDocument(document, nil)})
+	c.JSON(http.StatusOK, document)
+}
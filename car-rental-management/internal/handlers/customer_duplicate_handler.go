@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetDuplicateCustomers handles GET /customers/duplicates?min_score=&limit=
+func HandleGetDuplicateCustomers(c *gin.Context) {
+	minScore, errScore := strconv.Atoi(c.DefaultQuery("min_score", strconv.Itoa(services.DefaultDuplicateMinScore)))
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if errScore != nil || minScore < 0 || minScore > 100 || errLimit != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_score (0-100) or limit"})
+		return
+	}
+	candidates, err := services.GetDuplicateCustomerCandidates(minScore, limit)
+	if err != nil {
+		log.Printf("❌ Handler: Error finding duplicate customers: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find duplicate customers"})
+		return
+	}
+	c.JSON(http.StatusOK, candidates)
+}
+
+// HandleMergeCustomers handles POST /customers/:id/merge, folding merge_customer_id into :id.
+func HandleMergeCustomers(c *gin.Context) {
+	survivorID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || survivorID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+	var input models.MergeCustomersInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	before := auditSnapshot(services.GetCustomerByID(input.MergeCustomerID))
+	result, err := services.MergeCustomers(survivorID, input.MergeCustomerID)
+	if err != nil {
+		switch {
+		case err.Error() == "customer not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMergeSameCustomer):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrMergeCustomerErased), errors.Is(err, services.ErrMergePendingErasure):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Handler: Error merging customer %d into %d: %v", input.MergeCustomerID, survivorID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge customers"})
+		}
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: gin.H{"merged_customer": before}, After: result})
+	c.JSON(http.StatusOK, result)
+}
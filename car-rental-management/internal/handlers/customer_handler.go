@@ -42,8 +42,20 @@ func GetCustomers(c *gin.Context) {
 	if phone := c.Query("phone"); phone != "" {
 		filters.Phone = &phone
 	}
+	if riskStatus := c.Query("risk_status"); riskStatus != "" {
+		filters.RiskStatus = &riskStatus
+	}
+
+	var ok bool
+	if filters.IncludeDeleted, ok = includeDeleted(c); !ok {
+		return
+	}
 
 	paginatedResponse, err := services.GetCustomersPaginated(filters)
+	if errors.Is(err, services.ErrInvalidRiskStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Println("Error fetching customers (paginated):", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customers"})
@@ -59,7 +71,15 @@ func GetCustomerByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
 		return
 	}
-	customer, err := services.GetCustomerByID(id)
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+	getCustomer := services.GetCustomerByID
+	if withDeleted {
+		getCustomer = services.GetCustomerByIDIncludingDeleted
+	}
+	customer, err := getCustomer(id)
 	if err != nil {
 		if errors.Is(err, errors.New("customer not found")) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -86,6 +106,7 @@ func UpdateCustomer(c *gin.Context) {
 		return
 	}
 
+	before := auditSnapshot(services.GetCustomerByID(id))
 	updatedCustomer, err := services.UpdateCustomer(id, input)
 	if err != nil {
 		log.Println("Error updating customer by staff:", err)
@@ -116,6 +137,7 @@ func UpdateCustomer(c *gin.Context) {
 		c.JSON(statusCode, gin.H{"error": errMsg})
 		return
 	}
+	setAuditChange(c, services.AuditChange{Before: before, After: updatedCustomer})
 	c.JSON(http.StatusOK, updatedCustomer)
 }
 
@@ -126,6 +148,7 @@ func DeleteCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
 		return
 	}
+	before := auditSnapshot(services.GetCustomerByID(id))
 	err = services.DeleteCustomer(id)
 	if err != nil {
 		log.Println("Error deleting customer by staff:", err)
@@ -136,7 +159,7 @@ func DeleteCustomer(c *gin.Context) {
 		if errors.Is(err, errors.New("customer not found for deletion")) {
 			statusCode = http.StatusNotFound
 			errMsg = specificErr
-		} else if specificErr == "cannot delete customer: they have associated rentals" {
+		} else if strings.HasPrefix(specificErr, "cannot delete customer") {
 			statusCode = http.StatusConflict
 			errMsg = specificErr
 		} else if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" {
@@ -149,6 +172,7 @@ func DeleteCustomer(c *gin.Context) {
 		c.JSON(statusCode, gin.H{"error": errMsg})
 		return
 	}
+	setAuditChange(c, services.AuditChange{Before: before})
 	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted successfully"})
 }
 
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const permCustomerNotesRestricted = "customer_notes:restricted"
+
+// respondCustomerNoteError maps note and timeline errors to HTTP statuses.
+func respondCustomerNoteError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrNoteNotFound), errors.Is(err, services.ErrRentalNotFound), err.Error() == "customer not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteNotAuthor), errors.Is(err, services.ErrNoteRestricted):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// noteViewer describes the caller for note visibility.
+func noteViewer(c *gin.Context) (services.NoteViewer, bool) {
+	seeRestricted, ok := callerHasPermission(c, permCustomerNotesRestricted)
+	if !ok {
+		return services.NoteViewer{}, false
+	}
+	viewer := services.NoteViewer{SeeRestricted: seeRestricted}
+	if employeeID := actingEmployeeID(c); employeeID != nil {
+		viewer.EmployeeID = *employeeID
+	}
+	return viewer, true
+}
+
+// auditableNote keeps the text of restricted notes out of the audit log, which has a wider audience.
+func auditableNote(note models.CustomerNote, err error) interface{} {
+	if note.Visibility == "restricted" {
+		note.Body = ""
+	}
+	return auditSnapshot(note, err)
+}
+
+func noteIDParam(c *gin.Context, message string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return id, true
+}
+
+// HandleGetCustomerNotes handles GET /customers/:id/notes
+func HandleGetCustomerNotes(c *gin.Context) {
+	customerID, ok := noteIDParam(c, "Invalid customer ID")
+	if !ok {
+		return
+	}
+	viewer, ok := noteViewer(c)
+	if !ok {
+		return
+	}
+	notes, err := services.GetCustomerNotes(customerID, viewer)
+	if err != nil {
+		respondCustomerNoteError(c, err, "Failed to fetch notes")
+		return
+	}
+	c.JSON(http.StatusOK, notes)
+}
+
+// HandleCreateCustomerNote handles POST /customers/:id/notes
+func HandleCreateCustomerNote(c *gin.Context) {
+	customerID, ok := noteIDParam(c, "Invalid customer ID")
+	if !ok {
+		return
+	}
+	viewer, ok := noteViewer(c)
+	if !ok {
+		return
+	}
+	var input models.CreateCustomerNoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	note, err := services.CreateCustomerNote(customerID, viewer, input)
+	if err != nil {
+		respondCustomerNoteError(c, err, "Failed to add note")
+		return
+	}
+	setAuditChange(c, services.AuditChange{After: auditableNote(note, nil)})
+	c.JSON(http.StatusCreated, note)
+}
+
+// HandleGetRentalNotes handles GET /rentals/:id/notes
+func HandleGetRentalNotes(c *gin.Context) {
+	rentalID, ok := noteIDParam(c, "Invalid rental ID")
+	if !ok || !authorizeRentalBranch(c, rentalID) {
+		return
+	}
+	viewer, ok := noteViewer(c)
+	if !ok {
+		return
+	}
+	notes, err := services.GetRentalNotes(rentalID, viewer)
+	if err != nil {
+		respondCustomerNoteError(c, err, "Failed to fetch notes")
+		return
+	}
+	c.JSON(http.StatusOK, notes)
+}
+
+// HandleCreateRentalNote handles POST /rentals/:id/notes
+func HandleCreateRentalNote(c *gin.Context) {
+	rentalID, ok := noteIDParam(c, "Invalid rental ID")
+	if !ok || !authorizeRentalBranch(c, rentalID) {
+		return
+	}
+	viewer, ok := noteViewer(c)
+	if !ok {
+		return
+	}
+	var input models.CreateCustomerNoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	note, err := services.CreateRentalNote(rentalID, viewer, input)
+	if err != nil {
+		respondCustomerNoteError(c, err, "Failed to add note")
+		return
+	}
+	setAuditChange(c, services.AuditChange{After: auditableNote(note, nil)})
+	c.JSON(http.StatusCreated, note)
+}
+
+// HandleUpdateCustomerNote handles PUT /customer-notes/:id
+func HandleUpdateCustomerNote(c *gin.Context) {
+	noteID, ok := noteIDParam(c, "Invalid note ID")
+	if !ok {
+		return
+	}
+	viewer, ok := noteViewer(c)
+	if !ok {
+		return
+	}
+	var input models.UpdateCustomerNoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	before := auditableNote(services.GetCustomerNoteByID(noteID, viewer))
+	note, err := services.UpdateCustomerNote(noteID, viewer, input)
+	if err != nil {
+		respondCustomerNoteError(c, err, "Failed to update note")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before, After: auditableNote(note, nil)})
+	c.JSON(http.StatusOK, note)
+}
+
+// HandleDeleteCustomerNote handles DELETE /customer-notes/:id
+func HandleDeleteCustomerNote(c *gin.Context) {
+	noteID, ok := noteIDParam(c, "Invalid note ID")
+	if !ok {
+		return
+	}
+	viewer, ok := noteViewer(c)
+	if !ok {
+		return
+	}
+	before := auditableNote(services.GetCustomerNoteByID(noteID, viewer))
+	if err := services.DeleteCustomerNote(noteID, viewer); err != nil {
+		respondCustomerNoteError(c, err, "Failed to delete note")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before})
+	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
+}
+
+// parseTimelineTime reads an optional RFC 3339 query parameter.
+func parseTimelineTime(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " (use RFC 3339, e.g. 2024-05-01T00:00:00Z)"})
+		return nil, false
+	}
+	return &t, true
+}
+
+// HandleGetCustomerTimeline handles GET /customers/:id/timeline?from=&to=&page=&limit=
+func HandleGetCustomerTimeline(c *gin.Context) {
+	customerID, ok := noteIDParam(c, "Invalid customer ID")
+	if !ok {
+		return
+	}
+	from, ok := parseTimelineTime(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTimelineTime(c, "to")
+	if !ok {
+		return
+	}
+	page, errPage := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	if errPage != nil || errLimit != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page or limit"})
+		return
+	}
+	viewer, ok := noteViewer(c)
+	if !ok {
+		return
+	}
+	timeline, err := services.GetCustomerTimeline(customerID, viewer, from, to, page, limit)
+	if err != nil {
+		respondCustomerNoteError(c, err, "Failed to fetch customer timeline")
+		return
+	}
+	c.JSON(http.StatusOK, timeline)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondRiskFlagError maps risk flag errors to HTTP statuses.
+func respondRiskFlagError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrRiskFlagNotFound), err.Error() == "customer not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRiskFlagInactive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "a reason is required", err.Error() == "expires_at must be in the future":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func riskFlagCustomerID(c *gin.Context) (int, bool) {
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || customerID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return 0, false
+	}
+	return customerID, true
+}
+
+// HandleGetCustomerRiskFlags handles GET /customers/:id/risk-flags
+func HandleGetCustomerRiskFlags(c *gin.Context) {
+	customerID, ok := riskFlagCustomerID(c)
+	if !ok {
+		return
+	}
+	flags, err := services.GetCustomerRiskFlags(customerID)
+	if err != nil {
+		respondRiskFlagError(c, err, "Failed to fetch risk flags")
+		return
+	}
+	c.JSON(http.StatusOK, flags)
+}
+
+// HandleCreateCustomerRiskFlag handles POST /customers/:id/risk-flags
+func HandleCreateCustomerRiskFlag(c *gin.Context) {
+	customerID, ok := riskFlagCustomerID(c)
+	if !ok {
+		return
+	}
+	var input models.CreateCustomerRiskFlagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	flag, err := services.CreateCustomerRiskFlag(customerID, actingEmployeeID(c), input)
+	if err != nil {
+		respondRiskFlagError(c, err, "Failed to flag customer")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Action: "customer.flag", EntityType: "customer", EntityID: strconv.Itoa(customerID), After: flag})
+	c.JSON(http.StatusCreated, flag)
+}
+
+// HandleLiftCustomerRiskFlag handles POST /customers/:id/risk-flags/:flagId/lift
+func HandleLiftCustomerRiskFlag(c *gin.Context) {
+	customerID, ok := riskFlagCustomerID(c)
+	if !ok {
+		return
+	}
+	flagID, err := strconv.Atoi(c.Param("flagId"))
+	if err != nil || flagID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid risk flag ID"})
+		return
+	}
+	var input models.LiftCustomerRiskFlagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	flag, err := services.LiftCustomerRiskFlag(customerID, flagID, actingEmployeeID(c), input.Reason)
+	if err != nil {
+		respondRiskFlagError(c, err, "Failed to lift risk flag")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Action: "customer.unflag", EntityType: "customer", EntityID: strconv.Itoa(customerID), After: flag})
+	c.JSON(http.StatusOK, flag)
+}
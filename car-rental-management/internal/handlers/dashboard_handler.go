@@ -12,7 +12,11 @@ import (
 
 // GetDashboard สำหรับ Admin Dashboard (ยังคงเดิม)
 func GetDashboard(c *gin.Context) {
-	data, err := services.GetDashboardData()
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	data, err := services.GetDashboardData(scope)
 	if err != nil {
 		log.Println("❌ Error fetching admin dashboard data:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch admin dashboard data"})
@@ -52,7 +56,11 @@ func HandleGetRevenueReport(c *gin.Context) {
 		return
 	}
 
-	reportData, err := services.GetRevenueReport(startDate, endDate)
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	reportData, err := services.GetRevenueReport(startDate, endDate, scope)
 	if err != nil {
 		log.Printf("❌ Handler: Error generating revenue report: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate revenue report"})
@@ -68,7 +76,11 @@ func HandleGetPopularCarsReport(c *gin.Context) {
 		limit = 10
 	}
 
-	reportData, err := services.GetPopularCarsReport(limit)
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	reportData, err := services.GetPopularCarsReport(limit, scope)
 	if err != nil {
 		log.Printf("❌ Handler: Error generating popular cars report: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate popular cars report"})
@@ -78,7 +90,11 @@ func HandleGetPopularCarsReport(c *gin.Context) {
 }
 
 func HandleGetBranchPerformanceReport(c *gin.Context) {
-	reportData, err := services.GetBranchPerformanceReport()
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	reportData, err := services.GetBranchPerformanceReport(scope)
 	if err != nil {
 		log.Printf("❌ Handler: Error generating branch performance report: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate branch performance report"})
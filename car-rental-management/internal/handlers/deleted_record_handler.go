@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const permDeletedRecordsManage = "deleted_records:manage"
+
+// callerHasPermission reports whether the employee or API key making the request holds permission,
+// for handlers whose behaviour, rather than access, depends on it. ok is false once it has answered
+// with an error.
+func callerHasPermission(c *gin.Context, permission string) (allowed bool, ok bool) {
+	if keyInterface, keyExists := c.Get("api_key"); keyExists {
+		key, _ := keyInterface.(services.APIKeyPrincipal)
+		return key.HasPermissions(permission), true
+	}
+	if _, empExists := c.Get("employee_id"); !empExists {
+		return false, true
+	}
+	role := c.GetString("user_role")
+	allowed, err := services.RoleHasPermissions(role, permission)
+	if err != nil {
+		log.Printf("❌ Handler: Error checking %s for role %s: %v", permission, role, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false, false
+	}
+	return allowed, true
+}
+
+// includeDeleted reads ?include_deleted=true. Only callers holding deleted_records:manage may set it;
+// anyone else gets a 403 and ok is false.
+func includeDeleted(c *gin.Context) (include bool, ok bool) {
+	include, err := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid include_deleted (must be true or false)"})
+		return false, false
+	}
+	if !include {
+		return false, true
+	}
+
+	allowed, ok := callerHasPermission(c, permDeletedRecordsManage)
+	if !ok {
+		return false, false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: include_deleted requires the " + permDeletedRecordsManage + " permission"})
+		return false, false
+	}
+	return true, true
+}
+
+// respondRestoreError answers a failed restore. notFound is the message for a missing row.
+func respondRestoreError(c *gin.Context, entity string, err error, notFound string) {
+	switch {
+	case errors.Is(err, services.ErrNothingToRestore), err.Error() == notFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrNothingToRestore.Error()})
+	case errors.Is(err, services.ErrRestoreParentFirst), errors.Is(err, services.ErrCarNotAvailable), errors.Is(err, services.ErrCustomerMerged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Handler: Error restoring %s: %v", entity, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore " + entity})
+	}
+}
+
+// HandleRestoreCar handles POST /cars/:id/restore
+func HandleRestoreCar(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid car ID"})
+		return
+	}
+	if !authorizeCarBranch(c, id) {
+		return
+	}
+	before := auditSnapshot(services.GetCarByIDIncludingDeleted(id))
+	car, err := services.RestoreCar(id)
+	if err != nil {
+		respondRestoreError(c, "car", err, "car not found")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before, After: car})
+	c.JSON(http.StatusOK, car)
+}
+
+// HandleRestoreBranch handles POST /branches/:id/restore
+func HandleRestoreBranch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch ID"})
+		return
+	}
+	if !authorizeBranch(c, id) {
+		return
+	}
+	before := auditSnapshot(services.GetBranchByIDIncludingDeleted(id))
+	branch, err := services.RestoreBranch(id)
+	if err != nil {
+		respondRestoreError(c, "branch", err, "branch not found")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before, After: branch})
+	c.JSON(http.StatusOK, branch)
+}
+
+// HandleRestoreCustomer handles POST /customers/:id/restore
+func HandleRestoreCustomer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+	before := auditSnapshot(services.GetCustomerByIDIncludingDeleted(id))
+	customer, err := services.RestoreCustomer(id)
+	if err != nil {
+		respondRestoreError(c, "customer", err, "customer not found")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before, After: customer})
+	c.JSON(http.StatusOK, customer)
+}
+
+// HandleRestoreUser handles POST /users/:id/restore
+func HandleRestoreUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	before := auditSnapshot(services.GetUserByIDIncludingDeleted(id))
+	employee, err := services.RestoreEmployee(id)
+	if err != nil {
+		respondRestoreError(c, "user", err, "employee not found")
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before, After: employee})
+	c.JSON(http.StatusOK, employee)
+}
+
+// HandleRestoreRental handles POST /rentals/:id/restore
+func HandleRestoreRental(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rental ID"})
+		return
+	}
+	if !authorizeRentalBranch(c, id) {
+		return
+	}
+	before := auditSnapshot(services.GetRentalByIDIncludingDeleted(id))
+	if err := services.RestoreRental(id); err != nil {
+		respondRestoreError(c, "rental", err, "")
+		return
+	}
+	rental, err := services.GetRentalByID(id)
+	if err != nil {
+		log.Printf("❌ Handler: Rental %d restored but could not be fetched: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Rental restored but failed to fetch it"})
+		return
+	}
+	setAuditChange(c, services.AuditChange{Before: before, After: rental})
+	c.JSON(http.StatusOK, rental)
+}
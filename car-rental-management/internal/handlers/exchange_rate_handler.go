@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exchangeRateErrorStatus maps exchange rate service errors to HTTP status codes.
+func exchangeRateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUnknownCurrency):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidCurrency), errors.Is(err, services.ErrInvalidExchangeRate):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// respondDisplayCurrencyError answers a bad ?currency= query parameter.
+func respondDisplayCurrencyError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnknownCurrency) || errors.Is(err, services.ErrInvalidCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency: " + err.Error()})
+		return
+	}
+	log.Printf("❌ Handler: Error converting prices for display: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert prices"})
+}
+
+// HandleGetExchangeRates handles GET /exchange-rates
+func HandleGetExchangeRates(c *gin.Context) {
+	rates, err := services.GetExchangeRates()
+	if err != nil {
+		log.Printf("❌ Handler: Error fetching exchange rates: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"base_currency": models.BaseCurrency, "rates": rates})
+}
+
+// HandleSetExchangeRate handles PUT /exchange-rates/:currency
+func HandleSetExchangeRate(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Employee authentication required"})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(int)
+	if !ok || employeeID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid employee authentication data"})
+		return
+	}
+	var input models.ExchangeRateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	rate, err := services.SetExchangeRate(c.Param("currency"), input.RateToBase, employeeID)
+	if err != nil {
+		statusCode := exchangeRateErrorStatus(err)
+		if statusCode == http.StatusInternalServerError {
+			c.JSON(statusCode, gin.H{"error": "Failed to save exchange rate"})
+			return
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rate)
+}
+
+// HandleDeleteExchangeRate handles DELETE /exchange-rates/:currency
+func HandleDeleteExchangeRate(c *gin.Context) {
+	if err := services.DeleteExchangeRate(c.Param("currency")); err != nil {
+		statusCode := exchangeRateErrorStatus(err)
+		if statusCode == http.StatusInternalServerError {
+			c.JSON(statusCode, gin.H{"error": "Failed to delete exchange rate"})
+			return
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
+
+// HandleImportExchangeRates handles POST /exchange-rates/import with a CSV file
+// (form field "file") of "currency,rate_to_base" rows.
+func HandleImportExchangeRates(c *gin.Context) {
+	employeeIDInterface, exists := c.Get("employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Employee authentication required"})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(int)
+	if !ok || employeeID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid employee authentication data"})
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required (form field 'file')"})
+		return
+	}
+	if fileHeader.Size > 1<<20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is too large (max 1 MB)"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Printf("❌ Handler: Error opening uploaded exchange rate file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	rates, err := services.ImportExchangeRatesCSV(file, employeeID)
+	if err != nil {
+		statusCode := exchangeRateErrorStatus(err)
+		if statusCode == http.StatusInternalServerError {
+			c.JSON(statusCode, gin.H{"error": "Failed to import exchange rates"})
+			return
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"imported": len(rates), "rates": rates})
+}
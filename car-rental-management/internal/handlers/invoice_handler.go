@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetRentalInvoicePDF handles GET /rentals/:id/invoice.pdf
+func HandleGetRentalInvoicePDF(c *gin.Context) {
+	serveInvoicePDF(c, models.InvoiceKindTaxInvoice)
+}
+
+// HandleGetRentalReceiptPDF handles GET /rentals/:id/receipt.pdf
+func HandleGetRentalReceiptPDF(c *gin.Context) {
+	serveInvoicePDF(c, models.InvoiceKindReceipt)
+}
+
+func serveInvoicePDF(c *gin.Context, kind string) {
+	rentalID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || rentalID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rental ID"})
+		return
+	}
+
+	// Staff can download documents for rentals in their branches; customers only their own.
+	var employeeID *int
+	isAllowed := false
+	empIDInterface, empExists := c.Get("employee_id")
+	custIDInterface, custExists := c.Get("customer_id")
+
+	if empExists {
+		if !authorizeRentalBranch(c, rentalID) {
+			return
+		}
+		if id, ok := empIDInterface.(int); ok {
+			employeeID = &id
+			isAllowed = true
+		}
+	} else if custExists {
+		rental, errRent := services.GetRentalByID(rentalID)
+		if errRent == nil {
+			customerID, ok := custIDInterface.(int)
+			if ok && rental.CustomerID == customerID {
+				isAllowed = true
+			}
+		} else if !errors.Is(errRent, services.ErrRentalNotFound) {
+			log.Printf("⚠️ serveInvoicePDF: Error checking rental ownership for rental %d: %v", rentalID, errRent)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify rental ownership"})
+			return
+		}
+	}
+	if !isAllowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this rental's documents"})
+		return
+	}
+
+	doc, err := services.GetInvoiceDocument(rentalID, kind, employeeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRentalNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvoiceNotAvailable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Handler: Error generating %s for rental %d: %v", kind, rentalID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate document"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, doc.Invoice.InvoiceNumber))
+	c.Data(http.StatusOK, "application/pdf", services.RenderInvoicePDF(doc))
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"car-rental-management/internal/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS handles GET /.well-known/jwks.json, publishing the public keys access tokens are
+// verified with so other services can check them without a shared secret. During a rotation
+// it lists both the new signing key and the keys still accepted.
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": config.JWKS()})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetTrialBalance handles GET /reports/trial-balance?as_of=YYYY-MM-DD
+func HandleGetTrialBalance(c *gin.Context) {
+	asOf := time.Now()
+	if asOfStr := c.Query("as_of"); asOfStr != "" {
+		parsed, err := time.Parse("2006-01-02", asOfStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid as_of format (use YYYY-MM-DD)"})
+			return
+		}
+		asOf = parsed.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+	// The ledger's balance sheet accounts are company-wide, so only unrestricted staff see them.
+	if scope, ok := currentBranchScope(c); !ok {
+		return
+	} else if !scope.All {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The trial balance covers every branch; it requires access to all branches"})
+		return
+	}
+
+	trialBalance, err := services.GetTrialBalance(asOf)
+	if err != nil {
+		log.Printf("❌ Handler: Error generating trial balance: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate trial balance"})
+		return
+	}
+	c.JSON(http.StatusOK, trialBalance)
+}
+
+// HandleGetRentalLedger handles GET /rentals/:id/ledger
+func HandleGetRentalLedger(c *gin.Context) {
+	rentalID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || rentalID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rental ID"})
+		return
+	}
+	if !authorizeRentalBranch(c, rentalID) {
+		return
+	}
+
+	transactions, err := services.GetLedgerTransactionsByRental(rentalID)
+	if err != nil {
+		log.Printf("❌ Handler: Error fetching ledger for rental %d: %v", rentalID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ledger transactions"})
+		return
+	}
+	c.JSON(http.StatusOK, transactions)
+}
+
+// HandleRefundPayment handles POST /payments/:paymentId/refund
+func HandleRefundPayment(c *gin.Context) {
+	paymentID, err := strconv.Atoi(c.Param("paymentId"))
+	if err != nil || paymentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+	employeeIDInterface, exists := c.Get("employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Employee authentication required"})
+		return
+	}
+	employeeID, ok := employeeIDInterface.(int)
+	if !ok || employeeID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid employee authentication data"})
+		return
+	}
+
+	if !authorizePaymentBranch(c, paymentID) {
+		return
+	}
+
+	refundedPayment, err := services.RefundPayment(paymentID, employeeID)
+	if err != nil {
+		log.Printf("❌ Handler: Error refunding payment %d: %v", paymentID, err)
+		statusCode := http.StatusInternalServerError
+		errMsg := err.Error()
+		if errMsg == "payment not found" {
+			statusCode = http.StatusNotFound
+		} else if errors.Is(err, services.ErrInvalidState) {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{"error": errMsg})
+		return
+	}
+	c.JSON(http.StatusOK, refundedPayment)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetLoginAttempts handles GET /security/login-attempts?user_type=&email=&ip=&failed=true&limit=
+func HandleGetLoginAttempts(c *gin.Context) {
+	userType := c.Query("user_type")
+	if userType != "" && userType != "employee" && userType != "customer" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_type must be 'employee' or 'customer'"})
+		return
+	}
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	attempts, err := services.GetLoginAttempts(userType, c.Query("email"), c.Query("ip"), c.Query("failed") == "true", limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login attempts"})
+		return
+	}
+	c.JSON(http.StatusOK, attempts)
+}
+
+// HandleGetLockouts handles GET /security/lockouts
+func HandleGetLockouts(c *gin.Context) {
+	lockouts, err := services.GetActiveLockouts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account lockouts"})
+		return
+	}
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// HandleUnlockAccount handles POST /security/lockouts/unlock
+func HandleUnlockAccount(c *gin.Context) {
+	var input models.UnlockAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if err := services.UnlockAccount(input.UserType, input.Email); err != nil {
+		if errors.Is(err, services.ErrLockoutNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+	if employeeID, exists := c.Get("employee_id"); exists {
+		log.Printf("🔓 Employee %v unlocked %s %s", employeeID, input.UserType, input.Email)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondLoyaltyError maps loyalty errors to HTTP statuses.
+func respondLoyaltyError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err.Error() == "customer not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInsufficientPoints):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "a reason is required":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// respondLoyaltySummary sends a customer's loyalty summary, paged by ?page=&limit=.
+func respondLoyaltySummary(c *gin.Context, customerID int) {
+	page, errPage := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if errPage != nil || errLimit != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page or limit"})
+		return
+	}
+	summary, err := services.GetLoyaltySummary(customerID, page, limit)
+	if err != nil {
+		respondLoyaltyError(c, err, "Failed to fetch loyalty points")
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// HandleGetLoyaltyTiers handles GET /loyalty/tiers
+func HandleGetLoyaltyTiers(c *gin.Context) {
+	tiers, err := services.GetLoyaltyTiers()
+	if err != nil {
+		respondLoyaltyError(c, err, "Failed to fetch loyalty tiers")
+		return
+	}
+	c.JSON(http.StatusOK, tiers)
+}
+
+// HandleGetMyLoyalty handles GET /me/loyalty?page=&limit=
+func HandleGetMyLoyalty(c *gin.Context) {
+	customerID, ok := currentCustomerID(c)
+	if !ok {
+		return
+	}
+	respondLoyaltySummary(c, customerID)
+}
+
+// HandleGetCustomerLoyalty handles GET /customers/:id/loyalty?page=&limit=
+func HandleGetCustomerLoyalty(c *gin.Context) {
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || customerID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+	respondLoyaltySummary(c, customerID)
+}
+
+// HandleAdjustCustomerLoyalty handles POST /customers/:id/loyalty/adjustments
+func HandleAdjustCustomerLoyalty(c *gin.Context) {
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || customerID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+	var input models.AdjustLoyaltyPointsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	entry, err := services.AdjustLoyaltyPoints(customerID, actingEmployeeID(c), input)
+	if err != nil {
+		respondLoyaltyError(c, err, "Failed to adjust loyalty points")
+		return
+	}
+	setAuditChange(c, services.AuditChange{After: entry})
+	c.JSON(http.StatusCreated, entry)
+}
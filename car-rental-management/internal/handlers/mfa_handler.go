@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mfaErrorStatus maps MFA service errors to HTTP status codes.
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFAChallenge), errors.Is(err, services.ErrInvalidMFACode):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrMFANotEnrolled), errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFAEnableSelfFirst):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrMFARequiredByPolicy):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrRoleNotFound):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// respondMFAError writes err with its mapped status, hiding internal error details.
+func respondMFAError(c *gin.Context, err error, fallback string) {
+	statusCode := mfaErrorStatus(err)
+	if statusCode == http.StatusInternalServerError {
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(statusCode, gin.H{"error": fallback})
+		return
+	}
+	c.JSON(statusCode, gin.H{"error": err.Error()})
+}
+
+// currentEmployeeID reads the authenticated employee's ID, answering 401 when it is missing.
+func currentEmployeeID(c *gin.Context) (int, bool) {
+	employeeIDInterface, exists := c.Get("employee_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Employee authentication required"})
+		return 0, false
+	}
+	employeeID, ok := employeeIDInterface.(int)
+	if !ok || employeeID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid employee authentication data"})
+		return 0, false
+	}
+	return employeeID, true
+}
+
+// actingEmployeeID is the employee making a staff request, or nil when an integration makes it
+// with an API key.
+func actingEmployeeID(c *gin.Context) *int {
+	if employeeIDInterface, exists := c.Get("employee_id"); exists {
+		if id, isInt := employeeIDInterface.(int); isInt {
+			return &id
+		}
+	}
+	return nil
+}
+
+// HandleVerifyMFALogin handles POST /auth/employee/mfa/verify
+func HandleVerifyMFALogin(c *gin.Context) {
+	var input models.MFAChallengeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	tokens, err := services.CompleteMFALogin(input, clientInfo(c))
+	if err != nil {
+		respondMFAError(c, err, "Failed to complete login")
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// HandleBeginMFAEnrollmentForLogin handles POST /auth/employee/mfa/enroll
+func HandleBeginMFAEnrollmentForLogin(c *gin.Context) {
+	var input struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	enrollment, err := services.BeginMFAEnrollmentForLogin(input.MFAToken)
+	if err != nil {
+		respondMFAError(c, err, "Failed to start two-factor enrollment")
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// HandleConfirmMFAEnrollmentForLogin handles POST /auth/employee/mfa/confirm
+func HandleConfirmMFAEnrollmentForLogin(c *gin.Context) {
+	var input models.MFAChallengeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	result, err := services.ConfirmMFAEnrollmentForLogin(input, clientInfo(c))
+	if err != nil {
+		respondMFAError(c, err, "Failed to confirm two-factor enrollment")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleGetMyMFAStatus handles GET /me/mfa
+func HandleGetMyMFAStatus(c *gin.Context) {
+	employeeID, ok := currentEmployeeID(c)
+	if !ok {
+		return
+	}
+	status, err := services.GetMFAStatus(employeeID)
+	if err != nil {
+		respondMFAError(c, err, "Failed to fetch two-factor status")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// HandleBeginMyMFAEnrollment handles POST /me/mfa/enroll
+func HandleBeginMyMFAEnrollment(c *gin.Context) {
+	employeeID, ok := currentEmployeeID(c)
+	if !ok {
+		return
+	}
+	enrollment, err := services.BeginMFAEnrollment(employeeID)
+	if err != nil {
+		respondMFAError(c, err, "Failed to start two-factor enrollment")
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// HandleConfirmMyMFAEnrollment handles POST /me/mfa/confirm
+func HandleConfirmMyMFAEnrollment(c *gin.Context) {
+	employeeID, ok := currentEmployeeID(c)
+	if !ok {
+		return
+	}
+	var input models.MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	codes, err := services.ConfirmMFAEnrollment(employeeID, input.Code)
+	if err != nil {
+		respondMFAError(c, err, "Failed to confirm two-factor enrollment")
+		return
+	}
+	c.JSON(http.StatusOK, models.MFAEnrollmentResult{RecoveryCodes: codes})
+}
+
+// HandleRegenerateMyRecoveryCodes handles POST /me/mfa/recovery-codes
+func HandleRegenerateMyRecoveryCodes(c *gin.Context) {
+	employeeID, ok := currentEmployeeID(c)
+	if !ok {
+		return
+	}
+	var input models.MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	codes, err := services.RegenerateRecoveryCodes(employeeID, input)
+	if err != nil {
+		respondMFAError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+	c.JSON(http.StatusOK, models.MFAEnrollmentResult{RecoveryCodes: codes})
+}
+
+// HandleDisableMyMFA handles POST /me/mfa/disable
+func HandleDisableMyMFA(c *gin.Context) {
+	employeeID, ok := currentEmployeeID(c)
+	if !ok {
+		return
+	}
+	var input models.MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if err := services.DisableMFA(employeeID, input); err != nil {
+		respondMFAError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// HandleGetMFAPolicies handles GET /security/mfa-policies
+func HandleGetMFAPolicies(c *gin.Context) {
+	policies, err := services.GetMFAPolicies()
+	if err != nil {
+		respondMFAError(c, err, "Failed to fetch MFA policies")
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+// HandleSetMFAPolicy handles PUT /security/mfa-policies
+func HandleSetMFAPolicy(c *gin.Context) {
+	employeeID, ok := currentEmployeeID(c)
+	if !ok {
+		return
+	}
+	var input models.MFAPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	policy, err := services.SetMFAPolicy(input, employeeID)
+	if err != nil {
+		respondMFAError(c, err, "Failed to save MFA policy")
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetLoginOptions handles GET /auth/employee/login-options.
+func HandleGetLoginOptions(c *gin.Context) {
+	c.JSON(http.StatusOK, services.GetLoginOptions())
+}
+
+// HandleBeginOIDCLogin handles GET /auth/employee/oidc/login. It returns the identity provider
+// URL to send the browser to, or redirects there directly with ?redirect=true.
+func HandleBeginOIDCLogin(c *gin.Context) {
+	start, err := services.BeginOIDCLogin()
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	if c.Query("redirect") == "true" {
+		c.Redirect(http.StatusFound, start.AuthorizationURL)
+		return
+	}
+	c.JSON(http.StatusOK, start)
+}
+
+// HandleCompleteOIDCLogin handles POST /auth/employee/oidc/callback. The page at the redirect
+// URL posts the code and state it received; the response matches the password login's.
+func HandleCompleteOIDCLogin(c *gin.Context) {
+	var input models.OIDCCallbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	result, err := services.CompleteOIDCLogin(input.Code, input.State, clientInfo(c))
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func respondOIDCError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOIDCNotConfigured):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOIDCInvalidState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOIDCLoginFailed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOIDCNoRole), errors.Is(err, services.ErrOIDCUnknownEmployee):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Handler: OIDC login error: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Single sign-on is currently unavailable"})
+	}
+}
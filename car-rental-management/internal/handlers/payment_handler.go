@@ -13,7 +13,11 @@ import (
 )
 
 func GetPayments(c *gin.Context) {
-	payments, err := services.GetPayments()
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	payments, err := services.GetPayments(scope)
 	if err != nil {
 		log.Println("❌ Handler: Error fetching payments:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payments"})
@@ -46,10 +50,21 @@ func ProcessPayment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment data: " + err.Error()})
 		return
 	}
+	if !input.Amount.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment data: amount must be greater than zero"})
+		return
+	}
+	if !authorizeRentalBranch(c, rentalID) {
+		return
+	}
 
 	createdPayment, err := services.ProcessPayment(rentalID, employeeID, input)
 	if err != nil {
 		log.Printf("❌ Handler: Error processing payment for rental %d by employee %d: %v", rentalID, employeeID, err)
+		if errors.Is(err, services.ErrUnknownCurrency) || errors.Is(err, services.ErrInvalidCurrency) || errors.Is(err, services.ErrRefundNotRecordable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment data: " + err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process payment: " + err.Error()})
 		return
 	}
@@ -69,6 +84,9 @@ func GetPaymentsByRental(c *gin.Context) {
 	custIDInterface, custExists := c.Get("customer_id")
 
 	if empExists {
+		if !authorizeRentalBranch(c, rentalID) {
+			return
+		}
 		isAllowed = true
 	} else if custExists {
 		rental, errRent := services.GetRentalByID(rentalID)
@@ -126,6 +144,9 @@ func GetPaymentStatus(c *gin.Context) {
 	custIDInterface, custExists := c.Get("customer_id")
 
 	if empExists {
+		if !authorizePaymentBranch(c, paymentID) {
+			return
+		}
 		isAllowed = true
 	} else if custExists {
 		rental, errRent := services.GetRentalByID(payment.RentalID)
@@ -162,7 +183,11 @@ func HandleGetRentalsPendingVerification(c *gin.Context) {
 		return
 	}
 
-	rentals, err := services.GetRentalsPendingVerification()
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	rentals, err := services.GetRentalsPendingVerification(scope)
 	if err != nil {
 		log.Printf("❌ Handler: Error fetching rentals pending verification: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rentals pending verification"})
@@ -197,7 +222,11 @@ func HandleVerifyPayment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: 'approved' field (boolean) is required"})
 		return
 	}
+	if !authorizeRentalBranch(c, rentalID) {
+		return
+	}
 
+	before := rentalPaymentsSnapshot(rentalID)
 	err = services.VerifyPayment(rentalID, input.Approved, employeeID)
 	if err != nil {
 		log.Printf("❌ Handler: Error verifying payment for rental %d: %v", rentalID, err)
@@ -225,5 +254,20 @@ func HandleVerifyPayment(c *gin.Context) {
 	if !input.Approved {
 		action = "rejected"
 	}
+	setAuditChange(c, services.AuditChange{Before: before, After: rentalPaymentsSnapshot(rentalID)})
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Payment for rental %d has been %s.", rentalID, action)})
 }
+
+// rentalPaymentsSnapshot is a rental with its payments, as recorded in the audit log when a
+// payment is verified.
+func rentalPaymentsSnapshot(rentalID int) interface{} {
+	rental, err := services.GetRentalByID(rentalID)
+	if err != nil {
+		return nil
+	}
+	payments, err := services.GetPaymentsByRentalID(rentalID)
+	if err != nil {
+		return nil
+	}
+	return gin.H{"status": rental.Status, "payments": payments}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentCustomerID reads the authenticated customer's ID, answering 401 when it is missing.
+func currentCustomerID(c *gin.Context) (int, bool) {
+	customerIDInterface, exists := c.Get("customer_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Customer authentication required"})
+		return 0, false
+	}
+	customerID, ok := customerIDInterface.(int)
+	if !ok || customerID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication data"})
+		return 0, false
+	}
+	return customerID, true
+}
+
+// respondErasureError maps erasure request errors to HTTP statuses.
+func respondErasureError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrErasureRequestNotFound), err.Error() == "customer not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrErasureAlreadyRequested), errors.Is(err, services.ErrErasureRequestNotPending),
+		errors.Is(err, services.ErrErasureHasLiveRental), errors.Is(err, services.ErrCustomerAlreadyErased):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// HandleExportMyData handles GET /me/data-export, a ZIP of everything held about the customer.
+func HandleExportMyData(c *gin.Context) {
+	customerID, ok := currentCustomerID(c)
+	if !ok {
+		return
+	}
+	archive, err := services.ExportCustomerData(customerID)
+	if err != nil {
+		respondErasureError(c, err, "Failed to export your data")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="my-data-%s.zip"`, time.Now().Format("2006-01-02")))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// HandleRequestMyErasure handles POST /me/erasure-requests
+func HandleRequestMyErasure(c *gin.Context) {
+	customerID, ok := currentCustomerID(c)
+	if !ok {
+		return
+	}
+	var input models.CreateErasureRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	request, err := services.RequestErasure(customerID, input)
+	if err != nil {
+		respondErasureError(c, err, "Failed to record erasure request")
+		return
+	}
+	c.JSON(http.StatusCreated, request)
+}
+
+// HandleGetMyErasureRequests handles GET /me/erasure-requests
+func HandleGetMyErasureRequests(c *gin.Context) {
+	customerID, ok := currentCustomerID(c)
+	if !ok {
+		return
+	}
+	requests, err := services.GetErasureRequests("", customerID)
+	if err != nil {
+		respondErasureError(c, err, "Failed to fetch erasure requests")
+		return
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+// HandleGetErasureRequests handles GET /erasure-requests?status=&customer_id=
+func HandleGetErasureRequests(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", "Pending", "Completed", "Rejected":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status filter (Pending, Completed or Rejected)"})
+		return
+	}
+	customerID := 0
+	if customerIDStr := c.Query("customer_id"); customerIDStr != "" {
+		id, err := strconv.Atoi(customerIDStr)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer_id"})
+			return
+		}
+		customerID = id
+	}
+	requests, err := services.GetErasureRequests(status, customerID)
+	if err != nil {
+		respondErasureError(c, err, "Failed to fetch erasure requests")
+		return
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+// erasureRequestAction reads the :id of an erasure request and the employee processing it,
+// which is nil for an integration.
+func erasureRequestAction(c *gin.Context) (requestID int, employeeID *int, ok bool) {
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || requestID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid erasure request ID"})
+		return 0, nil, false
+	}
+	return requestID, actingEmployeeID(c), true
+}
+
+// HandleCompleteErasureRequest handles POST /erasure-requests/:id/complete
+func HandleCompleteErasureRequest(c *gin.Context) {
+	requestID, employeeID, ok := erasureRequestAction(c)
+	if !ok {
+		return
+	}
+	request, err := services.CompleteErasureRequest(requestID, employeeID)
+	if err != nil {
+		respondErasureError(c, err, "Failed to erase customer data")
+		return
+	}
+	// The audit log cannot be changed later, so it records that the erasure happened and nothing
+	// of the data erased.
+	setAuditChange(c, services.AuditChange{Action: "customer.erase", EntityType: "customer", EntityID: strconv.Itoa(request.CustomerID)})
+	c.JSON(http.StatusOK, request)
+}
+
+// HandleRejectErasureRequest handles POST /erasure-requests/:id/reject
+func HandleRejectErasureRequest(c *gin.Context) {
+	requestID, employeeID, ok := erasureRequestAction(c)
+	if !ok {
+		return
+	}
+	var input models.RejectErasureRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	request, err := services.RejectErasureRequest(requestID, employeeID, input.Reason)
+	if err != nil {
+		respondErasureError(c, err, "Failed to reject erasure request")
+		return
+	}
+	setAuditChange(c, services.AuditChange{After: request})
+	c.JSON(http.StatusOK, request)
+}
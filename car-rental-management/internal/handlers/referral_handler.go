@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"car-rental-management/internal/services"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetMyReferral handles GET /me/referral
+func HandleGetMyReferral(c *gin.Context) {
+	customerID, ok := currentCustomerID(c)
+	if !ok {
+		return
+	}
+	summary, err := services.GetMyReferral(customerID)
+	if err != nil {
+		if err.Error() == "customer not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Handler: Error fetching referral code for customer %d: %v", customerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referral code"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// HandleGetReferralReport handles GET /reports/referrals?start_date=&end_date=, covering referrals
+// registered between the two dates inclusive (the last 30 days by default).
+func HandleGetReferralReport(c *gin.Context) {
+	startDate, errStart := time.Parse("2006-01-02", c.DefaultQuery("start_date", time.Now().AddDate(0, 0, -30).Format("2006-01-02")))
+	if errStart != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format (use YYYY-MM-DD)"})
+		return
+	}
+	endDate, errEnd := time.Parse("2006-01-02", c.DefaultQuery("end_date", time.Now().Format("2006-01-02")))
+	if errEnd != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format (use YYYY-MM-DD)"})
+		return
+	}
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date cannot be before start_date"})
+		return
+	}
+	// Customers are not tied to a branch, so only unrestricted staff see referrals.
+	if scope, ok := currentBranchScope(c); !ok {
+		return
+	} else if !scope.All {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The referral report covers every branch; it requires access to all branches"})
+		return
+	}
+
+	report, err := services.GetReferralReport(startDate, endDate.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("❌ Handler: Error generating referral report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate referral report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
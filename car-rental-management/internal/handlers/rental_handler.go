@@ -63,7 +63,15 @@ func GetRentals(c *gin.Context) {
 		}
 	}
 
-	paginatedResponse, err := services.GetRentalsPaginated(filters)
+	var ok bool
+	if filters.IncludeDeleted, ok = includeDeleted(c); !ok {
+		return
+	}
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	paginatedResponse, err := services.GetRentalsPaginated(filters, scope)
 	if err != nil {
 		log.Println("Error fetching rentals (paginated):", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rentals"})
@@ -128,7 +136,14 @@ func InitiateRental(c *gin.Context) {
 		if errors.Is(err, services.ErrCarNotFound) || errors.Is(err, services.ErrRentalNotFound) {
 			statusCode = http.StatusNotFound
 			errMsg = specificErr
-		} else if errors.Is(err, services.ErrInvalidDates) || errors.Is(err, services.ErrCarNotAvailable) || errors.Is(err, services.ErrInvalidState) || strings.Contains(specificErr, "overlap") {
+		} else if errors.Is(err, services.ErrNotCorporateMember) || errors.Is(err, services.ErrEmailNotVerified) || errors.Is(err, services.ErrCustomerBlacklisted) ||
+			errors.Is(err, services.ErrLicenceMissing) || errors.Is(err, services.ErrLicenceNotVerified) || errors.Is(err, services.ErrLicenceExpiresBeforeDropoff) {
+			statusCode = http.StatusForbidden
+			errMsg = specificErr
+		} else if errors.Is(err, services.ErrInvalidDates) || errors.Is(err, services.ErrCarNotAvailable) || errors.Is(err, services.ErrInvalidState) ||
+			errors.Is(err, services.ErrCorporateAccountInactive) || errors.Is(err, services.ErrSpendingLimitExceeded) || strings.Contains(specificErr, "overlap") ||
+			errors.Is(err, services.ErrInsufficientPoints) || errors.Is(err, services.ErrRedemptionTooSmall) || errors.Is(err, services.ErrRedemptionExceedsPrice) ||
+			errors.Is(err, services.ErrLoyaltyCorporateBooking) {
 			statusCode = http.StatusBadRequest
 			errMsg = specificErr
 		} else {
@@ -137,6 +152,10 @@ func InitiateRental(c *gin.Context) {
 		c.JSON(statusCode, gin.H{"error": errMsg})
 		return
 	}
+	if initiatedRental.CorporateAccountID != nil {
+		c.JSON(http.StatusCreated, gin.H{"message": "Rental booked and billed to your corporate account.", "id": initiatedRental.ID, "status": initiatedRental.Status})
+		return
+	}
 	c.JSON(http.StatusCreated, gin.H{"message": "Rental initiated successfully. Please proceed to payment.", "id": initiatedRental.ID, "status": initiatedRental.Status})
 }
 
@@ -222,7 +241,15 @@ func GetRentalByID(c *gin.Context) {
 	customerIDInterface, custExists := c.Get("customer_id")
 	_, empExists := c.Get("employee_id")
 
-	rental, err := services.GetRentalByID(rentalID)
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+	getRental := services.GetRentalByID
+	if withDeleted {
+		getRental = services.GetRentalByIDIncludingDeleted
+	}
+	rental, err := getRental(rentalID)
 	if err != nil {
 		if errors.Is(err, services.ErrRentalNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -235,6 +262,9 @@ func GetRentalByID(c *gin.Context) {
 
 	isAllowed := false
 	if empExists {
+		if !authorizeRentalBranch(c, rentalID) {
+			return
+		}
 		isAllowed = true
 	} else if custExists {
 		customerID, ok := customerIDInterface.(int)
@@ -314,7 +344,11 @@ func UpdateRentalStatusByStaff(c *gin.Context, targetStatus string) {
 	}
 
 	log.Printf("🔄 UpdateRentalStatusByStaff: Staff %d attempting to set rental %d status to '%s'", employeeID, rentalID, targetStatus)
+	if !authorizeRentalBranch(c, rentalID) {
+		return
+	}
 
+	before := auditSnapshot(services.GetRentalByID(rentalID))
 	// Pass nil for the tx argument, as the handler doesn't manage it.
 	// The service's UpdateRentalStatus will create its own transaction if tx is nil.
 	updatedRental, err := services.UpdateRentalStatus(nil, rentalID, targetStatus, &employeeID)
@@ -345,6 +379,7 @@ func UpdateRentalStatusByStaff(c *gin.Context, targetStatus string) {
 	}
 
 	log.Printf("✅ UpdateRentalStatusByStaff: Rental %d status updated to '%s' successfully", rentalID, targetStatus)
+	setAuditChange(c, services.AuditChange{Before: before, After: updatedRental})
 	c.JSON(http.StatusOK, updatedRental)
 }
 
@@ -363,7 +398,11 @@ func DeleteRental(c *gin.Context) {
 
 	// Add logging for staff action
 	log.Printf("🗑️ DeleteRental (Staff): Attempting to delete rental %d", rentalID)
+	if !authorizeRentalBranch(c, rentalID) {
+		return
+	}
 
+	before := auditSnapshot(services.GetRentalByID(rentalID))
 	err = services.DeleteRental(rentalID)
 	if err != nil {
 		log.Printf("❌ DeleteRental (Staff): Error deleting rental %d: %v", rentalID, err)
@@ -383,6 +422,7 @@ func DeleteRental(c *gin.Context) {
 	}
 
 	log.Printf("✅ DeleteRental (Staff): Rental %d deleted successfully", rentalID)
+	setAuditChange(c, services.AuditChange{Before: before})
 	c.JSON(http.StatusOK, gin.H{"message": "Rental deleted successfully"})
 }
 
@@ -400,7 +440,10 @@ func GetRentalPrice(c *gin.Context) {
 	custIDInterface, custExists := c.Get("customer_id")
 
 	if empExists {
-		isAllowed = true // Employees can see any rental price
+		if !authorizeRentalBranch(c, rentalID) {
+			return
+		}
+		isAllowed = true // Employees can see the price of any rental in their branches
 	} else if custExists {
 		// Customers can only see price for their own rentals
 		rental, errRent := services.GetRentalByID(rentalID) // Fetch rental to check owner
@@ -444,5 +487,21 @@ func GetRentalPrice(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"rental_id": rentalID, "amount": priceDetails.Amount, "currency": "THB"})
+	response := gin.H{"rental_id": rentalID, "amount": priceDetails.Amount, "currency": models.BaseCurrency}
+	if currency := c.Query("currency"); currency != "" {
+		rate, errRate := services.GetExchangeRate(currency)
+		if errRate != nil {
+			respondDisplayCurrencyError(c, errRate)
+			return
+		}
+		if rate.Currency != models.BaseCurrency {
+			display, errConv := services.ConvertFromBase(priceDetails.Amount, rate)
+			if errConv != nil {
+				respondDisplayCurrencyError(c, errConv)
+				return
+			}
+			response["display_price"] = display
+		}
+	}
+	c.JSON(http.StatusOK, response)
 }
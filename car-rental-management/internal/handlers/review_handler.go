@@ -117,7 +117,7 @@ func GetRentalReview(c *gin.Context) {
 		return
 	}
 
-	if actorRole == "admin" || actorRole == "manager" {
+	if actorRole != "customer" { // Any employee may view the review of a rental they can see
 		isAllowed = true
 		// Get employee_id for logging, though not strictly for permission logic itself here
 		if empIDInterface, empExists := c.Get("employee_id"); empExists {
@@ -206,6 +206,7 @@ func DeleteReview(c *gin.Context) {
 		return
 	}
 
+	before := auditSnapshot(services.GetReviewByID(reviewID))
 	err = services.DeleteReview(reviewID, actorID, actorRole)
 	if err != nil {
 		log.Printf("❌ Handler: Error deleting review %d by actor %d (%s): %v", reviewID, actorID, actorRole, err)
@@ -224,6 +225,7 @@ func DeleteReview(c *gin.Context) {
 		c.JSON(statusCode, gin.H{"error": errMsg})
 		return
 	}
+	setAuditChange(c, services.AuditChange{Before: before})
 	c.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully"})
 }
 
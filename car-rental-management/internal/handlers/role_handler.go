@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// roleErrorStatus maps role service errors to HTTP status codes.
+func roleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidRoleName), errors.Is(err, services.ErrUnknownPermission):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrRoleExists), errors.Is(err, services.ErrRoleInUse):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrSystemRole):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// respondRoleError writes err with its mapped status, hiding internal error details.
+func respondRoleError(c *gin.Context, err error, fallback string) {
+	statusCode := roleErrorStatus(err)
+	if statusCode == http.StatusInternalServerError {
+		log.Printf("❌ Handler: %s: %v", fallback, err)
+		c.JSON(statusCode, gin.H{"error": fallback})
+		return
+	}
+	c.JSON(statusCode, gin.H{"error": err.Error()})
+}
+
+// HandleGetPermissions handles GET /permissions
+func HandleGetPermissions(c *gin.Context) {
+	permissions, err := services.GetPermissions()
+	if err != nil {
+		respondRoleError(c, err, "Failed to fetch permissions")
+		return
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+// HandleGetRoles handles GET /roles
+func HandleGetRoles(c *gin.Context) {
+	roles, err := services.GetRoles()
+	if err != nil {
+		respondRoleError(c, err, "Failed to fetch roles")
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// HandleGetRole handles GET /roles/:name
+func HandleGetRole(c *gin.Context) {
+	role, err := services.GetRoleByName(c.Param("name"))
+	if err != nil {
+		respondRoleError(c, err, "Failed to fetch role")
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// HandleCreateRole handles POST /roles
+func HandleCreateRole(c *gin.Context) {
+	var input models.CreateRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	name, err := services.CreateRole(input)
+	if err != nil {
+		respondRoleError(c, err, "Failed to create role")
+		return
+	}
+	role, err := services.GetRoleByName(name)
+	if err != nil {
+		respondRoleError(c, err, "Role created but failed to fetch it")
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+// HandleUpdateRole handles PUT /roles/:name
+func HandleUpdateRole(c *gin.Context) {
+	var input models.UpdateRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	name := c.Param("name")
+	if err := services.UpdateRole(name, input); err != nil {
+		respondRoleError(c, err, "Failed to update role")
+		return
+	}
+	role, err := services.GetRoleByName(name)
+	if err != nil {
+		respondRoleError(c, err, "Role updated but failed to fetch it")
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// HandleDeleteRole handles DELETE /roles/:name
+func HandleDeleteRole(c *gin.Context) {
+	if err := services.DeleteRole(c.Param("name")); err != nil {
+		respondRoleError(c, err, "Failed to delete role")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// HandleGetMyPermissions handles GET /me/permissions, so clients can hide actions the employee or
+// API key cannot take.
+func HandleGetMyPermissions(c *gin.Context) {
+	if keyInterface, keyExists := c.Get("api_key"); keyExists {
+		key, _ := keyInterface.(services.APIKeyPrincipal)
+		c.JSON(http.StatusOK, gin.H{"user_type": "integration", "api_key_id": key.KeyID, "name": key.Name,
+			"permissions": key.Permissions, "all_branches": key.Scope.All, "branch_ids": key.Scope.BranchIDs})
+		return
+	}
+	role, _ := c.Get("user_role")
+	roleName, _ := role.(string)
+	permissions, err := services.GetRolePermissions(roleName)
+	if err != nil {
+		respondRoleError(c, err, "Failed to fetch permissions")
+		return
+	}
+	scope, ok := currentBranchScope(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"role": roleName, "permissions": permissions, "all_branches": scope.All, "branch_ids": scope.BranchIDs})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"car-rental-management/internal/services"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentSessionUser returns the logged-in employee or customer. Integrations have no sessions,
+// so they are refused with 403; the response has been written when ok is false.
+func currentSessionUser(c *gin.Context) (userType string, userID int, ok bool) {
+	if employeeIDInterface, exists := c.Get("employee_id"); exists {
+		userType = "employee"
+		userID, _ = employeeIDInterface.(int)
+	} else if customerIDInterface, exists := c.Get("customer_id"); exists {
+		userType = "customer"
+		userID, _ = customerIDInterface.(int)
+	} else if c.GetString("user_type") == "integration" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys have no sessions"})
+		return "", 0, false
+	}
+	if userID <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication data"})
+		return "", 0, false
+	}
+	return userType, userID, true
+}
+
+// HandleGetMySessions handles GET /me/sessions: the caller's signed-in devices, with the
+// session making the request marked current.
+func HandleGetMySessions(c *gin.Context) {
+	userType, userID, ok := currentSessionUser(c)
+	if !ok {
+		return
+	}
+	sessions, err := services.GetSessions(userType, userID, c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// HandleRevokeMySession handles DELETE /me/sessions/:id, signing one device out.
+func HandleRevokeMySession(c *gin.Context) {
+	userType, userID, ok := currentSessionUser(c)
+	if !ok {
+		return
+	}
+	if err := services.RevokeSession(userType, userID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ Failed to revoke session for %s %d: %v", userType, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// HandleRevokeMySessions handles DELETE /me/sessions, signing out every other device.
+// ?include_current=true signs out the current one as well.
+func HandleRevokeMySessions(c *gin.Context) {
+	userType, userID, ok := currentSessionUser(c)
+	if !ok {
+		return
+	}
+	keepSessionID := c.GetString("session_id")
+	if c.Query("include_current") == "true" {
+		keepSessionID = ""
+	}
+	revoked, err := services.RevokeAllSessions(userType, userID, keepSessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sessions revoked", "revoked": revoked})
+}
@@ -12,7 +12,11 @@ import (
 )
 
 func GetUsers(c *gin.Context) {
-	users, err := services.GetUsers()
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+	users, err := services.GetUsers(withDeleted)
 	if err != nil {
 		log.Println("❌ Error fetching users (employees):", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
@@ -35,10 +39,14 @@ func CreateUser(c *gin.Context) {
 		errMsg := "Failed to create user"
 
 		errStr := err.Error()
-		if errStr == "employee email already exists" {
+		if errors.Is(err, services.ErrRoleNotFound) {
+			statusCode = http.StatusBadRequest
+			errMsg = errStr
+		} else if errStr == "employee email already exists" {
 			statusCode = http.StatusConflict
 			errMsg = errStr
-		} else if errStr == "employee name cannot be empty" || errStr == "invalid email format" || errStr == "password must be at least 6 characters" {
+		} else if errStr == "employee name cannot be empty" || errStr == "invalid email format" || errStr == "password must be at least 6 characters" ||
+			errStr == "one or more branches do not exist" {
 			statusCode = http.StatusBadRequest
 			errMsg = errStr
 		} else if errStr == "failed to secure password" {
@@ -51,6 +59,7 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
+	setAuditChange(c, services.AuditChange{EntityID: strconv.Itoa(createdUser.ID), After: createdUser})
 	c.JSON(http.StatusCreated, createdUser)
 }
 
@@ -68,6 +77,7 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	before := auditSnapshot(services.GetUserByID(id))
 	updatedUser, err := services.UpdateEmployeeByAdmin(id, input)
 	if err != nil {
 		log.Printf("❌ Handler: Error updating user %d by admin: %v", id, err)
@@ -75,7 +85,10 @@ func UpdateUser(c *gin.Context) {
 		errMsg := "Failed to update user"
 
 		errStr := err.Error()
-		if errStr == "employee not found for update" {
+		if errors.Is(err, services.ErrRoleNotFound) {
+			statusCode = http.StatusBadRequest
+			errMsg = errStr
+		} else if errStr == "employee not found for update" {
 			statusCode = http.StatusNotFound
 			errMsg = errStr
 		} else if errStr == "email already exists for another employee" {
@@ -91,6 +104,7 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	setAuditChange(c, services.AuditChange{Before: before, After: updatedUser})
 	c.JSON(http.StatusOK, updatedUser)
 }
 
@@ -102,6 +116,7 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
+	before := auditSnapshot(services.GetUserByID(id))
 	err = services.DeleteEmployeeByAdmin(id)
 	if err != nil {
 		log.Printf("❌ Handler: Error deleting user %d by admin: %v", id, err)
@@ -118,5 +133,64 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
+	setAuditChange(c, services.AuditChange{Before: before})
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
+
+// RevokeUserTokens handles POST /users/:id/revoke-tokens, signing the employee out of every session.
+func RevokeUserTokens(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if err := services.RevokeEmployeeTokens(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke user tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions for this user have been revoked"})
+}
+
+// GetUserBranches handles GET /users/:id/branches.
+func GetUserBranches(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	branchIDs, err := services.GetEmployeeBranchIDs(id)
+	if err != nil {
+		log.Printf("❌ Handler: Error fetching branches for user %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user branches"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"employee_id": id, "branch_ids": branchIDs})
+}
+
+// SetUserBranches handles PUT /users/:id/branches, replacing the branches the employee works at.
+func SetUserBranches(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var input models.EmployeeBranchesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if err := services.SetEmployeeBranches(id, input.BranchIDs); err != nil {
+		switch err.Error() {
+		case "employee not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "one or more branches do not exist":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("❌ Handler: Error setting branches for user %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user branches"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"employee_id": id, "branch_ids": input.BranchIDs})
+}
@@ -0,0 +1,142 @@
+// Package mailer delivers transactional email through a pluggable sink chosen by
+// MAIL_DRIVER: "log" (default, prints to the server log), "file" (writes .eml files
+// to MAIL_FILE_DIR) or "smtp" (SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD).
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var (
+	defaultMailer Mailer
+	defaultOnce   sync.Once
+)
+
+// Default returns the mailer configured from the environment.
+func Default() Mailer {
+	defaultOnce.Do(func() {
+		defaultMailer = fromEnv()
+	})
+	return defaultMailer
+}
+
+// Send delivers msg with the default mailer.
+func Send(msg Message) error {
+	return Default().Send(msg)
+}
+
+func fromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@car-rental.local"
+	}
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		log.Printf("📧 Mailer: Sending mail via SMTP %s:%s", os.Getenv("SMTP_HOST"), port)
+		return SMTPMailer{
+			Addr:     net.JoinHostPort(os.Getenv("SMTP_HOST"), port),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		dir := os.Getenv("MAIL_FILE_DIR")
+		if dir == "" {
+			dir = "./mail"
+		}
+		log.Printf("📧 Mailer: Writing mail to %s", dir)
+		return FileMailer{Dir: dir, From: from}
+	case "", "log":
+		log.Println("📧 Mailer: Logging mail instead of sending it (set MAIL_DRIVER=smtp to deliver)")
+		return LogMailer{From: from}
+	default:
+		log.Printf("⚠️ Mailer: Unknown MAIL_DRIVER '%s', logging mail instead", driver)
+		return LogMailer{From: from}
+	}
+}
+
+// render formats msg as an RFC 5322 message.
+func render(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// LogMailer prints messages to the server log. For development only: it logs the full body.
+type LogMailer struct {
+	From string
+}
+
+func (m LogMailer) Send(msg Message) error {
+	log.Printf("📧 Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes each message to its own .eml file in Dir.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102-150405.000000000"), recipient)
+	if err := os.WriteFile(filepath.Join(m.Dir, name), render(m.From, msg), 0o600); err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	log.Printf("📧 Mail to %s written to %s", msg.To, filepath.Join(m.Dir, name))
+	return nil
+}
+
+// SMTPMailer sends messages through an SMTP server, using PLAIN auth when a username is set.
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := net.SplitHostPort(m.Addr)
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	if err := smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, render(m.From, msg)); err != nil {
+		return fmt.Errorf("failed to send mail via SMTP: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/services"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditStaffActions writes an audit log entry for every successful change an employee or API key
+// makes through the routes it guards. Handlers describe the change with the "audit_change" context
+// value (a services.AuditChange holding the before and after states); anything they leave out is
+// derived from the route, e.g. POST /api/rentals/:id/confirm is "rental.confirm" on rental :id.
+// Customers' own actions are not audited. It should be applied AFTER AuthMiddleware.
+func AuditStaffActions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest {
+			return
+		}
+
+		entry := models.AuditLogEntry{
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			StatusCode: status,
+			IPAddress:  c.ClientIP(),
+			RequestID:  c.GetString("request_id"),
+		}
+		if employeeIDInterface, exists := c.Get("employee_id"); exists {
+			employeeID, _ := employeeIDInterface.(int)
+			email := c.GetString("user_email")
+			entry.ActorType = "employee"
+			entry.ActorEmployeeID = &employeeID
+			entry.ActorEmail = &email
+		} else if keyIDInterface, exists := c.Get("api_key_id"); exists {
+			keyID, _ := keyIDInterface.(int)
+			entry.ActorType = "integration"
+			entry.ActorAPIKeyID = &keyID
+		} else {
+			return
+		}
+
+		var change services.AuditChange
+		if changeInterface, exists := c.Get("audit_change"); exists {
+			change, _ = changeInterface.(services.AuditChange)
+		}
+		action, entityType, entityID := auditRouteDefaults(c)
+		if change.Action == "" {
+			change.Action = action
+		}
+		if change.EntityType == "" {
+			change.EntityType = entityType
+		}
+		if change.EntityID == "" {
+			change.EntityID = entityID
+		}
+
+		// The change has already been made, so a failure here is logged rather than reported.
+		if err := services.RecordAudit(entry, change); err != nil {
+			log.Printf("🔥 Failed to audit %s %s (request %s): %v", entry.Method, entry.Path, entry.RequestID, err)
+		}
+	}
+}
+
+// auditRouteDefaults names an action after its route. The first path segment is the entity type,
+// the first path parameter the entity ID, and any later fixed segments the verb; routes without
+// them use create, update or delete. Routes under /me act on the calling employee,
+// e.g. POST /api/me/mfa/disable is "employee.mfa_disable".
+func auditRouteDefaults(c *gin.Context) (action, entityType, entityID string) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(c.FullPath(), "/api"), "/"), "/")
+	if segments[0] == "me" {
+		entityType = "employee"
+		if employeeIDInterface, exists := c.Get("employee_id"); exists {
+			employeeID, _ := employeeIDInterface.(int)
+			entityID = strconv.Itoa(employeeID)
+		}
+	} else {
+		entityType = singularEntityName(segments[0])
+	}
+
+	var verbs []string
+	for _, segment := range segments[1:] {
+		if strings.HasPrefix(segment, ":") {
+			if entityID == "" {
+				entityID = c.Param(segment[1:])
+			}
+			continue
+		}
+		verbs = append(verbs, strings.ReplaceAll(segment, "-", "_"))
+	}
+	switch c.Request.Method {
+	case http.MethodPut, http.MethodPatch:
+		verbs = append(verbs, "update")
+	case http.MethodDelete:
+		verbs = append(verbs, "delete")
+	case http.MethodPost:
+		if len(verbs) == 0 {
+			verbs = append(verbs, "create")
+		}
+	}
+	return entityType + "." + strings.Join(verbs, "_"), entityType, entityID
+}
+
+// singularEntityName turns a collection path segment such as "exchange-rates" into "exchange_rate".
+func singularEntityName(segment string) string {
+	name := strings.ReplaceAll(segment, "-", "_")
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "sses"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
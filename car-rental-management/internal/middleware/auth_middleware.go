@@ -2,19 +2,25 @@ package middleware
 
 import (
 	"car-rental-management/internal/config" // Use correct path
+	"car-rental-management/internal/services"
 	"errors"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware validates JWT tokens (Employee or Customer)
+// AuthMiddleware validates JWT tokens (Employee or Customer), or an X-API-Key header for integrations
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" && authHeader == "" {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
 		if authHeader == "" {
 			log.Println("❌ Missing Authorization header")
 			// Return JSON error for API consistency
@@ -35,13 +41,19 @@ func AuthMiddleware() gin.HandlerFunc {
 		claims := jwt.MapClaims{}
 
 		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			// The kid header picks the key; the key's own algorithm must match the token's
+			kid, _ := token.Header["kid"].(string)
+			key, ok := config.JWTVerificationKey(kid)
+			if !ok {
+				log.Printf("❌ Token signed with unknown key %q", kid)
+				return nil, jwt.ErrSignatureInvalid
+			}
+			if token.Method.Alg() != key.Algorithm {
 				log.Printf("❌ Unexpected signing method: %v", token.Header["alg"])
-				return nil, jwt.ErrSignatureInvalid // Or a more specific error
+				return nil, jwt.ErrSignatureInvalid
 			}
-			return []byte(config.JwtSecret), nil
-		})
+			return key.Public, nil
+		}, jwt.WithValidMethods([]string{"RS256", "EdDSA"}))
 
 		if err != nil {
 			log.Println("❌ Token error or invalid token:", err)
@@ -75,7 +87,16 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		c.Set("user_email", email) // Set common claim
+		c.Set("user_type", userType)
+
+		// jti and exp let /auth/logout revoke this exact token
+		jti, _ := claims["jti"].(string)
+		if expFloat, ok := claims["exp"].(float64); ok {
+			c.Set("token_expires_at", time.Unix(int64(expFloat), 0))
+		}
+		c.Set("token_jti", jti)
 
+		var userID int
 		if userType == "employee" {
 			role, roleOk := claims["role"].(string)
 			employeeIDFloat, employeeIDok := claims["employee_id"].(float64) // JWT numbers are often float64
@@ -87,6 +108,30 @@ func AuthMiddleware() gin.HandlerFunc {
 				return
 			}
 			employeeID := int(employeeIDFloat)
+			userID = employeeID
+
+			// Employee tokens are checked against the revocation list so that logging out,
+			// deleting an employee or revoking their sessions takes effect immediately.
+			if jti == "" {
+				log.Printf("❌ Employee token for %s has no jti (issued before revocation support)", email)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is no longer accepted, please log in again"})
+				c.Abort()
+				return
+			}
+			revoked, err := services.IsAccessTokenRevoked(jti)
+			if err != nil {
+				log.Printf("❌ Error checking revocation for employee %d: %v", employeeID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				log.Printf("❌ Revoked token presented by employee %d", employeeID)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+
 			log.Printf("✅ Authenticated Employee: %s (ID: %d) with role: %s", email, employeeID, role)
 			c.Set("user_role", role)
 			c.Set("employee_id", employeeID)
@@ -102,6 +147,7 @@ func AuthMiddleware() gin.HandlerFunc {
 				return
 			}
 			customerID := int(customerIDFloat)
+			userID = customerID
 			log.Printf("✅ Authenticated Customer: %s (ID: %d)", email, customerID)
 			c.Set("customer_id", customerID)
 			c.Set("user_role", "customer") // Set role for consistency if needed
@@ -112,6 +158,66 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Every token belongs to a server-side session, so ending a session from another
+		// device locks out its access tokens straight away.
+		sessionID, _ := claims["sid"].(string)
+		if sessionID == "" {
+			log.Printf("❌ Token for %s %d has no session (issued before session tracking)", userType, userID)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is no longer accepted, please log in again"})
+			c.Abort()
+			return
+		}
+		if err := services.CheckSession(sessionID, userType, userID, c.ClientIP()); err != nil {
+			if errors.Is(err, services.ErrSessionRevoked) {
+				log.Printf("❌ Token from ended session %s presented by %s %d", sessionID, userType, userID)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			} else {
+				log.Printf("❌ Error checking session for %s %d: %v", userType, userID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+			}
+			c.Abort()
+			return
+		}
+		c.Set("session_id", sessionID)
+
 		c.Next()
 	}
 }
+
+// OptionalAuth authenticates the caller like AuthMiddleware when they send credentials and lets
+// anonymous requests through, for public routes that show more to staff.
+func OptionalAuth() gin.HandlerFunc {
+	authenticate := AuthMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && c.GetHeader("X-API-Key") == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// authenticateAPIKey accepts an integration's API key. Integrations have the "integration" user
+// type and neither an employee_id nor a customer_id: staff routes check the key's permissions
+// and branches, and routes that act as a particular person refuse them.
+func authenticateAPIKey(c *gin.Context, rawKey string) {
+	key, err := services.AuthenticateAPIKey(rawKey, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			log.Printf("❌ Invalid API key presented from %s", c.ClientIP())
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			log.Printf("❌ Error checking API key: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+		}
+		c.Abort()
+		return
+	}
+
+	log.Printf("✅ Authenticated integration: %s (API key %d)", key.Name, key.KeyID)
+	c.Set("user_type", "integration")
+	c.Set("api_key", key)
+	c.Set("api_key_id", key.KeyID)
+	c.Set("branch_scope", key.Scope)
+	c.Next()
+}
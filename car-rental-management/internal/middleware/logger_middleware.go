@@ -40,6 +40,9 @@ func RequestLogger() gin.HandlerFunc {
 		if raw != "" {
 			logLine += "?" + raw
 		}
+		if requestID := c.GetString("request_id"); requestID != "" {
+			logLine += " | " + requestID
+		}
 		// if errorMessage != "" {
 		//	 logLine += " | " + errorMessage // เพิ่ม Error message ใน Log (ถ้าต้องการ)
 		// }
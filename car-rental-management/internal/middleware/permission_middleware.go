@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"car-rental-management/internal/services"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmployeeRequired checks that the authenticated user is an employee, whatever their role, or an
+// integration using an API key. It should be applied AFTER AuthMiddleware.
+func EmployeeRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, keyExists := c.Get("api_key"); keyExists {
+			c.Next()
+			return
+		}
+		if _, empExists := c.Get("employee_id"); !empExists {
+			log.Println("❌ Employee access required, but no employee_id found in context")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Employee role required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission checks that the authenticated employee's role, or the API key, holds every
+// listed permission. Permissions are read from the database on each request, so role edits apply
+// immediately. It should be applied AFTER AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if keyInterface, keyExists := c.Get("api_key"); keyExists {
+			key, _ := keyInterface.(services.APIKeyPrincipal)
+			if !key.HasPermissions(permissions...) {
+				log.Printf("❌ Access denied: API key %d (%s) lacks %v", key.KeyID, key.Name, permissions)
+				c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Insufficient permissions"})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		employeeIDInterface, empExists := c.Get("employee_id")
+		if !empExists {
+			log.Println("❌ RequirePermission applied, but user is not an employee (no employee_id in context)")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Employee role required"})
+			c.Abort()
+			return
+		}
+
+		userRoleInterface, roleExists := c.Get("user_role")
+		role, roleIsString := userRoleInterface.(string)
+		if !roleExists || !roleIsString || role == "" {
+			log.Println("❌ No user role found in context or role is not a string (for employee)")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Role information missing"})
+			c.Abort()
+			return
+		}
+
+		allowed, err := services.RoleHasPermissions(role, permissions...)
+		if err != nil {
+			log.Printf("❌ Error checking permissions %v for role %s: %v", permissions, role, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			log.Printf("❌ Access denied: Employee %v with role '%s' lacks %v", employeeIDInterface, role, permissions)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
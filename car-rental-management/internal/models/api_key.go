@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// APIKey describes an integration key. The secret itself is only returned once, on creation.
+type APIKey struct {
+	ID                  int        `db:"id" json:"id"`
+	Name                string     `db:"name" json:"name"`
+	KeyPrefix           string     `db:"key_prefix" json:"key_prefix"`
+	AllBranches         bool       `db:"all_branches" json:"all_branches"`
+	CreatedByEmployeeID *int       `db:"created_by_employee_id" json:"created_by_employee_id,omitempty"`
+	ExpiresAt           *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt          *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	LastUsedIP          *string    `db:"last_used_ip" json:"last_used_ip,omitempty"`
+	RevokedAt           *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+	Permissions         []string   `db:"-" json:"permissions"`
+	BranchIDs           []int      `db:"-" json:"branch_ids"`
+}
+
+// CreateAPIKeyInput is the payload for POST /api-keys. Leaving BranchIDs out lets the key act on
+// every branch; ExpiresAt is optional.
+type CreateAPIKeyInput struct {
+	Name        string     `json:"name" binding:"required,max=100"`
+	Permissions []string   `json:"permissions" binding:"required,min=1"`
+	BranchIDs   []int      `json:"branch_ids"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is returned once when a key is created; Key cannot be retrieved again.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
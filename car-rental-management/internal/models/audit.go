@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// AuditLogEntry is one staff or integration action. Before and After hold only the fields that
+// changed; a create has no Before and a delete no After.
+type AuditLogEntry struct {
+	ID              int64           `db:"id" json:"id"`
+	ActorType       string          `db:"actor_type" json:"actor_type"` // "employee" or "integration"
+	ActorEmployeeID *int            `db:"actor_employee_id" json:"actor_employee_id,omitempty"`
+	ActorAPIKeyID   *int            `db:"actor_api_key_id" json:"actor_api_key_id,omitempty"`
+	ActorEmail      *string         `db:"actor_email" json:"actor_email,omitempty"`
+	Action          string          `db:"action" json:"action"` // e.g. "rental.delete"
+	EntityType      string          `db:"entity_type" json:"entity_type"`
+	EntityID        *string         `db:"entity_id" json:"entity_id,omitempty"`
+	Before          *types.JSONText `db:"before_data" json:"before,omitempty"`
+	After           *types.JSONText `db:"after_data" json:"after,omitempty"`
+	Method          string          `db:"http_method" json:"method"`
+	Path            string          `db:"http_path" json:"path"`
+	StatusCode      int             `db:"status_code" json:"status_code"`
+	IPAddress       string          `db:"ip_address" json:"ip_address"`
+	RequestID       string          `db:"request_id" json:"request_id"`
+	CreatedAt       time.Time       `db:"created_at" json:"created_at"`
+}
+
+// AuditLogFilters narrows GET /audit. Zero values do not filter.
+type AuditLogFilters struct {
+	ActorEmployeeID *int
+	ActorAPIKeyID   *int
+	Action          string
+	EntityType      string
+	EntityID        string
+	RequestID       string
+	From            *time.Time
+	To              *time.Time
+	Page            int
+	Limit           int
+}
+
+// PaginatedAuditLogResponse is a page of audit entries, newest first.
+type PaginatedAuditLogResponse struct {
+	Entries    []AuditLogEntry `json:"entries"`
+	TotalCount int             `json:"total_count"`
+	Page       int             `json:"page"`
+	Limit      int             `json:"limit"`
+	TotalPages int             `json:"total_pages"`
+}
@@ -0,0 +1,39 @@
+package models
+
+// TokenPair is returned on login and refresh. Token is the short-lived access token,
+// kept under its original name so existing clients continue to work.
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// RefreshTokenInput carries the refresh token for /auth/refresh and /auth/logout.
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ForgotPasswordInput requests a password reset link, and is reused to re-send a
+// verification email. UserType defaults to "customer".
+type ForgotPasswordInput struct {
+	Email    string `json:"email" binding:"required,email"`
+	UserType string `json:"user_type" binding:"omitempty,oneof=customer employee"`
+}
+
+// ResetPasswordInput sets a new password using the token from a reset email.
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// VerifyEmailInput confirms an email address using the token from a verification email.
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ClientInfo describes the device a login or refresh came from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
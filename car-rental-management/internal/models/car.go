@@ -3,14 +3,17 @@ package models
 import "time"
 
 type Car struct {
-	ID           int       `db:"id" json:"id"`
-	Brand        string    `db:"brand" json:"brand" binding:"required"`
-	Model        string    `db:"model" json:"model" binding:"required"`
-	PricePerDay  float64   `db:"price_per_day" json:"price_per_day" binding:"required,gt=0"`
-	Availability bool      `db:"availability" json:"availability"`
-	ParkingSpot  *string   `db:"parking_spot" json:"parking_spot"` // Pointer for nullable
-	BranchID     int       `db:"branch_id" json:"branch_id" binding:"required"`
-	ImageURL     *string   `db:"image_url" json:"image_url"` // Pointer for nullable
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+	ID           int        `db:"id" json:"id"`
+	Brand        string     `db:"brand" json:"brand" binding:"required"`
+	Model        string     `db:"model" json:"model" binding:"required"`
+	PricePerDay  Money      `db:"price_per_day" json:"price_per_day"` // Validated (> 0) in the service layer
+	Availability bool       `db:"availability" json:"availability"`
+	ParkingSpot  *string    `db:"parking_spot" json:"parking_spot"` // Pointer for nullable
+	BranchID     int        `db:"branch_id" json:"branch_id" binding:"required"`
+	ImageURL     *string    `db:"image_url" json:"image_url"` // Pointer for nullable
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt    *time.Time `db:"deleted_at" json:"deleted_at,omitempty"` // Set when soft-deleted
+
+	DisplayPrice *DisplayPrice `db:"-" json:"display_price,omitempty"` // Set when a ?currency= other than the base is requested
 }
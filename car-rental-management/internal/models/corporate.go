@@ -0,0 +1,107 @@
+package models
+
+import "time"
+
+// CorporateAccount is a business billed monthly (net terms) for its members' rentals.
+type CorporateAccount struct {
+	ID               int       `db:"id" json:"id"`
+	Name             string    `db:"name" json:"name"`
+	TaxID            *string   `db:"tax_id" json:"tax_id"`
+	BillingEmail     string    `db:"billing_email" json:"billing_email"`
+	BillingAddress   *string   `db:"billing_address" json:"billing_address"`
+	PaymentTermsDays int       `db:"payment_terms_days" json:"payment_terms_days"`
+	IsActive         bool      `db:"is_active" json:"is_active"`
+	CreatedAt        time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CorporateAccountInput is used to create or update an account.
+type CorporateAccountInput struct {
+	Name             string  `json:"name" binding:"required"`
+	TaxID            *string `json:"tax_id"`
+	BillingEmail     string  `json:"billing_email" binding:"required,email"`
+	BillingAddress   *string `json:"billing_address"`
+	PaymentTermsDays *int    `json:"payment_terms_days" binding:"omitempty,min=0,max=120"` // Defaults to 30
+	IsActive         *bool   `json:"is_active"`                                            // Defaults to true
+}
+
+// CorporateAccountMember is a customer authorised to book on an account.
+type CorporateAccountMember struct {
+	AccountID            int       `db:"account_id" json:"account_id"`
+	CustomerID           int       `db:"customer_id" json:"customer_id"`
+	CustomerName         string    `db:"customer_name" json:"customer_name"`
+	CustomerEmail        string    `db:"customer_email" json:"customer_email"`
+	MonthlySpendingLimit *Money    `db:"monthly_spending_limit" json:"monthly_spending_limit"` // nil = no limit
+	CreatedAt            time.Time `db:"created_at" json:"created_at"`
+}
+
+// CorporateMemberInput adds a member or changes their limit.
+type CorporateMemberInput struct {
+	CustomerID           int    `json:"customer_id" binding:"required"`
+	MonthlySpendingLimit *Money `json:"monthly_spending_limit"`
+}
+
+// CorporateMembership is what a customer sees of the accounts they can book on.
+type CorporateMembership struct {
+	AccountID            int    `db:"account_id" json:"account_id"`
+	AccountName          string `db:"account_name" json:"account_name"`
+	MonthlySpendingLimit *Money `db:"monthly_spending_limit" json:"monthly_spending_limit"`
+	SpentThisMonth       Money  `db:"spent_this_month" json:"spent_this_month"`
+}
+
+// CorporateInvoice aggregates an account's rentals for one month.
+type CorporateInvoice struct {
+	ID                 int                       `db:"id" json:"id"`
+	AccountID          int                       `db:"account_id" json:"account_id"`
+	AccountName        string                    `db:"account_name" json:"account_name"`
+	InvoiceNumber      string                    `db:"invoice_number" json:"invoice_number"`
+	PeriodStart        time.Time                 `db:"period_start" json:"period_start"`
+	PeriodEnd          time.Time                 `db:"period_end" json:"period_end"`
+	NetAmount          Money                     `db:"net_amount" json:"net_amount"`
+	VATAmount          Money                     `db:"vat_amount" json:"vat_amount"`
+	TotalAmount        Money                     `db:"total_amount" json:"total_amount"`
+	AmountPaid         Money                     `db:"amount_paid" json:"amount_paid"`
+	Status             string                    `db:"status" json:"status"` // Issued, Partially Paid, Paid
+	IsOverdue          bool                      `db:"is_overdue" json:"is_overdue"`
+	IssuedAt           time.Time                 `db:"issued_at" json:"issued_at"`
+	DueDate            time.Time                 `db:"due_date" json:"due_date"`
+	IssuedByEmployeeID *int                      `db:"issued_by_employee_id" json:"issued_by_employee_id"`
+	UpdatedAt          time.Time                 `db:"updated_at" json:"updated_at"`
+	Items              []CorporateInvoiceItem    `db:"-" json:"items,omitempty"`
+	Payments           []CorporateInvoicePayment `db:"-" json:"payments,omitempty"`
+}
+
+// CorporateInvoiceItem is one rental billed on a CorporateInvoice.
+type CorporateInvoiceItem struct {
+	ID          int    `db:"id" json:"id"`
+	InvoiceID   int    `db:"invoice_id" json:"invoice_id"`
+	RentalID    int    `db:"rental_id" json:"rental_id"`
+	Description string `db:"description" json:"description"`
+	NetAmount   Money  `db:"net_amount" json:"net_amount"`
+	VATAmount   Money  `db:"vat_amount" json:"vat_amount"`
+	TotalAmount Money  `db:"total_amount" json:"total_amount"`
+}
+
+// CorporateInvoicePayment is money received against a CorporateInvoice.
+type CorporateInvoicePayment struct {
+	ID                   int       `db:"id" json:"id"`
+	InvoiceID            int       `db:"invoice_id" json:"invoice_id"`
+	Amount               Money     `db:"amount" json:"amount"`
+	PaymentMethod        string    `db:"payment_method" json:"payment_method"`
+	Reference            *string   `db:"reference" json:"reference"`
+	RecordedByEmployeeID *int      `db:"recorded_by_employee_id" json:"recorded_by_employee_id"`
+	PaidAt               time.Time `db:"paid_at" json:"paid_at"`
+}
+
+// CorporateInvoicePaymentInput records a payment against an invoice.
+type CorporateInvoicePaymentInput struct {
+	Amount        Money   `json:"amount"` // Must be > 0 and not exceed the balance, checked by the service
+	PaymentMethod string  `json:"payment_method" binding:"required"`
+	Reference     *string `json:"reference"`
+}
+
+// CorporateInvoiceRunInput selects the month to bill, e.g. "2026-09".
+type CorporateInvoiceRunInput struct {
+	Period    string `json:"period" binding:"required"`
+	AccountID *int   `json:"account_id"` // Optional: bill a single account
+}
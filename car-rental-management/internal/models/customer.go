@@ -11,6 +11,18 @@ type Customer struct {
 	Password  string    `db:"password" json:"-"`  // Always omit from JSON output
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at"` // nil until the email address is confirmed
+	DeletedAt       *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`     // Set when soft-deleted
+	ErasedAt        *time.Time `db:"erased_at" json:"erased_at,omitempty"`       // Set once their personal data has been erased
+	RiskStatus      string     `db:"risk_status" json:"risk_status,omitempty"`   // clear, watch or blacklisted; only filled in staff listings
+
+	MergedIntoCustomerID *int    `db:"merged_into_customer_id" json:"merged_into_customer_id,omitempty"` // Set once merged into another account
+	ReferralCode         *string `db:"referral_code" json:"referral_code,omitempty"`                     // Their code for referring others
+
+	// Printed on their tax invoices
+	BillingAddress *string `db:"billing_address" json:"billing_address"`
+	TaxID          *string `db:"tax_id" json:"tax_id"` // Thai national ID or taxpayer number; encrypted at rest
 }
 
 // RegisterCustomerInput struct for binding customer registration data.
@@ -19,12 +31,17 @@ type RegisterCustomerInput struct {
 	Email    string  `json:"email" binding:"required,email"`
 	Phone    *string `json:"phone"`                             // Optional
 	Password string  `json:"password" binding:"required,min=6"` // Password required for registration
+	// Optional: the code of the customer who referred them
+	ReferralCode *string `json:"referral_code"`
 }
 
 // UpdateCustomerProfileInput struct for binding data when a customer updates their own profile.
 type UpdateCustomerProfileInput struct {
 	Name  string  `json:"name" binding:"required"`
 	Phone *string `json:"phone"` // Optional
+	// Optional: left unchanged when omitted, cleared when empty
+	BillingAddress *string `json:"billing_address"`
+	TaxID          *string `json:"tax_id" binding:"omitempty,max=20"`
 }
 
 // UpdateCustomerByStaffInput struct for binding data when staff updates a customer.
@@ -33,5 +50,8 @@ type UpdateCustomerByStaffInput struct {
 	Name  string  `json:"name" binding:"required"`
 	Email string  `json:"email" binding:"required,email"`
 	Phone *string `json:"phone"` // Optional
+	// Optional: left unchanged when omitted, cleared when empty
+	BillingAddress *string `json:"billing_address"`
+	TaxID          *string `json:"tax_id" binding:"omitempty,max=20"`
 	// Note: Staff typically cannot change password directly here.
 }
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// CustomerDocument is a licence or identity document uploaded by a customer. Status is Pending
+// until staff approve or reject it, and Superseded once a newer upload of the same type replaces it.
+type CustomerDocument struct {
+	ID                   int        `db:"id" json:"id"`
+	CustomerID           int        `db:"customer_id" json:"customer_id"`
+	DocumentType         string     `db:"document_type" json:"document_type"` // licence_front, licence_back or id_document
+	FileName             string     `db:"file_name" json:"-"`
+	ContentType          string     `db:"content_type" json:"content_type"`
+	Status               string     `db:"status" json:"status"`
+	ExpiresOn            *time.Time `db:"expires_on" json:"expires_on,omitempty"`
+	DocumentNumber       *string    `db:"document_number" json:"document_number,omitempty"`
+	ReviewedByEmployeeID *int       `db:"reviewed_by_employee_id" json:"reviewed_by_employee_id,omitempty"`
+	ReviewedAt           *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	RejectionReason      *string    `db:"rejection_reason" json:"rejection_reason,omitempty"`
+	UploadedAt           time.Time  `db:"uploaded_at" json:"uploaded_at"`
+}
+
+// ApproveCustomerDocumentInput is the payload for POST /customer-documents/:id/approve.
+// ExpiresOn is the expiry date printed on the document, as YYYY-MM-DD.
+type ApproveCustomerDocumentInput struct {
+	ExpiresOn      string  `json:"expires_on" binding:"required"`
+	DocumentNumber *string `json:"document_number" binding:"omitempty,max=50"`
+}
+
+// RejectCustomerDocumentInput is the payload for POST /customer-documents/:id/reject.
+type RejectCustomerDocumentInput struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// DuplicateCustomerSummary is one side of a possible duplicate.
+type DuplicateCustomerSummary struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     *string   `json:"phone"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DuplicateCustomerCandidate is a pair of customers who may be the same person. CustomerA is the
+// older account, usually the one to keep. Score runs from 0 to 100; Reasons lists what matched.
+type DuplicateCustomerCandidate struct {
+	CustomerA DuplicateCustomerSummary `json:"customer_a"`
+	CustomerB DuplicateCustomerSummary `json:"customer_b"`
+	Score     int                      `json:"score"`
+	Reasons   []string                 `json:"reasons"`
+}
+
+// MergeCustomersInput is the payload for POST /customers/:id/merge. The customer in the URL is
+// kept; MergeCustomerID is folded into it and deleted.
+type MergeCustomersInput struct {
+	MergeCustomerID int `json:"merge_customer_id" binding:"required,gt=0"`
+}
+
+// CustomerMergeResult reports what a merge moved to the surviving customer.
+type CustomerMergeResult struct {
+	Customer         Customer `json:"customer"`
+	MergedCustomerID int      `json:"merged_customer_id"`
+	RentalsMoved     int64    `json:"rentals_moved"`
+	PaymentsMoved    int64    `json:"payments_moved"` // Payments belong to rentals and move with them
+	ReviewsMoved     int64    `json:"reviews_moved"`
+	DocumentsMoved   int64    `json:"documents_moved"`
+	RiskFlagsMoved   int64    `json:"risk_flags_moved"`
+	NotesMoved       int64    `json:"notes_moved"`
+	// Loyalty history moves too, so the points balances add up
+	LoyaltyEntriesMoved int64 `json:"loyalty_entries_moved"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// CustomerNote is a staff note about a customer, optionally tied to one of their rentals.
+// Visibility is staff (every employee who can see the customer) or restricted.
+type CustomerNote struct {
+	ID               int       `db:"id" json:"id"`
+	CustomerID       int       `db:"customer_id" json:"customer_id"`
+	RentalID         *int      `db:"rental_id" json:"rental_id,omitempty"`
+	AuthorEmployeeID *int      `db:"author_employee_id" json:"author_employee_id,omitempty"` // nil once the author is purged
+	AuthorName       *string   `db:"author_name" json:"author_name,omitempty"`
+	Body             string    `db:"body" json:"body"`
+	Visibility       string    `db:"visibility" json:"visibility"`
+	Pinned           bool      `db:"pinned" json:"pinned"`
+	CreatedAt        time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CreateCustomerNoteInput is the payload for POST /customers/:id/notes and POST /rentals/:id/notes.
+type CreateCustomerNoteInput struct {
+	Body       string `json:"body" binding:"required,max=5000"`
+	Visibility string `json:"visibility" binding:"omitempty,oneof=staff restricted"` // Default staff
+	Pinned     bool   `json:"pinned"`
+}
+
+// UpdateCustomerNoteInput is the payload for PUT /customer-notes/:id. Omitted fields are unchanged;
+// only the author may change the body or visibility.
+type UpdateCustomerNoteInput struct {
+	Body       *string `json:"body" binding:"omitempty,max=5000"`
+	Visibility *string `json:"visibility" binding:"omitempty,oneof=staff restricted"`
+	Pinned     *bool   `json:"pinned"`
+}
+
+// CustomerTimelineEvent is one entry of a customer's timeline. Type is one of
+// customer_registered, note, rental_created, rental_status, payment, review, risk_flag and
+// risk_flag_lifted; Data holds the details for that type.
+type CustomerTimelineEvent struct {
+	Type       string         `db:"event_type" json:"type"`
+	OccurredAt time.Time      `db:"occurred_at" json:"occurred_at"`
+	RentalID   *int           `db:"rental_id" json:"rental_id,omitempty"`
+	EmployeeID *int           `db:"employee_id" json:"employee_id,omitempty"` // Who acted, where known
+	Summary    string         `db:"summary" json:"summary"`
+	Data       types.JSONText `db:"data" json:"data"`
+}
+
+// CustomerTimeline is the response of GET /customers/:id/timeline, oldest event first.
+type CustomerTimeline struct {
+	CustomerID int                     `json:"customer_id"`
+	Events     []CustomerTimelineEvent `json:"events"`
+	Page       int                     `json:"page"`
+	Limit      int                     `json:"limit"`
+	HasMore    bool                    `json:"has_more"`
+}
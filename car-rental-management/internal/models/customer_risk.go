@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// CustomerRiskFlag marks a customer staff should be wary of. A watch flag is a warning shown to
+// staff; a blacklist flag also stops the customer logging in and booking. A flag is active until
+// it expires or is lifted.
+type CustomerRiskFlag struct {
+	ID                 int        `db:"id" json:"id"`
+	CustomerID         int        `db:"customer_id" json:"customer_id"`
+	FlagType           string     `db:"flag_type" json:"flag_type"` // watch or blacklist
+	Category           string     `db:"category" json:"category"`
+	Reason             string     `db:"reason" json:"reason"`
+	SetByEmployeeID    *int       `db:"set_by_employee_id" json:"set_by_employee_id,omitempty"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt          *time.Time `db:"expires_at" json:"expires_at,omitempty"` // nil for a permanent flag
+	LiftedAt           *time.Time `db:"lifted_at" json:"lifted_at,omitempty"`
+	LiftedByEmployeeID *int       `db:"lifted_by_employee_id" json:"lifted_by_employee_id,omitempty"`
+	LiftReason         *string    `db:"lift_reason" json:"lift_reason,omitempty"`
+	Active             bool       `db:"active" json:"active"`
+}
+
+// CreateCustomerRiskFlagInput is the payload for POST /customers/:id/risk-flags.
+type CreateCustomerRiskFlagInput struct {
+	FlagType  string     `json:"flag_type" binding:"required,oneof=watch blacklist"`
+	Category  string     `json:"category" binding:"required,oneof=vehicle_damage payment_fraud unpaid_balance abusive_behaviour other"`
+	Reason    string     `json:"reason" binding:"required,max=1000"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// LiftCustomerRiskFlagInput is the payload for POST /customers/:id/risk-flags/:flagId/lift.
+type LiftCustomerRiskFlagInput struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
@@ -10,17 +10,26 @@ type Employee struct {
 	Role      string    `db:"role" json:"role"`   // Removed binding for Create/Update
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+	DeletedAt       *time.Time `db:"deleted_at" json:"deleted_at,omitempty"` // Set when soft-deleted
 }
 
 type CreateEmployeeInput struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
-	Role     string `json:"role" binding:"required,oneof=admin manager"`
+	Role     string `json:"role" binding:"required,max=50"`
+	// Branches the employee works at. Roles holding branches:all ignore this.
+	BranchIDs []int `json:"branch_ids"`
+}
+
+type EmployeeBranchesInput struct {
+	BranchIDs []int `json:"branch_ids" binding:"required"`
 }
 
 type UpdateEmployeeInput struct {
 	Name  string `json:"name" binding:"required"`
 	Email string `json:"email" binding:"required,email"`
-	Role  string `json:"role" binding:"required,oneof=admin manager"`
+	Role  string `json:"role" binding:"required,max=50"`
 }
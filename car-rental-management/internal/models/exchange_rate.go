@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ExchangeRate is how many units of BaseCurrency one unit of Currency is worth,
+// e.g. USD 35.25 means 1 USD = 35.25 THB. Rates are maintained by admins.
+type ExchangeRate struct {
+	Currency            string      `db:"currency" json:"currency"`
+	RateToBase          json.Number `db:"rate_to_base" json:"rate_to_base"` // Exact decimal, up to 8 fractional digits
+	UpdatedByEmployeeID *int        `db:"updated_by_employee_id" json:"updated_by_employee_id"`
+	UpdatedAt           time.Time   `db:"updated_at" json:"updated_at"`
+}
+
+// Input struct for admins setting a rate
+type ExchangeRateInput struct {
+	RateToBase json.Number `json:"rate_to_base" binding:"required"`
+}
+
+// DisplayPrice is a base-currency amount converted for display in another currency.
+// It is informational only; charges and the ledger stay in BaseCurrency.
+type DisplayPrice struct {
+	Amount       Money       `json:"amount"`
+	Currency     string      `json:"currency"`
+	ExchangeRate json.Number `json:"exchange_rate"`
+}
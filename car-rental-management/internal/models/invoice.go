@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Invoice kinds. Each has its own gap-free number sequence per branch per year.
+const (
+	InvoiceKindTaxInvoice = "tax_invoice"
+	InvoiceKindReceipt    = "receipt"
+)
+
+// Invoice is an issued tax invoice or receipt. Amounts are a snapshot taken at
+// issue time; the row is immutable once written.
+type Invoice struct {
+	ID                 int       `db:"id" json:"id"`
+	RentalID           int       `db:"rental_id" json:"rental_id"`
+	Kind               string    `db:"kind" json:"kind"`
+	BranchID           int       `db:"branch_id" json:"branch_id"`
+	Year               int       `db:"year" json:"year"`
+	SequenceNumber     int       `db:"sequence_number" json:"sequence_number"`
+	InvoiceNumber      string    `db:"invoice_number" json:"invoice_number"`
+	NetAmount          Money     `db:"net_amount" json:"net_amount"`
+	VATAmount          Money     `db:"vat_amount" json:"vat_amount"`
+	TotalAmount        Money     `db:"total_amount" json:"total_amount"`
+	IssuedByEmployeeID *int      `db:"issued_by_employee_id" json:"issued_by_employee_id"`
+	IssuedAt           time.Time `db:"issued_at" json:"issued_at"`
+}
+
+// InvoiceLine is one itemised charge on an invoice (amounts exclude VAT).
+type InvoiceLine struct {
+	Description string
+	Quantity    int
+	UnitPrice   Money
+	Amount      Money
+}
+
+// InvoiceDocument is everything needed to render an invoice or receipt. The
+// buyer is the corporate account when the rental is billed to one, otherwise
+// the customer.
+type InvoiceDocument struct {
+	Invoice         Invoice
+	BranchName      string
+	BranchAddress   *string
+	BranchPhone     *string
+	BuyerName       string
+	BuyerAddress    *string
+	BuyerTaxID      *string
+	BuyerEmail      string
+	CorporateBuyer  bool
+	CustomerName    string
+	CustomerEmail   string
+	CustomerPhone   *string
+	CarDescription  string
+	PickupDatetime  time.Time
+	DropoffDatetime time.Time
+	Lines           []InvoiceLine
+	PaymentMethods  []string
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Ledger account codes. Every money movement is posted as a balanced set of
+// entries against these accounts (debits must equal credits).
+const (
+	AccountCash               = "cash"                // Asset: money received into our bank/cash
+	AccountCustomerReceivable = "customer_receivable" // Asset: amounts customers owe us
+	AccountDepositsHeld       = "deposits_held"       // Liability: transfers received but not yet verified
+	AccountVATPayable         = "vat_payable"         // Liability: output VAT owed to the Revenue Department
+	AccountRentalRevenue      = "rental_revenue"      // Revenue: rental income (net of VAT)
+	AccountRefunds            = "refunds"             // Contra-revenue: rental income given back to customers
+)
+
+// LedgerAccount is a row of the chart of accounts.
+type LedgerAccount struct {
+	Code string `db:"code" json:"code"`
+	Name string `db:"name" json:"name"`
+	Type string `db:"type" json:"type"` // asset, liability, revenue, contra_revenue
+}
+
+// LedgerTransaction groups balanced entries for a single business event.
+type LedgerTransaction struct {
+	ID                  int           `db:"id" json:"id"`
+	RentalID            *int          `db:"rental_id" json:"rental_id"`
+	PaymentID           *int          `db:"payment_id" json:"payment_id"`
+	Kind                string        `db:"kind" json:"kind"` // charge, receipt, deposit, deposit_release, deposit_return, refund
+	Description         string        `db:"description" json:"description"`
+	CreatedByEmployeeID *int          `db:"created_by_employee_id" json:"created_by_employee_id"`
+	CreatedAt           time.Time     `db:"created_at" json:"created_at"`
+	Entries             []LedgerEntry `db:"-" json:"entries"`
+}
+
+// LedgerEntry is one debit or credit line of a LedgerTransaction.
+type LedgerEntry struct {
+	ID            int    `db:"id" json:"id"`
+	TransactionID int    `db:"transaction_id" json:"transaction_id"`
+	AccountCode   string `db:"account_code" json:"account_code"`
+	Debit         Money  `db:"debit" json:"debit"`
+	Credit        Money  `db:"credit" json:"credit"`
+}
+
+// TrialBalanceLine is the aggregated debit/credit position of one account.
+type TrialBalanceLine struct {
+	AccountCode  string `db:"account_code" json:"account_code"`
+	AccountName  string `db:"account_name" json:"account_name"`
+	AccountType  string `db:"account_type" json:"account_type"`
+	TotalDebits  Money  `db:"total_debits" json:"total_debits"`
+	TotalCredits Money  `db:"total_credits" json:"total_credits"`
+	Balance      Money  `db:"balance" json:"balance"` // Debit-positive for assets, credit-positive otherwise
+}
+
+// TrialBalance is the response for the trial-balance report.
+type TrialBalance struct {
+	AsOf         time.Time          `json:"as_of"`
+	Lines        []TrialBalanceLine `json:"lines"`
+	TotalDebits  Money              `json:"total_debits"`
+	TotalCredits Money              `json:"total_credits"`
+	Balanced     bool               `json:"balanced"`
+}
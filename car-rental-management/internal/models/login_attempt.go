@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// LoginAttempt is one row of the login audit trail.
+type LoginAttempt struct {
+	ID            int64     `db:"id" json:"id"`
+	UserType      string    `db:"user_type" json:"user_type"`
+	Email         string    `db:"email" json:"email"`
+	IPAddress     string    `db:"ip_address" json:"ip_address"`
+	Success       bool      `db:"success" json:"success"`
+	FailureReason *string   `db:"failure_reason" json:"failure_reason"`
+	AttemptedAt   time.Time `db:"attempted_at" json:"attempted_at"`
+}
+
+// AccountLockout is the failed-login state of one account (or unregistered email).
+type AccountLockout struct {
+	UserType     string     `db:"user_type" json:"user_type"`
+	Email        string     `db:"email" json:"email"`
+	FailedCount  int        `db:"failed_count" json:"failed_count"`
+	LockedUntil  *time.Time `db:"locked_until" json:"locked_until"`
+	LastFailedAt *time.Time `db:"last_failed_at" json:"last_failed_at"`
+}
+
+// UnlockAccountInput identifies the account to unlock.
+type UnlockAccountInput struct {
+	UserType string `json:"user_type" binding:"required,oneof=customer employee"`
+	Email    string `json:"email" binding:"required,email"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// LoyaltyTier is a level of the loyalty programme, reached by earning MinPoints in 12 months.
+type LoyaltyTier struct {
+	Code            string `db:"code" json:"code"`
+	Name            string `db:"name" json:"name"`
+	MinPoints       int    `db:"min_points" json:"min_points"`
+	DiscountPercent int    `db:"discount_percent" json:"discount_percent"` // Off the net price of each booking
+	FreeUpgrade     bool   `db:"free_upgrade" json:"free_upgrade"`
+}
+
+// LoyaltyTransaction is one entry of a customer's points ledger. Type is earn, redeem, reinstate
+// (points given back when a rental they were spent on is cancelled), reverse (points taken back
+// after a refund), expire, adjust or referral (a referrer's credit). Points is negative for entries that spend points.
+type LoyaltyTransaction struct {
+	ID                  int64      `db:"id" json:"id"`
+	CustomerID          int        `db:"customer_id" json:"customer_id"`
+	RentalID            *int       `db:"rental_id" json:"rental_id,omitempty"`
+	EntryType           string     `db:"entry_type" json:"type"`
+	Points              int        `db:"points" json:"points"`
+	Remaining           int        `db:"remaining" json:"remaining"` // Still unspent, for entries that add points
+	ExpiresAt           *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	Description         string     `db:"description" json:"description"`
+	CreatedByEmployeeID *int       `db:"created_by_employee_id" json:"created_by_employee_id,omitempty"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+}
+
+// LoyaltySummary is the response of GET /me/loyalty and GET /customers/:id/loyalty.
+type LoyaltySummary struct {
+	CustomerID       int                  `json:"customer_id"`
+	Balance          int                  `json:"balance"`
+	BalanceValue     Money                `json:"balance_value"`     // What the balance takes off a booking
+	QualifyingPoints int                  `json:"qualifying_points"` // Earned in the last 12 months, which sets the tier
+	Tier             *LoyaltyTier         `json:"tier"`
+	NextTier         *LoyaltyTier         `json:"next_tier,omitempty"`
+	PointsToNextTier int                  `json:"points_to_next_tier,omitempty"`
+	ExpiringSoon     int                  `json:"expiring_soon"` // Points that expire within 30 days
+	History          []LoyaltyTransaction `json:"history"`       // Newest first
+	Page             int                  `json:"page"`
+	Limit            int                  `json:"limit"`
+	HasMore          bool                 `json:"has_more"`
+}
+
+// AdjustLoyaltyPointsInput is the payload for POST /customers/:id/loyalty/adjustments.
+type AdjustLoyaltyPointsInput struct {
+	Points int    `json:"points" binding:"required,ne=0"` // Negative to remove points
+	Reason string `json:"reason" binding:"required,max=500"`
+}
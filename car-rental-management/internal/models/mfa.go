@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// EmployeeLoginResult is the response to an employee password or single sign-on login. Either the
+// tokens are set, or MFA is needed and MFAToken must be exchanged at the MFA endpoints.
+type EmployeeLoginResult struct {
+	*TokenPair
+	MFARequired           bool   `json:"mfa_required,omitempty"`            // Send a TOTP or recovery code to /auth/employee/mfa/verify
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"` // Policy requires MFA: enroll via /auth/employee/mfa/enroll
+	MFAToken              string `json:"mfa_token,omitempty"`
+	MFATokenExpiresIn     int    `json:"mfa_token_expires_in,omitempty"` // Seconds
+}
+
+// MFAEnrollment is returned when enrollment starts. ProvisioningURI is shown as a QR code.
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFAStatus describes an employee's MFA setup.
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	ConfirmedAt            *time.Time `json:"confirmed_at"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	RequiredByPolicy       bool       `json:"required_by_policy"`
+}
+
+// MFACodeInput carries a TOTP code or, alternatively, a recovery code.
+type MFACodeInput struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFAChallengeInput completes a login or enrollment started with an MFA token.
+type MFAChallengeInput struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFAEnrollmentResult is returned once enrollment is confirmed. Recovery codes are shown only once.
+type MFAEnrollmentResult struct {
+	RecoveryCodes []string   `json:"recovery_codes"`
+	Tokens        *TokenPair `json:"tokens,omitempty"` // Set when enrolling during login
+}
+
+// MFAPolicy says whether a role must use MFA.
+type MFAPolicy struct {
+	Role                string    `db:"role" json:"role"`
+	Required            bool      `db:"required" json:"required"`
+	UpdatedByEmployeeID *int      `db:"updated_by_employee_id" json:"updated_by_employee_id"`
+	UpdatedAt           time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// MFAPolicyInput changes the policy for one role.
+type MFAPolicyInput struct {
+	Role     string `json:"role" binding:"required,max=50"`
+	Required *bool  `json:"required" binding:"required"`
+}
@@ -0,0 +1,274 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// BaseCurrency is the currency every price and DECIMAL money column is stored in.
+const BaseCurrency = "THB"
+
+// moneyScale is the number of minor units per major unit (satang per baht).
+const moneyScale = 100
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// RoundingMode selects how fractional minor units are resolved.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds .5 away from zero. Used for VAT, as the Revenue Department expects.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds .5 to the nearest even minor unit (banker's rounding).
+	RoundHalfEven
+	// RoundDown truncates towards zero. Used when computing discounts so we never over-discount.
+	RoundDown
+)
+
+// Money is an exact amount held as an integer number of minor units (satang) plus
+// an ISO 4217 currency code. An empty Currency means BaseCurrency.
+//
+// In JSON it is encoded as a plain decimal number with two fractional digits
+// (e.g. 1070.00) so existing clients keep working; the database form is the
+// DECIMAL(10,2) text representation, so values round-trip exactly.
+type Money struct {
+	Amount   int64  // Minor units
+	Currency string // ISO 4217 code, "" = BaseCurrency
+}
+
+// NewMoney creates Money from minor units.
+func NewMoney(minorUnits int64, currency string) Money {
+	return Money{Amount: minorUnits, Currency: currency}
+}
+
+// Baht creates base-currency Money from whole baht and satang, e.g. Baht(1070, 50).
+func Baht(baht int64, satang int64) Money {
+	return Money{Amount: baht*moneyScale + satang, Currency: BaseCurrency}
+}
+
+// ParseMoney parses an exact decimal string such as "1234.5" or "-0.07".
+// More than two fractional digits is an error rather than a silent rounding.
+func ParseMoney(value string, currency string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Money{}, fmt.Errorf("%w: empty value", ErrInvalidMoney)
+	}
+	negative := false
+	switch value[0] {
+	case '-':
+		negative = true
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(value, ".")
+	if whole == "" && (!hasFrac || frac == "") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > 2 {
+		// Allow trailing zeros from NUMERIC columns with a larger scale (e.g. "12.5000").
+		if strings.TrimRight(frac[2:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q has more than 2 decimal places", ErrInvalidMoney, value)
+		}
+		frac = frac[:2]
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	wholeUnits, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+	fracUnits, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || fracUnits < 0 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+	if wholeUnits > (math.MaxInt64-fracUnits)/moneyScale {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidMoney, value)
+	}
+	amount := wholeUnits*moneyScale + fracUnits
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// CurrencyCode returns the effective ISO currency code.
+func (m Money) CurrencyCode() string {
+	if m.Currency == "" {
+		return BaseCurrency
+	}
+	return m.Currency
+}
+
+// String formats the amount as a plain decimal, e.g. "1070.50".
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/moneyScale, amount%moneyScale)
+}
+
+// Display formats the amount with its currency code, e.g. "1070.50 THB".
+func (m Money) Display() string {
+	return m.String() + " " + m.CurrencyCode()
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+func (m Money) mustMatch(other Money) string {
+	if m.Currency != "" && other.Currency != "" && m.Currency != other.Currency {
+		panic(fmt.Sprintf("money: currency mismatch %s vs %s", m.Currency, other.Currency))
+	}
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return other.Currency
+}
+
+// Add returns m + other. Both must share a currency.
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount + other.Amount, Currency: m.mustMatch(other)}
+}
+
+// Sub returns m - other. Both must share a currency.
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount - other.Amount, Currency: m.mustMatch(other)}
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Cmp compares m and other: -1 if m < other, 0 if equal, +1 if m > other.
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	}
+	return 0
+}
+
+// MulInt returns m multiplied by a whole quantity (e.g. number of rental days).
+func (m Money) MulInt(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// MulRatio returns m * numerator / denominator, rounded to a whole minor unit with mode.
+// Tax and discount percentages go through here so rounding is always explicit.
+func (m Money) MulRatio(numerator, denominator int64, mode RoundingMode) Money {
+	if denominator == 0 {
+		panic("money: division by zero")
+	}
+	ratio := new(big.Rat).SetFrac(big.NewInt(numerator), big.NewInt(denominator))
+	return m.MulRat(ratio, mode)
+}
+
+// MulRat returns m * ratio rounded to a whole minor unit with mode.
+func (m Money) MulRat(ratio *big.Rat, mode RoundingMode) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), ratio)
+	return Money{Amount: roundRat(product, mode), Currency: m.Currency}
+}
+
+// roundRat rounds an exact rational to an integer using mode.
+func roundRat(value *big.Rat, mode RoundingMode) int64 {
+	num := new(big.Int).Set(value.Num())
+	den := value.Denom()
+	negative := num.Sign() < 0
+	num.Abs(num)
+
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	twiceRemainder := new(big.Int).Mul(remainder, big.NewInt(2))
+	switch mode {
+	case RoundHalfUp:
+		if twiceRemainder.Cmp(den) >= 0 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	case RoundHalfEven:
+		cmp := twiceRemainder.Cmp(den)
+		if cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1) {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	case RoundDown:
+		// Truncate.
+	}
+	result := quotient.Int64()
+	if negative {
+		result = -result
+	}
+	return result
+}
+
+// MarshalJSON encodes Money as a JSON number with two decimal places.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string. The currency is left
+// empty (base currency) and should be set by the caller when known.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" {
+		return nil
+	}
+	raw = strings.Trim(raw, `"`)
+	if strings.ContainsAny(raw, "eE") {
+		return fmt.Errorf("%w: exponent notation is not supported (%s)", ErrInvalidMoney, raw)
+	}
+	parsed, err := ParseMoney(raw, m.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL/NUMERIC columns.
+func (m *Money) Scan(src interface{}) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		*m = Money{Currency: BaseCurrency}
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	case int64:
+		*m = Money{Amount: v * moneyScale, Currency: BaseCurrency}
+		return nil
+	case float64:
+		// Should not happen for DECIMAL columns, but avoid silently losing precision.
+		text = strconv.FormatFloat(v, 'f', 2, 64)
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	parsed, err := ParseMoney(text, BaseCurrency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, producing the exact decimal text for the database.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
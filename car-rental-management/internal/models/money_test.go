@@ -0,0 +1,147 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMulRatioRounding(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      int64
+		num, den    int64
+		mode        RoundingMode
+		wantSatangs int64
+	}{
+		// 7% of 0.50 is 3.5 satang
+		{"half up at boundary", 50, 7, 100, RoundHalfUp, 4},
+		{"down at boundary", 50, 7, 100, RoundDown, 3},
+		{"half even at boundary, odd", 50, 7, 100, RoundHalfEven, 4},
+		{"half even at boundary, even", 25, 1, 10, RoundHalfEven, 2},
+		{"half up below boundary", 10, 7, 100, RoundHalfUp, 1}, // 0.7
+		{"down below boundary", 10, 7, 100, RoundDown, 0},
+		{"half up just under half", 7, 7, 100, RoundHalfUp, 0}, // 0.49
+		{"exact", 10000, 7, 100, RoundHalfUp, 700},
+		{"negative half up rounds away from zero", -50, 7, 100, RoundHalfUp, -4},
+		{"negative down truncates towards zero", -50, 7, 100, RoundDown, -3},
+		{"negative half even", -25, 1, 10, RoundHalfEven, -2},
+		{"negative denominator", 50, 7, -100, RoundHalfUp, -4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewMoney(tt.amount, BaseCurrency).MulRatio(tt.num, tt.den, tt.mode)
+			if got.Amount != tt.wantSatangs {
+				t.Errorf("%d * %d/%d = %d satang, want %d", tt.amount, tt.num, tt.den, got.Amount, tt.wantSatangs)
+			}
+			if got.Currency != BaseCurrency {
+				t.Errorf("currency = %q, want %q", got.Currency, BaseCurrency)
+			}
+		})
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"1234.5", 123450, false},
+		{"1234.56", 123456, false},
+		{"-0.07", -7, false},
+		{"+1", 100, false},
+		{".5", 50, false},
+		{"7.", 700, false},
+		{" 10.00 ", 1000, false},
+		{"12.5000", 1250, false}, // NUMERIC with a larger scale
+		{"0", 0, false},
+		{"12.345", 0, true}, // Never rounded silently
+		{"12.3400001", 0, true},
+		{"", 0, true},
+		{"-", 0, true},
+		{".", 0, true},
+		{"abc", 0, true},
+		{"1.2.3", 0, true},
+		{"1.-5", 0, true},
+		{"99999999999999999999", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in, BaseCurrency)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidMoney) {
+				t.Errorf("ParseMoney(%q) error = %v, want ErrInvalidMoney", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got.Amount != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v; want %d", tt.in, got.Amount, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyDecimalRoundTrip(t *testing.T) {
+	for _, amount := range []int64{0, 1, -1, 9, 10, 99, 100, -107, 107050, -107050, 99999999} {
+		m := NewMoney(amount, BaseCurrency)
+		value, err := m.Value()
+		if err != nil {
+			t.Fatalf("Value(%d): %v", amount, err)
+		}
+		// The driver hands DECIMAL columns back as text bytes.
+		var scanned Money
+		if err := scanned.Scan([]byte(value.(string))); err != nil {
+			t.Fatalf("Scan(%q): %v", value, err)
+		}
+		if scanned != m {
+			t.Errorf("%d round-tripped through %q as %+v", amount, value, scanned)
+		}
+
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("Marshal(%d): %v", amount, err)
+		}
+		var decoded Money
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if decoded.Amount != amount {
+			t.Errorf("%d round-tripped through JSON %s as %d", amount, data, decoded.Amount)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    int64
+		wantErr bool
+	}{
+		{"nil", nil, 0, false},
+		{"text", "1070.50", 107050, false},
+		{"bytes with larger scale", []byte("-12.5000"), -1250, false},
+		{"integer", int64(5), 500, false},
+		{"float", 10.5, 1050, false},
+		{"too many places", "1.005", 0, true},
+		{"unsupported type", true, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Money
+			err := m.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan(%v) error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			}
+			if err == nil && (m.Amount != tt.want || m.Currency != BaseCurrency) {
+				t.Errorf("Scan(%v) = %+v, want %d %s", tt.src, m, tt.want, BaseCurrency)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalRejectsExponent(t *testing.T) {
+	var m Money
+	if err := json.Unmarshal([]byte("1e3"), &m); !errors.Is(err, ErrInvalidMoney) {
+		t.Errorf("Unmarshal(1e3) error = %v, want ErrInvalidMoney", err)
+	}
+}
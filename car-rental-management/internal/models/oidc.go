@@ -0,0 +1,21 @@
+package models
+
+// LoginOptions lists the sign-in methods offered to staff.
+type LoginOptions struct {
+	PasswordLogin bool `json:"password_login"`
+	OIDCLogin     bool `json:"oidc_login"`
+}
+
+// OIDCLoginStart is returned when a single sign-on login begins. The client sends the browser
+// to AuthorizationURL; the identity provider redirects back with code and state.
+type OIDCLoginStart struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int    `json:"expires_in"` // Seconds the login has to complete
+}
+
+// OIDCCallbackInput carries the code and state the identity provider redirected back with.
+type OIDCCallbackInput struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
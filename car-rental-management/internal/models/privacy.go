@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// CustomerErasureRequest is a customer's request to have their personal data erased.
+// Status is Pending until staff complete or reject it.
+type CustomerErasureRequest struct {
+	ID                    int        `db:"id" json:"id"`
+	CustomerID            int        `db:"customer_id" json:"customer_id"`
+	Status                string     `db:"status" json:"status"`
+	Reason                string     `db:"reason" json:"reason"`
+	RequestedAt           time.Time  `db:"requested_at" json:"requested_at"`
+	ProcessedAt           *time.Time `db:"processed_at" json:"processed_at,omitempty"`
+	ProcessedByEmployeeID *int       `db:"processed_by_employee_id" json:"processed_by_employee_id,omitempty"`
+	RejectionReason       *string    `db:"rejection_reason" json:"rejection_reason,omitempty"`
+}
+
+// CreateErasureRequestInput is the payload for POST /me/erasure-requests.
+type CreateErasureRequestInput struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+// RejectErasureRequestInput is the payload for POST /erasure-requests/:id/reject.
+type RejectErasureRequestInput struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
+
+// CustomerDataExport is what GET /me/data-export puts in its ZIP, one JSON file per field.
+// Uploaded payment slips and licence and identity documents are added alongside as files.
+type CustomerDataExport struct {
+	ExportedAt      time.Time                `json:"exported_at"`
+	Profile         Customer                 `json:"profile"`
+	Rentals         []Rental                 `json:"rentals"`
+	Payments        []Payment                `json:"payments"`
+	Reviews         []Review                 `json:"reviews"`
+	ErasureRequests []CustomerErasureRequest `json:"erasure_requests"`
+	Documents       []CustomerDocument       `json:"documents"`
+	Loyalty         []LoyaltyTransaction     `json:"loyalty"`
+	Referral        *Referral                `json:"referral"` // How they were referred, if they were
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Referral records a customer who registered with another customer's referral code. Status is
+// pending until the referee's first rental is returned, then converted, or rejected if a fraud
+// check fails.
+type Referral struct {
+	ID                    int        `db:"id" json:"id"`
+	ReferrerCustomerID    int        `db:"referrer_customer_id" json:"referrer_customer_id"`
+	ReferrerName          string     `db:"referrer_name" json:"referrer_name,omitempty"`
+	RefereeCustomerID     int        `db:"referee_customer_id" json:"referee_customer_id"`
+	RefereeName           string     `db:"referee_name" json:"referee_name,omitempty"`
+	ReferralCode          string     `db:"referral_code" json:"referral_code"`
+	Status                string     `db:"status" json:"status"`
+	RejectionReason       *string    `db:"rejection_reason" json:"rejection_reason,omitempty"`
+	RegistrationIP        string     `db:"registration_ip" json:"registration_ip,omitempty"`
+	RegistrationUserAgent string     `db:"registration_user_agent" json:"registration_user_agent,omitempty"`
+	ConvertedRentalID     *int       `db:"converted_rental_id" json:"converted_rental_id,omitempty"`
+	RewardPoints          int        `db:"reward_points" json:"reward_points"`
+	CreatedAt             time.Time  `db:"created_at" json:"created_at"`
+	ConvertedAt           *time.Time `db:"converted_at" json:"converted_at,omitempty"`
+}
+
+// MyReferralSummary is the response of GET /me/referral: the customer's code, what it offers and
+// how the people they referred are doing. Referees are shown by first name only.
+type MyReferralSummary struct {
+	ReferralCode           string           `json:"referral_code"`
+	RefereeDiscountPercent int              `json:"referee_discount_percent"`
+	RefereeDiscountCap     Money            `json:"referee_discount_cap"`
+	RewardPoints           int              `json:"reward_points"` // Credited per converted referral
+	Referrals              []MyReferralItem `json:"referrals"`
+}
+
+// MyReferralItem is one person a customer referred.
+type MyReferralItem struct {
+	RefereeFirstName string     `db:"referee_name" json:"referee_first_name"`
+	Status           string     `db:"status" json:"status"`
+	RewardPoints     int        `db:"reward_points" json:"reward_points"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	ConvertedAt      *time.Time `db:"converted_at" json:"converted_at,omitempty"`
+}
+
+// ReferralReport is the response of GET /reports/referrals: referrals registered in [From, To).
+type ReferralReport struct {
+	From           time.Time            `json:"from"`
+	To             time.Time            `json:"to"`
+	Registered     int                  `json:"registered"`
+	Pending        int                  `json:"pending"`
+	Converted      int                  `json:"converted"`
+	Rejected       int                  `json:"rejected"`
+	ConversionRate float64              `json:"conversion_rate"` // Converted over registered less rejected
+	PointsCredited int                  `json:"points_credited"`
+	DiscountsGiven Money                `json:"discounts_given"` // Referee discounts on rentals that were not cancelled
+	TopReferrers   []ReferrerReportItem `json:"top_referrers"`
+	Referrals      []Referral           `json:"referrals"`
+}
+
+// ReferrerReportItem sums up one referrer's referrals in a ReferralReport.
+type ReferrerReportItem struct {
+	CustomerID int    `db:"customer_id" json:"customer_id"`
+	Name       string `db:"name" json:"name"`
+	Registered int    `db:"registered" json:"registered"`
+	Converted  int    `db:"converted" json:"converted"`
+	Rejected   int    `db:"rejected" json:"rejected"`
+}
@@ -10,25 +10,44 @@ type CarSummary struct {
 
 // Rental struct (ยังคงเดิม)
 type Rental struct {
-	ID              int        `db:"id" json:"id"`
-	CustomerID      int        `db:"customer_id" json:"customer_id"`
-	CarID           int        `db:"car_id" json:"car_id"`
-	BookingDate     *time.Time `db:"booking_date" json:"booking_date"` // Can be null if pending
-	PickupDatetime  time.Time  `db:"pickup_datetime" json:"pickup_datetime"`
-	DropoffDatetime time.Time  `db:"dropoff_datetime" json:"dropoff_datetime"`
-	PickupLocation  *string    `db:"pickup_location" json:"pickup_location"`
-	Status          string     `db:"status" json:"status"` // e.g., Pending, Booked, Confirmed, Active, Returned, Cancelled, Pending Verification
-	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
-	Car             CarSummary `db:"car" json:"car"` // For embedding car brand and model
+	ID                 int        `db:"id" json:"id"`
+	CustomerID         int        `db:"customer_id" json:"customer_id"`
+	CarID              int        `db:"car_id" json:"car_id"`
+	BookingDate        *time.Time `db:"booking_date" json:"booking_date"` // Can be null if pending
+	PickupDatetime     time.Time  `db:"pickup_datetime" json:"pickup_datetime"`
+	DropoffDatetime    time.Time  `db:"dropoff_datetime" json:"dropoff_datetime"`
+	PickupLocation     *string    `db:"pickup_location" json:"pickup_location"`
+	Status             string     `db:"status" json:"status"`                             // e.g., Pending, Booked, Confirmed, Active, Returned, Cancelled, Pending Verification
+	CorporateAccountID *int       `db:"corporate_account_id" json:"corporate_account_id"` // Set when billed to a corporate account instead of paid by slip
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt          *time.Time `db:"deleted_at" json:"deleted_at,omitempty"` // Set when soft-deleted
+	Car                CarSummary `db:"car" json:"car"`                         // For embedding car brand and model
+
+	// The price fixed at booking, after any discounts below. Invoices and corporate limits use
+	// these rather than the car's current daily rate.
+	BookedPricePerDay Money `db:"booked_price_per_day" json:"booked_price_per_day"`
+	BookedNet         Money `db:"booked_net" json:"booked_net"`
+	BookedVAT         Money `db:"booked_vat" json:"booked_vat"`
+	BookedTotal       Money `db:"booked_total" json:"booked_total"`
+
+	// Loyalty benefits fixed at booking; the discounts come off the net price before VAT.
+	LoyaltyTier        *string `db:"loyalty_tier" json:"loyalty_tier,omitempty"`
+	LoyaltyDiscount    Money   `db:"loyalty_discount" json:"loyalty_discount"`
+	PointsRedeemed     int     `db:"points_redeemed" json:"points_redeemed"`
+	PointsDiscount     Money   `db:"points_discount" json:"points_discount"`
+	LoyaltyFreeUpgrade bool    `db:"loyalty_free_upgrade" json:"loyalty_free_upgrade"` // Upgrade at pickup if a better car is free
+	ReferralDiscount   Money   `db:"referral_discount" json:"referral_discount"`       // A referred customer's first-rental discount
 }
 
 // InitiateRentalInput struct (ยังคงเดิม)
 type InitiateRentalInput struct {
-	CarID           int       `json:"car_id" binding:"required"`
-	PickupDatetime  time.Time `json:"pickup_datetime" binding:"required"`
-	DropoffDatetime time.Time `json:"dropoff_datetime" binding:"required,gtfield=PickupDatetime"`
-	PickupLocation  *string   `json:"pickup_location"`
+	CarID              int       `json:"car_id" binding:"required"`
+	PickupDatetime     time.Time `json:"pickup_datetime" binding:"required"`
+	DropoffDatetime    time.Time `json:"dropoff_datetime" binding:"required,gtfield=PickupDatetime"`
+	PickupLocation     *string   `json:"pickup_location"`
+	CorporateAccountID *int      `json:"corporate_account_id"`                    // Optional: bill to a corporate account the customer is a member of
+	RedeemPoints       int       `json:"redeem_points" binding:"omitempty,min=0"` // Optional: loyalty points to spend on this rental
 }
 
 // UpdateRentalStatusInput struct (ยังคงเดิม)
@@ -45,6 +64,7 @@ type RentalFiltersWithPagination struct {
 	CarID           *int       // กรองตาม car_id
 	Status          *string    // กรองตาม status
 	PickupDateAfter *time.Time // กรองตามวันที่รับรถ (หลังจากวันที่ระบุ)
+	IncludeDeleted  bool       // รวมรายการที่ถูกลบแล้ว
 	Page            int
 	Limit           int
 	SortBy          string // เช่น "id", "pickup_datetime", "status"
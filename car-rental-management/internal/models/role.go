@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Role is a named set of permissions assigned to employees.
+type Role struct {
+	Name          string    `db:"name" json:"name"`
+	Description   string    `db:"description" json:"description"`
+	IsSystem      bool      `db:"is_system" json:"is_system"`
+	EmployeeCount int       `db:"employee_count" json:"employee_count"`
+	Permissions   []string  `db:"-" json:"permissions"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Permission is one entry in the permission catalogue, e.g. "rentals:activate".
+type Permission struct {
+	Code        string `db:"code" json:"code"`
+	Description string `db:"description" json:"description"`
+}
+
+// CreateRoleInput is the payload for POST /roles.
+type CreateRoleInput struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleInput is the payload for PUT /roles/:name. Permissions replaces the role's whole set.
+type UpdateRoleInput struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
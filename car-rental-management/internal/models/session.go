@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session is one login on one device. Refreshing tokens keeps the same session.
+type Session struct {
+	ID         string    `db:"id" json:"id"`
+	Device     string    `db:"-" json:"device"` // Readable summary of UserAgent, e.g. "Chrome on Windows"
+	UserAgent  string    `db:"user_agent" json:"user_agent"`
+	IPAddress  string    `db:"ip_address" json:"ip_address"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  time.Time `db:"expires_at" json:"expires_at"`
+	Current    bool      `db:"-" json:"current"` // The session making the request
+}
@@ -0,0 +1,333 @@
+// Package pdf is a minimal PDF 1.4 writer: A4 pages, text and straight lines.
+// It has no dependencies so documents such as tax invoices can be generated
+// without a headless browser or cgo.
+//
+// Text is set in the standard Helvetica fonts unless UseTrueType supplies
+// fonts to embed. Helvetica only covers WinAnsi (Latin-1), so other
+// characters, including Thai, are rendered as '?'; embedded fonts are written
+// as Type0/Identity-H with a ToUnicode map, so any character the font has
+// prints and can be copied or searched. There is no shaping: Thai marks rely
+// on the font's zero-width glyphs to sit over the preceding consonant.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 page size in points (1/72 inch).
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font selects the regular or bold face: the built-in Helvetica ones, or the
+// TrueType fonts given to UseTrueType.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+func (f Font) resourceName() string {
+	if f == HelveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// Document is a PDF under construction.
+type Document struct {
+	title string
+	pages []*Page
+	fonts [2]*TrueTypeFont                  // by Font; nil uses Helvetica
+	used  map[*TrueTypeFont]map[uint16]rune // glyphs drawn, for the width and ToUnicode tables
+}
+
+// Page is a single page. Coordinates are in points from the top-left corner.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// New creates an empty document.
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// UseTrueType sets text in the given fonts instead of Helvetica. A nil bold
+// font uses the regular one for both.
+func (d *Document) UseTrueType(regular, bold *TrueTypeFont) {
+	if bold == nil {
+		bold = regular
+	}
+	d.fonts = [2]*TrueTypeFont{regular, bold}
+	d.used = map[*TrueTypeFont]map[uint16]rune{}
+	for _, font := range d.fonts {
+		if font != nil && d.used[font] == nil {
+			d.used[font] = map[uint16]rune{}
+		}
+	}
+}
+
+// trueType returns the embedded font used for font, if any.
+func (d *Document) trueType(font Font) *TrueTypeFont {
+	if font == HelveticaBold {
+		return d.fonts[1]
+	}
+	return d.fonts[0]
+}
+
+// AddPage appends a blank A4 page and returns it.
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Text draws text with its baseline starting at (x, y).
+func (p *Page) Text(x, y float64, font Font, size float64, text string) {
+	if tt := p.doc.trueType(font); tt != nil {
+		var glyphs strings.Builder
+		for _, r := range text {
+			if r == '\t' {
+				r = ' '
+			}
+			gid := tt.glyph(r)
+			if gid != 0 {
+				p.doc.used[tt][gid] = r
+			}
+			fmt.Fprintf(&glyphs, "%04X", gid)
+		}
+		fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td <%s> Tj ET\n",
+			font.resourceName(), size, x, PageHeight-y, glyphs.String())
+		return
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font.resourceName(), size, x, PageHeight-y, escapeString(encodeWinAnsi(text)))
+}
+
+// TextRight draws text so that it ends at x.
+func (p *Page) TextRight(x, y float64, font Font, size float64, text string) {
+	p.Text(x-p.doc.TextWidth(font, size, text), y, font, size, text)
+}
+
+// Line draws a straight line of the given width.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// TextWidth returns the width in points of text set in the document's font at size.
+func (d *Document) TextWidth(font Font, size float64, text string) float64 {
+	tt := d.trueType(font)
+	if tt == nil {
+		return TextWidth(font, size, text)
+	}
+	total := 0
+	for _, r := range text {
+		if r == '\t' {
+			r = ' '
+		}
+		total += tt.width(tt.glyph(r))
+	}
+	return float64(total) * size / 1000
+}
+
+// TextWidth returns the width in points of text set in the built-in font at size.
+func TextWidth(font Font, size float64, text string) float64 {
+	widths := &helveticaWidths
+	if font == HelveticaBold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, b := range []byte(encodeWinAnsi(text)) {
+		if b >= 32 && b <= 126 {
+			total += widths[b-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// WriteTo serialises the document.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	addObject := func(body string) int {
+		offsets = append(offsets, out.Len())
+		id := len(offsets)
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", id, body)
+		return id
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Fixed objects: 1 catalog, 2 page tree, 3/4 fonts, 5 info. Pages follow as (page, content)
+	// pairs, then each embedded font as (CIDFont, descriptor, font file, ToUnicode map).
+	pageIDs := make([]string, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = fmt.Sprintf("%d 0 R", 6+i*2)
+	}
+	embedded := []*TrueTypeFont{}
+	descendantIDs := map[*TrueTypeFont]int{}
+	for _, font := range d.fonts {
+		if font != nil && descendantIDs[font] == 0 {
+			descendantIDs[font] = 6 + len(d.pages)*2 + len(embedded)*4
+			embedded = append(embedded, font)
+		}
+	}
+
+	addObject("<< /Type /Catalog /Pages 2 0 R >>")
+	addObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageIDs, " "), len(d.pages)))
+	for i, standard := range []string{"Helvetica", "Helvetica-Bold"} {
+		if font := d.fonts[i]; font != nil {
+			id := descendantIDs[font]
+			addObject(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+				font.postScriptName, id, id+3))
+		} else {
+			addObject(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", standard))
+		}
+	}
+	addObject(fmt.Sprintf("<< /Title %s /Producer (car-rental-management) >>", textString(d.title)))
+	for i, page := range d.pages {
+		addObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, 7+i*2))
+		addObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+	for _, font := range embedded {
+		id := descendantIDs[font]
+		glyphs := sortedGlyphs(d.used[font])
+		var widths strings.Builder
+		for _, gid := range glyphs {
+			fmt.Fprintf(&widths, "%d [%d] ", gid, font.width(gid))
+		}
+		addObject(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW %d /W [%s] >>",
+			font.postScriptName, id+1, font.width(0), strings.TrimSpace(widths.String())))
+		addObject(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			font.postScriptName, font.scaled(font.bbox[0]), font.scaled(font.bbox[1]), font.scaled(font.bbox[2]), font.scaled(font.bbox[3]),
+			font.scaled(font.ascent), font.scaled(font.descent), font.scaled(font.capHeight), id+2))
+		data := font.compressedData()
+		addObject(fmt.Sprintf("<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(data), len(font.data), data))
+		cmap := toUnicodeCMap(glyphs, d.used[font])
+		addObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(cmap), cmap))
+	}
+
+	xrefOffset := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return out.WriteTo(w)
+}
+
+// Bytes returns the serialised document.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	_, _ = d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// sortedGlyphs returns the glyph IDs drawn with a font in ascending order.
+func sortedGlyphs(used map[uint16]rune) []uint16 {
+	glyphs := make([]uint16, 0, len(used))
+	for gid := range used {
+		glyphs = append(glyphs, gid)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// toUnicodeCMap maps glyph IDs back to the characters they were drawn for, so
+// text can be extracted from the document.
+func toUnicodeCMap(glyphs []uint16, used map[uint16]rune) string {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// bfchar blocks hold at most 100 entries.
+	for start := 0; start < len(glyphs); start += 100 {
+		end := min(start+100, len(glyphs))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, gid := range glyphs[start:end] {
+			fmt.Fprintf(&b, "<%04X> <", gid)
+			for _, unit := range utf16.Encode([]rune{used[gid]}) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.String()
+}
+
+// textString encodes text as a PDF text string: literal if it is printable
+// ASCII, otherwise UTF-16BE with a byte order mark.
+func textString(text string) string {
+	ascii := true
+	for _, r := range text {
+		if r < 32 || r > 126 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + escapeString(text) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// encodeWinAnsi maps text to single-byte Latin-1, replacing anything else with '?'.
+func encodeWinAnsi(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		case r == '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func escapeString(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return replacer.Replace(text)
+}
+
+// Glyph widths (1/1000 em) for ASCII 32..126, from the Adobe standard font metrics.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,247 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// ErrMalformedFont is returned for font files that cannot be parsed.
+var ErrMalformedFont = errors.New("malformed TrueType font")
+
+// ErrFontNotEmbeddable is returned for fonts whose licence forbids embedding.
+var ErrFontNotEmbeddable = errors.New("font licence does not allow embedding")
+
+// TrueTypeFont is a TrueType font that can be embedded in documents. The whole
+// file is embedded, so prefer a font that covers only the scripts needed.
+type TrueTypeFont struct {
+	data           []byte
+	postScriptName string
+	unitsPerEm     int
+	bbox           [4]int
+	ascent         int
+	descent        int
+	capHeight      int
+	advances       []int // per glyph, in font units
+	glyphs         map[rune]uint16
+
+	compressOnce sync.Once
+	compressed   []byte
+}
+
+// LoadTrueType reads and parses a .ttf file.
+func LoadTrueType(path string) (*TrueTypeFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTrueType(data)
+}
+
+// ParseTrueType parses a TrueType font (glyf outlines; CFF-flavoured OpenType
+// is not supported).
+func ParseTrueType(data []byte) (font *TrueTypeFont, err error) {
+	// Offsets come from the file, so a truncated or corrupt one can index out of range.
+	defer func() {
+		if r := recover(); r != nil {
+			font, err = nil, ErrMalformedFont
+		}
+	}()
+
+	if len(data) < 12 {
+		return nil, ErrMalformedFont
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 && version != 0x74727565 { // 'true'
+		return nil, fmt.Errorf("%w: not a TrueType font", ErrMalformedFont)
+	}
+	tables := map[string][]byte{}
+	numTables := int(u16(data, 4))
+	for i := 0; i < numTables; i++ {
+		record := 12 + i*16
+		offset, length := int(u32(data, record+8)), int(u32(data, record+12))
+		tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap", "glyf"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("%w: missing %s table", ErrMalformedFont, tag)
+		}
+	}
+
+	font = &TrueTypeFont{data: data}
+	head := tables["head"]
+	font.unitsPerEm = int(u16(head, 18))
+	if font.unitsPerEm == 0 {
+		return nil, fmt.Errorf("%w: zero unitsPerEm", ErrMalformedFont)
+	}
+	font.bbox = [4]int{int(i16(head, 36)), int(i16(head, 38)), int(i16(head, 40)), int(i16(head, 42))}
+
+	hhea := tables["hhea"]
+	font.ascent, font.descent = int(i16(hhea, 4)), int(i16(hhea, 6))
+	font.capHeight = font.ascent
+	if os2 := tables["OS/2"]; os2 != nil {
+		if u16(os2, 8)&0x000F == 0x0002 { // fsType: restricted licence embedding
+			return nil, ErrFontNotEmbeddable
+		}
+		if u16(os2, 0) >= 2 && len(os2) >= 90 {
+			font.capHeight = int(i16(os2, 88))
+		}
+	}
+
+	numGlyphs := int(u16(tables["maxp"], 4))
+	numMetrics := int(u16(hhea, 34))
+	if numMetrics == 0 || numMetrics > numGlyphs {
+		return nil, fmt.Errorf("%w: bad horizontal metrics", ErrMalformedFont)
+	}
+	hmtx := tables["hmtx"]
+	font.advances = make([]int, numGlyphs)
+	for gid := range font.advances {
+		if gid < numMetrics {
+			font.advances[gid] = int(u16(hmtx, gid*4))
+		} else {
+			font.advances[gid] = font.advances[numMetrics-1]
+		}
+	}
+
+	if font.glyphs, err = parseCmap(tables["cmap"], numGlyphs); err != nil {
+		return nil, err
+	}
+	font.postScriptName = postScriptName(tables["name"])
+	return font, nil
+}
+
+// parseCmap reads the Unicode character map, preferring the full-repertoire
+// format 12 subtable over the BMP-only format 4 one.
+func parseCmap(cmap []byte, numGlyphs int) (map[rune]uint16, error) {
+	var format4, format12 []byte
+	numSubtables := int(u16(cmap, 2))
+	for i := 0; i < numSubtables; i++ {
+		record := 4 + i*8
+		platform, encoding := u16(cmap, record), u16(cmap, record+2)
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode {
+			continue
+		}
+		subtable := cmap[u32(cmap, record+4):]
+		switch u16(subtable, 0) {
+		case 4:
+			format4 = subtable
+		case 12:
+			format12 = subtable
+		}
+	}
+
+	glyphs := map[rune]uint16{}
+	add := func(r rune, gid int) {
+		if gid > 0 && gid < numGlyphs {
+			glyphs[r] = uint16(gid)
+		}
+	}
+	switch {
+	case format12 != nil:
+		numGroups := int(u32(format12, 12))
+		for i := 0; i < numGroups; i++ {
+			group := 16 + i*12
+			start, end, startGlyph := u32(format12, group), u32(format12, group+4), u32(format12, group+8)
+			if end > 0x10FFFF || start > end {
+				return nil, fmt.Errorf("%w: bad cmap group", ErrMalformedFont)
+			}
+			for r := start; r <= end; r++ {
+				add(rune(r), int(startGlyph+r-start))
+			}
+		}
+	case format4 != nil:
+		segCount := int(u16(format4, 6)) / 2
+		endCodes, startCodes := 14, 16+segCount*2
+		deltas, rangeOffsets := startCodes+segCount*2, startCodes+segCount*4
+		for i := 0; i < segCount; i++ {
+			start, end := int(u16(format4, startCodes+i*2)), int(u16(format4, endCodes+i*2))
+			delta, rangeOffset := int(u16(format4, deltas+i*2)), int(u16(format4, rangeOffsets+i*2))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				gid := 0
+				if rangeOffset == 0 {
+					gid = (c + delta) & 0xFFFF
+				} else if gid = int(u16(format4, rangeOffsets+i*2+rangeOffset+(c-start)*2)); gid != 0 {
+					gid = (gid + delta) & 0xFFFF
+				}
+				add(rune(c), gid)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: no Unicode character map", ErrMalformedFont)
+	}
+	return glyphs, nil
+}
+
+// postScriptName returns the font's PostScript name (name ID 6), reduced to
+// characters that are safe in a PDF name.
+func postScriptName(table []byte) string {
+	name := ""
+	if table != nil {
+		count, storage := int(u16(table, 2)), int(u16(table, 4))
+		for i := 0; i < count && name == ""; i++ {
+			record := 6 + i*12
+			if u16(table, record+6) != 6 {
+				continue
+			}
+			platform := u16(table, record)
+			length, offset := int(u16(table, record+8)), int(u16(table, record+10))
+			raw := table[storage+offset : storage+offset+length]
+			if platform == 0 || platform == 3 {
+				units := make([]uint16, len(raw)/2)
+				for j := range units {
+					units[j] = u16(raw, j*2)
+				}
+				name = string(utf16.Decode(units))
+			} else {
+				name = string(raw)
+			}
+		}
+	}
+	name = strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return -1
+	}, name)
+	if name == "" {
+		return "EmbeddedFont"
+	}
+	return name
+}
+
+// glyph returns the glyph for r, or 0 (.notdef) if the font has none.
+func (f *TrueTypeFont) glyph(r rune) uint16 {
+	return f.glyphs[r]
+}
+
+// width returns a glyph's advance in 1/1000 em.
+func (f *TrueTypeFont) width(gid uint16) int {
+	return f.advances[gid] * 1000 / f.unitsPerEm
+}
+
+// scaled converts font units to 1/1000 em.
+func (f *TrueTypeFont) scaled(units int) int {
+	return units * 1000 / f.unitsPerEm
+}
+
+// compressedData returns the font file deflated for embedding, compressing it once.
+func (f *TrueTypeFont) compressedData() []byte {
+	f.compressOnce.Do(func() {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		_, _ = w.Write(f.data)
+		_ = w.Close()
+		f.compressed = buf.Bytes()
+	})
+	return f.compressed
+}
+
+func u16(b []byte, offset int) uint16 { return binary.BigEndian.Uint16(b[offset:]) }
+func i16(b []byte, offset int) int16  { return int16(binary.BigEndian.Uint16(b[offset:])) }
+func u32(b []byte, offset int) uint32 { return binary.BigEndian.Uint32(b[offset:]) }
@@ -15,6 +15,7 @@ import (
 func SetupRouter() *gin.Engine {
 	r := gin.Default()
 
+	r.Use(middleware.RequestID())
 	r.Use(CORSMiddleware())
 	r.Use(middleware.RequestLogger())
 
@@ -30,6 +31,7 @@ func SetupRouter() *gin.Engine {
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 	})
+	r.GET("/.well-known/jwks.json", handlers.GetJWKS)
 
 	api := r.Group("/api")
 	{
@@ -38,77 +40,171 @@ func SetupRouter() *gin.Engine {
 		auth := api.Group("/auth")
 		{
 			auth.POST("/employee/login", handlers.LoginEmployee)
+			auth.GET("/employee/login-options", handlers.HandleGetLoginOptions)
+			auth.GET("/employee/oidc/login", handlers.HandleBeginOIDCLogin)
+			auth.POST("/employee/oidc/callback", handlers.HandleCompleteOIDCLogin)
+			auth.POST("/employee/mfa/verify", handlers.HandleVerifyMFALogin)
+			auth.POST("/employee/mfa/enroll", handlers.HandleBeginMFAEnrollmentForLogin)
+			auth.POST("/employee/mfa/confirm", handlers.HandleConfirmMFAEnrollmentForLogin)
 			auth.POST("/customer/register", handlers.RegisterCustomer)
 			auth.POST("/customer/login", handlers.LoginCustomer)
+			auth.POST("/refresh", handlers.RefreshToken)
+			auth.POST("/logout", middleware.AuthMiddleware(), handlers.Logout)
+			auth.POST("/password/forgot", handlers.ForgotPassword)
+			auth.POST("/password/reset", handlers.ResetPassword)
+			auth.POST("/email/verify", handlers.VerifyEmail)
+			auth.POST("/email/resend", handlers.ResendVerificationEmail)
 		}
 
-		api.GET("/cars", handlers.GetCars)
-		api.GET("/cars/:id", handlers.GetCarByID)
+		// Staff may add ?include_deleted=true, so these read credentials when given
+		api.GET("/cars", middleware.OptionalAuth(), handlers.GetCars)
+		api.GET("/cars/:id", middleware.OptionalAuth(), handlers.GetCarByID)
 		api.GET("/cars/:id/reviews", handlers.GetCarReviews) // Public endpoint to get reviews for a specific car
-		api.GET("/branches", handlers.GetBranches)
-		api.GET("/branches/:id", handlers.GetBranchByID)
+		api.GET("/branches", middleware.OptionalAuth(), handlers.GetBranches)
+		api.GET("/branches/:id", middleware.OptionalAuth(), handlers.GetBranchByID)
+		api.GET("/exchange-rates", handlers.HandleGetExchangeRates)
 
 		protected := api.Group("/")
 		protected.Use(middleware.AuthMiddleware())
 		{
 			protected.GET("/rentals/:id", handlers.GetRentalByID)
 			protected.GET("/rentals/:id/price", handlers.GetRentalPrice)
+			protected.GET("/rentals/:id/invoice.pdf", handlers.HandleGetRentalInvoicePDF)
+			protected.GET("/rentals/:id/receipt.pdf", handlers.HandleGetRentalReceiptPDF)
 			protected.GET("/payments/:paymentId/status", handlers.GetPaymentStatus)
+			protected.GET("/me/sessions", handlers.HandleGetMySessions)
+			protected.GET("/loyalty/tiers", handlers.HandleGetLoyaltyTiers)
+			protected.DELETE("/me/sessions", handlers.HandleRevokeMySessions)
+			protected.DELETE("/me/sessions/:id", handlers.HandleRevokeMySession)
 			// DELETE /reviews/:id is now an admin/manager action or customer's own review
-			protected.DELETE("/reviews/:id", handlers.DeleteReview)        // Keep this for customer deleting their own, or admin can also use it
-			protected.GET("/rentals/:id/review", handlers.GetRentalReview) // Customer getting their own review for a rental
+			protected.DELETE("/reviews/:id", middleware.AuditStaffActions(), handlers.DeleteReview) // Keep this for customer deleting their own, or admin can also use it
+			protected.GET("/rentals/:id/review", handlers.GetRentalReview)                          // Customer getting their own review for a rental
 
+			// Each staff route names the permission it needs; roles and their permissions are managed under /roles.
+			// Every change made through a staff route is written to the audit log.
 			staff := protected.Group("/")
-			staff.Use(middleware.RoleMiddleware("admin", "manager"))
+			staff.Use(middleware.EmployeeRequired(), middleware.AuditStaffActions())
 			{
-				staff.POST("/branches", handlers.CreateBranch)
-				staff.PUT("/branches/:id", handlers.UpdateBranch)
-				staff.DELETE("/branches/:id", handlers.DeleteBranch)
+				staff.POST("/branches", middleware.RequirePermission("branches:manage"), handlers.CreateBranch)
+				staff.PUT("/branches/:id", middleware.RequirePermission("branches:manage"), handlers.UpdateBranch)
+				staff.DELETE("/branches/:id", middleware.RequirePermission("branches:manage"), handlers.DeleteBranch)
+				staff.POST("/branches/:id/restore", middleware.RequirePermission("deleted_records:manage"), handlers.HandleRestoreBranch)
 
-				staff.POST("/cars", handlers.AddCar)
-				staff.PUT("/cars/:id", handlers.UpdateCar)
-				staff.DELETE("/cars/:id", handlers.DeleteCar)
+				staff.POST("/cars", middleware.RequirePermission("cars:manage"), handlers.AddCar)
+				staff.PUT("/cars/:id", middleware.RequirePermission("cars:manage"), handlers.UpdateCar)
+				staff.DELETE("/cars/:id", middleware.RequirePermission("cars:manage"), handlers.DeleteCar)
+				staff.POST("/cars/:id/restore", middleware.RequirePermission("deleted_records:manage"), handlers.HandleRestoreCar)
 
-				staff.GET("/customers", handlers.GetCustomers)
-				staff.GET("/customers/:id", handlers.GetCustomerByID)
-				staff.PUT("/customers/:id", handlers.UpdateCustomer)
-				staff.DELETE("/customers/:id", handlers.DeleteCustomer)
+				staff.GET("/customers", middleware.RequirePermission("customers:view"), handlers.GetCustomers)
+				staff.GET("/customers/duplicates", middleware.RequirePermission("customers:view"), handlers.HandleGetDuplicateCustomers)
+				staff.GET("/customers/:id", middleware.RequirePermission("customers:view"), handlers.GetCustomerByID)
+				staff.POST("/customers/:id/merge", middleware.RequirePermission("customers:merge"), handlers.HandleMergeCustomers)
+				staff.PUT("/customers/:id", middleware.RequirePermission("customers:update"), handlers.UpdateCustomer)
+				staff.DELETE("/customers/:id", middleware.RequirePermission("customers:delete"), handlers.DeleteCustomer)
+				staff.POST("/customers/:id/restore", middleware.RequirePermission("deleted_records:manage"), handlers.HandleRestoreCustomer)
+				staff.GET("/customers/:id/risk-flags", middleware.RequirePermission("customers:view"), handlers.HandleGetCustomerRiskFlags)
+				staff.POST("/customers/:id/risk-flags", middleware.RequirePermission("customers:flag"), handlers.HandleCreateCustomerRiskFlag)
+				staff.POST("/customers/:id/risk-flags/:flagId/lift", middleware.RequirePermission("customers:flag"), handlers.HandleLiftCustomerRiskFlag)
+				staff.GET("/customers/:id/notes", middleware.RequirePermission("customers:view"), handlers.HandleGetCustomerNotes)
+				staff.POST("/customers/:id/notes", middleware.RequirePermission("customer_notes:write"), handlers.HandleCreateCustomerNote)
+				staff.GET("/customers/:id/timeline", middleware.RequirePermission("customers:view"), handlers.HandleGetCustomerTimeline)
+				staff.GET("/customers/:id/loyalty", middleware.RequirePermission("customers:view"), handlers.HandleGetCustomerLoyalty)
+				staff.POST("/customers/:id/loyalty/adjustments", middleware.RequirePermission("loyalty:manage"), handlers.HandleAdjustCustomerLoyalty)
+				staff.PUT("/customer-notes/:id", middleware.RequirePermission("customer_notes:write"), handlers.HandleUpdateCustomerNote)
+				staff.DELETE("/customer-notes/:id", middleware.RequirePermission("customer_notes:write"), handlers.HandleDeleteCustomerNote)
+				staff.GET("/erasure-requests", middleware.RequirePermission("customers:erase"), handlers.HandleGetErasureRequests)
+				staff.POST("/erasure-requests/:id/complete", middleware.RequirePermission("customers:erase"), handlers.HandleCompleteErasureRequest)
+				staff.POST("/erasure-requests/:id/reject", middleware.RequirePermission("customers:erase"), handlers.HandleRejectErasureRequest)
+				staff.GET("/customer-documents", middleware.RequirePermission("customer_documents:review"), handlers.HandleGetCustomerDocuments)
+				staff.GET("/customer-documents/:id/file", middleware.RequirePermission("customer_documents:review"), handlers.HandleGetCustomerDocumentFile)
+				staff.POST("/customer-documents/:id/approve", middleware.RequirePermission("customer_documents:review"), handlers.HandleApproveCustomerDocument)
+				staff.POST("/customer-documents/:id/reject", middleware.RequirePermission("customer_documents:review"), handlers.HandleRejectCustomerDocument)
 
-				staff.GET("/rentals", handlers.GetRentals) // Admin get all rentals
-				staff.POST("/rentals/:id/confirm", handlers.ConfirmRental)
-				staff.POST("/rentals/:id/activate", handlers.ActivateRental)
-				staff.POST("/rentals/:id/return", handlers.ReturnRental)
-				staff.POST("/rentals/:id/cancel", handlers.CancelRentalByStaff)
-				staff.DELETE("/rentals/:id", handlers.DeleteRental) // Admin delete rental
+				staff.GET("/rentals", middleware.RequirePermission("rentals:view"), handlers.GetRentals) // Admin get all rentals
+				staff.POST("/rentals/:id/confirm", middleware.RequirePermission("rentals:confirm"), handlers.ConfirmRental)
+				staff.POST("/rentals/:id/activate", middleware.RequirePermission("rentals:activate"), handlers.ActivateRental)
+				staff.POST("/rentals/:id/return", middleware.RequirePermission("rentals:return"), handlers.ReturnRental)
+				staff.POST("/rentals/:id/cancel", middleware.RequirePermission("rentals:cancel"), handlers.CancelRentalByStaff)
+				staff.DELETE("/rentals/:id", middleware.RequirePermission("rentals:delete"), handlers.DeleteRental) // Admin delete rental
+				staff.GET("/rentals/:id/notes", middleware.RequirePermission("rentals:view"), handlers.HandleGetRentalNotes)
+				staff.POST("/rentals/:id/notes", middleware.RequirePermission("customer_notes:write"), handlers.HandleCreateRentalNote)
+				staff.POST("/rentals/:id/restore", middleware.RequirePermission("deleted_records:manage"), handlers.HandleRestoreRental)
 
-				staff.GET("/payments", handlers.GetPayments)
-				staff.GET("/rentals/:id/payments", handlers.GetPaymentsByRental)
-				staff.POST("/rentals/:id/payments", handlers.ProcessPayment)
+				staff.GET("/payments", middleware.RequirePermission("payments:view"), handlers.GetPayments)
+				staff.GET("/rentals/:id/payments", middleware.RequirePermission("payments:view"), handlers.GetPaymentsByRental)
+				staff.POST("/rentals/:id/payments", middleware.RequirePermission("payments:record"), handlers.ProcessPayment)
+				staff.POST("/payments/:paymentId/refund", middleware.RequirePermission("payments:refund"), handlers.HandleRefundPayment)
+				staff.GET("/rentals/:id/ledger", middleware.RequirePermission("ledger:view"), handlers.HandleGetRentalLedger)
 
-				staff.GET("/rentals/pending-verification", handlers.HandleGetRentalsPendingVerification)
-				staff.POST("/rentals/:id/verify-payment", handlers.HandleVerifyPayment)
+				staff.GET("/rentals/pending-verification", middleware.RequirePermission("payments:verify"), handlers.HandleGetRentalsPendingVerification)
+				staff.POST("/rentals/:id/verify-payment", middleware.RequirePermission("payments:verify"), handlers.HandleVerifyPayment)
 
-				staff.GET("/dashboard", handlers.GetDashboard)
+				staff.GET("/dashboard", middleware.RequirePermission("dashboard:view"), handlers.GetDashboard)
+
+				staff.GET("/audit", middleware.RequirePermission("audit:view"), handlers.HandleGetAuditLog)
+
+				// Every employee manages their own second factor, whatever their role.
+				staff.GET("/me/mfa", handlers.HandleGetMyMFAStatus)
+				staff.POST("/me/mfa/enroll", handlers.HandleBeginMyMFAEnrollment)
+				staff.POST("/me/mfa/confirm", handlers.HandleConfirmMyMFAEnrollment)
+				staff.POST("/me/mfa/recovery-codes", handlers.HandleRegenerateMyRecoveryCodes)
+				staff.POST("/me/mfa/disable", handlers.HandleDisableMyMFA)
+				staff.GET("/me/permissions", handlers.HandleGetMyPermissions)
 
 				reports := staff.Group("/reports")
 				{
-					reports.GET("/revenue", handlers.HandleGetRevenueReport)
-					reports.GET("/popular-cars", handlers.HandleGetPopularCarsReport)
-					reports.GET("/branch-performance", handlers.HandleGetBranchPerformanceReport)
+					reports.GET("/revenue", middleware.RequirePermission("reports:view"), handlers.HandleGetRevenueReport)
+					reports.GET("/popular-cars", middleware.RequirePermission("reports:view"), handlers.HandleGetPopularCarsReport)
+					reports.GET("/branch-performance", middleware.RequirePermission("reports:view"), handlers.HandleGetBranchPerformanceReport)
+					reports.GET("/trial-balance", middleware.RequirePermission("ledger:view"), handlers.HandleGetTrialBalance)
+					reports.GET("/referrals", middleware.RequirePermission("reports:view"), handlers.HandleGetReferralReport)
 				}
 
-				// Review Management for Admin/Manager
-				staff.GET("/reviews", handlers.HandleGetAllReviewsAdmin) // New route for admin to get all reviews
-				// DELETE /api/reviews/:id is already defined above and can be used by admin/manager due to RoleMiddleware on `protected`
-			}
+				staff.GET("/corporate-accounts", middleware.RequirePermission("corporate:view"), handlers.HandleGetCorporateAccounts)
+				staff.POST("/corporate-accounts", middleware.RequirePermission("corporate:manage"), handlers.HandleCreateCorporateAccount)
+				staff.GET("/corporate-accounts/:id", middleware.RequirePermission("corporate:view"), handlers.HandleGetCorporateAccountByID)
+				staff.PUT("/corporate-accounts/:id", middleware.RequirePermission("corporate:manage"), handlers.HandleUpdateCorporateAccount)
+				staff.GET("/corporate-accounts/:id/members", middleware.RequirePermission("corporate:view"), handlers.HandleGetCorporateAccountMembers)
+				staff.POST("/corporate-accounts/:id/members", middleware.RequirePermission("corporate:manage"), handlers.HandleSetCorporateAccountMember)
+				staff.DELETE("/corporate-accounts/:id/members/:customerId", middleware.RequirePermission("corporate:manage"), handlers.HandleRemoveCorporateAccountMember)
+				staff.GET("/corporate-accounts/:id/invoices", middleware.RequirePermission("corporate:view"), handlers.HandleGetCorporateInvoices)
+				staff.GET("/corporate-invoices", middleware.RequirePermission("corporate:view"), handlers.HandleGetCorporateInvoices)
+				staff.POST("/corporate-invoices/run", middleware.RequirePermission("corporate:invoice"), handlers.HandleRunCorporateInvoicing)
+				staff.GET("/corporate-invoices/:id", middleware.RequirePermission("corporate:view"), handlers.HandleGetCorporateInvoiceByID)
+				staff.POST("/corporate-invoices/:id/payments", middleware.RequirePermission("corporate:invoice"), handlers.HandleRecordCorporateInvoicePayment)
 
-			adminOnly := protected.Group("/")
-			adminOnly.Use(middleware.RoleMiddleware("admin")) // Strictly Admin
-			{
-				adminOnly.GET("/users", handlers.GetUsers)
-				adminOnly.POST("/users", handlers.CreateUser)
-				adminOnly.PUT("/users/:id", handlers.UpdateUser)
-				adminOnly.DELETE("/users/:id", handlers.DeleteUser)
+				// Review moderation; DELETE /api/reviews/:id above checks reviews:moderate for employees
+				staff.GET("/reviews", middleware.RequirePermission("reviews:moderate"), handlers.HandleGetAllReviewsAdmin)
+
+				staff.GET("/users", middleware.RequirePermission("users:manage"), handlers.GetUsers)
+				staff.POST("/users", middleware.RequirePermission("users:manage"), handlers.CreateUser)
+				staff.PUT("/users/:id", middleware.RequirePermission("users:manage"), handlers.UpdateUser)
+				staff.DELETE("/users/:id", middleware.RequirePermission("users:manage"), handlers.DeleteUser)
+				staff.POST("/users/:id/restore", middleware.RequirePermission("deleted_records:manage"), handlers.HandleRestoreUser)
+				staff.POST("/users/:id/revoke-tokens", middleware.RequirePermission("users:manage"), handlers.RevokeUserTokens)
+				staff.GET("/users/:id/branches", middleware.RequirePermission("users:manage"), handlers.GetUserBranches)
+				staff.PUT("/users/:id/branches", middleware.RequirePermission("users:manage"), handlers.SetUserBranches)
+
+				staff.GET("/api-keys", middleware.RequirePermission("api_keys:manage"), handlers.HandleGetAPIKeys)
+				staff.POST("/api-keys", middleware.RequirePermission("api_keys:manage"), handlers.HandleCreateAPIKey)
+				staff.DELETE("/api-keys/:id", middleware.RequirePermission("api_keys:manage"), handlers.HandleRevokeAPIKey)
+
+				staff.GET("/permissions", middleware.RequirePermission("roles:manage"), handlers.HandleGetPermissions)
+				staff.GET("/roles", middleware.RequirePermission("roles:manage"), handlers.HandleGetRoles)
+				staff.POST("/roles", middleware.RequirePermission("roles:manage"), handlers.HandleCreateRole)
+				staff.GET("/roles/:name", middleware.RequirePermission("roles:manage"), handlers.HandleGetRole)
+				staff.PUT("/roles/:name", middleware.RequirePermission("roles:manage"), handlers.HandleUpdateRole)
+				staff.DELETE("/roles/:name", middleware.RequirePermission("roles:manage"), handlers.HandleDeleteRole)
+
+				staff.GET("/security/mfa-policies", middleware.RequirePermission("security:manage"), handlers.HandleGetMFAPolicies)
+				staff.PUT("/security/mfa-policies", middleware.RequirePermission("security:manage"), handlers.HandleSetMFAPolicy)
+				staff.GET("/security/login-attempts", middleware.RequirePermission("security:manage"), handlers.HandleGetLoginAttempts)
+				staff.GET("/security/lockouts", middleware.RequirePermission("security:manage"), handlers.HandleGetLockouts)
+				staff.POST("/security/lockouts/unlock", middleware.RequirePermission("security:manage"), handlers.HandleUnlockAccount)
+
+				staff.PUT("/exchange-rates/:currency", middleware.RequirePermission("exchange_rates:manage"), handlers.HandleSetExchangeRate)
+				staff.DELETE("/exchange-rates/:currency", middleware.RequirePermission("exchange_rates:manage"), handlers.HandleDeleteExchangeRate)
+				staff.POST("/exchange-rates/import", middleware.RequirePermission("exchange_rates:manage"), handlers.HandleImportExchangeRates)
 			}
 
 			customerOnly := protected.Group("/")
@@ -116,6 +212,15 @@ func SetupRouter() *gin.Engine {
 			{
 				customerOnly.GET("/me/profile", handlers.GetMyProfile)
 				customerOnly.PUT("/me/profile", handlers.UpdateMyProfile)
+				customerOnly.GET("/me/data-export", handlers.HandleExportMyData)
+				customerOnly.GET("/me/erasure-requests", handlers.HandleGetMyErasureRequests)
+				customerOnly.POST("/me/erasure-requests", handlers.HandleRequestMyErasure)
+				customerOnly.GET("/me/documents", handlers.HandleGetMyDocuments)
+				customerOnly.POST("/me/documents", handlers.HandleUploadMyDocument)
+				customerOnly.GET("/me/documents/:id/file", handlers.HandleGetMyDocumentFile)
+				customerOnly.GET("/me/corporate-accounts", handlers.HandleGetMyCorporateAccounts)
+				customerOnly.GET("/me/loyalty", handlers.HandleGetMyLoyalty)
+				customerOnly.GET("/me/referral", handlers.HandleGetMyReferral)
 				customerOnly.POST("/rentals/initiate", handlers.InitiateRental)
 				customerOnly.POST("/rentals/:id/upload-slip", handlers.UploadSlip)
 				customerOnly.GET("/my/rentals", handlers.GetMyRentals) // Customer get their own rentals
@@ -145,7 +250,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		}
 		c.Writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
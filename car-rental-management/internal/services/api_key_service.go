@@ -0,0 +1,278 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// API keys look like crk_<8 hex>_<secret>. The crk_<8 hex> part is stored as key_prefix and
+// identifies the key; only a hash of the whole key is kept.
+const (
+	apiKeyScheme    = "crk_"
+	apiKeyPrefixLen = len(apiKeyScheme) + 8
+
+	// last_used_at is refreshed at most this often, so busy integrations do not write on every call.
+	apiKeyLastUsedResolution = 1 * time.Minute
+)
+
+var (
+	ErrAPIKeyNotFound             = errors.New("API key not found")
+	ErrInvalidAPIKey              = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyPermissionDenied     = errors.New("API keys cannot be given permissions you do not hold yourself")
+	ErrAPIKeyPermissionNotAllowed = errors.New("API keys cannot be given user, role, security or API key management permissions")
+	ErrAPIKeyBranchesRequired     = errors.New("you are limited to some branches, so the key must list branch_ids within them")
+	ErrAPIKeyExpiryInPast         = errors.New("expires_at must be in the future")
+)
+
+// Permissions that would let a leaked key grant itself or others more access.
+var nonDelegablePermissions = map[string]bool{
+	"users:manage":    true,
+	"roles:manage":    true,
+	"security:manage": true,
+	"api_keys:manage": true,
+}
+
+// APIKeyPrincipal is the integration an API key authenticates as.
+type APIKeyPrincipal struct {
+	KeyID       int
+	Name        string
+	Permissions []string
+	Scope       BranchScope
+}
+
+// HasPermissions reports whether the key holds every one of permissions.
+func (p APIKeyPrincipal) HasPermissions(permissions ...string) bool {
+	held := make(map[string]bool, len(p.Permissions))
+	for _, permission := range p.Permissions {
+		held[permission] = true
+	}
+	for _, permission := range permissions {
+		if !held[permission] {
+			return false
+		}
+	}
+	return true
+}
+
+// newAPIKey returns a fresh key and its stored prefix.
+func newAPIKey() (rawKey, prefix string, err error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := randomURLToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	prefix = apiKeyScheme + id[:8]
+	return prefix + "_" + secret, prefix, nil
+}
+
+// CreateAPIKey issues a key with a subset of the creator's permissions and branches.
+func CreateAPIKey(input models.CreateAPIKeyInput, creatorID int, creatorRole string, creatorScope BranchScope) (created models.CreatedAPIKey, err error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return models.CreatedAPIKey{}, errors.New("API key name cannot be empty")
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return models.CreatedAPIKey{}, ErrAPIKeyExpiryInPast
+	}
+	permissions := uniqueStrings(input.Permissions)
+	for _, permission := range permissions {
+		if nonDelegablePermissions[permission] {
+			return models.CreatedAPIKey{}, ErrAPIKeyPermissionNotAllowed
+		}
+	}
+	held, err := RoleHasPermissions(creatorRole, permissions...)
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	if !held {
+		return models.CreatedAPIKey{}, ErrAPIKeyPermissionDenied
+	}
+	allBranches := len(input.BranchIDs) == 0
+	if !creatorScope.All {
+		if allBranches {
+			return models.CreatedAPIKey{}, ErrAPIKeyBranchesRequired
+		}
+		for _, branchID := range input.BranchIDs {
+			if !creatorScope.Allows(branchID) {
+				return models.CreatedAPIKey{}, ErrBranchForbidden
+			}
+		}
+	}
+
+	rawKey, prefix, err := newAPIKey()
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.CreatedAPIKey{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if err = checkPermissionsExist(tx, permissions); err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	err = tx.Get(&created.APIKey, `INSERT INTO api_keys (name, key_prefix, key_hash, all_branches, created_by_employee_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, name, key_prefix, all_branches, created_by_employee_id, expires_at, last_used_at, last_used_ip, revoked_at, created_at`,
+		name, prefix, hashOpaqueToken(rawKey), allBranches, creatorID, input.ExpiresAt)
+	if err != nil {
+		return models.CreatedAPIKey{}, fmt.Errorf("failed to create API key: %w", err)
+	}
+	if _, err = tx.Exec("INSERT INTO api_key_permissions (api_key_id, permission) SELECT $1, UNNEST($2::text[])",
+		created.ID, pq.Array(permissions)); err != nil {
+		return models.CreatedAPIKey{}, fmt.Errorf("failed to grant API key permissions: %w", err)
+	}
+	created.BranchIDs = []int{}
+	if !allBranches {
+		var branchIDs []int64
+		if branchIDs, err = existingBranchIDs(tx, input.BranchIDs); err != nil {
+			return models.CreatedAPIKey{}, err
+		}
+		if _, err = tx.Exec("INSERT INTO api_key_branches (api_key_id, branch_id) SELECT $1, UNNEST($2::int[])",
+			created.ID, pq.Array(branchIDs)); err != nil {
+			return models.CreatedAPIKey{}, fmt.Errorf("failed to assign API key branches: %w", err)
+		}
+		for _, id := range branchIDs {
+			created.BranchIDs = append(created.BranchIDs, int(id))
+		}
+	}
+	created.Permissions = permissions
+	created.Key = rawKey
+
+	log.Printf("✅ Service: API key %d (%s, %s) created by employee %d with %v", created.ID, name, prefix, creatorID, permissions)
+	return created, nil
+}
+
+// GetAPIKeys lists every key, newest first, with its permissions and branches.
+func GetAPIKeys() ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := config.DB.Select(&keys, `SELECT id, name, key_prefix, all_branches, created_by_employee_id, expires_at, last_used_at, last_used_ip, revoked_at, created_at
+		FROM api_keys ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		log.Printf("❌ Service: Error fetching API keys: %v", err)
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+
+	var grants []struct {
+		KeyID      int    `db:"api_key_id"`
+		Permission string `db:"permission"`
+	}
+	if err := config.DB.Select(&grants, "SELECT api_key_id, permission FROM api_key_permissions ORDER BY permission"); err != nil {
+		return nil, fmt.Errorf("failed to fetch API key permissions: %w", err)
+	}
+	var branches []struct {
+		KeyID    int `db:"api_key_id"`
+		BranchID int `db:"branch_id"`
+	}
+	if err := config.DB.Select(&branches, "SELECT api_key_id, branch_id FROM api_key_branches ORDER BY branch_id"); err != nil {
+		return nil, fmt.Errorf("failed to fetch API key branches: %w", err)
+	}
+
+	permissionsByKey := make(map[int][]string)
+	for _, grant := range grants {
+		permissionsByKey[grant.KeyID] = append(permissionsByKey[grant.KeyID], grant.Permission)
+	}
+	branchesByKey := make(map[int][]int)
+	for _, branch := range branches {
+		branchesByKey[branch.KeyID] = append(branchesByKey[branch.KeyID], branch.BranchID)
+	}
+	for i := range keys {
+		keys[i].Permissions = permissionsByKey[keys[i].ID]
+		if keys[i].Permissions == nil {
+			keys[i].Permissions = []string{}
+		}
+		keys[i].BranchIDs = branchesByKey[keys[i].ID]
+		if keys[i].BranchIDs == nil {
+			keys[i].BranchIDs = []int{}
+		}
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey stops a key working immediately. Revoked keys stay listed for the audit trail.
+func RevokeAPIKey(id int) error {
+	result, err := config.DB.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		log.Printf("❌ Service: Error revoking API key %d: %v", id, err)
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	log.Printf("✅ Service: API key %d revoked", id)
+	return nil
+}
+
+// AuthenticateAPIKey resolves a presented key to its integration, checking it has not expired
+// or been revoked. Permissions and branches are read on every call, so edits apply at once.
+func AuthenticateAPIKey(rawKey, ip string) (APIKeyPrincipal, error) {
+	if !strings.HasPrefix(rawKey, apiKeyScheme) || len(rawKey) <= apiKeyPrefixLen+1 || rawKey[apiKeyPrefixLen] != '_' {
+		return APIKeyPrincipal{}, ErrInvalidAPIKey
+	}
+
+	var key struct {
+		ID          int        `db:"id"`
+		Name        string     `db:"name"`
+		KeyHash     string     `db:"key_hash"`
+		AllBranches bool       `db:"all_branches"`
+		ExpiresAt   *time.Time `db:"expires_at"`
+		LastUsedAt  *time.Time `db:"last_used_at"`
+		RevokedAt   *time.Time `db:"revoked_at"`
+	}
+	err := config.DB.Get(&key, "SELECT id, name, key_hash, all_branches, expires_at, last_used_at, revoked_at FROM api_keys WHERE key_prefix = $1",
+		rawKey[:apiKeyPrefixLen])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIKeyPrincipal{}, ErrInvalidAPIKey
+		}
+		return APIKeyPrincipal{}, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashOpaqueToken(rawKey))) != 1 {
+		return APIKeyPrincipal{}, ErrInvalidAPIKey
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now())) {
+		log.Printf("❌ Revoked or expired API key %d presented from %s", key.ID, ip)
+		return APIKeyPrincipal{}, ErrInvalidAPIKey
+	}
+
+	principal := APIKeyPrincipal{KeyID: key.ID, Name: key.Name, Permissions: []string{}, Scope: AllBranches}
+	if err := config.DB.Select(&principal.Permissions, "SELECT permission FROM api_key_permissions WHERE api_key_id = $1", key.ID); err != nil {
+		return APIKeyPrincipal{}, fmt.Errorf("failed to fetch API key permissions: %w", err)
+	}
+	if !key.AllBranches {
+		principal.Scope = BranchScope{BranchIDs: []int{}}
+		if err := config.DB.Select(&principal.Scope.BranchIDs, "SELECT branch_id FROM api_key_branches WHERE api_key_id = $1", key.ID); err != nil {
+			return APIKeyPrincipal{}, fmt.Errorf("failed to fetch API key branches: %w", err)
+		}
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyLastUsedResolution {
+		if _, err := config.DB.Exec("UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $1 WHERE id = $2", ip, key.ID); err != nil {
+			log.Printf("⚠️ Failed to record use of API key %d: %v", key.ID, err)
+		}
+	}
+	return principal, nil
+}
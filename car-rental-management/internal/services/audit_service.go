@@ -0,0 +1,297 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Fields never copied into the audit log, whatever entity they appear on.
+var auditRedactedFields = map[string]bool{
+	"password":       true,
+	"new_password":   true,
+	"secret":         true,
+	"key":            true,
+	"recovery_codes": true,
+	"token":          true,
+	"refresh_token":  true,
+}
+
+// Personal data is not copied into the audit log either, at any depth, since its rows cannot be
+// erased with the customer. A field that changed still shows, with auditPersonalDataMarker in
+// place of its value.
+var auditPersonalDataFields = map[string]bool{
+	"name":            true,
+	"email":           true,
+	"phone":           true,
+	"billing_address": true,
+	"tax_id":          true,
+	"document_number": true,
+	"customer_name":   true,
+	"customer_email":  true,
+	"customer_phone":  true,
+}
+
+const auditPersonalDataMarker = "[personal data]"
+
+// AuditChange is what a handler reports about the action it performed. Before is the entity as
+// it was and After as it now is; either may be nil.
+type AuditChange struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     interface{}
+	After      interface{}
+}
+
+// RecordAudit appends entry to the audit log, storing only the fields change altered.
+func RecordAudit(entry models.AuditLogEntry, change AuditChange) error {
+	before, after, err := auditDiff(change.Before, change.After)
+	if err != nil {
+		return fmt.Errorf("failed to compare audit states: %w", err)
+	}
+	var entityID interface{}
+	if change.EntityID != "" {
+		entityID = change.EntityID
+	}
+	_, err = config.DB.Exec(`INSERT INTO audit_log (actor_type, actor_employee_id, actor_api_key_id, actor_email, action, entity_type, entity_id,
+			before_data, after_data, http_method, http_path, status_code, ip_address, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb, $9::jsonb, $10, $11, $12, $13, $14)`,
+		entry.ActorType, entry.ActorEmployeeID, entry.ActorAPIKeyID, entry.ActorEmail, change.Action, change.EntityType, entityID,
+		before, after, entry.Method, entry.Path, entry.StatusCode, entry.IPAddress, entry.RequestID)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// auditDiff renders before and after as JSON objects holding only the fields that differ, with
+// personal data masked. A nil side is returned as nil, so a create stores no before and a delete
+// no after.
+func auditDiff(before, after interface{}) (beforeJSON, afterJSON interface{}, err error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if beforeFields != nil && afterFields != nil {
+		for field, value := range beforeFields {
+			if other, ok := afterFields[field]; ok && reflect.DeepEqual(value, other) {
+				delete(beforeFields, field)
+				delete(afterFields, field)
+			}
+		}
+	}
+	maskAuditPersonalData(beforeFields)
+	maskAuditPersonalData(afterFields)
+	if beforeJSON, err = marshalAuditFields(beforeFields); err != nil {
+		return nil, nil, err
+	}
+	if afterJSON, err = marshalAuditFields(afterFields); err != nil {
+		return nil, nil, err
+	}
+	return beforeJSON, afterJSON, nil
+}
+
+// auditFields flattens state to its top-level JSON fields, without redacted ones.
+// Values that are not JSON objects are kept under "value".
+func auditFields(state interface{}) (map[string]interface{}, error) {
+	if state == nil {
+		return nil, nil
+	}
+	if value := reflect.ValueOf(state); value.Kind() == reflect.Ptr && value.IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"value": value}, nil
+	}
+	for field := range fields {
+		if auditRedactedFields[field] {
+			delete(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// maskAuditPersonalData replaces the values of personal data fields in fields, and in any
+// objects nested in it, with auditPersonalDataMarker. Empty values are left as they are.
+func maskAuditPersonalData(fields map[string]interface{}) {
+	for field, value := range fields {
+		if auditPersonalDataFields[field] && value != nil && value != "" {
+			fields[field] = auditPersonalDataMarker
+			continue
+		}
+		maskNestedAuditPersonalData(value)
+	}
+}
+
+func maskNestedAuditPersonalData(value interface{}) {
+	switch nested := value.(type) {
+	case map[string]interface{}:
+		maskAuditPersonalData(nested)
+	case []interface{}:
+		for _, item := range nested {
+			maskNestedAuditPersonalData(item)
+		}
+	}
+}
+
+// maskCustomerAuditEntries masks personal data in the audit log entries about a customer, or
+// that mention their email address (a merge into another account, say), which were recorded
+// before personal data was masked on the way in. It is part of erasing the customer.
+func maskCustomerAuditEntries(tx *sqlx.Tx, customerID int, email string) error {
+	var entries []struct {
+		ID     int64           `db:"id"`
+		Before *types.JSONText `db:"before_data"`
+		After  *types.JSONText `db:"after_data"`
+	}
+	err := tx.Select(&entries, `SELECT id, before_data, after_data FROM audit_log
+		WHERE (entity_type = 'customer' AND entity_id = $1)
+		   OR jsonb_path_exists(COALESCE(before_data, '{}'), '$.** ? (@ == $email)', jsonb_build_object('email', $2::text))
+		   OR jsonb_path_exists(COALESCE(after_data, '{}'), '$.** ? (@ == $email)', jsonb_build_object('email', $2::text))`,
+		strconv.Itoa(customerID), email)
+	if err != nil {
+		return fmt.Errorf("failed to find audit entries about customer: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec("SET LOCAL car_rental.audit_erasure = 'on'"); err != nil {
+		return fmt.Errorf("failed to allow audit entry masking: %w", err)
+	}
+	for _, entry := range entries {
+		before, errBefore := maskAuditJSON(entry.Before)
+		after, errAfter := maskAuditJSON(entry.After)
+		if err := errors.Join(errBefore, errAfter); err != nil {
+			return fmt.Errorf("failed to mask audit entry %d: %w", entry.ID, err)
+		}
+		if _, err := tx.Exec("UPDATE audit_log SET before_data = $1::jsonb, after_data = $2::jsonb WHERE id = $3", before, after, entry.ID); err != nil {
+			return fmt.Errorf("failed to mask audit entry %d: %w", entry.ID, err)
+		}
+	}
+	if _, err := tx.Exec("SET LOCAL car_rental.audit_erasure = 'off'"); err != nil {
+		return fmt.Errorf("failed to restore audit log protection: %w", err)
+	}
+	log.Printf("🧹 Masked personal data in %d audit log entries about customer %d", len(entries), customerID)
+	return nil
+}
+
+// maskAuditJSON masks personal data in a stored audit state.
+func maskAuditJSON(data *types.JSONText) (interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(*data, &value); err != nil {
+		return nil, err
+	}
+	if fields, ok := value.(map[string]interface{}); ok {
+		maskAuditPersonalData(fields)
+	} else {
+		maskNestedAuditPersonalData(value)
+	}
+	masked, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(masked), nil
+}
+
+func marshalAuditFields(fields map[string]interface{}) (interface{}, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// GetAuditLog returns a page of audit entries matching filters, newest first.
+func GetAuditLog(filters models.AuditLogFilters) (models.PaginatedAuditLogResponse, error) {
+	response := models.PaginatedAuditLogResponse{Entries: []models.AuditLogEntry{}}
+	if filters.Limit <= 0 || filters.Limit > 200 {
+		filters.Limit = 50
+	}
+	if filters.Page <= 0 {
+		filters.Page = 1
+	}
+
+	var conditions []string
+	args := []interface{}{}
+	addCondition := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+	if filters.ActorEmployeeID != nil {
+		addCondition("actor_employee_id = $%d", *filters.ActorEmployeeID)
+	}
+	if filters.ActorAPIKeyID != nil {
+		addCondition("actor_api_key_id = $%d", *filters.ActorAPIKeyID)
+	}
+	if filters.Action != "" {
+		addCondition("action = $%d", filters.Action)
+	}
+	if filters.EntityType != "" {
+		addCondition("entity_type = $%d", filters.EntityType)
+	}
+	if filters.EntityID != "" {
+		addCondition("entity_id = $%d", filters.EntityID)
+	}
+	if filters.RequestID != "" {
+		addCondition("request_id = $%d", filters.RequestID)
+	}
+	if filters.From != nil {
+		addCondition("created_at >= $%d", *filters.From)
+	}
+	if filters.To != nil {
+		addCondition("created_at < $%d", *filters.To)
+	}
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if err := config.DB.Get(&response.TotalCount, "SELECT COUNT(*) FROM audit_log"+whereClause, args...); err != nil {
+		log.Printf("❌ Service: Error counting audit log entries: %v", err)
+		return response, fmt.Errorf("failed to count audit log entries: %w", err)
+	}
+	query := `SELECT id, actor_type, actor_employee_id, actor_api_key_id, actor_email, action, entity_type, entity_id,
+			before_data, after_data, http_method, http_path, status_code, ip_address, request_id, created_at
+		FROM audit_log` + whereClause + fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filters.Limit, (filters.Page-1)*filters.Limit)
+	if err := config.DB.Select(&response.Entries, query, args...); err != nil {
+		log.Printf("❌ Service: Error fetching audit log: %v", err)
+		return response, fmt.Errorf("failed to fetch audit log: %w", err)
+	}
+
+	response.Page = filters.Page
+	response.Limit = filters.Limit
+	if response.TotalCount > 0 {
+		response.TotalPages = int(math.Ceil(float64(response.TotalCount) / float64(response.Limit)))
+	}
+	return response, nil
+}
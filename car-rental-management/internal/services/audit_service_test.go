@@ -0,0 +1,49 @@
+package services
+
+import (
+	"car-rental-management/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestAuditDiffMasksPersonalData(t *testing.T) {
+	phone := "0812345678"
+	newPhone := "0898765432"
+	before := models.Customer{ID: 7, Name: "Somchai Jaidee", Email: "somchai@example.com", Phone: &phone}
+	after := models.Customer{ID: 7, Name: "Somchai Jaidee", Email: "somchai@example.com", Phone: &newPhone}
+
+	beforeJSON, afterJSON, err := auditDiff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []interface{}{beforeJSON, afterJSON} {
+		text, _ := data.(string)
+		for _, personal := range []string{"Somchai", "somchai@example.com", phone, newPhone} {
+			if strings.Contains(text, personal) {
+				t.Errorf("audit data %s contains %q", text, personal)
+			}
+		}
+		// The phone changed, so the entry says so; the unchanged name and email are left out.
+		if text != `{"phone":"[personal data]"}` {
+			t.Errorf("audit data = %s, want only a phone change marker", text)
+		}
+	}
+}
+
+func TestAuditDiffMasksNestedPersonalData(t *testing.T) {
+	merged := models.Customer{ID: 8, Name: "Somchai Jaidee", Email: "somchai@example.com"}
+	beforeJSON, afterJSON, err := auditDiff(map[string]interface{}{"merged_customer": merged},
+		models.CustomerMergeResult{Customer: merged, MergedCustomerID: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []interface{}{beforeJSON, afterJSON} {
+		text, _ := data.(string)
+		if strings.Contains(text, "Somchai") || strings.Contains(text, "somchai@example.com") {
+			t.Errorf("audit data %s contains personal data", text)
+		}
+		if !strings.Contains(text, `"name":"[personal data]"`) {
+			t.Errorf("audit data %s does not mark the nested name", text)
+		}
+	}
+}
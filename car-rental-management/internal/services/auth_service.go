@@ -65,46 +65,70 @@ func RegisterEmployee(employee models.Employee) error {
 	return nil
 }
 
-func AuthenticateEmployee(email, password string) (string, error) {
+// AuthenticateEmployee checks the password. Employees with MFA get a challenge instead of tokens.
+// client's address is used for throttling and the login audit log, and both it and the user agent
+// are recorded against the new session.
+func AuthenticateEmployee(email, password string, client models.ClientInfo) (models.EmployeeLoginResult, error) {
+	if !config.EmployeePasswordLoginEnabled() {
+		return models.EmployeeLoginResult{}, ErrPasswordLoginDisabled
+	}
+	if err := checkLoginAllowed("employee", email, client.IP); err != nil {
+		return models.EmployeeLoginResult{}, err
+	}
+
 	var employee models.Employee
-	query := "SELECT id, name, email, password, role FROM employees WHERE email=$1"
+	query := "SELECT id, name, email, password, role FROM employees WHERE email=$1 AND deleted_at IS NULL"
 	// Use Get instead of QueryRowx/StructScan for simpler error checking
 	err := config.DB.Get(&employee, query, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Printf("❌ Employee email not found: %s", email)
-			return "", errors.New("invalid email or password") // Keep error generic
+			utils.DummyPasswordCheck(password) // Take as long as a real password check
+			recordLoginAttempt("employee", email, client.IP, false, loginFailureBadCredentials)
+			return models.EmployeeLoginResult{}, ErrInvalidCredentials
 		}
 		log.Printf("❌ Error fetching employee %s: %v", email, err)
 		// Wrap the database error
-		return "", fmt.Errorf("error fetching employee data: %w", err)
+		return models.EmployeeLoginResult{}, fmt.Errorf("error fetching employee data: %w", err)
 	}
 
 	// Check password using utility function
 	if !utils.CheckPasswordHash(password, employee.Password) {
 		log.Printf("❌ Employee password mismatch for: %s", email)
-		return "", errors.New("invalid email or password") // Keep error generic
+		recordLoginAttempt("employee", email, client.IP, false, loginFailureBadCredentials)
+		return models.EmployeeLoginResult{}, ErrInvalidCredentials
 	}
+	recordLoginAttempt("employee", email, client.IP, true, "")
 
-	// Generate token (passing employee ID and role)
-	token, err := generateEmployeeToken(employee.ID, employee.Email, employee.Role)
+	// Issue tokens, or an MFA challenge when a second factor is needed
+	result, err := employeeLoginStep(employee, client)
 	if err != nil {
 		log.Println("❌ Error generating employee token:", err)
 		// Don't wrap internal token generation error usually, return generic auth failure
-		return "", errors.New("authentication failed")
+		return models.EmployeeLoginResult{}, errors.New("authentication failed")
 	}
 
+	if result.TokenPair == nil {
+		log.Printf("🔐 Password accepted for employee %s, second factor required", email)
+		return result, nil
+	}
 	log.Printf("✅ Authentication successful for employee: %s", email)
-	return token, nil
+	return result, nil
 }
 
 // --- Customer Auth ---
 
 // RegisterCustomer now accepts RegisterCustomerInput
-func RegisterCustomer(input models.RegisterCustomerInput) (models.Customer, error) {
+// A customer registering with a referral code is recorded as referred; client is kept for the
+// referral's fraud checks.
+func RegisterCustomer(input models.RegisterCustomerInput, client models.ClientInfo) (models.Customer, error) {
 	log.Println("🔍 Validating customer data for registration:", input.Email)
 	// Validation performed via binding tags in the handler mostly
 	// Re-check here for service-level assurance if needed, but redundant if binding is robust
+	// Emails and phones are stored normalised so the same person typing them differently is
+	// recognised as the same person.
+	input.Email = utils.NormaliseEmail(input.Email)
+	input.Phone = utils.NormalisePhonePtr(input.Phone)
 	if strings.TrimSpace(input.Name) == "" {
 		return models.Customer{}, errors.New("customer name cannot be empty")
 	}
@@ -117,7 +141,8 @@ func RegisterCustomer(input models.RegisterCustomerInput) (models.Customer, erro
 
 	// Check existing email
 	var count int
-	err := config.DB.Get(&count, "SELECT COUNT(*) FROM customers WHERE email=$1", input.Email)
+	emailMatch, emailArgs := customerEmailMatch(1, input.Email)
+	err := config.DB.Get(&count, "SELECT COUNT(*) FROM customers WHERE "+emailMatch, emailArgs...)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) { // Should return 0 if no rows
 		log.Println("❌ Error checking existing customer email:", err)
 		return models.Customer{}, fmt.Errorf("database error checking email existence: %w", err)
@@ -134,6 +159,14 @@ func RegisterCustomer(input models.RegisterCustomerInput) (models.Customer, erro
 		return models.Customer{}, errors.New("failed to secure password")
 	}
 
+	// A mistyped code is refused so the customer can correct it, rather than silently losing the discount.
+	referrerID := 0
+	if input.ReferralCode != nil && strings.TrimSpace(*input.ReferralCode) != "" {
+		if referrerID, err = referrerForCode(config.DB, *input.ReferralCode); err != nil {
+			return models.Customer{}, err
+		}
+	}
+
 	log.Println("✅ Customer validation passed. Inserting customer...")
 	// Create the customer model to insert
 	customer := models.Customer{
@@ -143,13 +176,19 @@ func RegisterCustomer(input models.RegisterCustomerInput) (models.Customer, erro
 		Password: hashedPassword, // Use the hash
 	}
 
-	query := `INSERT INTO customers (name, email, phone, password) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
-	err = config.DB.QueryRow(query, customer.Name, customer.Email, customer.Phone, customer.Password).Scan(&customer.ID, &customer.CreatedAt, &customer.UpdatedAt)
+	contact, err := encryptCustomerContact(customer.Email, customer.Phone)
 	if err != nil {
-		log.Println("❌ Error inserting customer:", err)
-		// Check for unique constraint error just in case of race condition?
+		log.Println("❌ Error encrypting customer details:", err)
 		return models.Customer{}, fmt.Errorf("failed to register customer: %w", err)
 	}
+	if customer, err = insertCustomer(customer, contact, referrerID, input.ReferralCode, client); err != nil {
+		return models.Customer{}, err
+	}
+
+	// Registration succeeds even if the email cannot be sent; the customer can ask for it again.
+	if err := sendEmailVerification(tokenUser{UserType: "customer", ID: customer.ID, Email: customer.Email}); err != nil {
+		log.Printf("⚠️ Could not send verification email to customer %d: %v", customer.ID, err)
+	}
 
 	// Important: Clear password hash before returning the struct
 	customer.Password = ""
@@ -157,75 +196,155 @@ func RegisterCustomer(input models.RegisterCustomerInput) (models.Customer, erro
 	return customer, nil
 }
 
-func AuthenticateCustomer(email, password string) (string, error) {
+// insertCustomer stores a new customer with their referral code and, if referrerID is set, the
+// referral they registered with.
+func insertCustomer(customer models.Customer, contact encryptedCustomerContact, referrerID int, referralCode *string, client models.ClientInfo) (_ models.Customer, err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	query := `INSERT INTO customers (name, email, email_bidx, phone, phone_bidx, password) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+	err = tx.QueryRow(query, customer.Name, contact.Email, contact.EmailBidx, contact.Phone, contact.PhoneBidx, customer.Password).Scan(&customer.ID, &customer.CreatedAt, &customer.UpdatedAt)
+	if err != nil {
+		log.Println("❌ Error inserting customer:", err)
+		// Check for unique constraint error just in case of race condition?
+		return models.Customer{}, fmt.Errorf("failed to register customer: %w", err)
+	}
+	code, err := assignReferralCode(tx, customer.ID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	customer.ReferralCode = &code
+	if referrerID != 0 {
+		if err = recordReferral(tx, customer.ID, referrerID, *referralCode, client); err != nil {
+			return models.Customer{}, err
+		}
+	}
+	return customer, nil
+}
+
+// AuthenticateCustomer checks the password and issues tokens. client's address is used for
+// throttling and the login audit log, and is recorded against the new session.
+func AuthenticateCustomer(email, password string, client models.ClientInfo) (models.TokenPair, error) {
+	if err := checkLoginAllowed("customer", email, client.IP); err != nil {
+		return models.TokenPair{}, err
+	}
+
 	var customer models.Customer
 	// Select required fields including password hash for checking
-	query := "SELECT id, name, email, password, phone, created_at, updated_at FROM customers WHERE email=$1"
-	err := config.DB.Get(&customer, query, email)
+	emailMatch, emailArgs := customerEmailMatch(1, email)
+	query := "SELECT id, name, email, password, phone, created_at, updated_at FROM customers WHERE " + emailMatch + " AND deleted_at IS NULL"
+	err := config.DB.Get(&customer, query, emailArgs...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Printf("❌ Customer email not found: %s", email)
-			return "", errors.New("invalid email or password") // Generic error
+			utils.DummyPasswordCheck(password) // Take as long as a real password check
+			recordLoginAttempt("customer", email, client.IP, false, loginFailureBadCredentials)
+			return models.TokenPair{}, ErrInvalidCredentials
 		}
 		log.Printf("❌ Error fetching customer %s: %v", email, err)
-		return "", fmt.Errorf("error fetching customer data: %w", err)
+		return models.TokenPair{}, fmt.Errorf("error fetching customer data: %w", err)
+	}
+	if err := decryptCustomer(&customer); err != nil {
+		return models.TokenPair{}, err
 	}
 
 	// Check password
 	if !utils.CheckPasswordHash(password, customer.Password) {
 		log.Printf("❌ Customer password mismatch for: %s", email)
-		return "", errors.New("invalid email or password") // Generic error
+		recordLoginAttempt("customer", email, client.IP, false, loginFailureBadCredentials)
+		return models.TokenPair{}, ErrInvalidCredentials
+	}
+	// Checked only once the password is right, so the answer does not reveal who is blacklisted.
+	if err := checkCustomerNotBlacklisted(config.DB, customer.ID); err != nil {
+		if errors.Is(err, ErrCustomerBlacklisted) {
+			log.Printf("🚫 Blacklisted customer %d tried to log in", customer.ID)
+			recordLoginAttempt("customer", email, client.IP, false, loginFailureSuspended)
+		}
+		return models.TokenPair{}, err
 	}
+	recordLoginAttempt("customer", email, client.IP, true, "")
 
-	// Generate token
-	token, err := generateCustomerToken(customer.ID, customer.Email)
+	// Generate access and refresh tokens
+	tokens, err := issueTokenPair(config.DB, tokenUser{UserType: "customer", ID: customer.ID, Email: customer.Email}, "", client)
 	if err != nil {
 		log.Println("❌ Error generating customer token:", err)
-		return "", errors.New("authentication failed")
+		return models.TokenPair{}, errors.New("authentication failed")
 	}
 
 	log.Printf("✅ Authentication successful for customer: %s", email)
-	return token, nil
+	return tokens, nil
 }
 
-// --- Token Generation (Keep as is, logic is specific) ---
+// --- Token Generation ---
 
-func generateEmployeeToken(employeeID int, email, role string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Consider making expiration configurable
+// generateEmployeeToken signs a short-lived access token. The jti lets the token be revoked before it expires.
+func generateEmployeeToken(employeeID int, email, role, sessionID string, ttl time.Duration) (tokenString, jti string, expiresAt time.Time, err error) {
+	jti, err = newTokenID()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	expiresAt = time.Now().Add(ttl)
 	claims := jwt.MapClaims{
 		"user_type":   "employee", // Add user type claim
 		"employee_id": employeeID,
 		"email":       email,
 		"role":        role,
-		"exp":         expirationTime.Unix(),
+		"jti":         jti,
+		"sid":         sessionID, // Session the token belongs to, checked on every request
+		"exp":         expiresAt.Unix(),
 		"iss":         "car-rental-api", // Example issuer
 		"iat":         time.Now().Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.JwtSecret))
+	tokenString, err = signToken(claims)
 	if err != nil {
 		log.Println("❌ Error signing employee token:", err)
-		return "", fmt.Errorf("failed to sign employee token: %w", err) // Wrap internal error
+		return "", "", time.Time{}, fmt.Errorf("failed to sign employee token: %w", err) // Wrap internal error
 	}
-	return tokenString, nil
+	return tokenString, jti, expiresAt, nil
 }
 
-func generateCustomerToken(customerID int, email string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Consider making expiration configurable
+func generateCustomerToken(customerID int, email, sessionID string, ttl time.Duration) (tokenString, jti string, expiresAt time.Time, err error) {
+	jti, err = newTokenID()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	expiresAt = time.Now().Add(ttl)
 	claims := jwt.MapClaims{
 		"user_type":   "customer", // Add user type claim
 		"customer_id": customerID,
 		"email":       email,
 		"role":        "customer", // Explicit role for consistency
-		"exp":         expirationTime.Unix(),
+		"jti":         jti,
+		"sid":         sessionID, // Session the token belongs to, checked on every request
+		"exp":         expiresAt.Unix(),
 		"iss":         "car-rental-api", // Example issuer
 		"iat":         time.Now().Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.JwtSecret))
+	tokenString, err = signToken(claims)
 	if err != nil {
 		log.Println("❌ Error signing customer token:", err)
-		return "", fmt.Errorf("failed to sign customer token: %w", err) // Wrap internal error
+		return "", "", time.Time{}, fmt.Errorf("failed to sign customer token: %w", err) // Wrap internal error
 	}
-	return tokenString, nil
+	return tokenString, jti, expiresAt, nil
+}
+
+// signToken signs claims with the current signing key, naming it in the kid header so
+// verifiers can pick the right key after a rotation.
+func signToken(claims jwt.MapClaims) (string, error) {
+	key := config.JWTSigningKey()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
 }
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// permBranchesAll lets an employee act on every branch regardless of assignment.
+const permBranchesAll = "branches:all"
+
+var ErrBranchForbidden = errors.New("access denied: this belongs to a branch you are not assigned to")
+
+// BranchScope is the set of branches an employee may see and act on.
+type BranchScope struct {
+	All       bool  // Unrestricted; BranchIDs is ignored
+	BranchIDs []int // Assigned branches; empty means none
+}
+
+// AllBranches is the scope for callers that are not restricted by branch.
+var AllBranches = BranchScope{All: true}
+
+// Allows reports whether branchID is in the scope.
+func (s BranchScope) Allows(branchID int) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.BranchIDs {
+		if id == branchID {
+			return true
+		}
+	}
+	return false
+}
+
+// filterArg is a query argument for conditions of the form
+// ($n::int[] IS NULL OR branch_id = ANY($n)): NULL when unrestricted, otherwise the branch IDs.
+func (s BranchScope) filterArg() interface{} {
+	if s.All {
+		return nil
+	}
+	ids := make([]int64, len(s.BranchIDs))
+	for i, id := range s.BranchIDs {
+		ids[i] = int64(id)
+	}
+	return pq.Array(ids)
+}
+
+// GetBranchScope returns the branches employeeID may work on, given their role.
+func GetBranchScope(employeeID int, role string) (BranchScope, error) {
+	all, err := RoleHasPermissions(role, permBranchesAll)
+	if err != nil {
+		return BranchScope{}, err
+	}
+	if all {
+		return AllBranches, nil
+	}
+	branchIDs, err := GetEmployeeBranchIDs(employeeID)
+	if err != nil {
+		return BranchScope{}, err
+	}
+	return BranchScope{BranchIDs: branchIDs}, nil
+}
+
+// GetEmployeeBranchIDs lists the branches an employee is assigned to.
+func GetEmployeeBranchIDs(employeeID int) ([]int, error) {
+	branchIDs := []int{}
+	err := config.DB.Select(&branchIDs, "SELECT branch_id FROM employee_branches WHERE employee_id = $1 ORDER BY branch_id", employeeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch employee branches: %w", err)
+	}
+	return branchIDs, nil
+}
+
+// existingBranchIDs drops duplicates from branchIDs and checks that every branch exists.
+func existingBranchIDs(tx *sqlx.Tx, branchIDs []int) ([]int64, error) {
+	unique := make([]int64, 0, len(branchIDs))
+	seen := make(map[int]bool, len(branchIDs))
+	for _, id := range branchIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, int64(id))
+		}
+	}
+
+	var found int
+	if err := tx.Get(&found, "SELECT COUNT(*) FROM branches WHERE id = ANY($1) AND deleted_at IS NULL", pq.Array(unique)); err != nil {
+		return nil, fmt.Errorf("failed to check branches: %w", err)
+	}
+	if found != len(unique) {
+		return nil, errors.New("one or more branches do not exist")
+	}
+	return unique, nil
+}
+
+// setEmployeeBranches replaces an employee's branch assignments.
+func setEmployeeBranches(tx *sqlx.Tx, employeeID int, branchIDs []int) error {
+	unique, err := existingBranchIDs(tx, branchIDs)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM employee_branches WHERE employee_id = $1", employeeID); err != nil {
+		return fmt.Errorf("failed to clear employee branches: %w", err)
+	}
+	_, err = tx.Exec("INSERT INTO employee_branches (employee_id, branch_id) SELECT $1, UNNEST($2::int[])", employeeID, pq.Array(unique))
+	if err != nil {
+		return fmt.Errorf("failed to assign employee branches: %w", err)
+	}
+	return nil
+}
+
+// SetEmployeeBranches replaces the branches an employee is assigned to.
+func SetEmployeeBranches(employeeID int, branchIDs []int) (err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var exists bool
+	if err = tx.Get(&exists, "SELECT EXISTS(SELECT 1 FROM employees WHERE id = $1 AND deleted_at IS NULL)", employeeID); err != nil {
+		return fmt.Errorf("failed to check employee: %w", err)
+	}
+	if !exists {
+		return errors.New("employee not found")
+	}
+	if err = setEmployeeBranches(tx, employeeID, branchIDs); err != nil {
+		return err
+	}
+	log.Printf("✅ Service: Employee %d assigned to branches %v", employeeID, branchIDs)
+	return nil
+}
+
+// checkBranchOf looks up the branch a row belongs to with query and checks it against scope.
+func checkBranchOf(query string, id int, scope BranchScope, notFound error) error {
+	var branchID int
+	if err := config.DB.Get(&branchID, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFound
+		}
+		return fmt.Errorf("failed to look up branch: %w", err)
+	}
+	if !scope.Allows(branchID) {
+		return ErrBranchForbidden
+	}
+	return nil
+}
+
+// CheckRentalBranchAccess checks that a rental's car belongs to a branch in scope.
+func CheckRentalBranchAccess(rentalID int, scope BranchScope) error {
+	if scope.All {
+		return nil
+	}
+	return checkBranchOf("SELECT c.branch_id FROM rentals r JOIN cars c ON r.car_id = c.id WHERE r.id = $1", rentalID, scope, ErrRentalNotFound)
+}
+
+// CheckPaymentBranchAccess checks that a payment's rental belongs to a branch in scope.
+func CheckPaymentBranchAccess(paymentID int, scope BranchScope) error {
+	if scope.All {
+		return nil
+	}
+	return checkBranchOf(`SELECT c.branch_id FROM payments p JOIN rentals r ON p.rental_id = r.id JOIN cars c ON r.car_id = c.id
+		WHERE p.id = $1`, paymentID, scope, errors.New("payment not found"))
+}
+
+// CheckCarBranchAccess checks that a car belongs to a branch in scope.
+func CheckCarBranchAccess(carID int, scope BranchScope) error {
+	if scope.All {
+		return nil
+	}
+	return checkBranchOf("SELECT branch_id FROM cars WHERE id = $1", carID, scope, ErrCarNotFound)
+}
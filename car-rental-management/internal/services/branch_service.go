@@ -39,11 +39,15 @@ func CreateBranch(branch models.Branch) (models.Branch, error) {
 	return branch, nil
 }
 
-// GetBranches retrieves all branches
-func GetBranches() ([]models.Branch, error) {
+// GetBranches retrieves all branches, and deleted ones too if includeDeleted
+func GetBranches(includeDeleted bool) ([]models.Branch, error) {
 	log.Println("Fetching all branches")
 	var branches []models.Branch
-	query := "SELECT id, name, address, phone, created_at, updated_at FROM branches ORDER BY name"
+	query := "SELECT id, name, address, phone, created_at, updated_at, deleted_at FROM branches"
+	if !includeDeleted {
+		query += " WHERE deleted_at IS NULL"
+	}
+	query += " ORDER BY name"
 	err := config.DB.Select(&branches, query)
 	if err != nil {
 		log.Println("❌ Error fetching branches:", err)
@@ -54,14 +58,26 @@ func GetBranches() ([]models.Branch, error) {
 	return branches, nil
 }
 
-// GetBranchByID retrieves a single branch by ID
+// GetBranchByID retrieves a single branch by ID, unless it has been deleted
 func GetBranchByID(id int) (models.Branch, error) {
+	return getBranch(id, false)
+}
+
+// GetBranchByIDIncludingDeleted retrieves a single branch by ID, even if it has been deleted
+func GetBranchByIDIncludingDeleted(id int) (models.Branch, error) {
+	return getBranch(id, true)
+}
+
+func getBranch(id int, includeDeleted bool) (models.Branch, error) {
 	if id <= 0 {
 		return models.Branch{}, errors.New("invalid branch ID")
 	}
 	log.Println("Fetching branch by ID:", id)
 	var branch models.Branch
-	query := "SELECT id, name, address, phone, created_at, updated_at FROM branches WHERE id=$1"
+	query := "SELECT id, name, address, phone, created_at, updated_at, deleted_at FROM branches WHERE id=$1"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 	err := config.DB.Get(&branch, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -86,7 +102,7 @@ func UpdateBranch(branch models.Branch) (models.Branch, error) {
 		return models.Branch{}, errors.New("branch name cannot be empty")
 	}
 
-	query := `UPDATE branches SET name=:name, address=:address, phone=:phone WHERE id=:id`
+	query := `UPDATE branches SET name=:name, address=:address, phone=:phone WHERE id=:id AND deleted_at IS NULL`
 	result, err := config.DB.NamedExec(query, branch)
 	if err != nil {
 		log.Printf("❌ Error updating branch %d: %v", branch.ID, err)
@@ -116,7 +132,7 @@ func UpdateBranch(branch models.Branch) (models.Branch, error) {
 	return updatedBranch, nil
 }
 
-// DeleteBranch removes a branch after checking dependencies
+// DeleteBranch soft-deletes a branch after checking that no cars remain in it
 func DeleteBranch(id int) error {
 	log.Println("Attempting to delete branch:", id)
 	if id <= 0 {
@@ -125,7 +141,7 @@ func DeleteBranch(id int) error {
 
 	var carCount int
 	// Use QueryRow for single value count check
-	err := config.DB.QueryRow("SELECT COUNT(*) FROM cars WHERE branch_id=$1", id).Scan(&carCount)
+	err := config.DB.QueryRow("SELECT COUNT(*) FROM cars WHERE branch_id=$1 AND deleted_at IS NULL", id).Scan(&carCount)
 	if err != nil {
 		log.Printf("❌ Error checking cars in branch %d: %v", id, err)
 		// Wrap the error
@@ -136,13 +152,9 @@ func DeleteBranch(id int) error {
 		return fmt.Errorf("cannot delete branch: %d car(s) assigned to it", carCount)
 	}
 
-	result, err := config.DB.Exec("DELETE FROM branches WHERE id=$1", id)
+	result, err := config.DB.Exec("UPDATE branches SET deleted_at = NOW() WHERE id=$1 AND deleted_at IS NULL", id)
 	if err != nil {
 		log.Printf("❌ Error deleting branch %d: %v", id, err)
-		// Check for FK constraint just in case (though check above should prevent)
-		if strings.Contains(err.Error(), "violates foreign key constraint") {
-			return errors.New("cannot delete branch due to existing dependencies (e.g., cars)")
-		}
 		// Wrap the error
 		return fmt.Errorf("failed to delete branch %d from database: %w", id, err)
 	}
@@ -157,3 +169,17 @@ func DeleteBranch(id int) error {
 	log.Printf("✅ Branch %d deleted successfully", id)
 	return nil
 }
+
+// RestoreBranch undoes DeleteBranch.
+func RestoreBranch(id int) (models.Branch, error) {
+	result, err := config.DB.Exec("UPDATE branches SET deleted_at = NULL WHERE id=$1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		log.Printf("❌ Error restoring branch %d: %v", id, err)
+		return models.Branch{}, fmt.Errorf("failed to restore branch %d: %w", id, err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return models.Branch{}, ErrNothingToRestore
+	}
+	log.Printf("♻️ Branch %d restored", id)
+	return GetBranchByID(id)
+}
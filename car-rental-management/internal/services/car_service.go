@@ -12,16 +12,17 @@ import (
 )
 
 type CarFiltersWithPagination struct {
-	Brand         *string
-	Model         *string
-	BranchID      *int
-	MinPrice      *float64
-	MaxPrice      *float64
-	Availability  *bool
-	Page          int
-	Limit         int
-	SortBy        string
-	SortDirection string
+	Brand          *string
+	Model          *string
+	BranchID       *int
+	MinPrice       *models.Money
+	MaxPrice       *models.Money
+	Availability   *bool
+	IncludeDeleted bool
+	Page           int
+	Limit          int
+	SortBy         string
+	SortDirection  string
 }
 
 type PaginatedCarsResponse struct {
@@ -41,10 +42,13 @@ func GetCarsPaginated(filters CarFiltersWithPagination) (PaginatedCarsResponse,
 	args := []interface{}{}
 	paramCount := 1
 
-	queryBuilder.WriteString("SELECT c.id, c.brand, c.model, c.price_per_day, c.availability, c.parking_spot, c.branch_id, c.image_url, c.created_at, c.updated_at FROM cars c")
+	queryBuilder.WriteString("SELECT c.id, c.brand, c.model, c.price_per_day, c.availability, c.parking_spot, c.branch_id, c.image_url, c.created_at, c.updated_at, c.deleted_at FROM cars c")
 	countQueryBuilder.WriteString("SELECT COUNT(*) FROM cars c")
 
 	var conditions []string
+	if !filters.IncludeDeleted {
+		conditions = append(conditions, "c.deleted_at IS NULL")
+	}
 
 	if filters.Brand != nil && *filters.Brand != "" {
 		conditions = append(conditions, fmt.Sprintf("c.brand ILIKE $%d", paramCount))
@@ -184,14 +188,14 @@ func AddCar(car models.Car) (models.Car, error) {
 	if strings.TrimSpace(car.Model) == "" {
 		return models.Car{}, errors.New("car model cannot be empty")
 	}
-	if car.PricePerDay <= 0 {
+	if !car.PricePerDay.IsPositive() {
 		return models.Car{}, errors.New("price per day must be greater than zero")
 	}
 	if car.BranchID <= 0 {
 		return models.Car{}, errors.New("invalid branch ID")
 	}
 	var exists bool
-	err := config.DB.Get(&exists, "SELECT EXISTS(SELECT 1 FROM branches WHERE id=$1)", car.BranchID)
+	err := config.DB.Get(&exists, "SELECT EXISTS(SELECT 1 FROM branches WHERE id=$1 AND deleted_at IS NULL)", car.BranchID)
 	if err != nil {
 		log.Printf("Database error checking branch for car add: %v", err)
 		return models.Car{}, errors.New("database error checking branch")
@@ -213,20 +217,34 @@ func AddCar(car models.Car) (models.Car, error) {
 		return models.Car{}, errors.New("failed to add car to database")
 	}
 	car.ID = insertedCar.ID
+	car.DeletedAt = nil
 	car.CreatedAt = insertedCar.CreatedAt
 	car.UpdatedAt = insertedCar.UpdatedAt
 	log.Printf("Car added successfully with ID: %d", car.ID)
 	return car, nil
 }
 
+// GetCarByID returns a car, unless it has been deleted.
 func GetCarByID(carID int) (models.Car, error) {
+	return getCar(carID, false)
+}
+
+// GetCarByIDIncludingDeleted returns a car even if it has been deleted.
+func GetCarByIDIncludingDeleted(carID int) (models.Car, error) {
+	return getCar(carID, true)
+}
+
+func getCar(carID int, includeDeleted bool) (models.Car, error) {
 	var car models.Car
 	if carID <= 0 {
 		return models.Car{}, errors.New("invalid car ID")
 	}
-	query := `SELECT c.id, c.brand, c.model, c.price_per_day, c.availability, c.parking_spot, c.branch_id, c.image_url, c.created_at, c.updated_at
+	query := `SELECT c.id, c.brand, c.model, c.price_per_day, c.availability, c.parking_spot, c.branch_id, c.image_url, c.created_at, c.updated_at, c.deleted_at
 			  FROM cars c
 			  WHERE c.id=$1`
+	if !includeDeleted {
+		query += " AND c.deleted_at IS NULL"
+	}
 	err := config.DB.Get(&car, query, carID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -248,14 +266,14 @@ func UpdateCar(car models.Car) (models.Car, error) {
 	if strings.TrimSpace(car.Model) == "" {
 		return models.Car{}, errors.New("car model cannot be empty")
 	}
-	if car.PricePerDay <= 0 {
+	if !car.PricePerDay.IsPositive() {
 		return models.Car{}, errors.New("price per day must be greater than zero")
 	}
 	if car.BranchID <= 0 {
 		return models.Car{}, errors.New("invalid branch ID")
 	}
 	var branchExists bool
-	err := config.DB.Get(&branchExists, "SELECT EXISTS(SELECT 1 FROM branches WHERE id=$1)", car.BranchID)
+	err := config.DB.Get(&branchExists, "SELECT EXISTS(SELECT 1 FROM branches WHERE id=$1 AND deleted_at IS NULL)", car.BranchID)
 	if err != nil {
 		log.Printf("Database error checking branch for car update: %v", err)
 		return models.Car{}, errors.New("database error checking branch for update")
@@ -269,7 +287,7 @@ func UpdateCar(car models.Car) (models.Car, error) {
 			brand=:brand, model=:model, price_per_day=:price_per_day,
 			availability=:availability, parking_spot=:parking_spot,
 			branch_id=:branch_id, image_url=:image_url
-		WHERE id=:id`
+		WHERE id=:id AND deleted_at IS NULL`
 	result, err := config.DB.NamedExec(query, car)
 	if err != nil {
 		log.Printf("Error updating car ID %d: %v", car.ID, err)
@@ -287,15 +305,22 @@ func UpdateCar(car models.Car) (models.Car, error) {
 	return updatedCar, nil
 }
 
+// DeleteCar soft-deletes a car. Its past rentals keep pointing at it.
 func DeleteCar(carID int) error {
 	if carID <= 0 {
 		return errors.New("invalid car ID for deletion")
 	}
-	result, err := config.DB.Exec("DELETE FROM cars WHERE id=$1", carID)
+	var liveRentals int
+	err := config.DB.Get(&liveRentals, "SELECT COUNT(*) FROM rentals WHERE car_id=$1 AND deleted_at IS NULL AND status IN "+liveRentalStatuses, carID)
+	if err != nil {
+		log.Printf("Error checking rentals of car ID %d: %v", carID, err)
+		return errors.New("failed to delete car")
+	}
+	if liveRentals > 0 {
+		return errors.New("cannot delete car: it has rentals that are still in progress")
+	}
+	result, err := config.DB.Exec("UPDATE cars SET deleted_at = NOW() WHERE id=$1 AND deleted_at IS NULL", carID)
 	if err != nil {
-		if strings.Contains(err.Error(), "rentals_car_id_fkey") {
-			return errors.New("cannot delete car: it has associated rentals")
-		}
 		log.Printf("Error deleting car ID %d: %v", carID, err)
 		return errors.New("failed to delete car")
 	}
@@ -305,3 +330,29 @@ func DeleteCar(carID int) error {
 	}
 	return nil
 }
+
+// RestoreCar undoes DeleteCar. The car's branch must not be deleted.
+func RestoreCar(carID int) (models.Car, error) {
+	car, err := getCar(carID, true)
+	if err != nil {
+		if err.Error() == "car not found" {
+			return models.Car{}, ErrNothingToRestore
+		}
+		return models.Car{}, err
+	}
+	if car.DeletedAt == nil {
+		return models.Car{}, ErrNothingToRestore
+	}
+	if _, err := GetBranchByID(car.BranchID); err != nil {
+		if err.Error() == "branch not found" {
+			return models.Car{}, ErrRestoreParentFirst
+		}
+		return models.Car{}, err
+	}
+	if _, err := config.DB.Exec("UPDATE cars SET deleted_at = NULL WHERE id=$1", carID); err != nil {
+		log.Printf("Error restoring car ID %d: %v", carID, err)
+		return models.Car{}, errors.New("failed to restore car")
+	}
+	log.Printf("♻️ Car %d restored", carID)
+	return GetCarByID(carID)
+}
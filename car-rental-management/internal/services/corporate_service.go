@@ -0,0 +1,557 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrCorporateAccountNotFound = errors.New("corporate account not found")
+	ErrCorporateAccountInactive = errors.New("corporate account is not active")
+	ErrNotCorporateMember       = errors.New("customer is not a member of this corporate account")
+	ErrSpendingLimitExceeded    = errors.New("booking exceeds the member's monthly spending limit")
+	ErrCorporateInvoiceNotFound = errors.New("corporate invoice not found")
+	ErrInvoicePaymentExceedsDue = errors.New("payment exceeds the invoice balance")
+	ErrInvalidInvoicePeriod     = errors.New("invalid invoice period, expected YYYY-MM")
+	ErrInvoicePeriodNotFinished = errors.New("cannot invoice a month that has not ended")
+)
+
+const (
+	defaultCorporatePaymentTerms  = 30
+	corporateInvoiceSequenceScope = 0 // Corporate invoices are numbered company-wide, not per branch
+)
+
+const corporateAccountColumns = `id, name, tax_id, billing_email, billing_address, payment_terms_days, is_active, created_at, updated_at`
+
+const corporateInvoiceSelect = `
+	SELECT i.id, i.account_id, a.name AS account_name, i.invoice_number, i.period_start, i.period_end,
+		i.net_amount, i.vat_amount, i.total_amount, i.amount_paid, i.status,
+		(i.status <> 'Paid' AND i.due_date < CURRENT_DATE) AS is_overdue,
+		i.issued_at, i.due_date, i.issued_by_employee_id, i.updated_at
+	FROM corporate_invoices i
+	JOIN corporate_accounts a ON i.account_id = a.id`
+
+// monthStart returns the first instant of t's calendar month in Thai local time.
+func monthStart(t time.Time) time.Time {
+	local := t.In(invoiceTimeZone)
+	return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, invoiceTimeZone)
+}
+
+// corporateMemberSpend totals, at their booked prices, a member's non-cancelled rentals on an account
+// picked up in the month starting at from.
+func corporateMemberSpend(q sqlx.Queryer, accountID, customerID int, from time.Time) (models.Money, error) {
+	var totals []models.Money
+	query := `SELECT r.booked_total FROM rentals r
+		WHERE r.corporate_account_id = $1 AND r.customer_id = $2
+		  AND r.status NOT IN ('Cancelled', 'Failed')
+		  AND r.pickup_datetime >= $3 AND r.pickup_datetime < $4`
+	err := sqlx.Select(q, &totals, query, accountID, customerID, from, from.AddDate(0, 1, 0))
+	if err != nil {
+		return models.Money{}, fmt.Errorf("failed to total member spending: %w", err)
+	}
+	spent := models.NewMoney(0, models.BaseCurrency)
+	for _, total := range totals {
+		spent = spent.Add(total)
+	}
+	return spent, nil
+}
+
+// checkCorporateBooking verifies the customer may bill a rental costing total to the account.
+// The membership row is locked so concurrent bookings by the same member cannot both pass the limit.
+func checkCorporateBooking(tx *sqlx.Tx, accountID, customerID int, pickup time.Time, total models.Money) error {
+	var membership struct {
+		IsActive bool          `db:"is_active"`
+		Limit    *models.Money `db:"monthly_spending_limit"`
+	}
+	query := `SELECT a.is_active, m.monthly_spending_limit
+		FROM corporate_account_members m JOIN corporate_accounts a ON m.account_id = a.id
+		WHERE m.account_id = $1 AND m.customer_id = $2
+		FOR UPDATE OF m`
+	err := tx.Get(&membership, query, accountID, customerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotCorporateMember
+		}
+		return fmt.Errorf("failed to check corporate membership: %w", err)
+	}
+	if !membership.IsActive {
+		return ErrCorporateAccountInactive
+	}
+	if membership.Limit == nil {
+		return nil
+	}
+
+	spent, err := corporateMemberSpend(tx, accountID, customerID, monthStart(pickup))
+	if err != nil {
+		return err
+	}
+	if spent.Add(total).Cmp(*membership.Limit) > 0 {
+		return fmt.Errorf("%w (limit %s, already booked %s, this rental %s)", ErrSpendingLimitExceeded, *membership.Limit, spent, total)
+	}
+	return nil
+}
+
+// CreateCorporateAccount adds a new corporate account.
+func CreateCorporateAccount(input models.CorporateAccountInput) (models.CorporateAccount, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return models.CorporateAccount{}, errors.New("account name cannot be empty")
+	}
+	terms := defaultCorporatePaymentTerms
+	if input.PaymentTermsDays != nil {
+		terms = *input.PaymentTermsDays
+	}
+	isActive := true
+	if input.IsActive != nil {
+		isActive = *input.IsActive
+	}
+
+	var account models.CorporateAccount
+	query := `INSERT INTO corporate_accounts (name, tax_id, billing_email, billing_address, payment_terms_days, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + corporateAccountColumns
+	err := config.DB.Get(&account, query, strings.TrimSpace(input.Name), input.TaxID, input.BillingEmail, input.BillingAddress, terms, isActive)
+	if err != nil {
+		log.Printf("❌ Error creating corporate account '%s': %v", input.Name, err)
+		if strings.Contains(err.Error(), "corporate_accounts_name_key") {
+			return models.CorporateAccount{}, errors.New("corporate account name already exists")
+		}
+		return models.CorporateAccount{}, fmt.Errorf("failed to create corporate account: %w", err)
+	}
+	log.Printf("✅ Corporate account created with ID: %d", account.ID)
+	return account, nil
+}
+
+// UpdateCorporateAccount replaces an account's details.
+func UpdateCorporateAccount(accountID int, input models.CorporateAccountInput) (models.CorporateAccount, error) {
+	if strings.TrimSpace(input.Name) == "" {
+		return models.CorporateAccount{}, errors.New("account name cannot be empty")
+	}
+	current, err := GetCorporateAccountByID(accountID)
+	if err != nil {
+		return models.CorporateAccount{}, err
+	}
+	terms := current.PaymentTermsDays
+	if input.PaymentTermsDays != nil {
+		terms = *input.PaymentTermsDays
+	}
+	isActive := current.IsActive
+	if input.IsActive != nil {
+		isActive = *input.IsActive
+	}
+
+	var account models.CorporateAccount
+	query := `UPDATE corporate_accounts SET name = $1, tax_id = $2, billing_email = $3, billing_address = $4,
+			payment_terms_days = $5, is_active = $6, updated_at = NOW()
+		WHERE id = $7 RETURNING ` + corporateAccountColumns
+	err = config.DB.Get(&account, query, strings.TrimSpace(input.Name), input.TaxID, input.BillingEmail, input.BillingAddress, terms, isActive, accountID)
+	if err != nil {
+		log.Printf("❌ Error updating corporate account %d: %v", accountID, err)
+		if strings.Contains(err.Error(), "corporate_accounts_name_key") {
+			return models.CorporateAccount{}, errors.New("corporate account name already exists")
+		}
+		return models.CorporateAccount{}, fmt.Errorf("failed to update corporate account: %w", err)
+	}
+	log.Printf("✅ Corporate account %d updated", accountID)
+	return account, nil
+}
+
+// GetCorporateAccounts lists all corporate accounts.
+func GetCorporateAccounts() ([]models.CorporateAccount, error) {
+	accounts := []models.CorporateAccount{}
+	err := config.DB.Select(&accounts, `SELECT `+corporateAccountColumns+` FROM corporate_accounts ORDER BY name`)
+	if err != nil {
+		log.Println("❌ Error fetching corporate accounts:", err)
+		return nil, fmt.Errorf("failed to fetch corporate accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetCorporateAccountByID fetches one account.
+func GetCorporateAccountByID(accountID int) (models.CorporateAccount, error) {
+	var account models.CorporateAccount
+	err := config.DB.Get(&account, `SELECT `+corporateAccountColumns+` FROM corporate_accounts WHERE id = $1`, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CorporateAccount{}, ErrCorporateAccountNotFound
+		}
+		log.Printf("❌ Error fetching corporate account %d: %v", accountID, err)
+		return models.CorporateAccount{}, fmt.Errorf("failed to fetch corporate account: %w", err)
+	}
+	return account, nil
+}
+
+// GetCorporateAccountMembers lists the customers authorised on an account.
+func GetCorporateAccountMembers(accountID int) ([]models.CorporateAccountMember, error) {
+	if _, err := GetCorporateAccountByID(accountID); err != nil {
+		return nil, err
+	}
+	members := []models.CorporateAccountMember{}
+	query := `SELECT m.account_id, m.customer_id, c.name AS customer_name, c.email AS customer_email,
+			m.monthly_spending_limit, m.created_at
+		FROM corporate_account_members m JOIN customers c ON m.customer_id = c.id
+		WHERE m.account_id = $1 ORDER BY c.name`
+	err := config.DB.Select(&members, query, accountID)
+	if err != nil {
+		log.Printf("❌ Error fetching members of corporate account %d: %v", accountID, err)
+		return nil, fmt.Errorf("failed to fetch corporate account members: %w", err)
+	}
+	for i := range members {
+		if members[i].CustomerEmail, err = decryptPII(piiCustomerEmail, members[i].CustomerEmail); err != nil {
+			return nil, err
+		}
+	}
+	return members, nil
+}
+
+// SetCorporateAccountMember adds a customer to an account, or updates their spending limit.
+func SetCorporateAccountMember(accountID int, input models.CorporateMemberInput) (models.CorporateAccountMember, error) {
+	if input.CustomerID <= 0 {
+		return models.CorporateAccountMember{}, errors.New("invalid customer ID")
+	}
+	if input.MonthlySpendingLimit != nil && !input.MonthlySpendingLimit.IsPositive() {
+		return models.CorporateAccountMember{}, errors.New("monthly spending limit must be greater than zero")
+	}
+	if _, err := GetCorporateAccountByID(accountID); err != nil {
+		return models.CorporateAccountMember{}, err
+	}
+	var customerExists bool
+	if err := config.DB.Get(&customerExists, "SELECT EXISTS(SELECT 1 FROM customers WHERE id=$1 AND deleted_at IS NULL)", input.CustomerID); err != nil {
+		return models.CorporateAccountMember{}, fmt.Errorf("failed to verify customer: %w", err)
+	}
+	if !customerExists {
+		return models.CorporateAccountMember{}, errors.New("customer not found")
+	}
+
+	query := `INSERT INTO corporate_account_members (account_id, customer_id, monthly_spending_limit) VALUES ($1, $2, $3)
+		ON CONFLICT (account_id, customer_id) DO UPDATE SET monthly_spending_limit = EXCLUDED.monthly_spending_limit`
+	if _, err := config.DB.Exec(query, accountID, input.CustomerID, input.MonthlySpendingLimit); err != nil {
+		log.Printf("❌ Error saving member %d of corporate account %d: %v", input.CustomerID, accountID, err)
+		return models.CorporateAccountMember{}, fmt.Errorf("failed to save corporate account member: %w", err)
+	}
+
+	var member models.CorporateAccountMember
+	err := config.DB.Get(&member, `SELECT m.account_id, m.customer_id, c.name AS customer_name, c.email AS customer_email,
+			m.monthly_spending_limit, m.created_at
+		FROM corporate_account_members m JOIN customers c ON m.customer_id = c.id
+		WHERE m.account_id = $1 AND m.customer_id = $2`, accountID, input.CustomerID)
+	if err != nil {
+		return models.CorporateAccountMember{}, fmt.Errorf("failed to fetch saved member: %w", err)
+	}
+	if member.CustomerEmail, err = decryptPII(piiCustomerEmail, member.CustomerEmail); err != nil {
+		return models.CorporateAccountMember{}, err
+	}
+	log.Printf("✅ Customer %d is now a member of corporate account %d", input.CustomerID, accountID)
+	return member, nil
+}
+
+// RemoveCorporateAccountMember revokes a customer's authorisation. Existing rentals stay billed to the account.
+func RemoveCorporateAccountMember(accountID, customerID int) error {
+	result, err := config.DB.Exec("DELETE FROM corporate_account_members WHERE account_id = $1 AND customer_id = $2", accountID, customerID)
+	if err != nil {
+		log.Printf("❌ Error removing member %d from corporate account %d: %v", customerID, accountID, err)
+		return fmt.Errorf("failed to remove corporate account member: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrNotCorporateMember
+	}
+	log.Printf("✅ Customer %d removed from corporate account %d", customerID, accountID)
+	return nil
+}
+
+// GetMyCorporateMemberships lists the active accounts a customer can bill, with this month's usage.
+func GetMyCorporateMemberships(customerID int) ([]models.CorporateMembership, error) {
+	memberships := []models.CorporateMembership{}
+	query := `SELECT m.account_id, a.name AS account_name, m.monthly_spending_limit
+		FROM corporate_account_members m JOIN corporate_accounts a ON m.account_id = a.id
+		WHERE m.customer_id = $1 AND a.is_active ORDER BY a.name`
+	var rows []struct {
+		AccountID   int           `db:"account_id"`
+		AccountName string        `db:"account_name"`
+		Limit       *models.Money `db:"monthly_spending_limit"`
+	}
+	if err := config.DB.Select(&rows, query, customerID); err != nil {
+		log.Printf("❌ Error fetching corporate memberships for customer %d: %v", customerID, err)
+		return nil, fmt.Errorf("failed to fetch corporate memberships: %w", err)
+	}
+	from := monthStart(time.Now())
+	for _, row := range rows {
+		spent, err := corporateMemberSpend(config.DB, row.AccountID, customerID, from)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, models.CorporateMembership{
+			AccountID: row.AccountID, AccountName: row.AccountName, MonthlySpendingLimit: row.Limit, SpentThisMonth: spent,
+		})
+	}
+	return memberships, nil
+}
+
+// RunCorporateInvoicing issues one invoice per account for the month period ("YYYY-MM"), covering
+// every returned, not yet invoiced rental dropped off before the month ended. Each account is
+// invoiced in its own transaction; re-running a period skips accounts already invoiced for it.
+func RunCorporateInvoicing(period string, accountID *int, employeeID int) ([]models.CorporateInvoice, error) {
+	parsed, err := time.ParseInLocation("2006-01", strings.TrimSpace(period), invoiceTimeZone)
+	if err != nil {
+		return nil, ErrInvalidInvoicePeriod
+	}
+	periodStart := monthStart(parsed)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	if time.Now().Before(periodEnd) {
+		return nil, ErrInvoicePeriodNotFinished
+	}
+
+	var accountIDs []int
+	if accountID != nil {
+		if _, err = GetCorporateAccountByID(*accountID); err != nil {
+			return nil, err
+		}
+		accountIDs = []int{*accountID}
+	} else if err = config.DB.Select(&accountIDs, "SELECT id FROM corporate_accounts WHERE is_active ORDER BY id"); err != nil {
+		return nil, fmt.Errorf("failed to list corporate accounts: %w", err)
+	}
+
+	invoices := []models.CorporateInvoice{}
+	for _, id := range accountIDs {
+		invoiceID, errAccount := issueCorporateInvoice(id, periodStart, periodEnd, employeeID)
+		if errAccount != nil {
+			log.Printf("❌ RunCorporateInvoicing: Failed to invoice account %d for %s: %v", id, period, errAccount)
+			return invoices, fmt.Errorf("failed to invoice account %d: %w", id, errAccount)
+		}
+		if invoiceID == 0 {
+			continue
+		}
+		invoice, errFetch := GetCorporateInvoiceByID(invoiceID)
+		if errFetch != nil {
+			return invoices, errFetch
+		}
+		invoices = append(invoices, invoice)
+	}
+	log.Printf("✅ Service: Corporate invoice run for %s issued %d invoice(s)", period, len(invoices))
+	return invoices, nil
+}
+
+// issueCorporateInvoice invoices one account for a period. It returns 0 when there is nothing to bill.
+func issueCorporateInvoice(accountID int, periodStart, periodEnd time.Time, employeeID int) (invoiceID int, err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+			if err != nil {
+				invoiceID = 0
+			}
+		}
+	}()
+
+	var account models.CorporateAccount
+	if err = tx.Get(&account, `SELECT `+corporateAccountColumns+` FROM corporate_accounts WHERE id = $1 FOR UPDATE`, accountID); err != nil {
+		return 0, fmt.Errorf("failed to lock corporate account: %w", err)
+	}
+	var alreadyInvoiced bool
+	if err = tx.Get(&alreadyInvoiced, "SELECT EXISTS(SELECT 1 FROM corporate_invoices WHERE account_id = $1 AND period_start = $2)", accountID, periodStart); err != nil {
+		return 0, fmt.Errorf("failed to check existing invoice: %w", err)
+	}
+	if alreadyInvoiced {
+		log.Printf("ℹ️ issueCorporateInvoice: Account %d already invoiced for %s, skipping.", accountID, periodStart.Format("2006-01"))
+		return 0, nil
+	}
+
+	var rentals []struct {
+		ID       int          `db:"id"`
+		Pickup   time.Time    `db:"pickup_datetime"`
+		Dropoff  time.Time    `db:"dropoff_datetime"`
+		Price    models.Money `db:"booked_price_per_day"`
+		Net      models.Money `db:"booked_net"`
+		VAT      models.Money `db:"booked_vat"`
+		Total    models.Money `db:"booked_total"`
+		Brand    string       `db:"brand"`
+		Model    string       `db:"model"`
+		Customer string       `db:"customer_name"`
+	}
+	rentalsQuery := `SELECT r.id, r.pickup_datetime, r.dropoff_datetime, r.booked_price_per_day, r.booked_net, r.booked_vat, r.booked_total,
+			c.brand, c.model, cu.name AS customer_name
+		FROM rentals r
+		JOIN cars c ON r.car_id = c.id
+		JOIN customers cu ON r.customer_id = cu.id
+		LEFT JOIN corporate_invoice_items ii ON ii.rental_id = r.id
+		WHERE r.corporate_account_id = $1 AND r.status = 'Returned' AND r.dropoff_datetime < $2 AND ii.id IS NULL
+		ORDER BY r.dropoff_datetime, r.id
+		FOR UPDATE OF r`
+	if err = tx.Select(&rentals, rentalsQuery, accountID, periodEnd); err != nil {
+		return 0, fmt.Errorf("failed to fetch rentals to invoice: %w", err)
+	}
+	if len(rentals) == 0 {
+		return 0, nil
+	}
+
+	items := make([]models.CorporateInvoiceItem, 0, len(rentals))
+	net := models.NewMoney(0, models.BaseCurrency)
+	vat := models.NewMoney(0, models.BaseCurrency)
+	total := models.NewMoney(0, models.BaseCurrency)
+	for _, rental := range rentals {
+		days := billableRentalDays(rental.Pickup, rental.Dropoff)
+		itemNet, itemVAT, itemTotal := rental.Net, rental.VAT, rental.Total
+		items = append(items, models.CorporateInvoiceItem{
+			RentalID: rental.ID,
+			Description: fmt.Sprintf("Rental #%d: %s %s, %s, %s - %s (%d day(s) @ %s)", rental.ID, rental.Brand, rental.Model, rental.Customer,
+				rental.Pickup.In(invoiceTimeZone).Format("02 Jan 2006"), rental.Dropoff.In(invoiceTimeZone).Format("02 Jan 2006"), days, rental.Price),
+			NetAmount: itemNet, VATAmount: itemVAT, TotalAmount: itemTotal,
+		})
+		net, vat, total = net.Add(itemNet), vat.Add(itemVAT), total.Add(itemTotal)
+	}
+
+	issuedAt := time.Now().In(invoiceTimeZone)
+	sequenceNumber, err := nextInvoiceSequence(tx, corporateInvoiceSequenceScope, issuedAt.Year(), "corporate")
+	if err != nil {
+		return 0, err
+	}
+	invoiceNumber := fmt.Sprintf("CORP-%d-%06d", issuedAt.Year(), sequenceNumber)
+	dueDate := issuedAt.AddDate(0, 0, account.PaymentTermsDays)
+
+	insertInvoice := `INSERT INTO corporate_invoices (account_id, invoice_number, period_start, period_end, net_amount, vat_amount, total_amount, due_date, issued_by_employee_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	err = tx.Get(&invoiceID, insertInvoice, accountID, invoiceNumber, periodStart, periodEnd.AddDate(0, 0, -1), net, vat, total, dueDate, employeeID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create corporate invoice: %w", err)
+	}
+	for _, item := range items {
+		_, err = tx.Exec(`INSERT INTO corporate_invoice_items (invoice_id, rental_id, description, net_amount, vat_amount, total_amount)
+			VALUES ($1, $2, $3, $4, $5, $6)`, invoiceID, item.RentalID, item.Description, item.NetAmount, item.VATAmount, item.TotalAmount)
+		if err != nil {
+			return 0, fmt.Errorf("failed to add rental %d to invoice: %w", item.RentalID, err)
+		}
+		// Revenue is recognised when the rental is invoiced; the invoice payment settles the receivable.
+		if err = postRentalCharge(tx, item.RentalID, nil, item.TotalAmount, &employeeID); err != nil {
+			return 0, fmt.Errorf("failed to post charge for rental %d: %w", item.RentalID, err)
+		}
+	}
+	log.Printf("🧾 Service: Issued corporate invoice %s to account %d for %d rental(s), total %s", invoiceNumber, accountID, len(items), total)
+	return invoiceID, nil
+}
+
+// GetCorporateInvoices lists invoices, optionally for one account and/or with one status.
+func GetCorporateInvoices(accountID *int, status *string) ([]models.CorporateInvoice, error) {
+	invoices := []models.CorporateInvoice{}
+	query := strings.Builder{}
+	query.WriteString(corporateInvoiceSelect + " WHERE 1=1")
+	args := []interface{}{}
+	if accountID != nil {
+		args = append(args, *accountID)
+		query.WriteString(fmt.Sprintf(" AND i.account_id = $%d", len(args)))
+	}
+	if status != nil {
+		if *status == "Overdue" {
+			query.WriteString(" AND i.status <> 'Paid' AND i.due_date < CURRENT_DATE")
+		} else {
+			args = append(args, *status)
+			query.WriteString(fmt.Sprintf(" AND i.status = $%d", len(args)))
+		}
+	}
+	query.WriteString(" ORDER BY i.issued_at DESC, i.id DESC")
+	if err := config.DB.Select(&invoices, query.String(), args...); err != nil {
+		log.Println("❌ Error fetching corporate invoices:", err)
+		return nil, fmt.Errorf("failed to fetch corporate invoices: %w", err)
+	}
+	return invoices, nil
+}
+
+// GetCorporateInvoiceByID fetches an invoice with its items and payments.
+func GetCorporateInvoiceByID(invoiceID int) (models.CorporateInvoice, error) {
+	var invoice models.CorporateInvoice
+	err := config.DB.Get(&invoice, corporateInvoiceSelect+" WHERE i.id = $1", invoiceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CorporateInvoice{}, ErrCorporateInvoiceNotFound
+		}
+		log.Printf("❌ Error fetching corporate invoice %d: %v", invoiceID, err)
+		return models.CorporateInvoice{}, fmt.Errorf("failed to fetch corporate invoice: %w", err)
+	}
+	invoice.Items = []models.CorporateInvoiceItem{}
+	err = config.DB.Select(&invoice.Items, `SELECT id, invoice_id, rental_id, description, net_amount, vat_amount, total_amount
+		FROM corporate_invoice_items WHERE invoice_id = $1 ORDER BY id`, invoiceID)
+	if err != nil {
+		return models.CorporateInvoice{}, fmt.Errorf("failed to fetch corporate invoice items: %w", err)
+	}
+	invoice.Payments = []models.CorporateInvoicePayment{}
+	err = config.DB.Select(&invoice.Payments, `SELECT id, invoice_id, amount, payment_method, reference, recorded_by_employee_id, paid_at
+		FROM corporate_invoice_payments WHERE invoice_id = $1 ORDER BY paid_at, id`, invoiceID)
+	if err != nil {
+		return models.CorporateInvoice{}, fmt.Errorf("failed to fetch corporate invoice payments: %w", err)
+	}
+	return invoice, nil
+}
+
+// RecordCorporateInvoicePayment records money received against an invoice and updates its status.
+func RecordCorporateInvoicePayment(invoiceID, employeeID int, input models.CorporateInvoicePaymentInput) (err error) {
+	if !input.Amount.IsPositive() {
+		return errors.New("payment amount must be greater than zero")
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			log.Printf("❌ Rolling back corporate invoice payment due to error: %v", err)
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var invoice struct {
+		InvoiceNumber string       `db:"invoice_number"`
+		Total         models.Money `db:"total_amount"`
+		Paid          models.Money `db:"amount_paid"`
+	}
+	err = tx.Get(&invoice, "SELECT invoice_number, total_amount, amount_paid FROM corporate_invoices WHERE id = $1 FOR UPDATE", invoiceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCorporateInvoiceNotFound
+		}
+		return fmt.Errorf("failed to lock corporate invoice: %w", err)
+	}
+	balance := invoice.Total.Sub(invoice.Paid)
+	if input.Amount.Cmp(balance) > 0 {
+		return fmt.Errorf("%w (balance due %s)", ErrInvoicePaymentExceedsDue, balance)
+	}
+
+	_, err = tx.Exec(`INSERT INTO corporate_invoice_payments (invoice_id, amount, payment_method, reference, recorded_by_employee_id)
+		VALUES ($1, $2, $3, $4, $5)`, invoiceID, input.Amount, input.PaymentMethod, input.Reference, employeeID)
+	if err != nil {
+		return fmt.Errorf("failed to record invoice payment: %w", err)
+	}
+	newPaid := invoice.Paid.Add(input.Amount)
+	newStatus := "Partially Paid"
+	if newPaid.Cmp(invoice.Total) == 0 {
+		newStatus = "Paid"
+	}
+	_, err = tx.Exec("UPDATE corporate_invoices SET amount_paid = $1, status = $2, updated_at = NOW() WHERE id = $3", newPaid, newStatus, invoiceID)
+	if err != nil {
+		return fmt.Errorf("failed to update corporate invoice: %w", err)
+	}
+	if err = postCorporateInvoiceReceipt(tx, invoice.InvoiceNumber, input.Amount, &employeeID); err != nil {
+		return fmt.Errorf("failed to post invoice payment to ledger: %w", err)
+	}
+	log.Printf("✅ Service: Recorded %s against corporate invoice %s (now %s)", input.Amount, invoice.InvoiceNumber, newStatus)
+	return nil
+}
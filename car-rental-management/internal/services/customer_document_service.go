@@ -0,0 +1,333 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrDocumentNotFound    = errors.New("document not found")
+	ErrDocumentNotPending  = errors.New("only documents awaiting review can be approved or rejected")
+	ErrInvalidDocument     = errors.New("document type must be licence_front, licence_back or id_document")
+	ErrDocumentExpired     = errors.New("the document's expiry date has already passed")
+	ErrInvalidDocumentFile = errors.New("documents must be JPEG, PNG or PDF files")
+
+	ErrLicenceMissing              = errors.New("please upload both sides of your driving licence before booking")
+	ErrLicenceNotVerified          = errors.New("your driving licence has not been verified yet")
+	ErrLicenceExpiresBeforeDropoff = errors.New("your driving licence expires before the end of this rental")
+)
+
+const (
+	DocumentTypeLicenceFront = "licence_front"
+	DocumentTypeLicenceBack  = "licence_back"
+	DocumentTypeIDDocument   = "id_document"
+
+	customerDocumentSelectColumns = `id, customer_id, document_type, file_name, content_type, status, expires_on, document_number,
+		reviewed_by_employee_id, reviewed_at, rejection_reason, uploaded_at`
+)
+
+// Identity documents are kept out of ./uploads, which is served to anyone.
+var customerDocumentDir = filepath.Join(".", "private", "customer_documents")
+
+var customerDocumentTypes = map[string]bool{DocumentTypeLicenceFront: true, DocumentTypeLicenceBack: true, DocumentTypeIDDocument: true}
+
+// Accepted file types, by the content type sniffed from the upload, with the extension used when
+// a document is handed back to the customer in a data export.
+var customerDocumentExtensions = map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "application/pdf": ".pdf"}
+
+// UploadCustomerDocument stores a document for review, encrypted when a PII keyring is loaded.
+// An earlier upload of the same type still awaiting review is superseded by it.
+func UploadCustomerDocument(customerID int, documentType, contentType string, data []byte) (document models.CustomerDocument, err error) {
+	if !customerDocumentTypes[documentType] {
+		return models.CustomerDocument{}, ErrInvalidDocument
+	}
+	if _, ok := customerDocumentExtensions[contentType]; !ok {
+		return models.CustomerDocument{}, ErrInvalidDocumentFile
+	}
+	if _, err := GetCustomerByID(customerID); err != nil {
+		return models.CustomerDocument{}, err
+	}
+
+	stored, err := encryptPII(piiCustomerDocumentFile, string(data))
+	if err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("failed to encrypt document: %w", err)
+	}
+	if err := os.MkdirAll(customerDocumentDir, 0700); err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("failed to prepare document storage: %w", err)
+	}
+	fileName := fmt.Sprintf("cust_%d_%s_%d", customerID, documentType, time.Now().UnixNano())
+	filePath := filepath.Join(customerDocumentDir, fileName)
+	if err := os.WriteFile(filePath, []byte(stored), 0600); err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("failed to save document: %w", err)
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		os.Remove(filePath)
+		return models.CustomerDocument{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			os.Remove(filePath)
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+			os.Remove(filePath)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	_, err = tx.Exec("UPDATE customer_documents SET status = 'Superseded' WHERE customer_id = $1 AND document_type = $2 AND status = 'Pending'", customerID, documentType)
+	if err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("failed to supersede earlier upload: %w", err)
+	}
+	err = tx.Get(&document, `INSERT INTO customer_documents (customer_id, document_type, file_name, content_type)
+		VALUES ($1, $2, $3, $4) RETURNING `+customerDocumentSelectColumns, customerID, documentType, fileName, contentType)
+	if err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("failed to record document: %w", err)
+	}
+	log.Printf("🪪 Customer %d uploaded %s document %d", customerID, documentType, document.ID)
+	return document, nil
+}
+
+// GetCustomerDocuments lists documents, newest first. A customerID above zero limits the list to
+// that customer's documents, and a non-empty status to documents in that status.
+func GetCustomerDocuments(customerID int, status string) ([]models.CustomerDocument, error) {
+	documents := []models.CustomerDocument{}
+	query := "SELECT " + customerDocumentSelectColumns + " FROM customer_documents"
+	var conditions []string
+	args := []interface{}{}
+	if customerID > 0 {
+		args = append(args, customerID)
+		conditions = append(conditions, fmt.Sprintf("customer_id = $%d", len(args)))
+	}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY uploaded_at DESC, id DESC"
+	if err := config.DB.Select(&documents, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch customer documents: %w", err)
+	}
+	for i := range documents {
+		if err := decryptCustomerDocument(&documents[i]); err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
+}
+
+// GetCustomerDocumentByID returns a single document's details.
+func GetCustomerDocumentByID(documentID int) (models.CustomerDocument, error) {
+	var document models.CustomerDocument
+	err := config.DB.Get(&document, "SELECT "+customerDocumentSelectColumns+" FROM customer_documents WHERE id = $1", documentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CustomerDocument{}, ErrDocumentNotFound
+		}
+		return models.CustomerDocument{}, fmt.Errorf("failed to fetch document: %w", err)
+	}
+	if err := decryptCustomerDocument(&document); err != nil {
+		return models.CustomerDocument{}, err
+	}
+	return document, nil
+}
+
+// ReadCustomerDocumentFile returns the uploaded file of a document, decrypted.
+func ReadCustomerDocumentFile(document models.CustomerDocument) ([]byte, error) {
+	stored, err := os.ReadFile(filepath.Join(customerDocumentDir, filepath.Base(document.FileName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document %d: %w", document.ID, err)
+	}
+	data, err := decryptPII(piiCustomerDocumentFile, string(stored))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt document %d: %w", document.ID, err)
+	}
+	return []byte(data), nil
+}
+
+func decryptCustomerDocument(document *models.CustomerDocument) error {
+	var err error
+	if document.DocumentNumber, err = decryptPIIPtr(piiCustomerDocumentNumber, document.DocumentNumber); err != nil {
+		return fmt.Errorf("failed to decrypt number of document %d: %w", document.ID, err)
+	}
+	return nil
+}
+
+// lockPendingCustomerDocument locks a document for review, failing unless it is Pending.
+func lockPendingCustomerDocument(tx *sqlx.Tx, documentID int) (models.CustomerDocument, error) {
+	var document models.CustomerDocument
+	err := tx.Get(&document, "SELECT "+customerDocumentSelectColumns+" FROM customer_documents WHERE id = $1 FOR UPDATE", documentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CustomerDocument{}, ErrDocumentNotFound
+		}
+		return models.CustomerDocument{}, fmt.Errorf("failed to fetch document: %w", err)
+	}
+	if document.Status != "Pending" {
+		return models.CustomerDocument{}, ErrDocumentNotPending
+	}
+	return document, nil
+}
+
+// ApproveCustomerDocument marks a document verified until its expiry date. The customer's
+// previously approved document of the same type is superseded.
+func ApproveCustomerDocument(documentID int, employeeID *int, input models.ApproveCustomerDocumentInput) (document models.CustomerDocument, err error) {
+	expiresOn, err := time.Parse("2006-01-02", input.ExpiresOn)
+	if err != nil {
+		return models.CustomerDocument{}, errors.New("expires_on must be a date in YYYY-MM-DD format")
+	}
+	if expiresOn.Before(time.Now().Truncate(24 * time.Hour)) {
+		return models.CustomerDocument{}, ErrDocumentExpired
+	}
+	var documentNumber *string
+	if input.DocumentNumber != nil && strings.TrimSpace(*input.DocumentNumber) != "" {
+		trimmed := strings.TrimSpace(*input.DocumentNumber)
+		if documentNumber, err = encryptPIIPtr(piiCustomerDocumentNumber, &trimmed); err != nil {
+			return models.CustomerDocument{}, fmt.Errorf("failed to encrypt document number: %w", err)
+		}
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	document, err = lockPendingCustomerDocument(tx, documentID)
+	if err != nil {
+		return models.CustomerDocument{}, err
+	}
+	_, err = tx.Exec("UPDATE customer_documents SET status = 'Superseded' WHERE customer_id = $1 AND document_type = $2 AND status = 'Approved'",
+		document.CustomerID, document.DocumentType)
+	if err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("failed to supersede earlier document: %w", err)
+	}
+	err = tx.Get(&document, `UPDATE customer_documents SET status = 'Approved', expires_on = $1, document_number = $2,
+			reviewed_by_employee_id = $3, reviewed_at = NOW(), rejection_reason = NULL
+		WHERE id = $4 RETURNING `+customerDocumentSelectColumns, expiresOn, documentNumber, employeeID, documentID)
+	if err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("failed to approve document: %w", err)
+	}
+	if err = decryptCustomerDocument(&document); err != nil {
+		return models.CustomerDocument{}, err
+	}
+	log.Printf("✅ %s document %d of customer %d approved, expires %s", document.DocumentType, documentID, document.CustomerID, input.ExpiresOn)
+	return document, nil
+}
+
+// RejectCustomerDocument turns a document down, e.g. because it is unreadable; the customer
+// can upload another.
+func RejectCustomerDocument(documentID int, employeeID *int, reason string) (document models.CustomerDocument, err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if _, err = lockPendingCustomerDocument(tx, documentID); err != nil {
+		return models.CustomerDocument{}, err
+	}
+	err = tx.Get(&document, `UPDATE customer_documents SET status = 'Rejected', reviewed_by_employee_id = $1, reviewed_at = NOW(), rejection_reason = $2
+		WHERE id = $3 RETURNING `+customerDocumentSelectColumns, employeeID, strings.TrimSpace(reason), documentID)
+	if err != nil {
+		return models.CustomerDocument{}, fmt.Errorf("failed to reject document: %w", err)
+	}
+	log.Printf("🚫 %s document %d of customer %d rejected", document.DocumentType, documentID, document.CustomerID)
+	return document, nil
+}
+
+// checkDriverLicence refuses a booking unless both sides of the customer's licence are approved
+// and valid until at least the dropoff date.
+func checkDriverLicence(q sqlx.Queryer, customerID int, dropoff time.Time) error {
+	var documents []struct {
+		DocumentType string     `db:"document_type"`
+		Status       string     `db:"status"`
+		ExpiresOn    *time.Time `db:"expires_on"`
+	}
+	err := sqlx.Select(q, &documents, `SELECT document_type, status, expires_on FROM customer_documents
+		WHERE customer_id = $1 AND document_type IN ($2, $3) AND status IN ('Pending', 'Approved')`,
+		customerID, DocumentTypeLicenceFront, DocumentTypeLicenceBack)
+	if err != nil {
+		return fmt.Errorf("failed to check driving licence: %w", err)
+	}
+
+	// The licence is valid through its expiry date, so a dropoff on that day is fine.
+	dropoffDay := time.Date(dropoff.Year(), dropoff.Month(), dropoff.Day(), 0, 0, 0, 0, time.UTC)
+	missing, unverified, expires := false, false, false
+	for _, side := range []string{DocumentTypeLicenceFront, DocumentTypeLicenceBack} {
+		approved, pending := false, false
+		for _, document := range documents {
+			if document.DocumentType != side {
+				continue
+			}
+			if document.Status == "Pending" {
+				pending = true
+				continue
+			}
+			approved = true
+			if document.ExpiresOn == nil || document.ExpiresOn.Before(dropoffDay) {
+				expires = true
+			}
+		}
+		if !approved {
+			if pending {
+				unverified = true
+			} else {
+				missing = true
+			}
+		}
+	}
+	switch {
+	case missing:
+		return ErrLicenceMissing
+	case unverified:
+		return ErrLicenceNotVerified
+	case expires:
+		return ErrLicenceExpiresBeforeDropoff
+	}
+	return nil
+}
+
+// removeCustomerDocumentFiles deletes uploaded files whose rows are gone. It is called once the
+// deletion has committed; a file that cannot be removed is only logged.
+func removeCustomerDocumentFiles(fileNames []string) {
+	for _, fileName := range fileNames {
+		if err := os.Remove(filepath.Join(customerDocumentDir, filepath.Base(fileName))); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️ Could not remove customer document file %s: %v", fileName, err)
+		}
+	}
+}
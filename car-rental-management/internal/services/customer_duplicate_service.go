@@ -0,0 +1,377 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrMergeSameCustomer   = errors.New("a customer cannot be merged into themselves")
+	ErrMergeCustomerErased = errors.New("erased customers cannot be merged")
+	ErrMergePendingErasure = errors.New("a customer with a pending erasure request cannot be merged")
+	ErrCustomerMerged      = errors.New("this customer was merged into another account and cannot be restored")
+)
+
+const (
+	// Points each kind of match adds to a duplicate score, out of 100.
+	duplicateScoreSameEmail      = 45
+	duplicateScoreCanonicalEmail = 40
+	duplicateScoreEmailUsername  = 15
+	duplicateScoreSamePhone      = 35
+	duplicateScorePhoneDigits    = 30
+	duplicateScoreName           = 20
+
+	// Names at least this similar count towards the score.
+	duplicateNameThreshold = 0.75
+
+	// A match key shared by more customers than this (a common email username such as "info")
+	// says nothing about any two of them, and would make the report quadratic.
+	maxDuplicateBlockSize = 100
+
+	DefaultDuplicateMinScore = 40
+	maxDuplicateCandidates   = 500
+)
+
+// customerFingerprint is a customer's contact details in the forms duplicates are compared in.
+type customerFingerprint struct {
+	summary        models.DuplicateCustomerSummary
+	email          string // Normalised
+	canonicalEmail string // Without dots (Gmail) or +tags
+	emailUsername  string // canonicalEmail before the @
+	phone          string // Normalised
+	phoneDigits    string // The last 9 digits, which survive a country code being added or dropped
+	name           string // Lowercase words in alphabetical order
+}
+
+func newCustomerFingerprint(summary models.DuplicateCustomerSummary) customerFingerprint {
+	f := customerFingerprint{summary: summary, email: utils.NormaliseEmail(summary.Email)}
+	f.canonicalEmail, f.emailUsername = canonicalEmail(f.email)
+	if summary.Phone != nil {
+		f.phone = utils.NormalisePhone(*summary.Phone)
+		digits := strings.TrimPrefix(f.phone, "+")
+		if len(digits) >= 8 {
+			f.phoneDigits = digits[max(0, len(digits)-9):]
+		}
+	}
+	f.name = normaliseCustomerName(summary.Name)
+	return f
+}
+
+// canonicalEmail drops what mail providers ignore when delivering: a +tag on any address, and
+// dots in Gmail addresses.
+func canonicalEmail(email string) (canonical, username string) {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email, email
+	}
+	username, domain := email[:at], email[at+1:]
+	if plus := strings.Index(username, "+"); plus > 0 {
+		username = username[:plus]
+	}
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		username = strings.ReplaceAll(username, ".", "")
+		domain = "gmail.com"
+	}
+	return username + "@" + domain, username
+}
+
+// normaliseCustomerName lowercases a name and sorts its words, so "Smith, John" matches "john smith".
+func normaliseCustomerName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// nameSimilarity is 1 minus the edit distance between two normalised names over the longer one.
+func nameSimilarity(a, b string) float64 {
+	ar, br := []rune(a), []rune(b)
+	longest := max(len(ar), len(br))
+	if longest == 0 {
+		return 0
+	}
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(br)])/float64(longest)
+}
+
+// scoreDuplicate scores how likely two customers are the same person, and says why.
+func scoreDuplicate(a, b customerFingerprint) (int, []string) {
+	score := 0
+	reasons := []string{}
+	switch {
+	case a.email == b.email:
+		score += duplicateScoreSameEmail
+		reasons = append(reasons, "same email address ignoring case")
+	case a.canonicalEmail == b.canonicalEmail:
+		score += duplicateScoreCanonicalEmail
+		reasons = append(reasons, "same email address ignoring dots and +tags")
+	case a.emailUsername == b.emailUsername:
+		score += duplicateScoreEmailUsername
+		reasons = append(reasons, "same email username at a different domain")
+	}
+	switch {
+	case a.phone != "" && a.phone == b.phone:
+		score += duplicateScoreSamePhone
+		reasons = append(reasons, "same phone number")
+	case a.phoneDigits != "" && a.phoneDigits == b.phoneDigits:
+		score += duplicateScorePhoneDigits
+		reasons = append(reasons, "same phone number in a different format")
+	}
+	if similarity := nameSimilarity(a.name, b.name); similarity == 1 {
+		score += duplicateScoreName
+		reasons = append(reasons, "same name")
+	} else if similarity >= duplicateNameThreshold {
+		score += int(float64(duplicateScoreName) * similarity)
+		reasons = append(reasons, fmt.Sprintf("similar name (%.0f%%)", similarity*100))
+	}
+	return score, reasons
+}
+
+// GetDuplicateCustomerCandidates lists pairs of active customers who may be the same person,
+// highest score first. Email and phone are encrypted, so candidates are found in memory: customers
+// sharing an email, email username or phone number are compared, and pairs scoring at least
+// minScore are returned, up to limit.
+func GetDuplicateCustomerCandidates(minScore, limit int) ([]models.DuplicateCustomerCandidate, error) {
+	if limit <= 0 || limit > maxDuplicateCandidates {
+		limit = maxDuplicateCandidates
+	}
+	var rows []struct {
+		ID        int       `db:"id"`
+		Name      string    `db:"name"`
+		Email     string    `db:"email"`
+		Phone     *string   `db:"phone"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	err := config.DB.Select(&rows, "SELECT id, name, email, phone, created_at FROM customers WHERE deleted_at IS NULL AND erased_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch customers: %w", err)
+	}
+
+	fingerprints := make([]customerFingerprint, 0, len(rows))
+	blocks := map[string][]int{}
+	for _, row := range rows {
+		customer := models.Customer{ID: row.ID, Email: row.Email, Phone: row.Phone}
+		if err := decryptCustomer(&customer); err != nil {
+			return nil, err
+		}
+		f := newCustomerFingerprint(models.DuplicateCustomerSummary{
+			ID: row.ID, Name: row.Name, Email: customer.Email, Phone: customer.Phone, CreatedAt: row.CreatedAt,
+		})
+		i := len(fingerprints)
+		fingerprints = append(fingerprints, f)
+		blocks["email:"+f.canonicalEmail] = append(blocks["email:"+f.canonicalEmail], i)
+		if len(f.emailUsername) >= 4 {
+			blocks["username:"+f.emailUsername] = append(blocks["username:"+f.emailUsername], i)
+		}
+		if f.phoneDigits != "" {
+			blocks["phone:"+f.phoneDigits] = append(blocks["phone:"+f.phoneDigits], i)
+		}
+	}
+
+	compared := map[[2]int]bool{}
+	candidates := []models.DuplicateCustomerCandidate{}
+	for _, members := range blocks {
+		if len(members) < 2 || len(members) > maxDuplicateBlockSize {
+			continue
+		}
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				pair := [2]int{members[x], members[y]} // Rows are in ID order, so pair[0] is the older
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+				a, b := fingerprints[pair[0]], fingerprints[pair[1]]
+				score, reasons := scoreDuplicate(a, b)
+				if score >= minScore {
+					candidates = append(candidates, models.DuplicateCustomerCandidate{
+						CustomerA: a.summary, CustomerB: b.summary, Score: score, Reasons: reasons,
+					})
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].CustomerA.ID != candidates[j].CustomerA.ID {
+			return candidates[i].CustomerA.ID < candidates[j].CustomerA.ID
+		}
+		return candidates[i].CustomerB.ID < candidates[j].CustomerB.ID
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// MergeCustomers folds duplicateID into survivorID in one transaction. The duplicate's rentals
+// (and so their payments, invoices and ledger postings), reviews, documents, risk flags, notes
+// and corporate memberships move to the survivor, who also takes their phone number if they
+// have none. The duplicate is then soft-deleted, marked as merged and logged out.
+func MergeCustomers(survivorID, duplicateID int) (models.CustomerMergeResult, error) {
+	if survivorID == duplicateID {
+		return models.CustomerMergeResult{}, ErrMergeSameCustomer
+	}
+	result, err := mergeCustomers(survivorID, duplicateID)
+	if err != nil {
+		return models.CustomerMergeResult{}, err
+	}
+	if result.Customer, err = GetCustomerByID(survivorID); err != nil {
+		return models.CustomerMergeResult{}, err
+	}
+	return result, nil
+}
+
+func mergeCustomers(survivorID, duplicateID int) (result models.CustomerMergeResult, err error) {
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.CustomerMergeResult{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	// Both rows are locked, in ID order so two merges of the same pair cannot deadlock.
+	var customers []struct {
+		ID              int        `db:"id"`
+		Phone           *string    `db:"phone"`
+		PhoneBidx       *string    `db:"phone_bidx"`
+		ErasedAt        *time.Time `db:"erased_at"`
+		PendingErasure  bool       `db:"pending_erasure"`
+		CustomerDeleted bool       `db:"customer_deleted"`
+	}
+	err = tx.Select(&customers, `SELECT c.id, c.phone, c.phone_bidx, c.erased_at, c.deleted_at IS NOT NULL AS customer_deleted,
+			EXISTS (SELECT 1 FROM customer_erasure_requests e WHERE e.customer_id = c.id AND e.status = 'Pending') AS pending_erasure
+		FROM customers c WHERE c.id IN ($1, $2) ORDER BY c.id FOR UPDATE OF c`, survivorID, duplicateID)
+	if err != nil {
+		return models.CustomerMergeResult{}, fmt.Errorf("failed to lock customers: %w", err)
+	}
+	if len(customers) != 2 {
+		return models.CustomerMergeResult{}, errors.New("customer not found")
+	}
+	survivor, duplicate := customers[0], customers[1]
+	if survivor.ID != survivorID {
+		survivor, duplicate = duplicate, survivor
+	}
+	for _, customer := range customers {
+		switch {
+		case customer.CustomerDeleted:
+			return models.CustomerMergeResult{}, errors.New("customer not found")
+		case customer.ErasedAt != nil:
+			return models.CustomerMergeResult{}, ErrMergeCustomerErased
+		case customer.PendingErasure:
+			return models.CustomerMergeResult{}, ErrMergePendingErasure
+		}
+	}
+
+	err = tx.Get(&result.PaymentsMoved, "SELECT COUNT(*) FROM payments p JOIN rentals r ON r.id = p.rental_id WHERE r.customer_id = $1", duplicateID)
+	if err != nil {
+		return models.CustomerMergeResult{}, fmt.Errorf("failed to count payments: %w", err)
+	}
+	moves := []struct {
+		table string
+		count *int64
+	}{
+		{"rentals", &result.RentalsMoved},
+		{"reviews", &result.ReviewsMoved},
+		{"customer_documents", &result.DocumentsMoved},
+		{"customer_risk_flags", &result.RiskFlagsMoved},
+		{"customer_notes", &result.NotesMoved},
+		{"loyalty_transactions", &result.LoyaltyEntriesMoved},
+	}
+	for _, move := range moves {
+		res, err := tx.Exec("UPDATE "+move.table+" SET customer_id = $1 WHERE customer_id = $2", survivorID, duplicateID)
+		if err != nil {
+			return models.CustomerMergeResult{}, fmt.Errorf("failed to move %s: %w", move.table, err)
+		}
+		*move.count, _ = res.RowsAffected()
+	}
+
+	steps := []struct {
+		query string
+		args  []interface{}
+	}{
+		// Keep only the latest approved and latest pending document of each type, as a single
+		// account would have.
+		{`UPDATE customer_documents d SET status = 'Superseded'
+			WHERE d.customer_id = $1 AND d.status IN ('Approved', 'Pending') AND EXISTS (
+				SELECT 1 FROM customer_documents o
+				WHERE o.customer_id = d.customer_id AND o.document_type = d.document_type AND o.status = d.status
+					AND (COALESCE(o.reviewed_at, o.uploaded_at), o.id) > (COALESCE(d.reviewed_at, d.uploaded_at), d.id))`,
+			[]interface{}{survivorID}},
+		{`INSERT INTO corporate_account_members (account_id, customer_id, monthly_spending_limit, created_at)
+			SELECT account_id, $1, monthly_spending_limit, created_at FROM corporate_account_members WHERE customer_id = $2
+			ON CONFLICT (account_id, customer_id) DO NOTHING`, []interface{}{survivorID, duplicateID}},
+		{"DELETE FROM corporate_account_members WHERE customer_id = $1", []interface{}{duplicateID}},
+		{"DELETE FROM one_time_tokens WHERE customer_id = $1", []interface{}{duplicateID}},
+		// A referral between the two accounts was a customer referring themselves. It is left on
+		// the duplicate and rejected; the survivor keeps at most one referral as the referee.
+		{`UPDATE referrals SET status = 'rejected', rejection_reason = 'the referrer and referee accounts were merged'
+			WHERE status = 'pending' AND ((referrer_customer_id = $1 AND referee_customer_id = $2) OR (referrer_customer_id = $2 AND referee_customer_id = $1))`,
+			[]interface{}{survivorID, duplicateID}},
+		{"UPDATE referrals SET referrer_customer_id = $1 WHERE referrer_customer_id = $2 AND referee_customer_id <> $1",
+			[]interface{}{survivorID, duplicateID}},
+		{`UPDATE referrals SET referee_customer_id = $1 WHERE referee_customer_id = $2 AND referrer_customer_id <> $1
+			AND NOT EXISTS (SELECT 1 FROM referrals WHERE referee_customer_id = $1)`, []interface{}{survivorID, duplicateID}},
+		{"UPDATE customers SET deleted_at = NOW(), merged_into_customer_id = $1 WHERE id = $2", []interface{}{survivorID, duplicateID}},
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step.query, step.args...); err != nil {
+			return models.CustomerMergeResult{}, fmt.Errorf("failed to merge customers: %w", err)
+		}
+	}
+	if survivor.Phone == nil && duplicate.Phone != nil {
+		_, err = tx.Exec("UPDATE customers SET phone = $1, phone_bidx = $2 WHERE id = $3", duplicate.Phone, duplicate.PhoneBidx, survivorID)
+		if err != nil {
+			return models.CustomerMergeResult{}, fmt.Errorf("failed to copy phone number: %w", err)
+		}
+	}
+	if err = revokeTokensTx(tx, "customer_id", duplicateID); err != nil {
+		return models.CustomerMergeResult{}, err
+	}
+	// A blacklist on the duplicate now applies to the survivor, and takes effect straight away.
+	if err = checkCustomerNotBlacklisted(tx, survivorID); errors.Is(err, ErrCustomerBlacklisted) {
+		err = revokeTokensTx(tx, "customer_id", survivorID)
+	}
+	if err != nil {
+		return models.CustomerMergeResult{}, err
+	}
+
+	log.Printf("🔀 Customer %d merged into customer %d (%d rentals, %d reviews)", duplicateID, survivorID, result.RentalsMoved, result.ReviewsMoved)
+	result.MergedCustomerID = duplicateID
+	return result, nil
+}
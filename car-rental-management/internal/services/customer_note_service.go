@@ -0,0 +1,269 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoteNotFound   = errors.New("note not found")
+	ErrNoteNotAuthor  = errors.New("only the author of a note may edit it")
+	ErrNoteRestricted = errors.New("restricted notes require the customer_notes:restricted permission")
+	ErrNoteEmpty      = errors.New("note body cannot be empty")
+)
+
+const (
+	customerNoteSelect = `SELECT n.id, n.customer_id, n.rental_id, n.author_employee_id, e.name AS author_name, n.body,
+			n.visibility, n.pinned, n.created_at, n.updated_at
+		FROM customer_notes n LEFT JOIN employees e ON e.id = n.author_employee_id`
+
+	// Pinned notes come first, then the newest.
+	customerNoteOrder = " ORDER BY n.pinned DESC, n.created_at DESC, n.id DESC"
+
+	maxTimelineLimit = 1000
+)
+
+// NoteViewer is who is reading or writing notes. EmployeeID is 0 for an integration using an API
+// key; SeeRestricted is set for holders of customer_notes:restricted.
+type NoteViewer struct {
+	EmployeeID    int
+	SeeRestricted bool
+}
+
+// authorID is the viewer as a note author, nil for an integration.
+func (v NoteViewer) authorID() *int {
+	if v.EmployeeID <= 0 {
+		return nil
+	}
+	id := v.EmployeeID
+	return &id
+}
+
+// noteVisibleSQL is an SQL condition, on customer_notes aliased as n, for notes the viewer whose
+// employee ID and restricted permission are in the given placeholders may see.
+func noteVisibleSQL(employeeParam, restrictedParam string) string {
+	return "(n.visibility = 'staff' OR n.author_employee_id = " + employeeParam + " OR " + restrictedParam + ")"
+}
+
+// getVisibleCustomerNote returns a note, or ErrNoteNotFound if it is missing or hidden from the viewer.
+func getVisibleCustomerNote(noteID int, viewer NoteViewer) (models.CustomerNote, error) {
+	var note models.CustomerNote
+	err := config.DB.Get(&note, customerNoteSelect+" WHERE n.id = $1 AND "+noteVisibleSQL("$2", "$3"), noteID, viewer.EmployeeID, viewer.SeeRestricted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CustomerNote{}, ErrNoteNotFound
+		}
+		return models.CustomerNote{}, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	return note, nil
+}
+
+// GetCustomerNotes lists the notes on a customer the viewer may see, across all their rentals.
+func GetCustomerNotes(customerID int, viewer NoteViewer) ([]models.CustomerNote, error) {
+	if _, err := GetCustomerByIDIncludingDeleted(customerID); err != nil {
+		return nil, err
+	}
+	notes := []models.CustomerNote{}
+	err := config.DB.Select(&notes, customerNoteSelect+" WHERE n.customer_id = $1 AND "+noteVisibleSQL("$2", "$3")+customerNoteOrder,
+		customerID, viewer.EmployeeID, viewer.SeeRestricted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+	return notes, nil
+}
+
+// GetRentalNotes lists the notes on one rental the viewer may see.
+func GetRentalNotes(rentalID int, viewer NoteViewer) ([]models.CustomerNote, error) {
+	if _, err := GetRentalByIDIncludingDeleted(rentalID); err != nil {
+		return nil, err
+	}
+	notes := []models.CustomerNote{}
+	err := config.DB.Select(&notes, customerNoteSelect+" WHERE n.rental_id = $1 AND "+noteVisibleSQL("$2", "$3")+customerNoteOrder,
+		rentalID, viewer.EmployeeID, viewer.SeeRestricted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+	return notes, nil
+}
+
+// CreateCustomerNote adds a note about a customer.
+func CreateCustomerNote(customerID int, viewer NoteViewer, input models.CreateCustomerNoteInput) (models.CustomerNote, error) {
+	if _, err := GetCustomerByID(customerID); err != nil {
+		return models.CustomerNote{}, err
+	}
+	return insertCustomerNote(customerID, nil, viewer, input)
+}
+
+// CreateRentalNote adds a note about a rental, filed under the rental's customer.
+func CreateRentalNote(rentalID int, viewer NoteViewer, input models.CreateCustomerNoteInput) (models.CustomerNote, error) {
+	rental, err := GetRentalByID(rentalID)
+	if err != nil {
+		return models.CustomerNote{}, err
+	}
+	return insertCustomerNote(rental.CustomerID, &rentalID, viewer, input)
+}
+
+func insertCustomerNote(customerID int, rentalID *int, viewer NoteViewer, input models.CreateCustomerNoteInput) (models.CustomerNote, error) {
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return models.CustomerNote{}, ErrNoteEmpty
+	}
+	visibility := input.Visibility
+	if visibility == "" {
+		visibility = "staff"
+	}
+	if visibility == "restricted" && !viewer.SeeRestricted {
+		return models.CustomerNote{}, ErrNoteRestricted
+	}
+
+	var noteID int
+	err := config.DB.Get(&noteID, `INSERT INTO customer_notes (customer_id, rental_id, author_employee_id, body, visibility, pinned)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		customerID, rentalID, viewer.authorID(), body, visibility, input.Pinned)
+	if err != nil {
+		return models.CustomerNote{}, fmt.Errorf("failed to add note: %w", err)
+	}
+	log.Printf("📝 Note %d added to customer %d (%s)", noteID, customerID, visibility)
+	return getVisibleCustomerNote(noteID, viewer)
+}
+
+// GetCustomerNoteByID returns a note the viewer may see, for the audit log.
+func GetCustomerNoteByID(noteID int, viewer NoteViewer) (models.CustomerNote, error) {
+	return getVisibleCustomerNote(noteID, viewer)
+}
+
+// UpdateCustomerNote edits a note. Anyone who can see a note may pin or unpin it, but only its
+// author may change what it says or who can see it.
+func UpdateCustomerNote(noteID int, viewer NoteViewer, input models.UpdateCustomerNoteInput) (models.CustomerNote, error) {
+	note, err := getVisibleCustomerNote(noteID, viewer)
+	if err != nil {
+		return models.CustomerNote{}, err
+	}
+	if input.Body != nil || input.Visibility != nil {
+		if note.AuthorEmployeeID == nil || *note.AuthorEmployeeID != viewer.EmployeeID {
+			return models.CustomerNote{}, ErrNoteNotAuthor
+		}
+	}
+	var body *string
+	if input.Body != nil {
+		trimmed := strings.TrimSpace(*input.Body)
+		if trimmed == "" {
+			return models.CustomerNote{}, ErrNoteEmpty
+		}
+		body = &trimmed
+	}
+	if input.Visibility != nil && *input.Visibility == "restricted" && !viewer.SeeRestricted {
+		return models.CustomerNote{}, ErrNoteRestricted
+	}
+
+	_, err = config.DB.Exec(`UPDATE customer_notes SET body = COALESCE($1, body), visibility = COALESCE($2, visibility),
+		pinned = COALESCE($3, pinned) WHERE id = $4`, body, input.Visibility, input.Pinned, noteID)
+	if err != nil {
+		return models.CustomerNote{}, fmt.Errorf("failed to update note: %w", err)
+	}
+	return getVisibleCustomerNote(noteID, viewer)
+}
+
+// DeleteCustomerNote removes a note. Its author may delete it, as may holders of
+// customer_notes:restricted, so notes whose author has left can still be cleaned up.
+func DeleteCustomerNote(noteID int, viewer NoteViewer) error {
+	note, err := getVisibleCustomerNote(noteID, viewer)
+	if err != nil {
+		return err
+	}
+	isAuthor := note.AuthorEmployeeID != nil && *note.AuthorEmployeeID == viewer.EmployeeID
+	if !isAuthor && !viewer.SeeRestricted {
+		return ErrNoteNotAuthor
+	}
+	if _, err := config.DB.Exec("DELETE FROM customer_notes WHERE id = $1", noteID); err != nil {
+		return fmt.Errorf("failed to delete note: %w", err)
+	}
+	log.Printf("🗑️ Note %d of customer %d deleted", noteID, note.CustomerID)
+	return nil
+}
+
+// customerTimelineSQL gathers everything that happened to a customer. $1 is the customer, $2 and $3
+// the viewer for notes. source_id keeps events at the same instant in a stable order between pages.
+const customerTimelineSQL = `
+	SELECT 'customer_registered' AS event_type, c.created_at AS occurred_at, NULL::int AS rental_id, NULL::int AS employee_id,
+		'Customer registered' AS summary, '{}'::jsonb AS data, c.id AS source_id
+	FROM customers c WHERE c.id = $1
+	UNION ALL
+	SELECT 'note', n.created_at, n.rental_id, n.author_employee_id,
+		CASE WHEN n.pinned THEN 'Pinned note' ELSE 'Note' END || COALESCE(' by ' || e.name, ''),
+		jsonb_build_object('note_id', n.id, 'body', n.body, 'visibility', n.visibility, 'pinned', n.pinned), n.id
+	FROM customer_notes n LEFT JOIN employees e ON e.id = n.author_employee_id
+	WHERE n.customer_id = $1 AND (n.visibility = 'staff' OR n.author_employee_id = $2 OR $3)
+	UNION ALL
+	SELECT 'rental_created', r.created_at, r.id, NULL,
+		'Booked ' || ca.brand || ' ' || ca.model,
+		jsonb_build_object('car_id', r.car_id, 'status', r.status, 'pickup_datetime', r.pickup_datetime, 'dropoff_datetime', r.dropoff_datetime), r.id
+	FROM rentals r JOIN cars ca ON ca.id = r.car_id
+	WHERE r.customer_id = $1 AND r.deleted_at IS NULL
+	UNION ALL
+	SELECT 'rental_status', h.changed_at, h.rental_id, NULL,
+		'Rental #' || h.rental_id || ' ' || h.from_status || ' → ' || h.to_status,
+		jsonb_build_object('from_status', h.from_status, 'to_status', h.to_status), h.id
+	FROM rental_status_history h JOIN rentals r ON r.id = h.rental_id
+	WHERE r.customer_id = $1 AND r.deleted_at IS NULL AND h.from_status IS NOT NULL
+	UNION ALL
+	SELECT 'payment', COALESCE(p.payment_date, p.created_at), p.rental_id, p.recorded_by_employee_id,
+		'Payment of ' || COALESCE(p.charged_amount, p.amount) || ' ' || p.currency || ' (' || p.payment_status || ')',
+		jsonb_build_object('payment_id', p.id, 'amount', p.amount, 'charged_amount', p.charged_amount, 'currency', p.currency,
+			'status', p.payment_status, 'method', p.payment_method), p.id
+	FROM payments p JOIN rentals r ON r.id = p.rental_id
+	WHERE r.customer_id = $1 AND r.deleted_at IS NULL
+	UNION ALL
+	SELECT 'review', v.created_at, v.rental_id, NULL,
+		v.rating || '-star review',
+		jsonb_build_object('review_id', v.id, 'rating', v.rating, 'comment', v.comment), v.id
+	FROM reviews v WHERE v.customer_id = $1
+	UNION ALL
+	SELECT 'risk_flag', f.created_at, NULL, f.set_by_employee_id,
+		initcap(f.flag_type) || ' flag: ' || f.category,
+		jsonb_build_object('flag_id', f.id, 'flag_type', f.flag_type, 'category', f.category, 'reason', f.reason, 'expires_at', f.expires_at), f.id
+	FROM customer_risk_flags f WHERE f.customer_id = $1
+	UNION ALL
+	SELECT 'risk_flag_lifted', f.lifted_at, NULL, f.lifted_by_employee_id,
+		initcap(f.flag_type) || ' flag lifted',
+		jsonb_build_object('flag_id', f.id, 'flag_type', f.flag_type, 'lift_reason', f.lift_reason), f.id
+	FROM customer_risk_flags f WHERE f.customer_id = $1 AND f.lifted_at IS NOT NULL`
+
+// GetCustomerTimeline lists what has happened to a customer in chronological order: registration,
+// notes the viewer may see, bookings, rental status changes, payments, reviews and risk flags.
+// from is inclusive and to exclusive; either may be nil.
+func GetCustomerTimeline(customerID int, viewer NoteViewer, from, to *time.Time, page, limit int) (models.CustomerTimeline, error) {
+	if _, err := GetCustomerByIDIncludingDeleted(customerID); err != nil {
+		return models.CustomerTimeline{}, err
+	}
+	if limit <= 0 {
+		limit = 200
+	}
+	if limit > maxTimelineLimit {
+		limit = maxTimelineLimit
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	events := []models.CustomerTimelineEvent{}
+	query := `SELECT event_type, occurred_at, rental_id, employee_id, summary, data FROM (` + customerTimelineSQL + `) events
+		WHERE occurred_at IS NOT NULL AND ($4::timestamptz IS NULL OR occurred_at >= $4) AND ($5::timestamptz IS NULL OR occurred_at < $5)
+		ORDER BY occurred_at, event_type, source_id LIMIT $6 OFFSET $7`
+	// One extra row tells whether there is another page.
+	err := config.DB.Select(&events, query, customerID, viewer.EmployeeID, viewer.SeeRestricted, from, to, limit+1, (page-1)*limit)
+	if err != nil {
+		return models.CustomerTimeline{}, fmt.Errorf("failed to fetch customer timeline: %w", err)
+	}
+	hasMore := len(events) > limit
+	if hasMore {
+		events = events[:limit]
+	}
+	return models.CustomerTimeline{CustomerID: customerID, Events: events, Page: page, Limit: limit, HasMore: hasMore}, nil
+}
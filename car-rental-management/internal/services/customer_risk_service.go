@@ -0,0 +1,135 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrCustomerBlacklisted = errors.New("this account has been suspended; please contact us")
+	ErrRiskFlagNotFound    = errors.New("risk flag not found")
+	ErrRiskFlagInactive    = errors.New("this flag has already been lifted or has expired")
+	ErrInvalidRiskStatus   = errors.New("risk status must be clear, watch or blacklisted")
+)
+
+const (
+	// activeRiskFlag is true for flags that still apply, on customer_risk_flags aliased as f.
+	activeRiskFlag = "f.lifted_at IS NULL AND (f.expires_at IS NULL OR f.expires_at > NOW())"
+
+	customerRiskFlagSelectColumns = `f.id, f.customer_id, f.flag_type, f.category, f.reason, f.set_by_employee_id, f.created_at,
+		f.expires_at, f.lifted_at, f.lifted_by_employee_id, f.lift_reason, (` + activeRiskFlag + `) AS active`
+)
+
+var customerRiskStatuses = map[string]bool{"clear": true, "watch": true, "blacklisted": true}
+
+// customerRiskStatusSQL is an SQL expression giving the risk status (clear, watch or blacklisted)
+// of the customer whose ID is in customerIDColumn.
+func customerRiskStatusSQL(customerIDColumn string) string {
+	return `CASE
+		WHEN EXISTS (SELECT 1 FROM customer_risk_flags f WHERE f.customer_id = ` + customerIDColumn + ` AND f.flag_type = 'blacklist' AND ` + activeRiskFlag + `) THEN 'blacklisted'
+		WHEN EXISTS (SELECT 1 FROM customer_risk_flags f WHERE f.customer_id = ` + customerIDColumn + ` AND ` + activeRiskFlag + `) THEN 'watch'
+		ELSE 'clear' END`
+}
+
+// GetCustomerRiskFlags lists a customer's flags, newest first, including lifted and expired ones.
+func GetCustomerRiskFlags(customerID int) ([]models.CustomerRiskFlag, error) {
+	if _, err := GetCustomerByIDIncludingDeleted(customerID); err != nil {
+		return nil, err
+	}
+	flags := []models.CustomerRiskFlag{}
+	err := config.DB.Select(&flags, "SELECT "+customerRiskFlagSelectColumns+" FROM customer_risk_flags f WHERE f.customer_id = $1 ORDER BY f.created_at DESC, f.id DESC", customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch risk flags: %w", err)
+	}
+	return flags, nil
+}
+
+// CreateCustomerRiskFlag flags a customer. Blacklisting also logs them out everywhere, so the
+// ban takes effect straight away rather than when their access token expires.
+func CreateCustomerRiskFlag(customerID int, employeeID *int, input models.CreateCustomerRiskFlagInput) (flag models.CustomerRiskFlag, err error) {
+	if strings.TrimSpace(input.Reason) == "" {
+		return models.CustomerRiskFlag{}, errors.New("a reason is required")
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return models.CustomerRiskFlag{}, errors.New("expires_at must be in the future")
+	}
+	if _, err := GetCustomerByID(customerID); err != nil {
+		return models.CustomerRiskFlag{}, err
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.CustomerRiskFlag{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	err = tx.Get(&flag, `WITH f AS (
+			INSERT INTO customer_risk_flags (customer_id, flag_type, category, reason, set_by_employee_id, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING *
+		) SELECT `+customerRiskFlagSelectColumns+` FROM f`,
+		customerID, input.FlagType, input.Category, strings.TrimSpace(input.Reason), employeeID, input.ExpiresAt)
+	if err != nil {
+		return models.CustomerRiskFlag{}, fmt.Errorf("failed to flag customer: %w", err)
+	}
+	if input.FlagType == "blacklist" {
+		if err = revokeTokensTx(tx, "customer_id", customerID); err != nil {
+			return models.CustomerRiskFlag{}, err
+		}
+	}
+	log.Printf("🚩 Customer %d flagged (%s, %s)", customerID, input.FlagType, input.Category)
+	return flag, nil
+}
+
+// LiftCustomerRiskFlag ends an active flag early, e.g. once a damage claim is settled.
+func LiftCustomerRiskFlag(customerID, flagID int, employeeID *int, reason string) (flag models.CustomerRiskFlag, err error) {
+	err = config.DB.Get(&flag, `WITH f AS (
+			UPDATE customer_risk_flags f SET lifted_at = NOW(), lifted_by_employee_id = $1, lift_reason = $2
+			WHERE f.id = $3 AND f.customer_id = $4 AND `+activeRiskFlag+` RETURNING *
+		) SELECT `+customerRiskFlagSelectColumns+` FROM f`,
+		employeeID, strings.TrimSpace(reason), flagID, customerID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return models.CustomerRiskFlag{}, fmt.Errorf("failed to lift risk flag: %w", err)
+		}
+		var exists bool
+		if err := config.DB.Get(&exists, "SELECT EXISTS(SELECT 1 FROM customer_risk_flags WHERE id = $1 AND customer_id = $2)", flagID, customerID); err != nil {
+			return models.CustomerRiskFlag{}, fmt.Errorf("failed to fetch risk flag: %w", err)
+		}
+		if exists {
+			return models.CustomerRiskFlag{}, ErrRiskFlagInactive
+		}
+		return models.CustomerRiskFlag{}, ErrRiskFlagNotFound
+	}
+	log.Printf("🏳️ Risk flag %d of customer %d lifted", flagID, customerID)
+	return flag, nil
+}
+
+// checkCustomerNotBlacklisted refuses a login or booking by a blacklisted customer.
+func checkCustomerNotBlacklisted(q sqlx.Queryer, customerID int) error {
+	var blacklisted bool
+	err := sqlx.Get(q, &blacklisted, "SELECT EXISTS(SELECT 1 FROM customer_risk_flags f WHERE f.customer_id = $1 AND f.flag_type = 'blacklist' AND "+activeRiskFlag+")", customerID)
+	if err != nil {
+		return fmt.Errorf("failed to check customer risk flags: %w", err)
+	}
+	if blacklisted {
+		return ErrCustomerBlacklisted
+	}
+	return nil
+}
@@ -13,13 +13,15 @@ import (
 )
 
 type CustomerFiltersWithPagination struct {
-	Name          *string
-	Email         *string
-	Phone         *string
-	Page          int
-	Limit         int
-	SortBy        string
-	SortDirection string
+	Name           *string
+	Email          *string
+	Phone          *string
+	RiskStatus     *string // clear, watch or blacklisted
+	IncludeDeleted bool
+	Page           int
+	Limit          int
+	SortBy         string
+	SortDirection  string
 }
 
 type PaginatedCustomersResponse struct {
@@ -39,23 +41,37 @@ func GetCustomersPaginated(filters CustomerFiltersWithPagination) (PaginatedCust
 	args := []interface{}{}
 	paramCount := 1
 
-	queryBuilder.WriteString("SELECT id, name, email, phone, created_at, updated_at FROM customers")
+	queryBuilder.WriteString("SELECT id, name, email, phone, created_at, updated_at, email_verified_at, deleted_at, erased_at, " +
+		customerRiskStatusSQL("customers.id") + " AS risk_status FROM customers")
 	countQueryBuilder.WriteString("SELECT COUNT(*) FROM customers")
 
 	var conditions []string
+	if !filters.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
 	if filters.Name != nil && *filters.Name != "" {
 		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", paramCount))
 		args = append(args, "%"+*filters.Name+"%")
 		paramCount++
 	}
+	// Encrypted emails and phones can only be matched exactly, through their blind index; rows
+	// not yet encrypted are still searched by substring.
 	if filters.Email != nil && *filters.Email != "" {
-		conditions = append(conditions, fmt.Sprintf("email ILIKE $%d", paramCount))
-		args = append(args, "%"+*filters.Email+"%")
-		paramCount++
+		conditions = append(conditions, fmt.Sprintf("(email_bidx = $%d OR (email_bidx IS NULL AND email ILIKE $%d))", paramCount, paramCount+1))
+		args = append(args, piiBlindIndex(piiCustomerEmail, *filters.Email), "%"+*filters.Email+"%")
+		paramCount += 2
 	}
 	if filters.Phone != nil && *filters.Phone != "" {
-		conditions = append(conditions, fmt.Sprintf("phone ILIKE $%d", paramCount))
-		args = append(args, "%"+*filters.Phone+"%")
+		conditions = append(conditions, fmt.Sprintf("(phone_bidx = $%d OR (phone_bidx IS NULL AND phone ILIKE $%d))", paramCount, paramCount+1))
+		args = append(args, piiBlindIndex(piiCustomerPhone, *filters.Phone), "%"+*filters.Phone+"%")
+		paramCount += 2
+	}
+	if filters.RiskStatus != nil && *filters.RiskStatus != "" {
+		if !customerRiskStatuses[*filters.RiskStatus] {
+			return response, ErrInvalidRiskStatus
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s) = $%d", customerRiskStatusSQL("customers.id"), paramCount))
+		args = append(args, *filters.RiskStatus)
 		paramCount++
 	}
 
@@ -73,7 +89,8 @@ func GetCustomersPaginated(filters CustomerFiltersWithPagination) (PaginatedCust
 
 	orderByClause := " ORDER BY id ASC"
 	if filters.SortBy != "" {
-		validSortByFields := map[string]bool{"id": true, "name": true, "email": true, "created_at": true}
+		// Encrypted emails have no meaningful order
+		validSortByFields := map[string]bool{"id": true, "name": true, "created_at": true}
 		if validSortByFields[filters.SortBy] {
 			sortDir := "ASC"
 			if strings.ToUpper(filters.SortDirection) == "DESC" {
@@ -110,6 +127,10 @@ func GetCustomersPaginated(filters CustomerFiltersWithPagination) (PaginatedCust
 			log.Printf("Error scanning customer: %v", err)
 			continue
 		}
+		if err := decryptCustomer(&customer); err != nil {
+			log.Printf("Error decrypting customer: %v", err)
+			continue
+		}
 		response.Customers = append(response.Customers, customer)
 	}
 	if err = rows.Err(); err != nil {
@@ -142,12 +163,25 @@ func GetCustomers() ([]models.Customer, error) {
 	return result.Customers, nil
 }
 
+// GetCustomerByID returns a customer, unless they have been deleted.
 func GetCustomerByID(id int) (models.Customer, error) {
+	return getCustomer(id, false)
+}
+
+// GetCustomerByIDIncludingDeleted returns a customer even if they have been deleted.
+func GetCustomerByIDIncludingDeleted(id int) (models.Customer, error) {
+	return getCustomer(id, true)
+}
+
+func getCustomer(id int, includeDeleted bool) (models.Customer, error) {
 	var customer models.Customer
 	if id <= 0 {
 		return models.Customer{}, errors.New("invalid customer ID")
 	}
-	query := "SELECT id, name, email, phone, created_at, updated_at FROM customers WHERE id=$1"
+	query := "SELECT id, name, email, phone, created_at, updated_at, email_verified_at, deleted_at, erased_at, merged_into_customer_id, referral_code, billing_address, tax_id FROM customers WHERE id=$1"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 	err := config.DB.Get(&customer, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -155,6 +189,9 @@ func GetCustomerByID(id int) (models.Customer, error) {
 		}
 		return models.Customer{}, fmt.Errorf("failed to fetch customer: %w", err)
 	}
+	if err := decryptCustomer(&customer); err != nil {
+		return models.Customer{}, err
+	}
 	return customer, nil
 }
 
@@ -162,6 +199,8 @@ func UpdateCustomer(customerID int, input models.UpdateCustomerByStaffInput) (mo
 	if customerID <= 0 {
 		return models.Customer{}, errors.New("invalid customer ID for update")
 	}
+	input.Email = utils.NormaliseEmail(input.Email)
+	input.Phone = utils.NormalisePhonePtr(input.Phone)
 	if strings.TrimSpace(input.Name) == "" {
 		return models.Customer{}, errors.New("customer name cannot be empty")
 	}
@@ -169,10 +208,25 @@ func UpdateCustomer(customerID int, input models.UpdateCustomerByStaffInput) (mo
 		return models.Customer{}, errors.New("invalid email format")
 	}
 
-	query := `UPDATE customers SET name=$1, email=$2, phone=$3 WHERE id=$4`
-	result, err := config.DB.Exec(query, input.Name, input.Email, input.Phone, customerID)
+	contact, err := encryptCustomerContact(input.Email, input.Phone)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("failed to encrypt customer details: %w", err)
+	}
+	billing, err := prepareCustomerBilling(input.BillingAddress, input.TaxID)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("failed to encrypt customer details: %w", err)
+	}
+
+	// A changed address has not been verified yet
+	sameEmail, sameEmailArgs := customerEmailMatch(11, input.Email)
+	query := `UPDATE customers SET name=$1, email=$2, email_bidx=$3, phone=$4, phone_bidx=$5, ` + customerBillingSet(7) + `,
+		email_verified_at = CASE WHEN ` + sameEmail + ` THEN email_verified_at ELSE NULL END
+		WHERE id=$6 AND deleted_at IS NULL`
+	args := append([]interface{}{input.Name, contact.Email, contact.EmailBidx, contact.Phone, contact.PhoneBidx, customerID}, billing.args()...)
+	args = append(args, sameEmailArgs...)
+	result, err := config.DB.Exec(query, args...)
 	if err != nil {
-		if strings.Contains(err.Error(), "customers_email_key") {
+		if strings.Contains(err.Error(), "customers_email_key") || strings.Contains(err.Error(), "idx_customers_email_bidx") {
 			return models.Customer{}, errors.New("email already exists for another customer")
 		}
 		return models.Customer{}, fmt.Errorf("failed to update customer: %w", err)
@@ -195,9 +249,19 @@ func UpdateCustomerProfile(customerID int, input models.UpdateCustomerProfileInp
 	if strings.TrimSpace(input.Name) == "" {
 		return models.Customer{}, errors.New("customer name cannot be empty")
 	}
+	input.Phone = utils.NormalisePhonePtr(input.Phone)
 
-	query := `UPDATE customers SET name=$1, phone=$2 WHERE id=$3`
-	result, err := config.DB.Exec(query, input.Name, input.Phone, customerID)
+	phone, err := encryptPIIPtr(piiCustomerPhone, input.Phone)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("failed to encrypt phone: %w", err)
+	}
+	billing, err := prepareCustomerBilling(input.BillingAddress, input.TaxID)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("failed to encrypt tax ID: %w", err)
+	}
+	query := `UPDATE customers SET name=$1, phone=$2, phone_bidx=$3, ` + customerBillingSet(5) + ` WHERE id=$4 AND deleted_at IS NULL`
+	args := append([]interface{}{input.Name, phone, piiBlindIndexPtr(piiCustomerPhone, input.Phone), customerID}, billing.args()...)
+	result, err := config.DB.Exec(query, args...)
 	if err != nil {
 		return models.Customer{}, fmt.Errorf("failed to update profile: %w", err)
 	}
@@ -212,20 +276,99 @@ func UpdateCustomerProfile(customerID int, input models.UpdateCustomerProfileInp
 	return updatedCustomer, nil
 }
 
+// customerBilling is a billing address and tax ID from an update, ready for storage. Each is
+// left unchanged when the input omits it and cleared when it is empty.
+type customerBilling struct {
+	SetAddress bool
+	Address    *string
+	SetTaxID   bool
+	TaxID      *string // encrypted
+}
+
+// prepareCustomerBilling trims the billing details and encrypts the tax ID. Spaces and dashes
+// are dropped from the tax ID, so 1-2345-67890-12-3 is stored as 1234567890123.
+func prepareCustomerBilling(address, taxID *string) (customerBilling, error) {
+	var billing customerBilling
+	if address != nil {
+		billing.SetAddress = true
+		if trimmed := strings.TrimSpace(*address); trimmed != "" {
+			billing.Address = &trimmed
+		}
+	}
+	if taxID != nil {
+		billing.SetTaxID = true
+		if compact := strings.NewReplacer(" ", "", "-", "").Replace(*taxID); compact != "" {
+			encrypted, err := encryptPII(piiCustomerTaxID, compact)
+			if err != nil {
+				return customerBilling{}, err
+			}
+			billing.TaxID = &encrypted
+		}
+	}
+	return billing, nil
+}
+
+// customerBillingSet is the SET clause for prepareCustomerBilling's result, taking four
+// parameters from $first.
+func customerBillingSet(first int) string {
+	return fmt.Sprintf("billing_address = CASE WHEN $%d THEN $%d ELSE billing_address END, tax_id = CASE WHEN $%d THEN $%d ELSE tax_id END",
+		first, first+1, first+2, first+3)
+}
+
+func (b customerBilling) args() []interface{} {
+	return []interface{}{b.SetAddress, b.Address, b.SetTaxID, b.TaxID}
+}
+
+// DeleteCustomer soft-deletes a customer and logs them out everywhere. Their past rentals keep
+// pointing at them.
 func DeleteCustomer(customerID int) error {
 	if customerID <= 0 {
 		return errors.New("invalid customer ID for deletion")
 	}
-	result, err := config.DB.Exec("DELETE FROM customers WHERE id=$1", customerID)
+	var liveRentals int
+	err := config.DB.Get(&liveRentals, "SELECT COUNT(*) FROM rentals WHERE customer_id=$1 AND deleted_at IS NULL AND status IN "+liveRentalStatuses, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to check customer rentals: %w", err)
+	}
+	if liveRentals > 0 {
+		return errors.New("cannot delete customer: they have rentals that are still in progress")
+	}
+	result, err := config.DB.Exec("UPDATE customers SET deleted_at = NOW() WHERE id=$1 AND deleted_at IS NULL", customerID)
 	if err != nil {
-		if strings.Contains(err.Error(), "rentals_customer_id_fkey") {
-			return errors.New("cannot delete customer: they have associated rentals")
-		}
 		return fmt.Errorf("failed to delete customer: %w", err)
 	}
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
 		return errors.New("customer not found for deletion")
 	}
+	if err := revokeTokens("customer_id", customerID); err != nil {
+		log.Printf("⚠️ Customer %d deleted but their tokens could not be revoked: %v", customerID, err)
+	}
 	return nil
 }
+
+// RestoreCustomer undoes DeleteCustomer. They have to log in again.
+func RestoreCustomer(customerID int) (models.Customer, error) {
+	if customerID <= 0 {
+		return models.Customer{}, errors.New("invalid customer ID")
+	}
+	// A merged customer's rentals now belong to another account; restoring it would bring back an
+	// empty duplicate.
+	var merged bool
+	if err := config.DB.Get(&merged, "SELECT EXISTS(SELECT 1 FROM customers WHERE id=$1 AND merged_into_customer_id IS NOT NULL)", customerID); err != nil {
+		return models.Customer{}, fmt.Errorf("failed to fetch customer: %w", err)
+	}
+	if merged {
+		return models.Customer{}, ErrCustomerMerged
+	}
+	result, err := config.DB.Exec("UPDATE customers SET deleted_at = NULL WHERE id=$1 AND deleted_at IS NOT NULL", customerID)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("failed to restore customer: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.Customer{}, ErrNothingToRestore
+	}
+	log.Printf("♻️ Customer %d restored", customerID)
+	return GetCustomerByID(customerID)
+}
@@ -10,24 +10,34 @@ import (
 	"time"
 )
 
-func GetDashboardData() (models.DashboardData, error) {
+// ledgerInScopeCondition limits ledger transaction lt to rentals of cars in the branch scope
+// passed as $n. Unrestricted callers also see postings without a rental, such as corporate receipts.
+func ledgerInScopeCondition(n int) string {
+	return fmt.Sprintf(`($%[1]d::int[] IS NULL OR EXISTS (
+		SELECT 1 FROM rentals sr JOIN cars sc ON sr.car_id = sc.id WHERE sr.id = lt.rental_id AND sc.branch_id = ANY($%[1]d)))`, n)
+}
+
+// GetDashboardData returns headline figures for the branches in scope. Customers are not
+// tied to a branch, so total_customers always counts everyone.
+func GetDashboardData(scope BranchScope) (models.DashboardData, error) {
 	var dashboard models.DashboardData
 	log.Println("🔍 Fetching dashboard data for admin...")
 
 	query := `
 		SELECT
-			(SELECT COUNT(*) FROM rentals) AS total_rentals,
-			(SELECT COALESCE(SUM(amount), 0) FROM payments WHERE payment_status='Paid') AS total_revenue,
-			(SELECT COUNT(*) FROM customers) AS total_customers,
-			(SELECT COUNT(*) FROM cars) AS total_cars,
-			(SELECT COUNT(*) FROM cars WHERE availability = TRUE) AS total_available_cars,
-			(SELECT COUNT(*) FROM cars WHERE availability = FALSE) AS unavailable_cars,
-			(SELECT COUNT(*) FROM branches) AS total_branches
+			(SELECT COUNT(*) FROM rentals r JOIN cars c ON r.car_id = c.id WHERE r.deleted_at IS NULL AND ($1::int[] IS NULL OR c.branch_id = ANY($1))) AS total_rentals,
+			(SELECT COALESCE(SUM(e.credit - e.debit), 0) FROM ledger_entries e JOIN ledger_transactions lt ON e.transaction_id = lt.id
+				WHERE ` + netRevenueCondition + ` AND ` + ledgerInScopeCondition(1) + `) AS total_revenue,
+			(SELECT COUNT(*) FROM customers WHERE deleted_at IS NULL) AS total_customers,
+			(SELECT COUNT(*) FROM cars WHERE deleted_at IS NULL AND ($1::int[] IS NULL OR branch_id = ANY($1))) AS total_cars,
+			(SELECT COUNT(*) FROM cars WHERE deleted_at IS NULL AND availability = TRUE AND ($1::int[] IS NULL OR branch_id = ANY($1))) AS total_available_cars,
+			(SELECT COUNT(*) FROM cars WHERE deleted_at IS NULL AND availability = FALSE AND ($1::int[] IS NULL OR branch_id = ANY($1))) AS unavailable_cars,
+			(SELECT COUNT(*) FROM branches WHERE deleted_at IS NULL AND ($1::int[] IS NULL OR id = ANY($1))) AS total_branches
 	`
 
 	log.Println("Executing admin dashboard query:", query)
 
-	err := config.DB.Get(&dashboard, query)
+	err := config.DB.Get(&dashboard, query, scope.filterArg())
 	if err != nil {
 		log.Printf("❌ Error fetching admin dashboard data: %v", err)
 		return models.DashboardData{}, fmt.Errorf("error fetching admin dashboard data: %v", err)
@@ -43,8 +53,8 @@ func GetPublicStatsData() (models.PublicStatsData, error) {
 
 	query := `
 		SELECT
-			(SELECT COUNT(*) FROM cars WHERE availability = TRUE) AS total_available_cars,
-			(SELECT COUNT(*) FROM branches) AS total_branches
+			(SELECT COUNT(*) FROM cars WHERE availability = TRUE AND deleted_at IS NULL) AS total_available_cars,
+			(SELECT COUNT(*) FROM branches WHERE deleted_at IS NULL) AS total_branches
 	`
 	log.Println("Executing public stats query:", query)
 	err := config.DB.Get(&stats, query)
@@ -56,22 +66,25 @@ func GetPublicStatsData() (models.PublicStatsData, error) {
 	return stats, nil
 }
 
-func GetRevenueReport(startDate, endDate time.Time) ([]models.RevenueReportItem, error) {
+// GetRevenueReport returns net revenue per day for the branches in scope.
+func GetRevenueReport(startDate, endDate time.Time, scope BranchScope) ([]models.RevenueReportItem, error) {
 	log.Printf("⚙️ Service: Fetching revenue report from %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 	var report []models.RevenueReportItem
 	endDate = endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
 	query := `
 		SELECT
-			to_char(date_trunc('day', p.payment_date), 'YYYY-MM-DD') AS period,
-			SUM(p.amount) AS amount
-		FROM payments p
-		WHERE p.payment_status = 'Paid'
-		  AND p.payment_date >= $1
-		  AND p.payment_date <= $2
-		GROUP BY date_trunc('day', p.payment_date)
+			to_char(date_trunc('day', lt.created_at), 'YYYY-MM-DD') AS period,
+			SUM(e.credit - e.debit) AS amount
+		FROM ledger_entries e
+		JOIN ledger_transactions lt ON e.transaction_id = lt.id
+		WHERE ` + netRevenueCondition + `
+		  AND lt.created_at >= $1
+		  AND lt.created_at <= $2
+		  AND ` + ledgerInScopeCondition(3) + `
+		GROUP BY date_trunc('day', lt.created_at)
 		ORDER BY period ASC;
 	`
-	err := config.DB.Select(&report, query, startDate, endDate)
+	err := config.DB.Select(&report, query, startDate, endDate, scope.filterArg())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return []models.RevenueReportItem{}, nil
@@ -83,7 +96,8 @@ func GetRevenueReport(startDate, endDate time.Time) ([]models.RevenueReportItem,
 	return report, nil
 }
 
-func GetPopularCarsReport(limit int) ([]models.PopularCarReportItem, error) {
+// GetPopularCarsReport returns the most rented cars in the branches in scope.
+func GetPopularCarsReport(limit int, scope BranchScope) ([]models.PopularCarReportItem, error) {
 	log.Printf("⚙️ Service: Fetching popular cars report (limit %d)", limit)
 	var report []models.PopularCarReportItem
 	if limit <= 0 {
@@ -97,11 +111,12 @@ func GetPopularCarsReport(limit int) ([]models.PopularCarReportItem, error) {
 			COUNT(r.id) AS rental_count
 		FROM rentals r
 		JOIN cars c ON r.car_id = c.id
+		WHERE r.deleted_at IS NULL AND c.deleted_at IS NULL AND ($2::int[] IS NULL OR c.branch_id = ANY($2))
 		GROUP BY r.car_id, c.brand, c.model
 		ORDER BY rental_count DESC
 		LIMIT $1;
 	`
-	err := config.DB.Select(&report, query, limit)
+	err := config.DB.Select(&report, query, limit, scope.filterArg())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return []models.PopularCarReportItem{}, nil
@@ -113,7 +128,8 @@ func GetPopularCarsReport(limit int) ([]models.PopularCarReportItem, error) {
 	return report, nil
 }
 
-func GetBranchPerformanceReport() ([]models.BranchPerformanceReportItem, error) {
+// GetBranchPerformanceReport returns rentals and revenue per branch for the branches in scope.
+func GetBranchPerformanceReport(scope BranchScope) ([]models.BranchPerformanceReportItem, error) {
 	log.Println("⚙️ Service: Fetching branch performance report")
 	var report []models.BranchPerformanceReportItem
 	query := `
@@ -121,15 +137,22 @@ func GetBranchPerformanceReport() ([]models.BranchPerformanceReportItem, error)
 			b.id AS branch_id,
 			b.name AS branch_name,
 			COUNT(DISTINCT r.id) AS total_rentals,
-			COALESCE(SUM(CASE WHEN p.payment_status = 'Paid' THEN p.amount ELSE 0 END), 0) AS total_revenue
+			COALESCE((
+				SELECT SUM(e.credit - e.debit)
+				FROM ledger_entries e
+				JOIN ledger_transactions lt ON e.transaction_id = lt.id
+				JOIN rentals lr ON lt.rental_id = lr.id
+				JOIN cars lc ON lr.car_id = lc.id
+				WHERE lc.branch_id = b.id AND ` + netRevenueCondition + `
+			), 0) AS total_revenue
 		FROM branches b
 		LEFT JOIN cars c ON b.id = c.branch_id
-		LEFT JOIN rentals r ON c.id = r.car_id
-		LEFT JOIN payments p ON r.id = p.rental_id
+		LEFT JOIN rentals r ON c.id = r.car_id AND r.deleted_at IS NULL
+		WHERE b.deleted_at IS NULL AND ($1::int[] IS NULL OR b.id = ANY($1))
 		GROUP BY b.id, b.name
 		ORDER BY b.name ASC;
 	`
-	err := config.DB.Select(&report, query)
+	err := config.DB.Select(&report, query, scope.filterArg())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return []models.BranchPerformanceReportItem{}, nil
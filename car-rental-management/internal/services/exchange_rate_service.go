@@ -0,0 +1,234 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrUnknownCurrency     = errors.New("no exchange rate for currency")
+	ErrInvalidCurrency     = errors.New("invalid currency code")
+	ErrInvalidExchangeRate = errors.New("invalid exchange rate")
+)
+
+var (
+	currencyCodeRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+	// Matches the DECIMAL(18,8) column: up to 10 integer and 8 fractional digits, no exponent.
+	exchangeRateRegex = regexp.MustCompile(`^[0-9]{1,10}(\.[0-9]{1,8})?$`)
+)
+
+// NormalizeCurrency upper-cases and validates an ISO 4217 code. Empty means BaseCurrency.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return models.BaseCurrency, nil
+	}
+	if !currencyCodeRegex.MatchString(code) {
+		return "", fmt.Errorf("%w: '%s'", ErrInvalidCurrency, code)
+	}
+	return code, nil
+}
+
+func parseRate(rate json.Number) (*big.Rat, error) {
+	text := strings.TrimSpace(rate.String())
+	if !exchangeRateRegex.MatchString(text) {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidExchangeRate, text)
+	}
+	parsed, ok := new(big.Rat).SetString(text)
+	if !ok || parsed.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: '%s' must be greater than zero", ErrInvalidExchangeRate, text)
+	}
+	return parsed, nil
+}
+
+// GetExchangeRate returns the current rate for currency. BaseCurrency always has rate 1.
+func GetExchangeRate(currency string) (models.ExchangeRate, error) {
+	currency, err := NormalizeCurrency(currency)
+	if err != nil {
+		return models.ExchangeRate{}, err
+	}
+	if currency == models.BaseCurrency {
+		return models.ExchangeRate{Currency: models.BaseCurrency, RateToBase: "1"}, nil
+	}
+	var rate models.ExchangeRate
+	query := `SELECT currency, rate_to_base, updated_by_employee_id, updated_at FROM exchange_rates WHERE currency = $1`
+	err = config.DB.Get(&rate, query, currency)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ExchangeRate{}, fmt.Errorf("%w '%s'", ErrUnknownCurrency, currency)
+		}
+		log.Printf("❌ GetExchangeRate: DB error fetching rate for %s: %v", currency, err)
+		return models.ExchangeRate{}, fmt.Errorf("failed to fetch exchange rate: %w", err)
+	}
+	return rate, nil
+}
+
+// ConvertFromBase converts a base-currency amount for display in currency (rounded half-up).
+func ConvertFromBase(amount models.Money, rate models.ExchangeRate) (models.DisplayPrice, error) {
+	parsed, err := parseRate(rate.RateToBase)
+	if err != nil {
+		return models.DisplayPrice{}, err
+	}
+	converted := amount.MulRat(new(big.Rat).Inv(parsed), models.RoundHalfUp)
+	converted.Currency = rate.Currency
+	return models.DisplayPrice{Amount: converted, Currency: rate.Currency, ExchangeRate: rate.RateToBase}, nil
+}
+
+// ConvertToBase converts an amount charged in rate.Currency into BaseCurrency (rounded half-up).
+func ConvertToBase(amount models.Money, rate models.ExchangeRate) (models.Money, error) {
+	parsed, err := parseRate(rate.RateToBase)
+	if err != nil {
+		return models.Money{}, err
+	}
+	converted := amount.MulRat(parsed, models.RoundHalfUp)
+	converted.Currency = models.BaseCurrency
+	return converted, nil
+}
+
+// ApplyDisplayCurrency fills DisplayPrice on each car. It is a no-op for BaseCurrency.
+func ApplyDisplayCurrency(cars []models.Car, currency string) error {
+	rate, err := GetExchangeRate(currency)
+	if err != nil {
+		return err
+	}
+	if rate.Currency == models.BaseCurrency {
+		return nil
+	}
+	for i := range cars {
+		display, errConv := ConvertFromBase(cars[i].PricePerDay, rate)
+		if errConv != nil {
+			return errConv
+		}
+		cars[i].DisplayPrice = &display
+	}
+	return nil
+}
+
+// GetExchangeRates lists all configured rates.
+func GetExchangeRates() ([]models.ExchangeRate, error) {
+	rates := []models.ExchangeRate{}
+	query := `SELECT currency, rate_to_base, updated_by_employee_id, updated_at FROM exchange_rates ORDER BY currency`
+	err := config.DB.Select(&rates, query)
+	if err != nil {
+		log.Println("❌ Error fetching exchange rates:", err)
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+func upsertExchangeRate(db sqlx.Queryer, currency string, rateToBase json.Number, employeeID int) (models.ExchangeRate, error) {
+	currency, err := NormalizeCurrency(currency)
+	if err != nil {
+		return models.ExchangeRate{}, err
+	}
+	if currency == models.BaseCurrency {
+		return models.ExchangeRate{}, fmt.Errorf("%w: the base currency %s always has rate 1", ErrInvalidCurrency, models.BaseCurrency)
+	}
+	if _, err = parseRate(rateToBase); err != nil {
+		return models.ExchangeRate{}, err
+	}
+	var rate models.ExchangeRate
+	query := `INSERT INTO exchange_rates (currency, rate_to_base, updated_by_employee_id, updated_at)
+			  VALUES ($1, $2, $3, NOW())
+			  ON CONFLICT (currency) DO UPDATE SET rate_to_base = EXCLUDED.rate_to_base,
+				updated_by_employee_id = EXCLUDED.updated_by_employee_id, updated_at = NOW()
+			  RETURNING currency, rate_to_base, updated_by_employee_id, updated_at`
+	err = db.QueryRowx(query, currency, rateToBase.String(), employeeID).StructScan(&rate)
+	if err != nil {
+		return models.ExchangeRate{}, fmt.Errorf("failed to save exchange rate for %s: %w", currency, err)
+	}
+	return rate, nil
+}
+
+// SetExchangeRate creates or replaces the rate for one currency.
+func SetExchangeRate(currency string, rateToBase json.Number, employeeID int) (models.ExchangeRate, error) {
+	rate, err := upsertExchangeRate(config.DB, currency, rateToBase, employeeID)
+	if err != nil {
+		log.Printf("❌ SetExchangeRate: %v", err)
+		return models.ExchangeRate{}, err
+	}
+	log.Printf("✅ Service: Exchange rate %s set to %s by employee %d", rate.Currency, rate.RateToBase, employeeID)
+	return rate, nil
+}
+
+// DeleteExchangeRate removes a currency so it can no longer be quoted or charged.
+func DeleteExchangeRate(currency string) error {
+	currency, err := NormalizeCurrency(currency)
+	if err != nil {
+		return err
+	}
+	result, err := config.DB.Exec("DELETE FROM exchange_rates WHERE currency = $1", currency)
+	if err != nil {
+		log.Printf("❌ DeleteExchangeRate: DB error deleting %s: %v", currency, err)
+		return fmt.Errorf("failed to delete exchange rate: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w '%s'", ErrUnknownCurrency, currency)
+	}
+	log.Printf("✅ Service: Exchange rate for %s deleted", currency)
+	return nil
+}
+
+// ImportExchangeRatesCSV upserts rates from CSV rows of "currency,rate_to_base".
+// A header row is skipped if present. The import is all-or-nothing.
+func ImportExchangeRatesCSV(reader io.Reader, employeeID int) (imported []models.ExchangeRate, err error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = 2
+	csvReader.TrimLeadingSpace = true
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not read CSV: %v", ErrInvalidExchangeRate, err)
+	}
+	firstLine := 1
+	if len(records) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), "currency") {
+		records = records[1:]
+		firstLine = 2
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: CSV contains no rates", ErrInvalidExchangeRate)
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			log.Printf("❌ Rolling back exchange rate import due to error: %v", err)
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+			if err != nil {
+				log.Printf("❌ Error committing exchange rate import: %v", err)
+				imported = nil
+			}
+		}
+	}()
+
+	for i, record := range records {
+		rate, errRow := upsertExchangeRate(tx, record[0], json.Number(strings.TrimSpace(record[1])), employeeID)
+		if errRow != nil {
+			err = fmt.Errorf("line %d: %w", firstLine+i, errRow)
+			return nil, err
+		}
+		imported = append(imported, rate)
+	}
+	log.Printf("✅ Service: Imported %d exchange rates by employee %d", len(imported), employeeID)
+	return imported, nil
+}
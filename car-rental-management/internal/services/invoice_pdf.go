@@ -0,0 +1,204 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/pdf"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+const (
+	invoiceMarginLeft  = 50.0
+	invoiceMarginRight = pdf.PageWidth - 50.0
+)
+
+var (
+	invoiceFontsOnce   sync.Once
+	invoiceRegularFont *pdf.TrueTypeFont
+	invoiceBoldFont    *pdf.TrueTypeFont
+)
+
+// invoiceFonts loads the configured invoice fonts the first time they are needed. A font that
+// is not configured or cannot be loaded is left nil, and Helvetica is used instead.
+func invoiceFonts() (regular, bold *pdf.TrueTypeFont) {
+	invoiceFontsOnce.Do(func() {
+		regularFile, boldFile := config.InvoiceFontFiles()
+		if regularFile == "" {
+			log.Println("⚠️ INVOICE_FONT_FILE is not set: invoices use Helvetica and print Thai as '?'")
+			return
+		}
+		var err error
+		if invoiceRegularFont, err = pdf.LoadTrueType(regularFile); err != nil {
+			log.Printf("⚠️ Cannot load invoice font %s, falling back to Helvetica: %v", regularFile, err)
+			return
+		}
+		if boldFile != "" {
+			if invoiceBoldFont, err = pdf.LoadTrueType(boldFile); err != nil {
+				log.Printf("⚠️ Cannot load bold invoice font %s, using the regular one: %v", boldFile, err)
+			}
+		}
+	})
+	return invoiceRegularFont, invoiceBoldFont
+}
+
+// RenderInvoicePDF lays out a tax invoice or receipt on a single A4 page.
+func RenderInvoicePDF(doc models.InvoiceDocument) []byte {
+	invoice := doc.Invoice
+	company := config.Company()
+
+	title := "TAX INVOICE"
+	if invoice.Kind == models.InvoiceKindReceipt {
+		title = "RECEIPT"
+	}
+	document := pdf.New(title + " " + invoice.InvoiceNumber)
+	if regular, bold := invoiceFonts(); regular != nil {
+		document.UseTrueType(regular, bold)
+	}
+	page := document.AddPage()
+
+	// Seller
+	y := 60.0
+	page.Text(invoiceMarginLeft, y, pdf.HelveticaBold, 14, company.Name)
+	y += 16
+	for _, line := range strings.Split(company.Address, "\n") {
+		if strings.TrimSpace(line) != "" {
+			page.Text(invoiceMarginLeft, y, pdf.Helvetica, 9, strings.TrimSpace(line))
+			y += 12
+		}
+	}
+	if company.TaxID != "" {
+		page.Text(invoiceMarginLeft, y, pdf.Helvetica, 9, "Tax ID: "+company.TaxID)
+		y += 12
+	}
+	if company.Phone != "" {
+		page.Text(invoiceMarginLeft, y, pdf.Helvetica, 9, "Tel: "+company.Phone)
+		y += 12
+	}
+	page.Text(invoiceMarginLeft, y, pdf.Helvetica, 9, fmt.Sprintf("Issued by branch: %s (%03d)", doc.BranchName, invoice.BranchID))
+	y += 12
+	if doc.BranchAddress != nil && *doc.BranchAddress != "" {
+		page.Text(invoiceMarginLeft, y, pdf.Helvetica, 9, strings.ReplaceAll(*doc.BranchAddress, "\n", ", "))
+		y += 12
+	}
+
+	// Document header
+	page.TextRight(invoiceMarginRight, 60, pdf.HelveticaBold, 18, title)
+	page.TextRight(invoiceMarginRight, 80, pdf.Helvetica, 10, "No. "+invoice.InvoiceNumber)
+	page.TextRight(invoiceMarginRight, 94, pdf.Helvetica, 10, "Date: "+invoice.IssuedAt.In(invoiceTimeZone).Format("02 Jan 2006"))
+	page.TextRight(invoiceMarginRight, 108, pdf.Helvetica, 9, "Original")
+
+	y += 10
+	page.Line(invoiceMarginLeft, y, invoiceMarginRight, y, 0.5)
+	y += 18
+
+	// Buyer and rental
+	page.Text(invoiceMarginLeft, y, pdf.HelveticaBold, 10, "Buyer")
+	page.Text(320, y, pdf.HelveticaBold, 10, "Rental")
+	y += 14
+	buyer := []string{doc.BuyerName}
+	if doc.BuyerAddress != nil {
+		for _, line := range strings.Split(*doc.BuyerAddress, "\n") {
+			if strings.TrimSpace(line) != "" {
+				buyer = append(buyer, strings.TrimSpace(line))
+			}
+		}
+	}
+	if doc.BuyerTaxID != nil && *doc.BuyerTaxID != "" {
+		buyer = append(buyer, "Tax ID: "+*doc.BuyerTaxID)
+	}
+	buyer = append(buyer, doc.BuyerEmail)
+	if doc.CorporateBuyer {
+		buyer = append(buyer, "Rented by: "+doc.CustomerName)
+	} else if doc.CustomerPhone != nil && *doc.CustomerPhone != "" {
+		buyer = append(buyer, "Tel: "+*doc.CustomerPhone)
+	}
+	rental := []string{
+		fmt.Sprintf("Rental #%d: %s", invoice.RentalID, doc.CarDescription),
+		"Pickup:   " + doc.PickupDatetime.In(invoiceTimeZone).Format("02 Jan 2006 15:04"),
+		"Drop-off: " + doc.DropoffDatetime.In(invoiceTimeZone).Format("02 Jan 2006 15:04"),
+	}
+	for i := 0; i < max(len(buyer), len(rental)); i++ {
+		if i < len(buyer) {
+			page.Text(invoiceMarginLeft, y, pdf.Helvetica, 9, buyer[i])
+		}
+		if i < len(rental) {
+			page.Text(320, y, pdf.Helvetica, 9, rental[i])
+		}
+		y += 12
+	}
+	y += 14
+
+	// Itemised charges
+	const (
+		colQuantity  = 360.0
+		colUnitPrice = 450.0
+	)
+	page.Line(invoiceMarginLeft, y-12, invoiceMarginRight, y-12, 0.5)
+	page.Text(invoiceMarginLeft, y, pdf.HelveticaBold, 9, "Description")
+	page.TextRight(colQuantity, y, pdf.HelveticaBold, 9, "Days / Qty")
+	page.TextRight(colUnitPrice, y, pdf.HelveticaBold, 9, "Unit price")
+	page.TextRight(invoiceMarginRight, y, pdf.HelveticaBold, 9, "Amount (THB)")
+	y += 6
+	page.Line(invoiceMarginLeft, y, invoiceMarginRight, y, 0.5)
+	y += 14
+	for _, line := range doc.Lines {
+		page.Text(invoiceMarginLeft, y, pdf.Helvetica, 9, line.Description)
+		page.TextRight(colQuantity, y, pdf.Helvetica, 9, fmt.Sprintf("%d", line.Quantity))
+		page.TextRight(colUnitPrice, y, pdf.Helvetica, 9, formatAmount(line.UnitPrice))
+		page.TextRight(invoiceMarginRight, y, pdf.Helvetica, 9, formatAmount(line.Amount))
+		y += 14
+	}
+	page.Line(invoiceMarginLeft, y-6, invoiceMarginRight, y-6, 0.5)
+	y += 10
+
+	// VAT breakdown
+	totals := []struct {
+		label  string
+		amount models.Money
+		font   pdf.Font
+	}{
+		{"Total before VAT", invoice.NetAmount, pdf.Helvetica},
+		{fmt.Sprintf("VAT %d%%", vatRateNumerator*100/vatRateDenominator), invoice.VATAmount, pdf.Helvetica},
+		{"Grand total", invoice.TotalAmount, pdf.HelveticaBold},
+	}
+	for _, total := range totals {
+		page.TextRight(colUnitPrice, y, total.font, 10, total.label)
+		page.TextRight(invoiceMarginRight, y, total.font, 10, formatAmount(total.amount))
+		y += 15
+	}
+
+	y += 20
+	if invoice.Kind == models.InvoiceKindReceipt {
+		page.Text(invoiceMarginLeft, y, pdf.Helvetica, 10, fmt.Sprintf("Received with thanks the sum of THB %s by %s.",
+			formatAmount(invoice.TotalAmount), strings.Join(doc.PaymentMethods, ", ")))
+	} else {
+		page.Text(invoiceMarginLeft, y, pdf.Helvetica, 10, "Paid by: "+strings.Join(doc.PaymentMethods, ", "))
+	}
+
+	page.Line(380, 760, invoiceMarginRight, 760, 0.5)
+	page.TextRight(invoiceMarginRight, 774, pdf.Helvetica, 9, "Authorised signature")
+	page.Text(invoiceMarginLeft, 810, pdf.Helvetica, 7, "This document was generated electronically.")
+
+	return document.Bytes()
+}
+
+// formatAmount renders money with thousands separators, e.g. 12,345.67.
+func formatAmount(amount models.Money) string {
+	text := amount.String()
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, frac, _ := strings.Cut(text, ".")
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + "." + frac
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInvoiceNotAvailable = errors.New("rental has no paid payment to invoice")
+
+// Invoice years follow Thai local time so numbering resets at midnight on 1 January in Bangkok.
+var invoiceTimeZone = time.FixedZone("ICT", 7*60*60)
+
+const invoiceColumns = `id, rental_id, kind, branch_id, year, sequence_number, invoice_number,
+	net_amount, vat_amount, total_amount, issued_by_employee_id, issued_at`
+
+func invoiceNumberPrefix(kind string) string {
+	if kind == models.InvoiceKindReceipt {
+		return "RCT"
+	}
+	return "INV"
+}
+
+// nextInvoiceSequence allocates the next number in a sequence. The row stays locked until tx
+// ends, and a rollback returns the number, which keeps the sequence gap-free.
+func nextInvoiceSequence(tx *sqlx.Tx, branchID, year int, kind string) (int, error) {
+	var sequenceNumber int
+	sequenceQuery := `INSERT INTO invoice_sequences (branch_id, year, kind, last_number) VALUES ($1, $2, $3, 1)
+		ON CONFLICT (branch_id, year, kind) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number`
+	err := tx.Get(&sequenceNumber, sequenceQuery, branchID, year, kind)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate invoice number: %w", err)
+	}
+	return sequenceNumber, nil
+}
+
+// GetOrIssueInvoice returns the rental's invoice of the given kind, issuing it on first request.
+// Numbers come from invoice_sequences inside the same transaction as the invoice row, so a
+// failed issue rolls its number back and the sequence stays gap-free per branch per year.
+func GetOrIssueInvoice(rentalID int, kind string, employeeID *int) (invoice models.Invoice, err error) {
+	if rentalID <= 0 {
+		return models.Invoice{}, errors.New("invalid rental ID")
+	}
+	if kind != models.InvoiceKindTaxInvoice && kind != models.InvoiceKindReceipt {
+		return models.Invoice{}, fmt.Errorf("invalid invoice kind '%s'", kind)
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.Invoice{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+			if err != nil {
+				log.Printf("❌ GetOrIssueInvoice: Error committing invoice for rental %d: %v", rentalID, err)
+				invoice = models.Invoice{}
+			}
+		}
+	}()
+
+	// Locking the rental serialises concurrent first requests for the same document.
+	var branchID int
+	err = tx.Get(&branchID, `SELECT c.branch_id FROM rentals r JOIN cars c ON r.car_id = c.id WHERE r.id = $1 FOR UPDATE OF r`, rentalID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Invoice{}, ErrRentalNotFound
+		}
+		return models.Invoice{}, fmt.Errorf("failed to lock rental %d: %w", rentalID, err)
+	}
+
+	err = tx.Get(&invoice, `SELECT `+invoiceColumns+` FROM invoices WHERE rental_id = $1 AND kind = $2`, rentalID, kind)
+	if err == nil {
+		return invoice, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Invoice{}, fmt.Errorf("failed to look up invoice: %w", err)
+	}
+
+	var paidTotal models.Money
+	err = tx.Get(&paidTotal, `SELECT COALESCE(SUM(amount), 0) FROM payments WHERE rental_id = $1 AND payment_status = 'Paid'`, rentalID)
+	if err != nil {
+		return models.Invoice{}, fmt.Errorf("failed to total payments: %w", err)
+	}
+	if !paidTotal.IsPositive() {
+		return models.Invoice{}, ErrInvoiceNotAvailable
+	}
+	net, vat := splitVATInclusive(paidTotal)
+
+	year := time.Now().In(invoiceTimeZone).Year()
+	sequenceNumber, err := nextInvoiceSequence(tx, branchID, year, kind)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+
+	invoiceNumber := fmt.Sprintf("%s-%03d-%d-%06d", invoiceNumberPrefix(kind), branchID, year, sequenceNumber)
+	insertQuery := `INSERT INTO invoices (rental_id, kind, branch_id, year, sequence_number, invoice_number, net_amount, vat_amount, total_amount, issued_by_employee_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING ` + invoiceColumns
+	err = tx.Get(&invoice, insertQuery, rentalID, kind, branchID, year, sequenceNumber, invoiceNumber, net, vat, paidTotal, employeeID)
+	if err != nil {
+		return models.Invoice{}, fmt.Errorf("failed to issue invoice: %w", err)
+	}
+	log.Printf("🧾 Service: Issued %s %s for rental %d (total %s)", kind, invoiceNumber, rentalID, paidTotal)
+	return invoice, nil
+}
+
+// GetInvoiceDocument gathers everything printed on a rental's invoice or receipt.
+func GetInvoiceDocument(rentalID int, kind string, employeeID *int) (models.InvoiceDocument, error) {
+	invoice, err := GetOrIssueInvoice(rentalID, kind, employeeID)
+	if err != nil {
+		return models.InvoiceDocument{}, err
+	}
+
+	var details struct {
+		Pickup           time.Time    `db:"pickup_datetime"`
+		Dropoff          time.Time    `db:"dropoff_datetime"`
+		CustomerName     string       `db:"customer_name"`
+		CustomerEmail    string       `db:"customer_email"`
+		CustomerPhone    *string      `db:"customer_phone"`
+		CustomerAddress  *string      `db:"customer_billing_address"`
+		CustomerTaxID    *string      `db:"customer_tax_id"`
+		CorporateName    *string      `db:"corporate_name"`
+		CorporateAddress *string      `db:"corporate_billing_address"`
+		CorporateTaxID   *string      `db:"corporate_tax_id"`
+		CorporateEmail   *string      `db:"corporate_billing_email"`
+		CarBrand         string       `db:"car_brand"`
+		CarModel         string       `db:"car_model"`
+		PricePerDay      models.Money `db:"booked_price_per_day"`
+		BranchName       *string      `db:"branch_name"`
+		BranchAddress    *string      `db:"branch_address"`
+		BranchPhone      *string      `db:"branch_phone"`
+	}
+	query := `
+		SELECT r.pickup_datetime, r.dropoff_datetime,
+			cu.name AS customer_name, cu.email AS customer_email, cu.phone AS customer_phone,
+			cu.billing_address AS customer_billing_address, cu.tax_id AS customer_tax_id,
+			ca.name AS corporate_name, ca.billing_address AS corporate_billing_address,
+			ca.tax_id AS corporate_tax_id, ca.billing_email AS corporate_billing_email,
+			c.brand AS car_brand, c.model AS car_model, r.booked_price_per_day,
+			b.name AS branch_name, b.address AS branch_address, b.phone AS branch_phone
+		FROM rentals r
+		JOIN customers cu ON r.customer_id = cu.id
+		JOIN cars c ON r.car_id = c.id
+		LEFT JOIN corporate_accounts ca ON ca.id = r.corporate_account_id
+		LEFT JOIN branches b ON b.id = $2
+		WHERE r.id = $1`
+	err = config.DB.Get(&details, query, rentalID, invoice.BranchID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.InvoiceDocument{}, ErrRentalNotFound
+		}
+		log.Printf("❌ GetInvoiceDocument: DB error fetching details for rental %d: %v", rentalID, err)
+		return models.InvoiceDocument{}, fmt.Errorf("failed to fetch invoice details: %w", err)
+	}
+	if details.CustomerEmail, err = decryptPII(piiCustomerEmail, details.CustomerEmail); err != nil {
+		return models.InvoiceDocument{}, err
+	}
+	if details.CustomerPhone, err = decryptPIIPtr(piiCustomerPhone, details.CustomerPhone); err != nil {
+		return models.InvoiceDocument{}, err
+	}
+	if details.CustomerTaxID, err = decryptPIIPtr(piiCustomerTaxID, details.CustomerTaxID); err != nil {
+		return models.InvoiceDocument{}, err
+	}
+
+	doc := models.InvoiceDocument{
+		Invoice:         invoice,
+		BranchName:      fmt.Sprintf("Branch %d", invoice.BranchID),
+		BranchAddress:   details.BranchAddress,
+		BranchPhone:     details.BranchPhone,
+		BuyerName:       details.CustomerName,
+		BuyerAddress:    details.CustomerAddress,
+		BuyerTaxID:      details.CustomerTaxID,
+		BuyerEmail:      details.CustomerEmail,
+		CustomerName:    details.CustomerName,
+		CustomerEmail:   details.CustomerEmail,
+		CustomerPhone:   details.CustomerPhone,
+		CarDescription:  details.CarBrand + " " + details.CarModel,
+		PickupDatetime:  details.Pickup,
+		DropoffDatetime: details.Dropoff,
+	}
+	if details.BranchName != nil {
+		doc.BranchName = *details.BranchName
+	}
+	if details.CorporateName != nil {
+		doc.CorporateBuyer = true
+		doc.BuyerName = *details.CorporateName
+		doc.BuyerAddress = details.CorporateAddress
+		doc.BuyerTaxID = details.CorporateTaxID
+		if details.CorporateEmail != nil {
+			doc.BuyerEmail = *details.CorporateEmail
+		}
+	}
+
+	// Itemise the rental at the daily rate it was booked at; anything the customer actually paid beyond
+	// (or short of) that shows as an adjustment so the lines always add up to the invoiced net.
+	days := billableRentalDays(details.Pickup, details.Dropoff)
+	rentalCharge := details.PricePerDay.MulInt(int64(days))
+	doc.Lines = append(doc.Lines, models.InvoiceLine{
+		Description: "Car rental: " + doc.CarDescription,
+		Quantity:    days,
+		UnitPrice:   details.PricePerDay,
+		Amount:      rentalCharge,
+	})
+	if adjustment := invoice.NetAmount.Sub(rentalCharge); !adjustment.IsZero() {
+		doc.Lines = append(doc.Lines, models.InvoiceLine{Description: "Adjustment", Quantity: 1, UnitPrice: adjustment, Amount: adjustment})
+	}
+
+	err = config.DB.Select(&doc.PaymentMethods, `SELECT DISTINCT COALESCE(payment_method, 'Unspecified') FROM payments
+		WHERE rental_id = $1 AND payment_status = 'Paid' ORDER BY 1`, rentalID)
+	if err != nil {
+		log.Printf("❌ GetInvoiceDocument: DB error fetching payment methods for rental %d: %v", rentalID, err)
+		return models.InvoiceDocument{}, fmt.Errorf("failed to fetch payment methods: %w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Thai VAT is 7%. VAT amounts are rounded half-up to the satang; when a VAT-inclusive
+// total is split, the VAT part is rounded and the net part takes the remainder so the
+// two always add back up to the total exactly.
+const (
+	vatRateNumerator   = 7
+	vatRateDenominator = 100
+)
+
+// netRevenueCondition restricts ledger entries (aliased "e") to the accounts that make up
+// net rental revenue. Summing credit - debit over them gives revenue net of refunds and VAT.
+const netRevenueCondition = `e.account_code IN ('rental_revenue', 'refunds')`
+
+var ErrLedgerUnbalanced = errors.New("ledger transaction is not balanced")
+
+// vatOnNet returns the VAT due on a net (VAT-exclusive) amount.
+func vatOnNet(net models.Money) models.Money {
+	return net.MulRatio(vatRateNumerator, vatRateDenominator, models.RoundHalfUp)
+}
+
+// splitVATInclusive splits a VAT-inclusive total into its net and VAT parts.
+func splitVATInclusive(total models.Money) (net models.Money, vat models.Money) {
+	vat = total.MulRatio(vatRateNumerator, vatRateDenominator+vatRateNumerator, models.RoundHalfUp)
+	return total.Sub(vat), vat
+}
+
+// postLedgerTransaction validates and appends a balanced transaction inside tx.
+func postLedgerTransaction(tx *sqlx.Tx, txn models.LedgerTransaction) (models.LedgerTransaction, error) {
+	if len(txn.Entries) < 2 {
+		return txn, fmt.Errorf("ledger transaction '%s' needs at least two entries: %w", txn.Kind, ErrLedgerUnbalanced)
+	}
+	var debits, credits models.Money
+	for _, entry := range txn.Entries {
+		if entry.Debit.IsNegative() || entry.Credit.IsNegative() || entry.Debit.IsZero() == entry.Credit.IsZero() {
+			return txn, fmt.Errorf("invalid ledger entry on account '%s': exactly one of debit/credit must be positive", entry.AccountCode)
+		}
+		debits = debits.Add(entry.Debit)
+		credits = credits.Add(entry.Credit)
+	}
+	if debits.Cmp(credits) != 0 {
+		log.Printf("❌ postLedgerTransaction: Unbalanced '%s' transaction (debits %s, credits %s)", txn.Kind, debits, credits)
+		return txn, fmt.Errorf("debits %s != credits %s: %w", debits, credits, ErrLedgerUnbalanced)
+	}
+
+	insertTxnQuery := `INSERT INTO ledger_transactions (rental_id, payment_id, kind, description, created_by_employee_id)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	err := tx.QueryRowx(insertTxnQuery, txn.RentalID, txn.PaymentID, txn.Kind, txn.Description, txn.CreatedByEmployeeID).Scan(&txn.ID, &txn.CreatedAt)
+	if err != nil {
+		return txn, fmt.Errorf("failed to insert ledger transaction: %w", err)
+	}
+	for i := range txn.Entries {
+		txn.Entries[i].TransactionID = txn.ID
+		err = tx.QueryRowx(`INSERT INTO ledger_entries (transaction_id, account_code, debit, credit) VALUES ($1, $2, $3, $4) RETURNING id`,
+			txn.ID, txn.Entries[i].AccountCode, txn.Entries[i].Debit, txn.Entries[i].Credit).Scan(&txn.Entries[i].ID)
+		if err != nil {
+			return txn, fmt.Errorf("failed to insert ledger entry for account '%s': %w", txn.Entries[i].AccountCode, err)
+		}
+	}
+	log.Printf("📒 Ledger: Posted '%s' transaction %d (rental %v, payment %v)", txn.Kind, txn.ID, derefInt(txn.RentalID), derefInt(txn.PaymentID))
+	return txn, nil
+}
+
+func derefInt(value *int) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// postRentalCharge recognises revenue and VAT for a rental against the customer receivable.
+// paymentID is nil for rentals billed on a corporate invoice.
+func postRentalCharge(tx *sqlx.Tx, rentalID int, paymentID *int, total models.Money, employeeID *int) error {
+	net, vat := splitVATInclusive(total)
+	entries := []models.LedgerEntry{
+		{AccountCode: models.AccountCustomerReceivable, Debit: total},
+		{AccountCode: models.AccountRentalRevenue, Credit: net},
+	}
+	if vat.IsPositive() {
+		entries = append(entries, models.LedgerEntry{AccountCode: models.AccountVATPayable, Credit: vat})
+	}
+	_, err := postLedgerTransaction(tx, models.LedgerTransaction{
+		RentalID: &rentalID, PaymentID: paymentID, Kind: "charge", CreatedByEmployeeID: employeeID,
+		Description: fmt.Sprintf("Rental %d charge (net %s + VAT %s)", rentalID, net, vat),
+		Entries:     entries,
+	})
+	return err
+}
+
+// postPaymentReceipt settles the customer receivable with cash received directly.
+func postPaymentReceipt(tx *sqlx.Tx, rentalID, paymentID int, amount models.Money, employeeID *int) error {
+	_, err := postLedgerTransaction(tx, models.LedgerTransaction{
+		RentalID: &rentalID, PaymentID: &paymentID, Kind: "receipt", CreatedByEmployeeID: employeeID,
+		Description: fmt.Sprintf("Payment %d received for rental %d", paymentID, rentalID),
+		Entries: []models.LedgerEntry{
+			{AccountCode: models.AccountCash, Debit: amount},
+			{AccountCode: models.AccountCustomerReceivable, Credit: amount},
+		},
+	})
+	return err
+}
+
+// postCorporateInvoiceReceipt settles the receivable with money received against a corporate invoice.
+func postCorporateInvoiceReceipt(tx *sqlx.Tx, invoiceNumber string, amount models.Money, employeeID *int) error {
+	_, err := postLedgerTransaction(tx, models.LedgerTransaction{
+		Kind: "receipt", CreatedByEmployeeID: employeeID,
+		Description: fmt.Sprintf("Payment received for corporate invoice %s", invoiceNumber),
+		Entries: []models.LedgerEntry{
+			{AccountCode: models.AccountCash, Debit: amount},
+			{AccountCode: models.AccountCustomerReceivable, Credit: amount},
+		},
+	})
+	return err
+}
+
+// postSlipDeposit records an uploaded transfer slip as money held pending verification.
+func postSlipDeposit(tx *sqlx.Tx, rentalID, paymentID int, amount models.Money) error {
+	_, err := postLedgerTransaction(tx, models.LedgerTransaction{
+		RentalID: &rentalID, PaymentID: &paymentID, Kind: "deposit",
+		Description: fmt.Sprintf("Transfer slip for rental %d held pending verification", rentalID),
+		Entries: []models.LedgerEntry{
+			{AccountCode: models.AccountCash, Debit: amount},
+			{AccountCode: models.AccountDepositsHeld, Credit: amount},
+		},
+	})
+	return err
+}
+
+// postDepositApplied moves a verified deposit onto the customer receivable.
+func postDepositApplied(tx *sqlx.Tx, rentalID, paymentID int, amount models.Money, employeeID *int) error {
+	_, err := postLedgerTransaction(tx, models.LedgerTransaction{
+		RentalID: &rentalID, PaymentID: &paymentID, Kind: "deposit_release", CreatedByEmployeeID: employeeID,
+		Description: fmt.Sprintf("Verified slip applied to rental %d", rentalID),
+		Entries: []models.LedgerEntry{
+			{AccountCode: models.AccountDepositsHeld, Debit: amount},
+			{AccountCode: models.AccountCustomerReceivable, Credit: amount},
+		},
+	})
+	return err
+}
+
+// postDepositReturned gives back a deposit whose slip was rejected.
+func postDepositReturned(tx *sqlx.Tx, rentalID, paymentID int, amount models.Money, employeeID *int) error {
+	_, err := postLedgerTransaction(tx, models.LedgerTransaction{
+		RentalID: &rentalID, PaymentID: &paymentID, Kind: "deposit_return", CreatedByEmployeeID: employeeID,
+		Description: fmt.Sprintf("Rejected slip for rental %d returned", rentalID),
+		Entries: []models.LedgerEntry{
+			{AccountCode: models.AccountDepositsHeld, Debit: amount},
+			{AccountCode: models.AccountCash, Credit: amount},
+		},
+	})
+	return err
+}
+
+// postRefund reverses revenue and VAT for money paid back to the customer.
+func postRefund(tx *sqlx.Tx, rentalID, paymentID int, total models.Money, employeeID *int) error {
+	net, vat := splitVATInclusive(total)
+	entries := []models.LedgerEntry{
+		{AccountCode: models.AccountRefunds, Debit: net},
+		{AccountCode: models.AccountCash, Credit: total},
+	}
+	if vat.IsPositive() {
+		entries = append(entries, models.LedgerEntry{AccountCode: models.AccountVATPayable, Debit: vat})
+	}
+	_, err := postLedgerTransaction(tx, models.LedgerTransaction{
+		RentalID: &rentalID, PaymentID: &paymentID, Kind: "refund", CreatedByEmployeeID: employeeID,
+		Description: fmt.Sprintf("Refund of payment %d for rental %d (net %s + VAT %s)", paymentID, rentalID, net, vat),
+		Entries:     entries,
+	})
+	return err
+}
+
+// RefundPayment fully refunds a Paid payment and posts the reversing ledger entries.
+func RefundPayment(paymentID int, employeeID int) (payment models.Payment, err error) {
+	log.Printf("Service: Refunding payment %d by employee %d", paymentID, employeeID)
+	if paymentID <= 0 || employeeID <= 0 {
+		return models.Payment{}, errors.New("invalid payment or employee ID")
+	}
+
+	tx, errTx := config.DB.Beginx()
+	if errTx != nil {
+		log.Printf("❌ RefundPayment: Failed to begin transaction: %v", errTx)
+		return models.Payment{}, fmt.Errorf("database transaction error: %w", errTx)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			log.Printf("❌ Rolling back RefundPayment tx due to error: %v", err)
+			_ = tx.Rollback()
+		} else {
+			commitErr := tx.Commit()
+			if commitErr != nil {
+				log.Printf("❌ Error committing RefundPayment tx: %v", commitErr)
+				err = fmt.Errorf("commit error: %w", commitErr)
+			} else {
+				log.Println("✅ RefundPayment tx committed.")
+			}
+		}
+	}()
+
+	query := "SELECT id, rental_id, amount, payment_date, payment_status, payment_method, recorded_by_employee_id, transaction_id, slip_url, currency, charged_amount, exchange_rate, created_at, updated_at FROM payments WHERE id=$1 FOR UPDATE"
+	err = tx.Get(&payment, query, paymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = errors.New("payment not found")
+		} else {
+			err = fmt.Errorf("database error fetching payment: %w", err)
+		}
+		return models.Payment{}, err
+	}
+	labelChargedAmount(&payment)
+	if payment.PaymentStatus != "Paid" {
+		err = fmt.Errorf("cannot refund payment in status '%s': %w", payment.PaymentStatus, ErrInvalidState)
+		return models.Payment{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE payments SET payment_status = 'Refunded', recorded_by_employee_id = $1, updated_at = NOW() WHERE id = $2`, employeeID, paymentID)
+	if err != nil {
+		err = fmt.Errorf("database error updating payment: %w", err)
+		return models.Payment{}, err
+	}
+	err = postRefund(tx, payment.RentalID, payment.ID, payment.Amount, &employeeID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	err = reverseLoyaltyPoints(tx, payment.RentalID, payment.Amount)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	payment.PaymentStatus = "Refunded"
+	payment.RecordedByEmployeeID = &employeeID
+	log.Printf("✅ Service: Payment %d refunded (%s)", paymentID, payment.Amount)
+	return payment, nil
+}
+
+// GetTrialBalance aggregates all ledger entries posted up to and including asOf.
+func GetTrialBalance(asOf time.Time) (models.TrialBalance, error) {
+	log.Printf("⚙️ Service: Building trial balance as of %s", asOf.Format(time.RFC3339))
+	result := models.TrialBalance{AsOf: asOf, Lines: []models.TrialBalanceLine{}}
+	query := `
+		SELECT
+			a.code AS account_code,
+			a.name AS account_name,
+			a.type AS account_type,
+			COALESCE(SUM(e.debit), 0) AS total_debits,
+			COALESCE(SUM(e.credit), 0) AS total_credits,
+			CASE WHEN a.type = 'asset' OR a.type = 'contra_revenue'
+				THEN COALESCE(SUM(e.debit), 0) - COALESCE(SUM(e.credit), 0)
+				ELSE COALESCE(SUM(e.credit), 0) - COALESCE(SUM(e.debit), 0)
+			END AS balance
+		FROM ledger_accounts a
+		LEFT JOIN ledger_entries e ON e.account_code = a.code
+			AND e.transaction_id IN (SELECT id FROM ledger_transactions WHERE created_at <= $1)
+		GROUP BY a.code, a.name, a.type
+		ORDER BY a.code ASC`
+	err := config.DB.Select(&result.Lines, query, asOf)
+	if err != nil {
+		log.Printf("❌ Service: Error building trial balance: %v", err)
+		return result, fmt.Errorf("database error building trial balance: %w", err)
+	}
+
+	result.TotalDebits = models.NewMoney(0, models.BaseCurrency)
+	result.TotalCredits = models.NewMoney(0, models.BaseCurrency)
+	for _, line := range result.Lines {
+		result.TotalDebits = result.TotalDebits.Add(line.TotalDebits)
+		result.TotalCredits = result.TotalCredits.Add(line.TotalCredits)
+	}
+	result.Balanced = result.TotalDebits.Cmp(result.TotalCredits) == 0
+	if !result.Balanced {
+		log.Printf("🚨 Service: Trial balance does NOT balance (debits %s, credits %s)", result.TotalDebits, result.TotalCredits)
+	}
+	return result, nil
+}
+
+// GetLedgerTransactionsByRental returns every posting linked to a rental, oldest first.
+func GetLedgerTransactionsByRental(rentalID int) ([]models.LedgerTransaction, error) {
+	if rentalID <= 0 {
+		return nil, errors.New("invalid rental ID")
+	}
+	transactions := []models.LedgerTransaction{}
+	query := `SELECT id, rental_id, payment_id, kind, description, created_by_employee_id, created_at
+		FROM ledger_transactions WHERE rental_id=$1 ORDER BY id ASC`
+	if err := config.DB.Select(&transactions, query, rentalID); err != nil {
+		return nil, fmt.Errorf("failed to fetch ledger transactions for rental %d: %w", rentalID, err)
+	}
+	if len(transactions) == 0 {
+		return transactions, nil
+	}
+
+	ids := make([]int, len(transactions))
+	byID := make(map[int]int, len(transactions))
+	for i, txn := range transactions {
+		ids[i] = txn.ID
+		byID[txn.ID] = i
+		transactions[i].Entries = []models.LedgerEntry{}
+	}
+	entriesQuery, args, err := sqlx.In(`SELECT id, transaction_id, account_code, debit, credit FROM ledger_entries WHERE transaction_id IN (?) ORDER BY id ASC`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build ledger entries query: %w", err)
+	}
+	var entries []models.LedgerEntry
+	if err := config.DB.Select(&entries, config.DB.Rebind(entriesQuery), args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch ledger entries for rental %d: %w", rentalID, err)
+	}
+	for _, entry := range entries {
+		idx := byID[entry.TransactionID]
+		transactions[idx].Entries = append(transactions[idx].Entries, entry)
+	}
+	return transactions, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"car-rental-management/internal/models"
+	"testing"
+)
+
+func TestSplitVATInclusive(t *testing.T) {
+	tests := []struct {
+		total, net, vat int64
+	}{
+		{10700, 10000, 700},
+		{107, 100, 7},
+		{0, 0, 0},
+		{1, 1, 0},    // 7/107 of a satang rounds to nothing
+		{8, 7, 1},    // 0.523 satang of VAT rounds up
+		{100, 93, 7}, // 6.54 satang
+		{-10700, -10000, -700},
+	}
+	for _, tt := range tests {
+		net, vat := splitVATInclusive(models.NewMoney(tt.total, models.BaseCurrency))
+		if net.Amount != tt.net || vat.Amount != tt.vat {
+			t.Errorf("splitVATInclusive(%d) = %d + %d, want %d + %d", tt.total, net.Amount, vat.Amount, tt.net, tt.vat)
+		}
+	}
+}
+
+// Every total splits into parts that add back up to it, with VAT within a satang of 7% of the net.
+func TestSplitVATInclusiveSumsToTotal(t *testing.T) {
+	for total := int64(-5000); total <= 50000; total++ {
+		net, vat := splitVATInclusive(models.NewMoney(total, models.BaseCurrency))
+		if sum := net.Add(vat); sum.Amount != total {
+			t.Fatalf("splitVATInclusive(%d): %d + %d = %d", total, net.Amount, vat.Amount, sum.Amount)
+		}
+		if diff := vatOnNet(net).Amount - vat.Amount; diff < -1 || diff > 1 {
+			t.Fatalf("splitVATInclusive(%d): VAT %d is %d satang off 7%% of net %d", total, vat.Amount, diff, net.Amount)
+		}
+	}
+}
+
+func TestRentalChargeAddsVAT(t *testing.T) {
+	net, vat, total := rentalCharge(models.Baht(999, 99), 3)
+	if net.Amount != 299997 || vat.Amount != 21000 || total.Amount != 320997 {
+		t.Errorf("rentalCharge(999.99, 3) = %s + %s = %s", net, vat, total)
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Lockout policy. After lockoutThreshold consecutive failures an account is locked for
+// lockoutBaseDelay, doubling with every further failure up to lockoutMaxDelay. Failures are
+// tracked per email whether or not an account exists, so lockouts reveal nothing either.
+const (
+	lockoutThreshold = 5
+	lockoutBaseDelay = 1 * time.Minute
+	lockoutMaxDelay  = 1 * time.Hour
+
+	// A single address guessing across many accounts is throttled as a whole.
+	ipFailureLimit  = 20
+	ipFailureWindow = 15 * time.Minute
+
+	loginFailureBadCredentials = "bad_credentials"
+	loginFailureAccountLocked  = "account_locked"
+	loginFailureIPThrottled    = "ip_throttled"
+	loginFailureSuspended      = "account_suspended"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrLockoutNotFound    = errors.New("no lockout found for this account")
+)
+
+// LoginThrottledError is returned when a login is refused before the password is checked.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// normaliseLoginEmail is the key login attempts and lockouts are tracked under.
+func normaliseLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// lockoutDelay is how long to lock an account after failedCount consecutive failures.
+func lockoutDelay(failedCount int) time.Duration {
+	if failedCount < lockoutThreshold {
+		return 0
+	}
+	exponent := failedCount - lockoutThreshold
+	if exponent > 10 { // Far beyond the cap already; avoids overflowing the shift
+		return lockoutMaxDelay
+	}
+	delay := lockoutBaseDelay << exponent
+	if delay > lockoutMaxDelay {
+		return lockoutMaxDelay
+	}
+	return delay
+}
+
+// checkLoginAllowed refuses a login while the account is locked or the client IP has too many
+// recent failures. Refusals are recorded in the audit log like any other attempt.
+func checkLoginAllowed(userType, email, ip string) error {
+	email = normaliseLoginEmail(email)
+
+	var lockedUntil sql.NullTime
+	err := config.DB.Get(&lockedUntil, "SELECT locked_until FROM account_lockouts WHERE user_type = $1 AND email = $2", userType, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to check account lockout: %w", err)
+	}
+	if lockedUntil.Valid && lockedUntil.Time.After(time.Now()) {
+		log.Printf("🔒 Login refused for locked %s account %s from %s", userType, email, ip)
+		recordLoginAttempt(userType, email, ip, false, loginFailureAccountLocked)
+		return &LoginThrottledError{RetryAfter: time.Until(lockedUntil.Time)}
+	}
+
+	var ipFailures int
+	var oldestFailure sql.NullTime
+	err = config.DB.QueryRowx(`SELECT COUNT(*), MIN(attempted_at) FROM login_attempts
+		WHERE ip_address = $1 AND success = FALSE AND failure_reason = $2 AND attempted_at > $3`,
+		ip, loginFailureBadCredentials, time.Now().Add(-ipFailureWindow)).Scan(&ipFailures, &oldestFailure)
+	if err != nil {
+		return fmt.Errorf("failed to check login attempts for IP: %w", err)
+	}
+	if ipFailures >= ipFailureLimit {
+		retryAfter := ipFailureWindow
+		if oldestFailure.Valid {
+			retryAfter = time.Until(oldestFailure.Time.Add(ipFailureWindow))
+		}
+		log.Printf("🔒 Login refused for %s: %d failed attempts from %s in the last %s", email, ipFailures, ip, ipFailureWindow)
+		recordLoginAttempt(userType, email, ip, false, loginFailureIPThrottled)
+		return &LoginThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginAttempt writes the audit row and updates the account's failure count. A success
+// clears the count; a bad password may start or extend a lockout. Refusals only get an audit
+// row, so retrying while locked does not lengthen the lockout. Errors are logged, not returned:
+// a broken audit table must not stop people logging in.
+func recordLoginAttempt(userType, email, ip string, success bool, failureReason string) {
+	email = normaliseLoginEmail(email)
+	var reason *string
+	if !success {
+		reason = &failureReason
+	}
+	_, err := config.DB.Exec(`INSERT INTO login_attempts (user_type, email, ip_address, success, failure_reason)
+		VALUES ($1, $2, $3, $4, $5)`, userType, email, ip, success, reason)
+	if err != nil {
+		log.Printf("⚠️ Failed to record login attempt for %s %s: %v", userType, email, err)
+	}
+
+	if success {
+		if _, err = config.DB.Exec("DELETE FROM account_lockouts WHERE user_type = $1 AND email = $2", userType, email); err != nil {
+			log.Printf("⚠️ Failed to reset lockout for %s %s: %v", userType, email, err)
+		}
+		return
+	}
+	if failureReason != loginFailureBadCredentials {
+		return
+	}
+
+	var failedCount int
+	err = config.DB.Get(&failedCount, `INSERT INTO account_lockouts (user_type, email, failed_count, last_failed_at)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (user_type, email) DO UPDATE SET failed_count = account_lockouts.failed_count + 1, last_failed_at = NOW()
+		RETURNING failed_count`, userType, email)
+	if err != nil {
+		log.Printf("⚠️ Failed to update lockout for %s %s: %v", userType, email, err)
+		return
+	}
+	if delay := lockoutDelay(failedCount); delay > 0 {
+		_, err = config.DB.Exec("UPDATE account_lockouts SET locked_until = $1 WHERE user_type = $2 AND email = $3",
+			time.Now().Add(delay), userType, email)
+		if err != nil {
+			log.Printf("⚠️ Failed to lock %s %s: %v", userType, email, err)
+			return
+		}
+		log.Printf("🔒 Locked %s %s for %s after %d failed logins", userType, email, delay, failedCount)
+	}
+}
+
+// GetLoginAttempts returns the most recent login attempts, newest first, optionally filtered.
+func GetLoginAttempts(userType, email, ip string, failedOnly bool, limit int) ([]models.LoginAttempt, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	query := "SELECT id, user_type, email, ip_address, success, failure_reason, attempted_at FROM login_attempts WHERE 1=1"
+	args := []interface{}{}
+	if userType != "" {
+		args = append(args, userType)
+		query += fmt.Sprintf(" AND user_type = $%d", len(args))
+	}
+	if email != "" {
+		args = append(args, normaliseLoginEmail(email))
+		query += fmt.Sprintf(" AND email = $%d", len(args))
+	}
+	if ip != "" {
+		args = append(args, ip)
+		query += fmt.Sprintf(" AND ip_address = $%d", len(args))
+	}
+	if failedOnly {
+		query += " AND success = FALSE"
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY attempted_at DESC, id DESC LIMIT $%d", len(args))
+
+	attempts := []models.LoginAttempt{}
+	if err := config.DB.Select(&attempts, query, args...); err != nil {
+		log.Printf("❌ Service: Error fetching login attempts: %v", err)
+		return nil, fmt.Errorf("failed to fetch login attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// GetActiveLockouts lists accounts that are currently locked.
+func GetActiveLockouts() ([]models.AccountLockout, error) {
+	lockouts := []models.AccountLockout{}
+	err := config.DB.Select(&lockouts, `SELECT user_type, email, failed_count, locked_until, last_failed_at
+		FROM account_lockouts WHERE locked_until > NOW() ORDER BY locked_until DESC`)
+	if err != nil {
+		log.Printf("❌ Service: Error fetching account lockouts: %v", err)
+		return nil, fmt.Errorf("failed to fetch account lockouts: %w", err)
+	}
+	return lockouts, nil
+}
+
+// UnlockAccount clears an account's lockout and failure count.
+func UnlockAccount(userType, email string) error {
+	result, err := config.DB.Exec("DELETE FROM account_lockouts WHERE user_type = $1 AND email = $2", userType, normaliseLoginEmail(email))
+	if err != nil {
+		log.Printf("❌ Service: Error unlocking %s %s: %v", userType, email, err)
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrLockoutNotFound
+	}
+	log.Printf("✅ Service: Unlocked %s %s", userType, normaliseLoginEmail(email))
+	return nil
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInsufficientPoints      = errors.New("not enough loyalty points")
+	ErrRedemptionTooSmall      = fmt.Errorf("at least %d loyalty points must be redeemed at a time", loyaltyMinRedemption)
+	ErrRedemptionExceedsPrice  = errors.New("the points redeemed are worth more than the rental")
+	ErrLoyaltyCorporateBooking = errors.New("loyalty points cannot be redeemed on a corporate booking")
+)
+
+const (
+	loyaltyMinRedemption = 100
+
+	// Points expire this long after they are earned, as a PostgreSQL interval.
+	loyaltyPointValidity = "12 months"
+	// Tiers go by the points earned in this window.
+	loyaltyTierWindow = "12 months"
+	// Points about to expire are flagged this far ahead.
+	loyaltyExpiryWarning = "30 days"
+
+	loyaltyTransactionSelectColumns = `id, customer_id, rental_id, entry_type, points, remaining, expires_at, description,
+		created_by_employee_id, created_at`
+)
+
+var (
+	// loyaltyEarnUnit earns one point for each full amount of it paid.
+	loyaltyEarnUnit = models.Baht(10, 0)
+	// loyaltyPointValue is what one point takes off a booking.
+	loyaltyPointValue = models.Baht(0, 50)
+)
+
+// GetLoyaltyTiers lists the tiers, lowest first.
+func GetLoyaltyTiers() ([]models.LoyaltyTier, error) {
+	tiers := []models.LoyaltyTier{}
+	if err := config.DB.Select(&tiers, "SELECT code, name, min_points, discount_percent, free_upgrade FROM loyalty_tiers ORDER BY min_points"); err != nil {
+		return nil, fmt.Errorf("failed to fetch loyalty tiers: %w", err)
+	}
+	return tiers, nil
+}
+
+// loyaltyTierFor finds a customer's tier from the points they earned in the last 12 months. tier
+// is nil if no tier starts low enough.
+func loyaltyTierFor(q sqlx.Queryer, customerID int) (tier *models.LoyaltyTier, qualifyingPoints int, err error) {
+	err = sqlx.Get(q, &qualifyingPoints, `SELECT COALESCE(SUM(points), 0) FROM loyalty_transactions
+		WHERE customer_id = $1 AND entry_type = 'earn' AND created_at > NOW() - $2::interval`, customerID, loyaltyTierWindow)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count qualifying points: %w", err)
+	}
+	var found models.LoyaltyTier
+	err = sqlx.Get(q, &found, `SELECT code, name, min_points, discount_percent, free_upgrade FROM loyalty_tiers
+		WHERE min_points <= $1 ORDER BY min_points DESC LIMIT 1`, qualifyingPoints)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, qualifyingPoints, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch loyalty tier: %w", err)
+	}
+	return &found, qualifyingPoints, nil
+}
+
+func loyaltyBalance(q sqlx.Queryer, customerID int) (int, error) {
+	var balance int
+	if err := sqlx.Get(q, &balance, "SELECT COALESCE(SUM(points), 0) FROM loyalty_transactions WHERE customer_id = $1", customerID); err != nil {
+		return 0, fmt.Errorf("failed to fetch loyalty balance: %w", err)
+	}
+	return balance, nil
+}
+
+// expireLoyaltyPoints writes off what is left of lots past their expiry, for one customer or,
+// with a nil customerID, everyone.
+func expireLoyaltyPoints(e sqlx.Execer, customerID *int) (int64, error) {
+	result, err := e.Exec(`WITH lots AS (
+			SELECT id, customer_id, remaining FROM loyalty_transactions
+			WHERE remaining > 0 AND expires_at <= NOW() AND ($1::int IS NULL OR customer_id = $1)
+			FOR UPDATE
+		), cleared AS (
+			UPDATE loyalty_transactions t SET remaining = 0 FROM lots WHERE t.id = lots.id
+			RETURNING lots.id, lots.customer_id, lots.remaining
+		)
+		INSERT INTO loyalty_transactions (customer_id, entry_type, points, description)
+		SELECT customer_id, 'expire', -remaining, 'Expired: unspent points from entry #' || id FROM cleared`, customerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire loyalty points: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// StartLoyaltyPointExpiry expires points once a day in the background. Balances are also brought
+// up to date whenever they are read or spent, so this only keeps the ledger tidy in between.
+func StartLoyaltyPointExpiry() {
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			if expired, err := expireLoyaltyPoints(config.DB, nil); err != nil {
+				log.Printf("❌ Loyalty point expiry failed: %v", err)
+			} else if expired > 0 {
+				log.Printf("⌛ Expired %d loyalty point lots", expired)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// spendLoyaltyPoints takes up to points from a customer's lots, soonest expiring first, and
+// returns how many it took. The caller records the matching negative entry.
+func spendLoyaltyPoints(tx *sqlx.Tx, customerID, points int) (int, error) {
+	var lots []struct {
+		ID        int64 `db:"id"`
+		Remaining int   `db:"remaining"`
+	}
+	err := tx.Select(&lots, `SELECT id, remaining FROM loyalty_transactions
+		WHERE customer_id = $1 AND remaining > 0 AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY expires_at NULLS LAST, id FOR UPDATE`, customerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch loyalty points: %w", err)
+	}
+	left := points
+	for _, lot := range lots {
+		if left == 0 {
+			break
+		}
+		take := min(lot.Remaining, left)
+		if _, err := tx.Exec("UPDATE loyalty_transactions SET remaining = remaining - $1 WHERE id = $2", take, lot.ID); err != nil {
+			return 0, fmt.Errorf("failed to spend loyalty points: %w", err)
+		}
+		left -= take
+	}
+	return points - left, nil
+}
+
+// bookingLoyalty is what loyalty takes off a new booking.
+type bookingLoyalty struct {
+	Tier           *string
+	TierDiscount   models.Money
+	PointsRedeemed int
+	PointsDiscount models.Money
+	FreeUpgrade    bool
+}
+
+// loyaltyBookingDiscount works out a booking's tier discount and the value of the points the
+// customer wants to redeem, off net, the price before VAT. The points are spent by
+// redeemLoyaltyPoints once the rental exists.
+func loyaltyBookingDiscount(tx *sqlx.Tx, customerID int, net models.Money, redeemPoints int) (bookingLoyalty, error) {
+	discount := bookingLoyalty{TierDiscount: models.NewMoney(0, models.BaseCurrency), PointsDiscount: models.NewMoney(0, models.BaseCurrency)}
+	if _, err := expireLoyaltyPoints(tx, &customerID); err != nil {
+		return bookingLoyalty{}, err
+	}
+	tier, _, err := loyaltyTierFor(tx, customerID)
+	if err != nil {
+		return bookingLoyalty{}, err
+	}
+	if tier != nil {
+		discount.Tier = &tier.Code
+		discount.TierDiscount = net.MulRatio(int64(tier.DiscountPercent), 100, models.RoundDown)
+		discount.FreeUpgrade = tier.FreeUpgrade
+	}
+	if redeemPoints == 0 {
+		return discount, nil
+	}
+	if redeemPoints < loyaltyMinRedemption {
+		return bookingLoyalty{}, ErrRedemptionTooSmall
+	}
+	balance, err := loyaltyBalance(tx, customerID)
+	if err != nil {
+		return bookingLoyalty{}, err
+	}
+	if redeemPoints > balance {
+		return bookingLoyalty{}, ErrInsufficientPoints
+	}
+	discount.PointsRedeemed = redeemPoints
+	discount.PointsDiscount = loyaltyPointValue.MulInt(int64(redeemPoints))
+	if discount.PointsDiscount.Cmp(net.Sub(discount.TierDiscount)) > 0 {
+		return bookingLoyalty{}, ErrRedemptionExceedsPrice
+	}
+	return discount, nil
+}
+
+// redeemLoyaltyPoints spends points on a rental.
+func redeemLoyaltyPoints(tx *sqlx.Tx, customerID, rentalID, points int) error {
+	spent, err := spendLoyaltyPoints(tx, customerID, points)
+	if err != nil {
+		return err
+	}
+	if spent < points {
+		return ErrInsufficientPoints
+	}
+	_, err = tx.Exec(`INSERT INTO loyalty_transactions (customer_id, rental_id, entry_type, points, description)
+		VALUES ($1, $2, 'redeem', $3, $4)`, customerID, rentalID, -points, fmt.Sprintf("Redeemed on rental #%d", rentalID))
+	if err != nil {
+		return fmt.Errorf("failed to record redemption: %w", err)
+	}
+	log.Printf("🎁 Customer %d redeemed %d points on rental %d", customerID, points, rentalID)
+	return nil
+}
+
+// awardLoyaltyPoints credits a returned rental's points, one per loyaltyEarnUnit paid. Corporate
+// rentals are paid by invoice, not payments, and so earn nothing.
+func awardLoyaltyPoints(tx *sqlx.Tx, rentalID int) error {
+	var rental struct {
+		CustomerID int          `db:"customer_id"`
+		Paid       models.Money `db:"paid"`
+	}
+	err := tx.Get(&rental, `SELECT r.customer_id, COALESCE(SUM(p.amount), 0) AS paid
+		FROM rentals r LEFT JOIN payments p ON p.rental_id = r.id AND p.payment_status = 'Paid'
+		WHERE r.id = $1 GROUP BY r.customer_id`, rentalID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch rental payments: %w", err)
+	}
+	points := rental.Paid.Amount / loyaltyEarnUnit.Amount
+	if points <= 0 {
+		return nil
+	}
+	_, err = tx.Exec(`INSERT INTO loyalty_transactions (customer_id, rental_id, entry_type, points, remaining, expires_at, description)
+		VALUES ($1, $2, 'earn', $3, $3, NOW() + $4::interval, $5)
+		ON CONFLICT (rental_id, entry_type) WHERE entry_type IN ('earn', 'reinstate') DO NOTHING`,
+		rental.CustomerID, rentalID, points, loyaltyPointValidity, fmt.Sprintf("Earned on rental #%d (%s paid)", rentalID, rental.Paid.Display()))
+	if err != nil {
+		return fmt.Errorf("failed to award loyalty points: %w", err)
+	}
+	log.Printf("⭐ Customer %d earned %d points on rental %d", rental.CustomerID, points, rentalID)
+	return nil
+}
+
+// reinstateLoyaltyPoints gives back the points spent on a rental that was cancelled or failed, as
+// a new lot with a full validity period.
+func reinstateLoyaltyPoints(tx *sqlx.Tx, rentalID int) error {
+	var rental struct {
+		CustomerID     int `db:"customer_id"`
+		PointsRedeemed int `db:"points_redeemed"`
+	}
+	if err := tx.Get(&rental, "SELECT customer_id, points_redeemed FROM rentals WHERE id = $1", rentalID); err != nil {
+		return fmt.Errorf("failed to fetch rental: %w", err)
+	}
+	if rental.PointsRedeemed == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`INSERT INTO loyalty_transactions (customer_id, rental_id, entry_type, points, remaining, expires_at, description)
+		VALUES ($1, $2, 'reinstate', $3, $3, NOW() + $4::interval, $5)
+		ON CONFLICT (rental_id, entry_type) WHERE entry_type IN ('earn', 'reinstate') DO NOTHING`,
+		rental.CustomerID, rentalID, rental.PointsRedeemed, loyaltyPointValidity, fmt.Sprintf("Returned from cancelled rental #%d", rentalID))
+	if err != nil {
+		return fmt.Errorf("failed to reinstate loyalty points: %w", err)
+	}
+	return nil
+}
+
+// reverseLoyaltyPoints takes back the points a rental earned on a payment that has now been
+// refunded, as far as the customer has not spent them.
+func reverseLoyaltyPoints(tx *sqlx.Tx, rentalID int, refunded models.Money) error {
+	var earned struct {
+		CustomerID int `db:"customer_id"`
+		Points     int `db:"points"`
+		Reversed   int `db:"reversed"`
+	}
+	err := tx.Get(&earned, `SELECT e.customer_id, e.points,
+			COALESCE((SELECT -SUM(r.points) FROM loyalty_transactions r WHERE r.rental_id = e.rental_id AND r.entry_type = 'reverse'), 0) AS reversed
+		FROM loyalty_transactions e WHERE e.rental_id = $1 AND e.entry_type = 'earn'`, rentalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil // Not returned yet, so nothing was earned
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch earned points: %w", err)
+	}
+	points := min(int(refunded.Amount/loyaltyEarnUnit.Amount), earned.Points-earned.Reversed)
+	if points <= 0 {
+		return nil
+	}
+	if _, err := expireLoyaltyPoints(tx, &earned.CustomerID); err != nil {
+		return err
+	}
+	taken, err := spendLoyaltyPoints(tx, earned.CustomerID, points)
+	if err != nil || taken == 0 {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO loyalty_transactions (customer_id, rental_id, entry_type, points, description)
+		VALUES ($1, $2, 'reverse', $3, $4)`, earned.CustomerID, rentalID, -taken, fmt.Sprintf("Reversed after a refund on rental #%d", rentalID))
+	if err != nil {
+		return fmt.Errorf("failed to reverse loyalty points: %w", err)
+	}
+	return nil
+}
+
+// GetLoyaltySummary returns a customer's balance, tier and a page of their points history.
+func GetLoyaltySummary(customerID, page, limit int) (models.LoyaltySummary, error) {
+	if _, err := GetCustomerByIDIncludingDeleted(customerID); err != nil {
+		return models.LoyaltySummary{}, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if _, err := expireLoyaltyPoints(config.DB, &customerID); err != nil {
+		return models.LoyaltySummary{}, err
+	}
+
+	summary := models.LoyaltySummary{CustomerID: customerID, Page: page, Limit: limit, History: []models.LoyaltyTransaction{}}
+	var err error
+	if summary.Balance, err = loyaltyBalance(config.DB, customerID); err != nil {
+		return models.LoyaltySummary{}, err
+	}
+	summary.BalanceValue = loyaltyPointValue.MulInt(int64(summary.Balance))
+	if summary.Tier, summary.QualifyingPoints, err = loyaltyTierFor(config.DB, customerID); err != nil {
+		return models.LoyaltySummary{}, err
+	}
+	var next models.LoyaltyTier
+	err = config.DB.Get(&next, `SELECT code, name, min_points, discount_percent, free_upgrade FROM loyalty_tiers
+		WHERE min_points > $1 ORDER BY min_points LIMIT 1`, summary.QualifyingPoints)
+	if err == nil {
+		summary.NextTier = &next
+		summary.PointsToNextTier = next.MinPoints - summary.QualifyingPoints
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return models.LoyaltySummary{}, fmt.Errorf("failed to fetch next loyalty tier: %w", err)
+	}
+	err = config.DB.Get(&summary.ExpiringSoon, `SELECT COALESCE(SUM(remaining), 0) FROM loyalty_transactions
+		WHERE customer_id = $1 AND remaining > 0 AND expires_at <= NOW() + $2::interval`, customerID, loyaltyExpiryWarning)
+	if err != nil {
+		return models.LoyaltySummary{}, fmt.Errorf("failed to fetch expiring points: %w", err)
+	}
+
+	err = config.DB.Select(&summary.History, "SELECT "+loyaltyTransactionSelectColumns+` FROM loyalty_transactions
+		WHERE customer_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`, customerID, limit+1, (page-1)*limit)
+	if err != nil {
+		return models.LoyaltySummary{}, fmt.Errorf("failed to fetch loyalty history: %w", err)
+	}
+	if len(summary.History) > limit {
+		summary.History = summary.History[:limit]
+		summary.HasMore = true
+	}
+	return summary, nil
+}
+
+// AdjustLoyaltyPoints adds points to a customer by hand (a new lot with the usual validity), or
+// removes them, soonest expiring first.
+func AdjustLoyaltyPoints(customerID int, employeeID *int, input models.AdjustLoyaltyPointsInput) (entry models.LoyaltyTransaction, err error) {
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return models.LoyaltyTransaction{}, errors.New("a reason is required")
+	}
+	if _, err := GetCustomerByID(customerID); err != nil {
+		return models.LoyaltyTransaction{}, err
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.LoyaltyTransaction{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if input.Points > 0 {
+		err = tx.Get(&entry, `INSERT INTO loyalty_transactions (customer_id, entry_type, points, remaining, expires_at, description, created_by_employee_id)
+			VALUES ($1, 'adjust', $2, $2, NOW() + $3::interval, $4, $5) RETURNING `+loyaltyTransactionSelectColumns,
+			customerID, input.Points, loyaltyPointValidity, reason, employeeID)
+	} else {
+		if _, err = expireLoyaltyPoints(tx, &customerID); err != nil {
+			return models.LoyaltyTransaction{}, err
+		}
+		var taken int
+		if taken, err = spendLoyaltyPoints(tx, customerID, -input.Points); err != nil {
+			return models.LoyaltyTransaction{}, err
+		}
+		if taken < -input.Points {
+			err = ErrInsufficientPoints
+			return models.LoyaltyTransaction{}, err
+		}
+		err = tx.Get(&entry, `INSERT INTO loyalty_transactions (customer_id, entry_type, points, description, created_by_employee_id)
+			VALUES ($1, 'adjust', $2, $3, $4) RETURNING `+loyaltyTransactionSelectColumns,
+			customerID, input.Points, reason, employeeID)
+	}
+	if err != nil {
+		return models.LoyaltyTransaction{}, fmt.Errorf("failed to adjust loyalty points: %w", err)
+	}
+	log.Printf("⭐ Loyalty points of customer %d adjusted by %d", customerID, input.Points)
+	return entry, nil
+}
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/utils"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	tokenPurposeMFAChallenge  = "mfa_challenge"
+	tokenPurposeMFAEnrollment = "mfa_enrollment"
+
+	mfaChallengeTTL    = 5 * time.Minute
+	mfaMaxAttempts     = 5
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+var (
+	ErrInvalidMFAChallenge = errors.New("invalid or expired MFA token, please log in again")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrMFANotEnrolled      = errors.New("two-factor authentication is not set up")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFARequiredByPolicy = errors.New("two-factor authentication is required for your role and cannot be disabled")
+	ErrMFAEnableSelfFirst  = errors.New("enable two-factor authentication on your own account before requiring it for your role")
+)
+
+// isMFARequiredForRole reports whether the MFA policy covers role.
+func isMFARequiredForRole(role string) (bool, error) {
+	var required bool
+	err := config.DB.Get(&required, "SELECT required FROM mfa_policies WHERE role = $1", role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read MFA policy: %w", err)
+	}
+	return required, nil
+}
+
+// isMFAEnabled reports whether the employee has confirmed an authenticator.
+func isMFAEnabled(employeeID int) (bool, error) {
+	var enabled bool
+	err := config.DB.Get(&enabled, "SELECT EXISTS(SELECT 1 FROM employee_mfa WHERE employee_id = $1 AND confirmed_at IS NOT NULL)", employeeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check MFA status: %w", err)
+	}
+	return enabled, nil
+}
+
+// employeeLoginStep decides what a correct password or a single sign-on login gets: tokens, an MFA
+// challenge, or an enrollment challenge.
+func employeeLoginStep(employee models.Employee, client models.ClientInfo) (models.EmployeeLoginResult, error) {
+	user := tokenUser{UserType: "employee", ID: employee.ID, Email: employee.Email, Role: employee.Role}
+
+	enabled, err := isMFAEnabled(employee.ID)
+	if err != nil {
+		return models.EmployeeLoginResult{}, err
+	}
+	purpose := ""
+	if enabled {
+		purpose = tokenPurposeMFAChallenge
+	} else {
+		required, err := isMFARequiredForRole(employee.Role)
+		if err != nil {
+			return models.EmployeeLoginResult{}, err
+		}
+		if required {
+			purpose = tokenPurposeMFAEnrollment
+		}
+	}
+
+	if purpose == "" {
+		tokens, err := issueTokenPair(config.DB, user, "", client)
+		if err != nil {
+			return models.EmployeeLoginResult{}, err
+		}
+		return models.EmployeeLoginResult{TokenPair: &tokens}, nil
+	}
+
+	mfaToken, err := issueOneTimeToken(config.DB, purpose, user, mfaChallengeTTL)
+	if err != nil {
+		return models.EmployeeLoginResult{}, err
+	}
+	return models.EmployeeLoginResult{
+		MFARequired:           purpose == tokenPurposeMFAChallenge,
+		MFAEnrollmentRequired: purpose == tokenPurposeMFAEnrollment,
+		MFAToken:              mfaToken,
+		MFATokenExpiresIn:     int(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+type mfaChallengeRow struct {
+	ID         int        `db:"id"`
+	EmployeeID *int       `db:"employee_id"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	UsedAt     *time.Time `db:"used_at"`
+	Attempts   int        `db:"attempts"`
+}
+
+// loadMFAChallenge returns a live MFA token without consuming it.
+func loadMFAChallenge(purpose, rawToken string) (mfaChallengeRow, error) {
+	var row mfaChallengeRow
+	err := config.DB.Get(&row, `SELECT id, employee_id, expires_at, used_at, attempts FROM one_time_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND user_type = 'employee'`, hashOpaqueToken(rawToken), purpose)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mfaChallengeRow{}, ErrInvalidMFAChallenge
+		}
+		return mfaChallengeRow{}, fmt.Errorf("failed to look up MFA token: %w", err)
+	}
+	if row.EmployeeID == nil || row.UsedAt != nil || row.Attempts >= mfaMaxAttempts || time.Now().After(row.ExpiresAt) {
+		return mfaChallengeRow{}, ErrInvalidMFAChallenge
+	}
+	return row, nil
+}
+
+// recordMFAChallengeFailure counts a wrong code; the challenge dies after mfaMaxAttempts.
+func recordMFAChallengeFailure(challengeID int) {
+	_, err := config.DB.Exec(`UPDATE one_time_tokens SET attempts = attempts + 1,
+			used_at = CASE WHEN attempts + 1 >= $2 THEN NOW() ELSE used_at END
+		WHERE id = $1`, challengeID, mfaMaxAttempts)
+	if err != nil {
+		log.Printf("⚠️ Service: Failed to record MFA attempt on challenge %d: %v", challengeID, err)
+	}
+}
+
+// consumeMFAChallenge marks a challenge used. It fails if a concurrent request got there first.
+func consumeMFAChallenge(challengeID int) error {
+	result, err := config.DB.Exec("UPDATE one_time_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", challengeID)
+	if err != nil {
+		return fmt.Errorf("failed to consume MFA token: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrInvalidMFAChallenge
+	}
+	return nil
+}
+
+// verifyTOTP checks a code against the employee's secret. A code is accepted only once; confirmed
+// selects whether the confirmed authenticator or a pending enrollment is checked.
+func verifyTOTP(employeeID int, code string, confirmed bool) error {
+	var secret string
+	query := "SELECT secret FROM employee_mfa WHERE employee_id = $1 AND confirmed_at IS NOT NULL"
+	if !confirmed {
+		query = "SELECT secret FROM employee_mfa WHERE employee_id = $1 AND confirmed_at IS NULL"
+	}
+	if err := config.DB.Get(&secret, query, employeeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMFANotEnrolled
+		}
+		return fmt.Errorf("failed to load MFA secret: %w", err)
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	result, err := config.DB.Exec(`UPDATE employee_mfa SET last_used_step = $1
+		WHERE employee_id = $2 AND (last_used_step IS NULL OR last_used_step < $1)`, step, employeeID)
+	if err != nil {
+		return fmt.Errorf("failed to record MFA code use: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrInvalidMFACode // Replayed code
+	}
+	return nil
+}
+
+// normalizeRecoveryCode lets users type codes with or without the dash and in any case.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// useRecoveryCode consumes one of the employee's unused recovery codes.
+func useRecoveryCode(employeeID int, code string) error {
+	result, err := config.DB.Exec(`UPDATE employee_recovery_codes SET used_at = NOW()
+		WHERE employee_id = $1 AND code_hash = $2 AND used_at IS NULL`, employeeID, hashOpaqueToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("failed to check recovery code: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	log.Printf("⚠️ Service: Employee %d signed in with a recovery code", employeeID)
+	return nil
+}
+
+// verifyEmployeeMFA accepts either a TOTP code or a recovery code.
+func verifyEmployeeMFA(employeeID int, input models.MFACodeInput) error {
+	if strings.TrimSpace(input.Code) != "" {
+		return verifyTOTP(employeeID, input.Code, true)
+	}
+	if strings.TrimSpace(input.RecoveryCode) != "" {
+		return useRecoveryCode(employeeID, input.RecoveryCode)
+	}
+	return ErrInvalidMFACode
+}
+
+// replaceRecoveryCodes discards an employee's recovery codes and returns a fresh set.
+func replaceRecoveryCodes(employeeID int) (codes []string, err error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789" // No look-alike characters
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+			codes = nil
+		} else {
+			err = tx.Commit()
+			if err != nil {
+				codes = nil
+			}
+		}
+	}()
+
+	if _, err = tx.Exec("DELETE FROM employee_recovery_codes WHERE employee_id = $1", employeeID); err != nil {
+		return nil, fmt.Errorf("failed to clear recovery codes: %w", err)
+	}
+	buf := make([]byte, recoveryCodeLength)
+	for i := 0; i < recoveryCodeCount; i++ {
+		if _, err = rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		var code strings.Builder
+		for j, b := range buf {
+			if j == recoveryCodeLength/2 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(alphabet[int(b)%len(alphabet)])
+		}
+		codes = append(codes, code.String())
+		_, err = tx.Exec("INSERT INTO employee_recovery_codes (employee_id, code_hash) VALUES ($1, $2)", employeeID, hashOpaqueToken(normalizeRecoveryCode(code.String())))
+		if err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+	}
+	return codes, nil
+}
+
+// GetMFAStatus reports an employee's MFA setup.
+func GetMFAStatus(employeeID int) (models.MFAStatus, error) {
+	var row struct {
+		Role        string     `db:"role"`
+		ConfirmedAt *time.Time `db:"confirmed_at"`
+		Remaining   int        `db:"remaining"`
+	}
+	err := config.DB.Get(&row, `SELECT e.role, m.confirmed_at,
+			(SELECT COUNT(*) FROM employee_recovery_codes rc WHERE rc.employee_id = e.id AND rc.used_at IS NULL) AS remaining
+		FROM employees e LEFT JOIN employee_mfa m ON m.employee_id = e.id
+		WHERE e.id = $1`, employeeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MFAStatus{}, errors.New("employee not found")
+		}
+		return models.MFAStatus{}, fmt.Errorf("failed to fetch MFA status: %w", err)
+	}
+	required, err := isMFARequiredForRole(row.Role)
+	if err != nil {
+		return models.MFAStatus{}, err
+	}
+	return models.MFAStatus{
+		Enabled:                row.ConfirmedAt != nil,
+		ConfirmedAt:            row.ConfirmedAt,
+		RecoveryCodesRemaining: row.Remaining,
+		RequiredByPolicy:       required,
+	}, nil
+}
+
+// BeginMFAEnrollment generates a new secret for the employee. It only takes effect once confirmed.
+func BeginMFAEnrollment(employeeID int) (models.MFAEnrollment, error) {
+	enabled, err := isMFAEnabled(employeeID)
+	if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	if enabled {
+		return models.MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+	var email string
+	if err = config.DB.Get(&email, "SELECT email FROM employees WHERE id = $1", employeeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MFAEnrollment{}, errors.New("employee not found")
+		}
+		return models.MFAEnrollment{}, fmt.Errorf("failed to fetch employee: %w", err)
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	_, err = config.DB.Exec(`INSERT INTO employee_mfa (employee_id, secret) VALUES ($1, $2)
+		ON CONFLICT (employee_id) DO UPDATE SET secret = EXCLUDED.secret, confirmed_at = NULL, last_used_step = NULL, created_at = NOW()`, employeeID, secret)
+	if err != nil {
+		log.Printf("❌ Service: Error starting MFA enrollment for employee %d: %v", employeeID, err)
+		return models.MFAEnrollment{}, fmt.Errorf("failed to start MFA enrollment: %w", err)
+	}
+	log.Printf("🔐 Service: MFA enrollment started for employee %d", employeeID)
+	return models.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(config.Company().Name, email, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment activates a pending enrollment with a first valid code and returns recovery codes.
+func ConfirmMFAEnrollment(employeeID int, code string) ([]string, error) {
+	if err := verifyTOTP(employeeID, code, false); err != nil {
+		return nil, err
+	}
+	result, err := config.DB.Exec("UPDATE employee_mfa SET confirmed_at = NOW() WHERE employee_id = $1 AND confirmed_at IS NULL", employeeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm MFA enrollment: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	codes, err := replaceRecoveryCodes(employeeID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✅ Service: MFA enabled for employee %d", employeeID)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after re-checking a second factor.
+func RegenerateRecoveryCodes(employeeID int, input models.MFACodeInput) ([]string, error) {
+	if err := verifyEmployeeMFA(employeeID, input); err != nil {
+		return nil, err
+	}
+	codes, err := replaceRecoveryCodes(employeeID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✅ Service: Recovery codes regenerated for employee %d", employeeID)
+	return codes, nil
+}
+
+// DisableMFA removes the employee's authenticator, unless policy requires MFA for their role.
+func DisableMFA(employeeID int, input models.MFACodeInput) error {
+	var role string
+	if err := config.DB.Get(&role, "SELECT role FROM employees WHERE id = $1", employeeID); err != nil {
+		return fmt.Errorf("failed to fetch employee: %w", err)
+	}
+	required, err := isMFARequiredForRole(role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredByPolicy
+	}
+	if err = verifyEmployeeMFA(employeeID, input); err != nil {
+		return err
+	}
+	if _, err = config.DB.Exec("DELETE FROM employee_mfa WHERE employee_id = $1", employeeID); err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	if _, err = config.DB.Exec("DELETE FROM employee_recovery_codes WHERE employee_id = $1", employeeID); err != nil {
+		return fmt.Errorf("failed to remove recovery codes: %w", err)
+	}
+	log.Printf("✅ Service: MFA disabled for employee %d", employeeID)
+	return nil
+}
+
+// CompleteMFALogin exchanges an MFA challenge and a second factor for a token pair.
+func CompleteMFALogin(input models.MFAChallengeInput, client models.ClientInfo) (models.TokenPair, error) {
+	challenge, err := loadMFAChallenge(tokenPurposeMFAChallenge, input.MFAToken)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	employeeID := *challenge.EmployeeID
+	if err = verifyEmployeeMFA(employeeID, models.MFACodeInput{Code: input.Code, RecoveryCode: input.RecoveryCode}); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			recordMFAChallengeFailure(challenge.ID)
+		}
+		return models.TokenPair{}, err
+	}
+	if err = consumeMFAChallenge(challenge.ID); err != nil {
+		return models.TokenPair{}, err
+	}
+	return issueEmployeeTokens(employeeID, client)
+}
+
+// BeginMFAEnrollmentForLogin starts enrollment for an employee whose login was held back by policy.
+func BeginMFAEnrollmentForLogin(mfaToken string) (models.MFAEnrollment, error) {
+	challenge, err := loadMFAChallenge(tokenPurposeMFAEnrollment, mfaToken)
+	if err != nil {
+		return models.MFAEnrollment{}, err
+	}
+	return BeginMFAEnrollment(*challenge.EmployeeID)
+}
+
+// ConfirmMFAEnrollmentForLogin confirms enrollment during login and completes the login.
+func ConfirmMFAEnrollmentForLogin(input models.MFAChallengeInput, client models.ClientInfo) (models.MFAEnrollmentResult, error) {
+	challenge, err := loadMFAChallenge(tokenPurposeMFAEnrollment, input.MFAToken)
+	if err != nil {
+		return models.MFAEnrollmentResult{}, err
+	}
+	employeeID := *challenge.EmployeeID
+	codes, err := ConfirmMFAEnrollment(employeeID, input.Code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			recordMFAChallengeFailure(challenge.ID)
+		}
+		return models.MFAEnrollmentResult{}, err
+	}
+	if err = consumeMFAChallenge(challenge.ID); err != nil {
+		return models.MFAEnrollmentResult{}, err
+	}
+	tokens, err := issueEmployeeTokens(employeeID, client)
+	if err != nil {
+		return models.MFAEnrollmentResult{}, err
+	}
+	return models.MFAEnrollmentResult{RecoveryCodes: codes, Tokens: &tokens}, nil
+}
+
+// issueEmployeeTokens starts a new session for an employee who has passed every login step.
+func issueEmployeeTokens(employeeID int, client models.ClientInfo) (models.TokenPair, error) {
+	user := tokenUser{UserType: "employee", ID: employeeID}
+	if err := config.DB.QueryRowx("SELECT email, role FROM employees WHERE id = $1 AND deleted_at IS NULL", employeeID).Scan(&user.Email, &user.Role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TokenPair{}, ErrInvalidMFAChallenge
+		}
+		return models.TokenPair{}, fmt.Errorf("failed to fetch employee: %w", err)
+	}
+	tokens, err := issueTokenPair(config.DB, user, "", client)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	log.Printf("✅ Authentication with MFA successful for employee: %s", user.Email)
+	return tokens, nil
+}
+
+// GetMFAPolicies lists the MFA policy for every employee role.
+func GetMFAPolicies() ([]models.MFAPolicy, error) {
+	policies := []models.MFAPolicy{}
+	err := config.DB.Select(&policies, `SELECT r.role, COALESCE(p.required, FALSE) AS required, p.updated_by_employee_id,
+			COALESCE(p.updated_at, NOW()) AS updated_at
+		FROM (SELECT name AS role FROM roles) AS r
+		LEFT JOIN mfa_policies p ON p.role = r.role
+		ORDER BY r.role`)
+	if err != nil {
+		log.Println("❌ Error fetching MFA policies:", err)
+		return nil, fmt.Errorf("failed to fetch MFA policies: %w", err)
+	}
+	return policies, nil
+}
+
+// SetMFAPolicy turns the MFA requirement for a role on or off, for password and single sign-on
+// logins alike. It refuses to require MFA for a
+// role the acting admin belongs to unless they have enabled it themselves, so they cannot lock
+// themselves out mid-session.
+func SetMFAPolicy(input models.MFAPolicyInput, employeeID int) (models.MFAPolicy, error) {
+	if err := ensureRoleExists(config.DB, input.Role); err != nil {
+		return models.MFAPolicy{}, err
+	}
+	if *input.Required {
+		var role string
+		if err := config.DB.Get(&role, "SELECT role FROM employees WHERE id = $1", employeeID); err != nil {
+			return models.MFAPolicy{}, fmt.Errorf("failed to fetch employee: %w", err)
+		}
+		enabled, err := isMFAEnabled(employeeID)
+		if err != nil {
+			return models.MFAPolicy{}, err
+		}
+		if role == input.Role && !enabled {
+			return models.MFAPolicy{}, ErrMFAEnableSelfFirst
+		}
+	}
+
+	var policy models.MFAPolicy
+	err := config.DB.Get(&policy, `INSERT INTO mfa_policies (role, required, updated_by_employee_id, updated_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (role) DO UPDATE SET required = EXCLUDED.required, updated_by_employee_id = EXCLUDED.updated_by_employee_id, updated_at = NOW()
+		RETURNING role, required, updated_by_employee_id, updated_at`, input.Role, *input.Required, employeeID)
+	if err != nil {
+		log.Printf("❌ Error saving MFA policy for role %s: %v", input.Role, err)
+		return models.MFAPolicy{}, fmt.Errorf("failed to save MFA policy: %w", err)
+	}
+	log.Printf("✅ Service: MFA policy for role %s set to required=%t by employee %d", input.Role, *input.Required, employeeID)
+	return policy, nil
+}
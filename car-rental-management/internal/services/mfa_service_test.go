@@ -0,0 +1,24 @@
+package services
+
+import "testing"
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	// Codes are shown as xxxxx-xxxxx and hashed in normalised form, so every way of typing the
+	// shown code must normalise to the same value.
+	const shown = "abc23-hjk45"
+	want := normalizeRecoveryCode(shown)
+	if want != "abc23hjk45" {
+		t.Fatalf("normalizeRecoveryCode(%q) = %q, want %q", shown, want, "abc23hjk45")
+	}
+	for _, typed := range []string{"abc23hjk45", "ABC23-HJK45", "Abc23 Hjk45", " abc23 - hjk45 ", "abc23-hjk45"} {
+		if got := normalizeRecoveryCode(typed); got != want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", typed, got, want)
+		}
+	}
+	if got := normalizeRecoveryCode(want); got != want {
+		t.Errorf("normalizeRecoveryCode is not idempotent: %q -> %q", want, got)
+	}
+	if normalizeRecoveryCode("abc23-hjk46") == want {
+		t.Error("different codes normalised to the same value")
+	}
+}
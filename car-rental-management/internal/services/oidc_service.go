@@ -0,0 +1,500 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lib/pq"
+)
+
+const (
+	oidcStateTTL = 10 * time.Minute
+
+	// The JWKS is re-fetched when a token names an unknown kid, at most this often.
+	oidcJWKSRefreshInterval = 1 * time.Minute
+
+	loginFailureOIDCRejected = "oidc_rejected"
+)
+
+var (
+	ErrOIDCNotConfigured      = errors.New("single sign-on is not configured")
+	ErrOIDCInvalidState       = errors.New("sign-in request is invalid or has expired, please start again")
+	ErrOIDCLoginFailed        = errors.New("single sign-on failed")
+	ErrOIDCNoRole             = errors.New("your account is not in any group that grants staff access")
+	ErrOIDCUnknownEmployee    = errors.New("no employee account is linked to this identity")
+	ErrPasswordLoginDisabled  = errors.New("password login is disabled for staff; sign in with single sign-on")
+	errOIDCUnsupportedKeyType = errors.New("unsupported key type in identity provider JWKS")
+)
+
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// oidcProvider is the part of the provider's discovery document this service uses.
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcCache holds the discovery document and signing keys of the configured provider.
+var oidcCache struct {
+	sync.Mutex
+	issuerURL     string
+	provider      *oidcProvider
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// oidcGetJSON fetches url and decodes the JSON response into target.
+func oidcGetJSON(url string, target interface{}) error {
+	resp, err := oidcHTTPClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// discoverOIDCProvider returns the provider's endpoints, fetching the discovery document once.
+func discoverOIDCProvider(settings config.OIDCSettings) (*oidcProvider, error) {
+	oidcCache.Lock()
+	defer oidcCache.Unlock()
+	if oidcCache.provider != nil && oidcCache.issuerURL == settings.IssuerURL {
+		return oidcCache.provider, nil
+	}
+
+	var provider oidcProvider
+	if err := oidcGetJSON(settings.IssuerURL+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	if strings.TrimRight(provider.Issuer, "/") != settings.IssuerURL {
+		return nil, fmt.Errorf("OIDC discovery document is for issuer %s, expected %s", provider.Issuer, settings.IssuerURL)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is missing endpoints")
+	}
+	oidcCache.issuerURL = settings.IssuerURL
+	oidcCache.provider = &provider
+	oidcCache.keys = nil
+	return &provider, nil
+}
+
+// oidcSigningKey returns the provider's public key with the given kid, re-fetching the JWKS
+// when the kid is unknown so key rotation at the provider needs no restart.
+func oidcSigningKey(provider *oidcProvider, kid string) (interface{}, error) {
+	oidcCache.Lock()
+	defer oidcCache.Unlock()
+	if key, ok := oidcCache.keys[kid]; ok {
+		return key, nil
+	}
+	if oidcCache.keys != nil && time.Since(oidcCache.keysFetchedAt) < oidcJWKSRefreshInterval {
+		return nil, fmt.Errorf("identity provider key %q not found", kid)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KID     string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := oidcGetJSON(provider.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch identity provider keys: %w", err)
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var (
+			key interface{}
+			err error
+		)
+		switch jwk.KeyType {
+		case "RSA":
+			key, err = rsaKeyFromJWK(jwk.N, jwk.E)
+		case "EC":
+			key, err = ecKeyFromJWK(jwk.Curve, jwk.X, jwk.Y)
+		default:
+			err = errOIDCUnsupportedKeyType
+		}
+		if err != nil {
+			log.Printf("⚠️ Skipping identity provider key %q: %v", jwk.KID, err)
+			continue
+		}
+		keys[jwk.KID] = key
+	}
+	oidcCache.keys = keys
+	oidcCache.keysFetchedAt = time.Now()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("identity provider key %q not found", kid)
+}
+
+func rsaKeyFromJWK(n, e string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(new(big.Int).SetBytes(eBytes).Int64())}, nil
+}
+
+func ecKeyFromJWK(crv, x, y string) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, errOIDCUnsupportedKeyType
+	}
+	xBytes, err := base64.RawURLEncoding.DecodeString(x)
+	if err != nil {
+		return nil, err
+	}
+	yBytes, err := base64.RawURLEncoding.DecodeString(y)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(xBytes), Y: new(big.Int).SetBytes(yBytes)}, nil
+}
+
+// randomURLToken returns n random bytes, base64url encoded.
+func randomURLToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// GetLoginOptions tells the staff login page which sign-in methods to offer.
+func GetLoginOptions() models.LoginOptions {
+	return models.LoginOptions{
+		PasswordLogin: config.EmployeePasswordLoginEnabled(),
+		OIDCLogin:     config.OIDC().Enabled(),
+	}
+}
+
+// BeginOIDCLogin starts an authorization code + PKCE login. The state, nonce and PKCE verifier
+// are kept server-side; the browser is sent to AuthorizationURL and comes back with a code.
+func BeginOIDCLogin() (models.OIDCLoginStart, error) {
+	settings := config.OIDC()
+	if !settings.Enabled() {
+		return models.OIDCLoginStart{}, ErrOIDCNotConfigured
+	}
+	provider, err := discoverOIDCProvider(settings)
+	if err != nil {
+		log.Printf("❌ Service: OIDC discovery failed: %v", err)
+		return models.OIDCLoginStart{}, err
+	}
+
+	state, err := randomURLToken(32)
+	if err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+	nonce, err := randomURLToken(32)
+	if err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+	verifier, err := randomURLToken(32)
+	if err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	_, err = config.DB.Exec("INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, expires_at) VALUES ($1, $2, $3, $4)",
+		hashOpaqueToken(state), verifier, nonce, time.Now().Add(oidcStateTTL))
+	if err != nil {
+		return models.OIDCLoginStart{}, fmt.Errorf("failed to store OIDC login state: %w", err)
+	}
+	if _, err = config.DB.Exec("DELETE FROM oidc_login_states WHERE expires_at < NOW()"); err != nil {
+		log.Printf("⚠️ Service: Failed to purge expired OIDC login states: %v", err)
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {settings.ClientID},
+		"redirect_uri":          {settings.RedirectURL},
+		"scope":                 {strings.Join(settings.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return models.OIDCLoginStart{
+		AuthorizationURL: provider.AuthorizationEndpoint + separator + query.Encode(),
+		State:            state,
+		ExpiresIn:        int(oidcStateTTL.Seconds()),
+	}, nil
+}
+
+// oidcIdentity is what the ID token says about the person signing in.
+type oidcIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// exchangeOIDCCode redeems an authorization code and verifies the ID token it returns.
+func exchangeOIDCCode(settings config.OIDCSettings, provider *oidcProvider, code, verifier, nonce string) (oidcIdentity, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {settings.RedirectURL},
+		"client_id":     {settings.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oidcIdentity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if settings.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(settings.ClientID), url.QueryEscape(settings.ClientSecret))
+	}
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return oidcIdentity{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return oidcIdentity{}, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return oidcIdentity{}, fmt.Errorf("token endpoint returned %s: %s", resp.Status, body)
+	}
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil || tokenResponse.IDToken == "" {
+		return oidcIdentity{}, errors.New("token response has no id_token")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokenResponse.IDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return oidcSigningKey(provider, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(settings.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return oidcIdentity{}, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return oidcIdentity{}, errors.New("ID token nonce does not match")
+	}
+
+	identity := oidcIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+	switch groups := claims[settings.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	case string:
+		identity.Groups = strings.Fields(strings.ReplaceAll(groups, ",", " "))
+	}
+	if identity.Subject == "" {
+		return oidcIdentity{}, errors.New("ID token has no subject")
+	}
+	identity.Email = normaliseLoginEmail(identity.Email)
+	if strings.TrimSpace(identity.Name) == "" {
+		identity.Name = identity.Email
+	}
+	return identity, nil
+}
+
+// roleForGroups picks the role of the first configured group the user belongs to.
+func roleForGroups(settings config.OIDCSettings, groups []string) string {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+	for _, mapping := range settings.GroupRoles {
+		if member[mapping.Group] {
+			return mapping.Role
+		}
+	}
+	return settings.DefaultRole
+}
+
+// CompleteOIDCLogin finishes a login started with BeginOIDCLogin. The employee is found by their
+// identity provider subject, or linked by verified email on first use, or created when
+// just-in-time provisioning is on. Their role follows their groups on every login. The local MFA
+// policy applies as it does to password logins, whatever the identity provider checked: the
+// result may be an MFA or enrollment challenge instead of tokens.
+func CompleteOIDCLogin(code, state string, client models.ClientInfo) (models.EmployeeLoginResult, error) {
+	settings := config.OIDC()
+	if !settings.Enabled() {
+		return models.EmployeeLoginResult{}, ErrOIDCNotConfigured
+	}
+
+	var loginState struct {
+		CodeVerifier string `db:"code_verifier"`
+		Nonce        string `db:"nonce"`
+	}
+	err := config.DB.Get(&loginState, `DELETE FROM oidc_login_states WHERE state_hash = $1 AND expires_at > NOW()
+		RETURNING code_verifier, nonce`, hashOpaqueToken(state))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.EmployeeLoginResult{}, ErrOIDCInvalidState
+		}
+		return models.EmployeeLoginResult{}, fmt.Errorf("failed to load OIDC login state: %w", err)
+	}
+
+	provider, err := discoverOIDCProvider(settings)
+	if err != nil {
+		log.Printf("❌ Service: OIDC discovery failed: %v", err)
+		return models.EmployeeLoginResult{}, err
+	}
+	identity, err := exchangeOIDCCode(settings, provider, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		log.Printf("❌ Service: OIDC code exchange failed: %v", err)
+		return models.EmployeeLoginResult{}, ErrOIDCLoginFailed
+	}
+
+	role := roleForGroups(settings, identity.Groups)
+	if role == "" {
+		log.Printf("🔒 OIDC login refused for %s (%s): groups %v map to no role", identity.Email, identity.Subject, identity.Groups)
+		recordLoginAttempt("employee", identity.Email, client.IP, false, loginFailureOIDCRejected)
+		return models.EmployeeLoginResult{}, ErrOIDCNoRole
+	}
+	if err := ensureRoleExists(config.DB, role); err != nil {
+		log.Printf("❌ Service: OIDC group mapping names role '%s': %v", role, err)
+		return models.EmployeeLoginResult{}, err
+	}
+
+	employee, err := resolveOIDCEmployee(settings, provider.Issuer, identity, role)
+	if err != nil {
+		if errors.Is(err, ErrOIDCUnknownEmployee) {
+			recordLoginAttempt("employee", identity.Email, client.IP, false, loginFailureOIDCRejected)
+		}
+		return models.EmployeeLoginResult{}, err
+	}
+	recordLoginAttempt("employee", employee.Email, client.IP, true, "")
+
+	result, err := employeeLoginStep(employee, client)
+	if err != nil {
+		log.Println("❌ Error completing employee login:", err)
+		return models.EmployeeLoginResult{}, errors.New("authentication failed")
+	}
+	log.Printf("✅ OIDC authentication successful for employee %d (%s) as %s", employee.ID, employee.Email, employee.Role)
+	return result, nil
+}
+
+// resolveOIDCEmployee finds, links or creates the employee for identity and applies role.
+func resolveOIDCEmployee(settings config.OIDCSettings, issuer string, identity oidcIdentity, role string) (models.Employee, error) {
+	var employee models.Employee
+	const returning = " RETURNING id, name, email, role, created_at, updated_at"
+
+	err := config.DB.Get(&employee, "SELECT id, name, email, role, created_at, updated_at, deleted_at FROM employees WHERE oidc_issuer = $1 AND oidc_subject = $2",
+		issuer, identity.Subject)
+	if err == nil {
+		if employee.DeletedAt != nil {
+			log.Printf("🔒 OIDC login refused for %s (%s): the linked employee has been deleted", identity.Email, identity.Subject)
+			return models.Employee{}, ErrOIDCUnknownEmployee
+		}
+		if employee.Role != role {
+			log.Printf("🔄 Service: Employee %d role changed from %s to %s by identity provider groups", employee.ID, employee.Role, role)
+			err = config.DB.Get(&employee, "UPDATE employees SET role = $1 WHERE id = $2"+returning, role, employee.ID)
+			if err != nil {
+				return models.Employee{}, fmt.Errorf("failed to update employee role: %w", err)
+			}
+		}
+		return employee, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Employee{}, fmt.Errorf("failed to look up employee: %w", err)
+	}
+
+	// First sign-in: link an existing account with the same address, but only on the
+	// provider's word that the address is verified.
+	if identity.Email != "" && identity.EmailVerified {
+		err = config.DB.Get(&employee, `UPDATE employees SET oidc_issuer = $1, oidc_subject = $2, role = $3, email_verified_at = COALESCE(email_verified_at, NOW())
+			WHERE LOWER(email) = $4 AND oidc_subject IS NULL AND deleted_at IS NULL`+returning, issuer, identity.Subject, role, identity.Email)
+		if err == nil {
+			log.Printf("🔗 Service: Employee %d linked to identity provider subject %s", employee.ID, identity.Subject)
+			return employee, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return models.Employee{}, fmt.Errorf("failed to link employee: %w", err)
+		}
+	}
+
+	if !settings.JITProvision {
+		log.Printf("🔒 OIDC login refused for %s (%s): no linked employee and provisioning is off", identity.Email, identity.Subject)
+		return models.Employee{}, ErrOIDCUnknownEmployee
+	}
+	if identity.Email == "" || !identity.EmailVerified {
+		log.Printf("🔒 OIDC login refused for subject %s: a verified email is needed to create an employee", identity.Subject)
+		return models.Employee{}, ErrOIDCUnknownEmployee
+	}
+
+	// Provisioned employees get an empty password hash, which no password matches.
+	err = config.DB.Get(&employee, `INSERT INTO employees (name, email, password, role, oidc_issuer, oidc_subject, email_verified_at)
+		VALUES ($1, $2, '', $3, $4, $5, NOW())`+returning, identity.Name, identity.Email, role, issuer, identity.Subject)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			// The address belongs to an employee linked to another identity
+			log.Printf("🔒 OIDC login refused for %s (%s): email already used by another linked employee", identity.Email, identity.Subject)
+			return models.Employee{}, ErrOIDCUnknownEmployee
+		}
+		return models.Employee{}, fmt.Errorf("failed to provision employee: %w", err)
+	}
+	log.Printf("✅ Service: Provisioned employee %d (%s) as %s from identity provider", employee.ID, employee.Email, role)
+	return employee, nil
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/mailer"
+	"car-rental-management/internal/utils"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	tokenPurposePasswordReset     = "password_reset"
+	tokenPurposeEmailVerification = "email_verification"
+
+	passwordResetTTL     = 1 * time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+var (
+	ErrInvalidOneTimeToken = errors.New("invalid or expired token")
+	ErrEmailNotVerified    = errors.New("please verify your email address before booking")
+)
+
+// userTable maps a token user type to its table and owner column. Both values are trusted identifiers.
+func userTable(userType string) (table, ownerColumn string, err error) {
+	switch userType {
+	case "employee":
+		return "employees", "employee_id", nil
+	case "customer":
+		return "customers", "customer_id", nil
+	}
+	return "", "", fmt.Errorf("unknown user type '%s'", userType)
+}
+
+// issueOneTimeToken stores a new single-use token for user and returns its raw value.
+// Earlier unused tokens with the same purpose are invalidated, so only the latest email works.
+func issueOneTimeToken(db sqlx.Execer, purpose string, user tokenUser, ttl time.Duration) (string, error) {
+	_, ownerColumn, err := userTable(user.UserType)
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec(`UPDATE one_time_tokens SET used_at = NOW()
+		WHERE purpose = $1 AND `+ownerColumn+` = $2 AND used_at IS NULL`, purpose, user.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to invalidate previous tokens: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	rawToken := base64.RawURLEncoding.EncodeToString(buf)
+	_, err = db.Exec(`INSERT INTO one_time_tokens (token_hash, purpose, user_type, `+ownerColumn+`, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`, hashOpaqueToken(rawToken), purpose, user.UserType, user.ID, user.Email, time.Now().Add(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+	return rawToken, nil
+}
+
+type oneTimeTokenRow struct {
+	ID         int        `db:"id"`
+	UserType   string     `db:"user_type"`
+	EmployeeID *int       `db:"employee_id"`
+	CustomerID *int       `db:"customer_id"`
+	Email      string     `db:"email"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	UsedAt     *time.Time `db:"used_at"`
+}
+
+// consumeOneTimeToken locks and marks a token used, returning who it belongs to.
+func consumeOneTimeToken(tx *sqlx.Tx, purpose, rawToken string) (tokenUser, error) {
+	var row oneTimeTokenRow
+	err := tx.Get(&row, `SELECT id, user_type, employee_id, customer_id, email, expires_at, used_at
+		FROM one_time_tokens WHERE token_hash = $1 AND purpose = $2 FOR UPDATE`, hashOpaqueToken(rawToken), purpose)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return tokenUser{}, ErrInvalidOneTimeToken
+		}
+		return tokenUser{}, fmt.Errorf("failed to look up token: %w", err)
+	}
+	if row.UsedAt != nil || time.Now().After(row.ExpiresAt) {
+		return tokenUser{}, ErrInvalidOneTimeToken
+	}
+	if _, err = tx.Exec("UPDATE one_time_tokens SET used_at = NOW() WHERE id = $1", row.ID); err != nil {
+		return tokenUser{}, fmt.Errorf("failed to mark token used: %w", err)
+	}
+
+	user := tokenUser{UserType: row.UserType, Email: row.Email}
+	switch {
+	case row.UserType == "employee" && row.EmployeeID != nil:
+		user.ID = *row.EmployeeID
+	case row.UserType == "customer" && row.CustomerID != nil:
+		user.ID = *row.CustomerID
+	default:
+		return tokenUser{}, ErrInvalidOneTimeToken
+	}
+	return user, nil
+}
+
+// findTokenUser looks up an employee or customer by email. It returns sql.ErrNoRows when there is none.
+func findTokenUser(userType, email string) (tokenUser, bool, error) {
+	table, _, err := userTable(userType)
+	if err != nil {
+		return tokenUser{}, false, err
+	}
+	var row struct {
+		ID       int        `db:"id"`
+		Email    string     `db:"email"`
+		Verified *time.Time `db:"email_verified_at"`
+	}
+	emailMatch, args := "email = $1", []interface{}{email}
+	if userType == "customer" {
+		emailMatch, args = customerEmailMatch(1, email)
+	}
+	err = config.DB.Get(&row, "SELECT id, email, email_verified_at FROM "+table+" WHERE "+emailMatch+" AND deleted_at IS NULL", args...)
+	if err != nil {
+		return tokenUser{}, false, err
+	}
+	if userType == "customer" {
+		if row.Email, err = decryptPII(piiCustomerEmail, row.Email); err != nil {
+			return tokenUser{}, false, err
+		}
+	}
+	return tokenUser{UserType: userType, ID: row.ID, Email: row.Email}, row.Verified != nil, nil
+}
+
+// RequestPasswordReset emails a reset link if the account exists. It reports success either way
+// so the endpoint cannot be used to discover which addresses are registered.
+func RequestPasswordReset(userType, email string) error {
+	if userType == "employee" && !config.EmployeePasswordLoginEnabled() {
+		log.Printf("ℹ️ Password reset requested for employee %s while staff password login is disabled", email)
+		return nil
+	}
+	user, _, err := findTokenUser(userType, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("ℹ️ Password reset requested for unknown %s email %s", userType, email)
+			return nil
+		}
+		return fmt.Errorf("failed to look up account: %w", err)
+	}
+
+	rawToken, err := issueOneTimeToken(config.DB, tokenPurposePasswordReset, user, passwordResetTTL)
+	if err != nil {
+		log.Printf("❌ Service: Error issuing password reset token for %s %d: %v", userType, user.ID, err)
+		return err
+	}
+	link := config.AppBaseURL() + "/reset-password?token=" + url.QueryEscape(rawToken)
+	err = mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("We received a request to reset your password.\n\nOpen this link within %d minutes to choose a new one:\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.", int(passwordResetTTL.Minutes()), link),
+	})
+	if err != nil {
+		log.Printf("❌ Service: Error sending password reset email to %s: %v", user.Email, err)
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+	log.Printf("✅ Service: Password reset email sent to %s %d", userType, user.ID)
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and signs the user out everywhere.
+func ResetPassword(rawToken, newPassword string) (err error) {
+	if len(newPassword) < 6 {
+		return errors.New("password must be at least 6 characters")
+	}
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		log.Println("❌ Error hashing new password:", err)
+		return errors.New("failed to secure password")
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	user, err := consumeOneTimeToken(tx, tokenPurposePasswordReset, rawToken)
+	if err != nil {
+		return err
+	}
+	table, ownerColumn, err := userTable(user.UserType)
+	if err != nil {
+		return err
+	}
+	// Receiving the reset email proves the address, so it counts as verified.
+	result, err := tx.Exec(`UPDATE `+table+` SET password = $1,
+			email_verified_at = CASE WHEN email = $2 THEN COALESCE(email_verified_at, NOW()) ELSE email_verified_at END
+		WHERE id = $3`, hashedPassword, user.Email, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrInvalidOneTimeToken
+	}
+	// Anyone holding a session may be the reason for the reset, so end them all.
+	_, err = tx.Exec(`INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_tokens
+		WHERE `+ownerColumn+` = $1 AND access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING`, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE `+ownerColumn+` = $1 AND revoked_at IS NULL`, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	// Proving control of the mailbox is as good as an admin unlock.
+	_, err = tx.Exec("DELETE FROM account_lockouts WHERE user_type = $1 AND email = $2", user.UserType, normaliseLoginEmail(user.Email))
+	if err != nil {
+		return fmt.Errorf("failed to clear account lockout: %w", err)
+	}
+	log.Printf("✅ Service: Password reset for %s %d", user.UserType, user.ID)
+	return nil
+}
+
+// sendEmailVerification issues a verification token for user and emails the link.
+func sendEmailVerification(user tokenUser) error {
+	rawToken, err := issueOneTimeToken(config.DB, tokenPurposeEmailVerification, user, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := config.AppBaseURL() + "/verify-email?token=" + url.QueryEscape(rawToken)
+	err = mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Please confirm your email address by opening this link within %d hours:\n%s\n\n"+
+			"If you did not create an account, you can ignore this email.", int(emailVerificationTTL.Hours()), link),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	log.Printf("✅ Service: Verification email sent to %s %d", user.UserType, user.ID)
+	return nil
+}
+
+// RequestEmailVerification re-sends the verification email for an unverified account.
+// Like RequestPasswordReset it does not reveal whether the address is registered.
+func RequestEmailVerification(userType, email string) error {
+	user, verified, err := findTokenUser(userType, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("ℹ️ Verification requested for unknown %s email %s", userType, email)
+			return nil
+		}
+		return fmt.Errorf("failed to look up account: %w", err)
+	}
+	if verified {
+		return nil
+	}
+	if err = sendEmailVerification(user); err != nil {
+		log.Printf("❌ Service: Error sending verification email to %s %d: %v", userType, user.ID, err)
+		return err
+	}
+	return nil
+}
+
+// VerifyEmail marks an address verified. The token only counts for the address it was sent to.
+func VerifyEmail(rawToken string) (err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	user, err := consumeOneTimeToken(tx, tokenPurposeEmailVerification, rawToken)
+	if err != nil {
+		return err
+	}
+	table, _, err := userTable(user.UserType)
+	if err != nil {
+		return err
+	}
+	emailMatch, args := "email = $2", []interface{}{user.ID, user.Email}
+	if user.UserType == "customer" {
+		var matchArgs []interface{}
+		emailMatch, matchArgs = customerEmailMatch(2, user.Email)
+		args = append([]interface{}{user.ID}, matchArgs...)
+	}
+	result, err := tx.Exec(`UPDATE `+table+` SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1 AND `+emailMatch, args...)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrInvalidOneTimeToken // The account's email has changed since the token was sent
+	}
+	log.Printf("✅ Service: Email verified for %s %d", user.UserType, user.ID)
+	return nil
+}
+
+// checkCustomerEmailVerified enforces REQUIRE_EMAIL_VERIFICATION for bookings.
+func checkCustomerEmailVerified(q sqlx.Queryer, customerID int) error {
+	if !config.RequireEmailVerification() {
+		return nil
+	}
+	var verified bool
+	if err := sqlx.Get(q, &verified, "SELECT email_verified_at IS NOT NULL FROM customers WHERE id = $1", customerID); err != nil {
+		return fmt.Errorf("failed to check email verification: %w", err)
+	}
+	if !verified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
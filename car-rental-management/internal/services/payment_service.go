@@ -17,6 +17,10 @@ import (
 // Note: Error variables like ErrRentalNotFound, ErrForbidden, ErrInvalidState
 // are now defined in rental_service.go and are accessible within the 'services' package.
 
+// ErrRefundNotRecordable is returned when a refund is recorded as a new payment. A refund must
+// go through RefundPayment, which checks there is a paid payment to give back.
+var ErrRefundNotRecordable = errors.New("a refund cannot be recorded as a new payment; refund the original payment instead")
+
 func ProcessPayment(rentalID int, employeeID int, input models.RecordPaymentInput) (models.Payment, error) {
 	log.Printf("Service: Processing manual payment record for rental %d by employee %d", rentalID, employeeID)
 
@@ -26,17 +30,36 @@ func ProcessPayment(rentalID int, employeeID int, input models.RecordPaymentInpu
 	if employeeID <= 0 {
 		return models.Payment{}, errors.New("invalid employee ID")
 	}
+	if input.PaymentStatus == "Refunded" {
+		return models.Payment{}, ErrRefundNotRecordable
+	}
+
+	// The customer may pay in another currency; record what they were charged and the rate,
+	// but keep Amount (and therefore the ledger) in the base currency.
+	rate, errRate := GetExchangeRate(input.Currency)
+	if errRate != nil {
+		return models.Payment{}, errRate
+	}
+	chargedAmount := input.Amount
+	chargedAmount.Currency = rate.Currency
+	baseAmount, errConv := ConvertToBase(chargedAmount, rate)
+	if errConv != nil {
+		return models.Payment{}, errConv
+	}
 
 	expectedPaymentData, errCalc := CalculateRentalCost(rentalID) // Call from rental_service
 	if errCalc != nil {
 		log.Printf("⚠️ ProcessPayment: Could not calculate expected cost for rental %d: %v. Proceeding with input amount.", rentalID, errCalc)
-	} else if expectedPaymentData.Amount != input.Amount {
-		log.Printf("⚠️ ProcessPayment: Recorded amount %.2f differs from calculated/expected cost %.2f for rental %d", input.Amount, expectedPaymentData.Amount, rentalID)
+	} else if expectedPaymentData.Amount.Cmp(baseAmount) != 0 {
+		log.Printf("⚠️ ProcessPayment: Recorded amount %s (%s) differs from calculated/expected cost %s for rental %d", baseAmount, chargedAmount.Display(), expectedPaymentData.Amount, rentalID)
 	}
 
 	payment := models.Payment{
 		RentalID:             rentalID,
-		Amount:               input.Amount,
+		Amount:               baseAmount,
+		Currency:             rate.Currency,
+		ChargedAmount:        chargedAmount,
+		ExchangeRate:         rate.RateToBase,
 		PaymentStatus:        input.PaymentStatus,
 		PaymentMethod:        &input.PaymentMethod,
 		RecordedByEmployeeID: &employeeID,
@@ -45,8 +68,8 @@ func ProcessPayment(rentalID int, employeeID int, input models.RecordPaymentInpu
 		SlipURL:              nil,
 	}
 
-	query := `INSERT INTO payments (rental_id, amount, payment_status, payment_method, recorded_by_employee_id, transaction_id, payment_date, slip_url)
-			  VALUES (:rental_id, :amount, :payment_status, :payment_method, :recorded_by_employee_id, :transaction_id, :payment_date, :slip_url)
+	query := `INSERT INTO payments (rental_id, amount, currency, charged_amount, exchange_rate, payment_status, payment_method, recorded_by_employee_id, transaction_id, payment_date, slip_url)
+			  VALUES (:rental_id, :amount, :currency, :charged_amount, :exchange_rate, :payment_status, :payment_method, :recorded_by_employee_id, :transaction_id, :payment_date, :slip_url)
 			  RETURNING id, created_at, updated_at`
 
 	tx, errTx := config.DB.Beginx()
@@ -89,6 +112,20 @@ func ProcessPayment(rentalID int, employeeID int, input models.RecordPaymentInpu
 		return models.Payment{}, finalErr
 	}
 
+	switch payment.PaymentStatus {
+	case "Paid":
+		if err = postRentalCharge(tx, rentalID, &payment.ID, payment.Amount, &employeeID); err == nil {
+			err = postPaymentReceipt(tx, rentalID, payment.ID, payment.Amount, &employeeID)
+		}
+	default:
+		log.Printf("ℹ️ ProcessPayment: Payment %d recorded as '%s'; no money moved, nothing posted to the ledger.", payment.ID, payment.PaymentStatus)
+	}
+	if err != nil {
+		log.Println("❌ ProcessPayment: Error posting payment to ledger:", err)
+		finalErr = fmt.Errorf("failed to post payment to ledger: %w", err)
+		return models.Payment{}, finalErr
+	}
+
 	if payment.PaymentStatus == "Paid" {
 		log.Printf("ℹ️ ProcessPayment: Payment %d recorded as Paid. Attempting to update Rental %d status and car availability.", payment.ID, rentalID)
 		// Call UpdateRentalStatus from rental_service, passing the current transaction
@@ -117,9 +154,10 @@ func ProcessSlipUpload(rentalID int, customerID int, slipFilePathOrURL string) (
 		CustomerID int    `db:"customer_id"`
 		CarID      int    `db:"car_id"`
 		Status     string `db:"status"`
+		Corporate  bool   `db:"corporate"`
 	}
 	// This initial check can be outside a transaction
-	err = config.DB.Get(&rentalToCheck, "SELECT id, customer_id, car_id, status FROM rentals WHERE id=$1", rentalID)
+	err = config.DB.Get(&rentalToCheck, "SELECT id, customer_id, car_id, status, corporate_account_id IS NOT NULL AS corporate FROM rentals WHERE id=$1", rentalID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Printf("❌ ProcessSlipUpload: Rental %d not found.", rentalID)
@@ -134,6 +172,11 @@ func ProcessSlipUpload(rentalID int, customerID int, slipFilePathOrURL string) (
 		return fmt.Errorf("permission denied: %w", ErrForbidden)
 	}
 
+	if rentalToCheck.Corporate {
+		log.Printf("❌ ProcessSlipUpload: Rental %d is billed to a corporate account; no slip needed.", rentalID)
+		return fmt.Errorf("rental is billed to a corporate account: %w", ErrInvalidState)
+	}
+
 	if rentalToCheck.Status != "Pending" { // Only allow slip upload for "Pending" rentals
 		log.Printf("❌ ProcessSlipUpload: Cannot upload slip for rental %d with status '%s', expected 'Pending'", rentalID, rentalToCheck.Status)
 		return fmt.Errorf("cannot upload slip for rental with status '%s': %w", rentalToCheck.Status, ErrInvalidState)
@@ -167,9 +210,10 @@ func ProcessSlipUpload(rentalID int, customerID int, slipFilePathOrURL string) (
 
 	var paymentID int64
 	var currentPaymentStatus string
+	var depositAmount models.Money
 	// Lock the payment row if it exists to prevent concurrent updates
-	paymentQuery := "SELECT id, payment_status FROM payments WHERE rental_id = $1 ORDER BY created_at DESC LIMIT 1 FOR UPDATE"
-	dbErr := tx.QueryRowx(paymentQuery, rentalID).Scan(&paymentID, &currentPaymentStatus)
+	paymentQuery := "SELECT id, payment_status, amount FROM payments WHERE rental_id = $1 ORDER BY created_at DESC LIMIT 1 FOR UPDATE"
+	dbErr := tx.QueryRowx(paymentQuery, rentalID).Scan(&paymentID, &currentPaymentStatus, &depositAmount)
 
 	paymentMethod := "Bank Transfer" // Default for slip upload
 	newPaymentStatus := "Pending Verification"
@@ -183,12 +227,13 @@ func ProcessSlipUpload(rentalID int, customerID int, slipFilePathOrURL string) (
 				err = fmt.Errorf("failed to determine payment amount: %w", calcErr)
 				return // Defer will rollback
 			}
-			if calculatedPaymentData.Amount <= 0 {
+			if !calculatedPaymentData.Amount.IsPositive() {
 				err = errors.New("calculated payment amount is invalid or zero")
 				return // Defer will rollback
 			}
-			insertQuery := `INSERT INTO payments (rental_id, amount, payment_status, payment_method, slip_url, payment_date)
-                            VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+			depositAmount = calculatedPaymentData.Amount
+			insertQuery := `INSERT INTO payments (rental_id, amount, charged_amount, payment_status, payment_method, slip_url, payment_date)
+                            VALUES ($1, $2, $2, $3, $4, $5, $6) RETURNING id`
 			err = tx.QueryRowx(insertQuery,
 				rentalID, calculatedPaymentData.Amount, newPaymentStatus,
 				paymentMethod, slipFilePathOrURL, paymentDate,
@@ -229,6 +274,14 @@ func ProcessSlipUpload(rentalID int, customerID int, slipFilePathOrURL string) (
 		log.Printf("✅ ProcessSlipUpload: Payment record %d updated to status '%s'", paymentID, newPaymentStatus)
 	}
 
+	// The customer has transferred the money; hold it as a deposit until staff verify the slip.
+	err = postSlipDeposit(tx, rentalID, int(paymentID), depositAmount)
+	if err != nil {
+		log.Printf("❌ ProcessSlipUpload: Error posting slip deposit to ledger: %v", err)
+		err = fmt.Errorf("failed to post slip deposit to ledger: %w", err)
+		return // Defer will rollback
+	}
+
 	// If payment processing was successful up to this point, try to update rental status
 	// The UpdateRentalStatus function is now tx-aware.
 	_, errUpdate := UpdateRentalStatus(tx, rentalID, "Booked", nil) // Pass current transaction tx
@@ -272,13 +325,14 @@ func VerifyPayment(rentalId int, approved bool, employeeId int) (err error) {
 	}()
 
 	var fetchedData struct {
-		PaymentID     int    `db:"id"`
-		RentalID      int    `db:"rental_id"`
-		PaymentStatus string `db:"payment_status"`
-		RentalStatus  string `db:"rental_status"`
+		PaymentID     int          `db:"id"`
+		RentalID      int          `db:"rental_id"`
+		Amount        models.Money `db:"amount"`
+		PaymentStatus string       `db:"payment_status"`
+		RentalStatus  string       `db:"rental_status"`
 	}
 	paymentQuery := `
-		SELECT p.id, p.rental_id, p.payment_status, r.status AS rental_status
+		SELECT p.id, p.rental_id, p.amount, p.payment_status, r.status AS rental_status
 		FROM payments p
 		JOIN rentals r ON p.rental_id = r.id
 		WHERE p.rental_id = $1 AND p.payment_status = 'Pending Verification'
@@ -331,6 +385,21 @@ func VerifyPayment(rentalId int, approved bool, employeeId int) (err error) {
 	}
 	log.Printf("✅ Payment %d status updated to '%s'", paymentID, newPaymentStatus)
 
+	// Release the held deposit: onto the rental's receivable when approved, back to the customer when rejected.
+	if approved {
+		err = postRentalCharge(tx, rentalId, &paymentID, fetchedData.Amount, &employeeId)
+		if err == nil {
+			err = postDepositApplied(tx, rentalId, paymentID, fetchedData.Amount, &employeeId)
+		}
+	} else {
+		err = postDepositReturned(tx, rentalId, paymentID, fetchedData.Amount, &employeeId)
+	}
+	if err != nil {
+		log.Printf("❌ VerifyPayment: Error posting verification to ledger: %v", err)
+		err = fmt.Errorf("failed to post payment verification to ledger: %w", err)
+		return // Defer will rollback
+	}
+
 	// Call UpdateRentalStatus using the current transaction 'tx'
 	_, err = UpdateRentalStatus(tx, rentalId, newRentalStatusForUpdate, &employeeId)
 	if err != nil {
@@ -344,13 +413,37 @@ func VerifyPayment(rentalId int, approved bool, employeeId int) (err error) {
 	return // If err is nil, commit will happen. Otherwise, rollback.
 }
 
-func GetPayments() ([]models.Payment, error) {
+// labelChargedAmount gives a payment's charged amount the currency in its currency column.
+// Money.Scan can only label what it reads as the base currency, so every query selecting
+// charged_amount must call this before the amount is used.
+func labelChargedAmount(payment *models.Payment) {
+	if payment.Currency != "" {
+		payment.ChargedAmount.Currency = payment.Currency
+	}
+}
+
+// labelChargedAmounts applies labelChargedAmount to each scanned payment.
+func labelChargedAmounts(payments []models.Payment) {
+	for i := range payments {
+		labelChargedAmount(&payments[i])
+	}
+}
+
+// GetPayments lists payments for rentals of cars in scope.
+func GetPayments(scope BranchScope) ([]models.Payment, error) {
 	var payments []models.Payment
-	query := "SELECT id, rental_id, amount, payment_date, payment_status, payment_method, recorded_by_employee_id, transaction_id, slip_url, created_at, updated_at FROM payments ORDER BY id ASC"
-	err := config.DB.Select(&payments, query)
+	query := `SELECT p.id, p.rental_id, p.amount, p.payment_date, p.payment_status, p.payment_method, p.recorded_by_employee_id, p.transaction_id,
+			p.slip_url, p.currency, p.charged_amount, p.exchange_rate, p.created_at, p.updated_at
+		FROM payments p
+		JOIN rentals r ON p.rental_id = r.id
+		JOIN cars c ON r.car_id = c.id
+		WHERE ($1::int[] IS NULL OR c.branch_id = ANY($1))
+		ORDER BY p.id ASC`
+	err := config.DB.Select(&payments, query, scope.filterArg())
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch payments: %w", err)
 	}
+	labelChargedAmounts(payments)
 	return payments, nil
 }
 
@@ -359,7 +452,7 @@ func GetPaymentsByRentalID(rentalID int) ([]models.Payment, error) {
 	if rentalID <= 0 {
 		return nil, errors.New("invalid rental ID")
 	}
-	query := "SELECT id, rental_id, amount, payment_date, payment_status, payment_method, recorded_by_employee_id, transaction_id, slip_url, created_at, updated_at FROM payments WHERE rental_id=$1 ORDER BY id ASC"
+	query := "SELECT id, rental_id, amount, payment_date, payment_status, payment_method, recorded_by_employee_id, transaction_id, slip_url, currency, charged_amount, exchange_rate, created_at, updated_at FROM payments WHERE rental_id=$1 ORDER BY id ASC"
 	err := config.DB.Select(&payments, query, rentalID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -367,6 +460,7 @@ func GetPaymentsByRentalID(rentalID int) ([]models.Payment, error) {
 		}
 		return nil, fmt.Errorf("failed to fetch payments for rental %d: %w", rentalID, err)
 	}
+	labelChargedAmounts(payments)
 	return payments, nil
 }
 
@@ -387,36 +481,40 @@ func GetPaymentStatus(paymentID int) (models.Payment, error) {
 }
 
 type RentalPendingVerification struct {
-	RentalID        int       `db:"rental_id" json:"rental_id"`
-	CustomerID      int       `db:"customer_id" json:"customer_id"`
-	CustomerName    string    `db:"customer_name" json:"customer_name"`
-	CarID           int       `db:"car_id" json:"car_id"`
-	CarBrand        string    `db:"car_brand" json:"car_brand"`
-	CarModel        string    `db:"car_model" json:"car_model"`
-	PaymentID       int       `db:"payment_id" json:"payment_id"`
-	PaymentAmount   float64   `db:"payment_amount" json:"payment_amount"`
-	SlipURL         *string   `db:"slip_url" json:"slip_url"`
-	PaymentDate     time.Time `db:"payment_date" json:"payment_date"`
-	PickupDatetime  time.Time `db:"pickup_datetime" json:"pickup_datetime"`
-	DropoffDatetime time.Time `db:"dropoff_datetime" json:"dropoff_datetime"`
+	RentalID        int          `db:"rental_id" json:"rental_id"`
+	CustomerID      int          `db:"customer_id" json:"customer_id"`
+	CustomerName    string       `db:"customer_name" json:"customer_name"`
+	CarID           int          `db:"car_id" json:"car_id"`
+	CarBrand        string       `db:"car_brand" json:"car_brand"`
+	CarModel        string       `db:"car_model" json:"car_model"`
+	PaymentID       int          `db:"payment_id" json:"payment_id"`
+	PaymentAmount   models.Money `db:"payment_amount" json:"payment_amount"`
+	SlipURL         *string      `db:"slip_url" json:"slip_url"`
+	PaymentDate     time.Time    `db:"payment_date" json:"payment_date"`
+	PickupDatetime  time.Time    `db:"pickup_datetime" json:"pickup_datetime"`
+	DropoffDatetime time.Time    `db:"dropoff_datetime" json:"dropoff_datetime"`
+	CustomerRisk    string       `db:"customer_risk_status" json:"customer_risk_status"` // clear, watch or blacklisted
 }
 
-func GetRentalsPendingVerification() ([]RentalPendingVerification, error) {
+// GetRentalsPendingVerification lists uploaded slips awaiting review for rentals of cars in scope.
+func GetRentalsPendingVerification(scope BranchScope) ([]RentalPendingVerification, error) {
 	var rentals []RentalPendingVerification
 	query := `
 		SELECT
 			r.id AS rental_id, r.customer_id, cust.name AS customer_name,
 			r.car_id, ca.brand AS car_brand, ca.model AS car_model,
 			p.id AS payment_id, p.amount AS payment_amount, p.slip_url, p.payment_date,
-			r.pickup_datetime, r.dropoff_datetime
+			r.pickup_datetime, r.dropoff_datetime,
+			` + customerRiskStatusSQL("r.customer_id") + ` AS customer_risk_status
 		FROM rentals r
 		JOIN payments p ON r.id = p.rental_id
 		JOIN customers cust ON r.customer_id = cust.id
 		JOIN cars ca ON r.car_id = ca.id
 		WHERE p.payment_status = 'Pending Verification'
+		  AND ($1::int[] IS NULL OR ca.branch_id = ANY($1))
 		ORDER BY p.payment_date ASC
 	`
-	err := config.DB.Select(&rentals, query)
+	err := config.DB.Select(&rentals, query, scope.filterArg())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return []RentalPendingVerification{}, nil
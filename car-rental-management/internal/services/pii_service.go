@@ -0,0 +1,457 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"car-rental-management/internal/utils"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Columns holding personal data that is encrypted at rest. The name is bound into each
+// ciphertext and blind index, so a value copied into another column does not decrypt or match.
+// A new column (a licence or ID number, say) needs a name here, TEXT storage and, if it must be
+// searchable, a *_bidx column.
+const (
+	piiCustomerEmail = "customers.email"
+	piiCustomerPhone = "customers.phone"
+	piiCustomerTaxID = "customers.tax_id"
+
+	piiCustomerDocumentFile   = "customer_documents.file"
+	piiCustomerDocumentNumber = "customer_documents.document_number"
+)
+
+// Encrypted values are stored as pii:v1:<KEK id>:<wrapped data key>:<ciphertext>, the last two
+// base64url-encoded with their AES-GCM nonce in front. Anything else is legacy plaintext.
+const piiCiphertextPrefix = "pii:v1:"
+
+const (
+	piiDataKeySize        = 32
+	piiReencryptBatchSize = 500
+)
+
+var (
+	ErrPIIKeyUnavailable = errors.New("personal data is encrypted with a key that is not in the keyring")
+	ErrPIIMalformed      = errors.New("encrypted personal data is malformed")
+	ErrPIIKeyringMissing = errors.New("PII_KEYRING_FILE is not set, so there is nothing to encrypt with")
+)
+
+var piiBase64 = base64.RawURLEncoding
+
+// sealAESGCM encrypts plaintext under key, returning the nonce followed by the ciphertext.
+func sealAESGCM(key, plaintext, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// openAESGCM reverses sealAESGCM.
+func openAESGCM(key, sealed, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrPIIMalformed
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+}
+
+// encryptPII encrypts value for field under a fresh data key wrapped with the active KEK.
+// Without a keyring the value is returned unchanged.
+func encryptPII(field, value string) (string, error) {
+	keyring := config.PIIKeys()
+	if keyring == nil {
+		return value, nil
+	}
+	dataKey := make([]byte, piiDataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrappedKey, err := sealAESGCM(keyring.Keys[keyring.Active], dataKey, []byte(keyring.Active))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	ciphertext, err := sealAESGCM(dataKey, []byte(value), []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt %s: %w", field, err)
+	}
+	return piiCiphertextPrefix + keyring.Active + ":" + piiBase64.EncodeToString(wrappedKey) + ":" + piiBase64.EncodeToString(ciphertext), nil
+}
+
+// encryptPIIPtr is encryptPII for nullable columns.
+func encryptPIIPtr(field string, value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encrypted, err := encryptPII(field, *value)
+	return &encrypted, err
+}
+
+// splitPII breaks a stored value into its KEK id, wrapped data key and ciphertext.
+// ok is false for legacy plaintext.
+func splitPII(stored string) (kekID string, wrappedKey, ciphertext []byte, ok bool, err error) {
+	if !strings.HasPrefix(stored, piiCiphertextPrefix) {
+		return "", nil, nil, false, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(stored, piiCiphertextPrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, true, ErrPIIMalformed
+	}
+	if wrappedKey, err = piiBase64.DecodeString(parts[1]); err != nil {
+		return "", nil, nil, true, ErrPIIMalformed
+	}
+	if ciphertext, err = piiBase64.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, true, ErrPIIMalformed
+	}
+	return parts[0], wrappedKey, ciphertext, true, nil
+}
+
+// unwrapPIIDataKey recovers the data key a value was encrypted under.
+func unwrapPIIDataKey(kekID string, wrappedKey []byte) ([]byte, error) {
+	keyring := config.PIIKeys()
+	if keyring == nil {
+		return nil, ErrPIIKeyUnavailable
+	}
+	kek, ok := keyring.Keys[kekID]
+	if !ok {
+		return nil, ErrPIIKeyUnavailable
+	}
+	dataKey, err := openAESGCM(kek, wrappedKey, []byte(kekID))
+	if err != nil {
+		return nil, ErrPIIMalformed
+	}
+	return dataKey, nil
+}
+
+// decryptPII returns the plaintext of a stored field value. Legacy plaintext is returned as is.
+func decryptPII(field, stored string) (string, error) {
+	kekID, wrappedKey, ciphertext, encrypted, err := splitPII(stored)
+	if err != nil || !encrypted {
+		return stored, err
+	}
+	dataKey, err := unwrapPIIDataKey(kekID, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openAESGCM(dataKey, ciphertext, []byte(field))
+	if err != nil {
+		return "", ErrPIIMalformed
+	}
+	return string(plaintext), nil
+}
+
+// decryptPIIPtr is decryptPII for nullable columns.
+func decryptPIIPtr(field string, stored *string) (*string, error) {
+	if stored == nil {
+		return nil, nil
+	}
+	plaintext, err := decryptPII(field, *stored)
+	if err != nil {
+		return nil, err
+	}
+	return &plaintext, nil
+}
+
+// normalisePII puts a value in the form its blind index is computed over, so that lookups
+// ignore case in emails and punctuation in phone numbers.
+func normalisePII(field, value string) string {
+	switch field {
+	case piiCustomerEmail:
+		return utils.NormaliseEmail(value)
+	case piiCustomerPhone:
+		return utils.NormalisePhone(value)
+	}
+	return strings.TrimSpace(value)
+}
+
+// piiBlindIndex is the keyed hash of a field value stored in its *_bidx column. It is nil without
+// a keyring or for an empty value, so the column stays NULL.
+func piiBlindIndex(field, value string) *string {
+	keyring := config.PIIKeys()
+	normalised := normalisePII(field, value)
+	if keyring == nil || normalised == "" {
+		return nil
+	}
+	mac := hmac.New(sha256.New, keyring.BlindIndexKey)
+	mac.Write([]byte(field + "\x00" + normalised))
+	index := hex.EncodeToString(mac.Sum(nil))
+	return &index
+}
+
+// piiBlindIndexPtr is piiBlindIndex for nullable columns.
+func piiBlindIndexPtr(field string, value *string) *string {
+	if value == nil {
+		return nil
+	}
+	return piiBlindIndex(field, *value)
+}
+
+// customerEmailMatch is a WHERE condition finding the customer with email, using parameters
+// $firstParam and $firstParam+1. Rows not yet encrypted have no blind index and are compared
+// as plaintext, ignoring case like the blind index does.
+func customerEmailMatch(firstParam int, email string) (string, []interface{}) {
+	condition := fmt.Sprintf("(email_bidx = $%d OR (email_bidx IS NULL AND LOWER(TRIM(email)) = $%d))", firstParam, firstParam+1)
+	return condition, []interface{}{piiBlindIndex(piiCustomerEmail, email), utils.NormaliseEmail(email)}
+}
+
+// decryptCustomer replaces a customer's stored email, phone and tax ID with their plaintext.
+func decryptCustomer(customer *models.Customer) error {
+	var err error
+	if customer.Email, err = decryptPII(piiCustomerEmail, customer.Email); err != nil {
+		return fmt.Errorf("failed to decrypt email of customer %d: %w", customer.ID, err)
+	}
+	if customer.Phone, err = decryptPIIPtr(piiCustomerPhone, customer.Phone); err != nil {
+		return fmt.Errorf("failed to decrypt phone of customer %d: %w", customer.ID, err)
+	}
+	if customer.TaxID, err = decryptPIIPtr(piiCustomerTaxID, customer.TaxID); err != nil {
+		return fmt.Errorf("failed to decrypt tax ID of customer %d: %w", customer.ID, err)
+	}
+	return nil
+}
+
+// encryptedCustomerContact is a customer's email and phone as stored, with their blind indexes.
+type encryptedCustomerContact struct {
+	Email     string
+	EmailBidx *string
+	Phone     *string
+	PhoneBidx *string
+}
+
+// encryptCustomerContact prepares a customer's email and phone for storage.
+func encryptCustomerContact(email string, phone *string) (encryptedCustomerContact, error) {
+	contact := encryptedCustomerContact{
+		EmailBidx: piiBlindIndex(piiCustomerEmail, email),
+		PhoneBidx: piiBlindIndexPtr(piiCustomerPhone, phone),
+	}
+	var err error
+	if contact.Email, err = encryptPII(piiCustomerEmail, email); err != nil {
+		return encryptedCustomerContact{}, err
+	}
+	if contact.Phone, err = encryptPIIPtr(piiCustomerPhone, phone); err != nil {
+		return encryptedCustomerContact{}, err
+	}
+	return contact, nil
+}
+
+// rewrapPII brings a stored value up to date with the keyring: plaintext is encrypted, and a value
+// whose data key is wrapped with an old KEK has it rewrapped with the active one, leaving the
+// ciphertext itself alone. changed reports whether the stored value needs updating.
+func rewrapPII(field, stored string) (updated string, changed bool, err error) {
+	keyring := config.PIIKeys()
+	kekID, wrappedKey, ciphertext, encrypted, err := splitPII(stored)
+	if err != nil {
+		return "", false, err
+	}
+	if !encrypted {
+		updated, err = encryptPII(field, stored)
+		return updated, err == nil, err
+	}
+	if kekID == keyring.Active {
+		return stored, false, nil
+	}
+	dataKey, err := unwrapPIIDataKey(kekID, wrappedKey)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err := sealAESGCM(keyring.Keys[keyring.Active], dataKey, []byte(keyring.Active))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return piiCiphertextPrefix + keyring.Active + ":" + piiBase64.EncodeToString(rewrapped) + ":" + piiBase64.EncodeToString(ciphertext), true, nil
+}
+
+// PIIReencryptResult counts what ReencryptPII did.
+type PIIReencryptResult struct {
+	CustomersScanned int `json:"customers_scanned"`
+	CustomersUpdated int `json:"customers_updated"`
+	DocumentsScanned int `json:"documents_scanned"`
+	DocumentsUpdated int `json:"documents_updated"`
+	Failed           int `json:"failed"`  // Values under a key no longer in the keyring, or corrupt
+	Skipped          int `json:"skipped"` // Changed by someone else meanwhile; a rerun picks them up
+}
+
+// ReencryptPII moves every encrypted column and uploaded document onto the active key: legacy
+// plaintext is encrypted, data keys wrapped with an old key are rewrapped, and missing blind
+// indexes are filled in. It works in batches and can be rerun safely, e.g. after an interruption.
+// A row changed while it runs is skipped rather than overwritten, and left for the next run.
+func ReencryptPII() (PIIReencryptResult, error) {
+	var result PIIReencryptResult
+	if config.PIIKeys() == nil {
+		return result, ErrPIIKeyringMissing
+	}
+
+	lastID := 0
+	for {
+		var rows []struct {
+			ID        int     `db:"id"`
+			Email     string  `db:"email"`
+			EmailBidx *string `db:"email_bidx"`
+			Phone     *string `db:"phone"`
+			PhoneBidx *string `db:"phone_bidx"`
+			TaxID     *string `db:"tax_id"`
+		}
+		err := config.DB.Select(&rows, "SELECT id, email, email_bidx, phone, phone_bidx, tax_id FROM customers WHERE id > $1 ORDER BY id LIMIT $2", lastID, piiReencryptBatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to fetch customers: %w", err)
+		}
+		if len(rows) == 0 {
+			return reencryptCustomerDocuments(result)
+		}
+
+		for _, row := range rows {
+			lastID = row.ID
+			result.CustomersScanned++
+
+			email, emailChanged, err := rewrapPII(piiCustomerEmail, row.Email)
+			if err != nil {
+				log.Printf("⚠️ Cannot re-encrypt email of customer %d: %v", row.ID, err)
+				result.Failed++
+				continue
+			}
+			phone, phoneChanged := row.Phone, false
+			if row.Phone != nil {
+				var rewrapped string
+				if rewrapped, phoneChanged, err = rewrapPII(piiCustomerPhone, *row.Phone); err != nil {
+					log.Printf("⚠️ Cannot re-encrypt phone of customer %d: %v", row.ID, err)
+					result.Failed++
+					continue
+				}
+				phone = &rewrapped
+			}
+			taxID, taxIDChanged := row.TaxID, false
+			if row.TaxID != nil {
+				var rewrapped string
+				if rewrapped, taxIDChanged, err = rewrapPII(piiCustomerTaxID, *row.TaxID); err != nil {
+					log.Printf("⚠️ Cannot re-encrypt tax ID of customer %d: %v", row.ID, err)
+					result.Failed++
+					continue
+				}
+				taxID = &rewrapped
+			}
+
+			// Blind indexes are only missing on rows written without a keyring.
+			emailBidx, phoneBidx := row.EmailBidx, row.PhoneBidx
+			if emailBidx == nil || (phoneBidx == nil && row.Phone != nil) {
+				plainEmail, errEmail := decryptPII(piiCustomerEmail, email)
+				plainPhone, errPhone := decryptPIIPtr(piiCustomerPhone, phone)
+				if errEmail != nil || errPhone != nil {
+					log.Printf("⚠️ Cannot index customer %d: %v", row.ID, errors.Join(errEmail, errPhone))
+					result.Failed++
+					continue
+				}
+				emailBidx = piiBlindIndex(piiCustomerEmail, plainEmail)
+				phoneBidx = piiBlindIndexPtr(piiCustomerPhone, plainPhone)
+				emailChanged = true
+			}
+
+			if !emailChanged && !phoneChanged && !taxIDChanged {
+				continue
+			}
+			// Only if nobody changed them since they were read, or their new details would be lost.
+			updated, err := config.DB.Exec(`UPDATE customers SET email = $1, email_bidx = $2, phone = $3, phone_bidx = $4, tax_id = $5
+				WHERE id = $6 AND email = $7 AND phone IS NOT DISTINCT FROM $8 AND tax_id IS NOT DISTINCT FROM $9`,
+				email, emailBidx, phone, phoneBidx, taxID, row.ID, row.Email, row.Phone, row.TaxID)
+			if err != nil {
+				return result, fmt.Errorf("failed to update customer %d: %w", row.ID, err)
+			}
+			if rowsAffected, _ := updated.RowsAffected(); rowsAffected == 0 {
+				log.Printf("ℹ️ Customer %d changed during re-encryption, skipped", row.ID)
+				result.Skipped++
+				continue
+			}
+			result.CustomersUpdated++
+		}
+	}
+}
+
+// reencryptCustomerDocuments does for uploaded documents, and their numbers, what ReencryptPII
+// does for customers. A rewritten file replaces the old one atomically.
+func reencryptCustomerDocuments(result PIIReencryptResult) (PIIReencryptResult, error) {
+	var documents []struct {
+		ID             int     `db:"id"`
+		FileName       string  `db:"file_name"`
+		DocumentNumber *string `db:"document_number"`
+	}
+	if err := config.DB.Select(&documents, "SELECT id, file_name, document_number FROM customer_documents ORDER BY id"); err != nil {
+		return result, fmt.Errorf("failed to fetch customer documents: %w", err)
+	}
+	for _, document := range documents {
+		result.DocumentsScanned++
+		updated := false
+
+		if document.DocumentNumber != nil {
+			number, changed, err := rewrapPII(piiCustomerDocumentNumber, *document.DocumentNumber)
+			if err != nil {
+				log.Printf("⚠️ Cannot re-encrypt number of document %d: %v", document.ID, err)
+				result.Failed++
+				continue
+			}
+			if changed {
+				numberUpdated, err := config.DB.Exec("UPDATE customer_documents SET document_number = $1 WHERE id = $2 AND document_number = $3",
+					number, document.ID, *document.DocumentNumber)
+				if err != nil {
+					return result, fmt.Errorf("failed to update document %d: %w", document.ID, err)
+				}
+				if rowsAffected, _ := numberUpdated.RowsAffected(); rowsAffected == 0 {
+					log.Printf("ℹ️ Number of document %d changed during re-encryption, skipped", document.ID)
+					result.Skipped++
+				} else {
+					updated = true
+				}
+			}
+		}
+
+		path := filepath.Join(customerDocumentDir, filepath.Base(document.FileName))
+		stored, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("⚠️ Cannot read file of document %d: %v", document.ID, err)
+			result.Failed++
+			continue
+		}
+		rewrapped, changed, err := rewrapPII(piiCustomerDocumentFile, string(stored))
+		if err != nil {
+			log.Printf("⚠️ Cannot re-encrypt file of document %d: %v", document.ID, err)
+			result.Failed++
+			continue
+		}
+		if changed {
+			if err := os.WriteFile(path+".tmp", []byte(rewrapped), 0600); err != nil {
+				return result, fmt.Errorf("failed to write document %d: %w", document.ID, err)
+			}
+			if err := os.Rename(path+".tmp", path); err != nil {
+				return result, fmt.Errorf("failed to replace document %d: %w", document.ID, err)
+			}
+			updated = true
+		}
+		if updated {
+			result.DocumentsUpdated++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrErasureRequestNotFound   = errors.New("erasure request not found")
+	ErrErasureAlreadyRequested  = errors.New("you already have an erasure request waiting to be processed")
+	ErrErasureRequestNotPending = errors.New("erasure request has already been processed")
+	ErrErasureHasLiveRental     = errors.New("the customer has rentals that are still in progress; erase them once those are finished")
+	ErrCustomerAlreadyErased    = errors.New("this customer's personal data has already been erased")
+)
+
+const (
+	erasedCustomerName          = "Erased customer"
+	slipURLPrefix               = "/uploads/slips/"
+	erasureRequestSelectColumns = "id, customer_id, status, reason, requested_at, processed_at, processed_by_employee_id, rejection_reason"
+)
+
+// ExportCustomerData gathers everything held about a customer into a ZIP: their profile, rentals,
+// payments, reviews, erasure requests, documents, loyalty points history and the referral they
+// registered with as JSON, plus the payment slips and documents they uploaded.
+func ExportCustomerData(customerID int) ([]byte, error) {
+	profile, err := GetCustomerByID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	export := models.CustomerDataExport{
+		ExportedAt:      time.Now(),
+		Profile:         profile,
+		Rentals:         []models.Rental{},
+		Payments:        []models.Payment{},
+		Reviews:         []models.Review{},
+		ErasureRequests: []models.CustomerErasureRequest{},
+		Loyalty:         []models.LoyaltyTransaction{},
+	}
+
+	// Deleted rentals are still the customer's data, so they are included.
+	err = config.DB.Select(&export.Rentals, `
+		SELECT
+			r.id, r.customer_id, r.car_id, r.booking_date,
+			r.pickup_datetime, r.dropoff_datetime, r.pickup_location,
+			r.status, r.corporate_account_id, r.created_at, r.updated_at, r.deleted_at,
+			`+rentalPricingColumns+`,
+			c.brand AS "car.brand",
+			c.model AS "car.model"
+		FROM rentals r
+		JOIN cars c ON r.car_id = c.id
+		WHERE r.customer_id = $1
+		ORDER BY r.id`, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rentals: %w", err)
+	}
+	err = config.DB.Select(&export.Payments, `
+		SELECT p.id, p.rental_id, p.amount, p.payment_date, p.payment_status, p.payment_method, p.recorded_by_employee_id,
+			p.transaction_id, p.slip_url, p.currency, p.charged_amount, p.exchange_rate, p.created_at, p.updated_at
+		FROM payments p
+		JOIN rentals r ON p.rental_id = r.id
+		WHERE r.customer_id = $1
+		ORDER BY p.id`, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payments: %w", err)
+	}
+	labelChargedAmounts(export.Payments)
+	err = config.DB.Select(&export.Reviews, "SELECT id, customer_id, rental_id, rating, comment, created_at, updated_at FROM reviews WHERE customer_id = $1 ORDER BY id", customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reviews: %w", err)
+	}
+	err = config.DB.Select(&export.ErasureRequests, "SELECT "+erasureRequestSelectColumns+" FROM customer_erasure_requests WHERE customer_id = $1 ORDER BY id", customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch erasure requests: %w", err)
+	}
+	if export.Documents, err = GetCustomerDocuments(customerID, ""); err != nil {
+		return nil, err
+	}
+	err = config.DB.Select(&export.Loyalty, "SELECT "+loyaltyTransactionSelectColumns+" FROM loyalty_transactions WHERE customer_id = $1 ORDER BY id", customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch loyalty history: %w", err)
+	}
+	var referral models.Referral
+	err = config.DB.Get(&referral, "SELECT "+referralSelectColumns+referralJoins+" WHERE f.referee_customer_id = $1", customerID)
+	if err == nil {
+		referral.ReferrerName = "" // Someone else's personal data
+		export.Referral = &referral
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to fetch referral: %w", err)
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"export.json", map[string]interface{}{"exported_at": export.ExportedAt, "customer_id": customerID}},
+		{"profile.json", export.Profile},
+		{"rentals.json", export.Rentals},
+		{"payments.json", export.Payments},
+		{"reviews.json", export.Reviews},
+		{"erasure_requests.json", export.ErasureRequests},
+		{"documents.json", export.Documents},
+		{"loyalty.json", export.Loyalty},
+		{"referral.json", export.Referral},
+	}
+	for _, file := range files {
+		data, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+		if err := writeZipFile(archive, file.name, data); err != nil {
+			return nil, err
+		}
+	}
+	for _, payment := range export.Payments {
+		if payment.SlipURL == nil || !strings.HasPrefix(*payment.SlipURL, slipURLPrefix) {
+			continue
+		}
+		name := filepath.Base(*payment.SlipURL)
+		data, err := os.ReadFile(filepath.Join(".", "uploads", "slips", name))
+		if err != nil {
+			log.Printf("⚠️ Data export for customer %d: slip %s of payment %d could not be read: %v", customerID, name, payment.ID, err)
+			continue
+		}
+		if err := writeZipFile(archive, "slips/"+name, data); err != nil {
+			return nil, err
+		}
+	}
+	for _, document := range export.Documents {
+		data, err := ReadCustomerDocumentFile(document)
+		if err != nil {
+			log.Printf("⚠️ Data export for customer %d: %v", customerID, err)
+			continue
+		}
+		name := fmt.Sprintf("documents/%d_%s%s", document.ID, document.DocumentType, customerDocumentExtensions[document.ContentType])
+		if err := writeZipFile(archive, name, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish export archive: %w", err)
+	}
+	log.Printf("📦 Exported personal data of customer %d (%d rentals, %d payments, %d reviews)", customerID, len(export.Rentals), len(export.Payments), len(export.Reviews))
+	return buf.Bytes(), nil
+}
+
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export archive: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to export archive: %w", name, err)
+	}
+	return nil
+}
+
+// RequestErasure records a customer's request to have their personal data erased.
+func RequestErasure(customerID int, input models.CreateErasureRequestInput) (models.CustomerErasureRequest, error) {
+	var request models.CustomerErasureRequest
+	var erasedAt *time.Time
+	if err := config.DB.Get(&erasedAt, "SELECT erased_at FROM customers WHERE id = $1 AND deleted_at IS NULL", customerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CustomerErasureRequest{}, errors.New("customer not found")
+		}
+		return models.CustomerErasureRequest{}, fmt.Errorf("failed to fetch customer: %w", err)
+	}
+	if erasedAt != nil {
+		return models.CustomerErasureRequest{}, ErrCustomerAlreadyErased
+	}
+	err := config.DB.Get(&request, `INSERT INTO customer_erasure_requests (customer_id, reason) VALUES ($1, $2)
+		RETURNING `+erasureRequestSelectColumns, customerID, strings.TrimSpace(input.Reason))
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return models.CustomerErasureRequest{}, ErrErasureAlreadyRequested
+		}
+		return models.CustomerErasureRequest{}, fmt.Errorf("failed to record erasure request: %w", err)
+	}
+	log.Printf("📝 Customer %d requested erasure of their personal data (request %d)", customerID, request.ID)
+	return request, nil
+}
+
+// GetErasureRequests lists erasure requests, oldest first, optionally only those with status.
+// A customerID above zero limits the list to that customer's requests.
+func GetErasureRequests(status string, customerID int) ([]models.CustomerErasureRequest, error) {
+	requests := []models.CustomerErasureRequest{}
+	query := "SELECT " + erasureRequestSelectColumns + " FROM customer_erasure_requests WHERE ($1 = '' OR status = $1) AND ($2 <= 0 OR customer_id = $2) ORDER BY requested_at, id"
+	if err := config.DB.Select(&requests, query, status, customerID); err != nil {
+		return nil, fmt.Errorf("failed to fetch erasure requests: %w", err)
+	}
+	return requests, nil
+}
+
+// lockPendingErasureRequest loads a request for processing and checks it is still Pending.
+func lockPendingErasureRequest(tx *sqlx.Tx, requestID int) (models.CustomerErasureRequest, error) {
+	var request models.CustomerErasureRequest
+	err := tx.Get(&request, "SELECT "+erasureRequestSelectColumns+" FROM customer_erasure_requests WHERE id = $1 FOR UPDATE", requestID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CustomerErasureRequest{}, ErrErasureRequestNotFound
+		}
+		return models.CustomerErasureRequest{}, fmt.Errorf("failed to fetch erasure request: %w", err)
+	}
+	if request.Status != "Pending" {
+		return models.CustomerErasureRequest{}, ErrErasureRequestNotPending
+	}
+	return request, nil
+}
+
+// CompleteErasureRequest erases the customer's personal data and closes the request.
+// employeeID is nil when an integration processes it.
+func CompleteErasureRequest(requestID int, employeeID *int) (request models.CustomerErasureRequest, err error) {
+	var documentFiles []string
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.CustomerErasureRequest{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else if err = tx.Commit(); err == nil {
+			removeCustomerDocumentFiles(documentFiles)
+		}
+	}()
+
+	request, err = lockPendingErasureRequest(tx, requestID)
+	if err != nil {
+		return models.CustomerErasureRequest{}, err
+	}
+	if documentFiles, err = eraseCustomer(tx, request.CustomerID); err != nil {
+		return models.CustomerErasureRequest{}, err
+	}
+	err = tx.Get(&request, `UPDATE customer_erasure_requests SET status = 'Completed', processed_at = NOW(), processed_by_employee_id = $1
+		WHERE id = $2 RETURNING `+erasureRequestSelectColumns, employeeID, requestID)
+	if err != nil {
+		return models.CustomerErasureRequest{}, fmt.Errorf("failed to close erasure request: %w", err)
+	}
+	return request, nil
+}
+
+// RejectErasureRequest closes a request without erasing anything, e.g. when the customer still owes money.
+func RejectErasureRequest(requestID int, employeeID *int, reason string) (request models.CustomerErasureRequest, err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.CustomerErasureRequest{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if _, err = lockPendingErasureRequest(tx, requestID); err != nil {
+		return models.CustomerErasureRequest{}, err
+	}
+	err = tx.Get(&request, `UPDATE customer_erasure_requests SET status = 'Rejected', processed_at = NOW(), processed_by_employee_id = $1, rejection_reason = $2
+		WHERE id = $3 RETURNING `+erasureRequestSelectColumns, employeeID, strings.TrimSpace(reason), requestID)
+	if err != nil {
+		return models.CustomerErasureRequest{}, fmt.Errorf("failed to reject erasure request: %w", err)
+	}
+	log.Printf("🚫 Erasure request %d of customer %d rejected", requestID, request.CustomerID)
+	return request, nil
+}
+
+// eraseCustomer anonymises a customer in place. Their rentals, payments, slips, invoices and
+// ledger postings are kept for accounting, now pointing at a customer with no name or contact
+// details; review ratings stay but the text goes, and audit log entries about them keep only
+// markers where their personal data was. The customer is logged out and can no longer
+// log in, since the address and password are replaced. Licence and identity documents are
+// deleted; the names of their files are returned for removal once the transaction commits.
+func eraseCustomer(tx *sqlx.Tx, customerID int) ([]string, error) {
+	var customer struct {
+		Email    string     `db:"email"`
+		ErasedAt *time.Time `db:"erased_at"`
+	}
+	if err := tx.Get(&customer, "SELECT email, erased_at FROM customers WHERE id = $1 FOR UPDATE", customerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("customer not found")
+		}
+		return nil, fmt.Errorf("failed to fetch customer: %w", err)
+	}
+	if customer.ErasedAt != nil {
+		return nil, ErrCustomerAlreadyErased
+	}
+	email, err := decryptPII(piiCustomerEmail, customer.Email)
+	if err != nil {
+		return nil, err
+	}
+	var liveRentals int
+	if err := tx.Get(&liveRentals, "SELECT COUNT(*) FROM rentals WHERE customer_id = $1 AND deleted_at IS NULL AND status IN "+liveRentalStatuses, customerID); err != nil {
+		return nil, fmt.Errorf("failed to check customer rentals: %w", err)
+	}
+	if liveRentals > 0 {
+		return nil, ErrErasureHasLiveRental
+	}
+
+	// An empty password hash never matches, and the .invalid address can receive no reset link.
+	steps := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE customers SET name = $1, email = 'erased-' || id || '@erased.invalid', email_bidx = NULL,
+			phone = NULL, phone_bidx = NULL, password = '', billing_address = NULL, tax_id = NULL,
+			email_verified_at = NULL, erased_at = NOW() WHERE id = $2`, []interface{}{erasedCustomerName, customerID}},
+		{"UPDATE reviews SET comment = NULL WHERE customer_id = $1", []interface{}{customerID}},
+		{"DELETE FROM customer_notes WHERE customer_id = $1", []interface{}{customerID}},
+		{"UPDATE referrals SET registration_ip = '', registration_user_agent = '' WHERE referee_customer_id = $1", []interface{}{customerID}},
+		{"DELETE FROM one_time_tokens WHERE customer_id = $1", []interface{}{customerID}},
+		{"UPDATE sessions SET user_agent = '', ip_address = '' WHERE customer_id = $1", []interface{}{customerID}},
+		{"DELETE FROM login_attempts WHERE user_type = 'customer' AND email = $1", []interface{}{normaliseLoginEmail(email)}},
+		{"DELETE FROM account_lockouts WHERE user_type = 'customer' AND email = $1", []interface{}{normaliseLoginEmail(email)}},
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(step.query, step.args...); err != nil {
+			return nil, fmt.Errorf("failed to erase customer data: %w", err)
+		}
+	}
+	if err := maskCustomerAuditEntries(tx, customerID, email); err != nil {
+		return nil, err
+	}
+	var documentFiles []string
+	if err := tx.Select(&documentFiles, "DELETE FROM customer_documents WHERE customer_id = $1 RETURNING file_name", customerID); err != nil {
+		return nil, fmt.Errorf("failed to delete customer documents: %w", err)
+	}
+	if err := revokeTokensTx(tx, "customer_id", customerID); err != nil {
+		return nil, err
+	}
+	log.Printf("🧹 Personal data of customer %d erased", customerID)
+	return documentFiles, nil
+}
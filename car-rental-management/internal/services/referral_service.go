@@ -0,0 +1,356 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInvalidReferralCode = errors.New("invalid referral code")
+
+const (
+	// No 0/O or 1/I, so codes survive being read out or copied by hand.
+	referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	referralCodeLength   = 8
+
+	referralRefereeDiscountPercent = 10
+	// referralRewardPoints are credited to the referrer when the referee's first rental is returned.
+	referralRewardPoints = 500
+
+	referralSelectColumns = `f.id, f.referrer_customer_id, rc.name AS referrer_name, f.referee_customer_id, ec.name AS referee_name,
+		f.referral_code, f.status, f.rejection_reason, f.registration_ip, f.registration_user_agent, f.converted_rental_id,
+		f.reward_points, f.created_at, f.converted_at`
+	referralJoins = ` FROM referrals f
+		JOIN customers rc ON rc.id = f.referrer_customer_id
+		JOIN customers ec ON ec.id = f.referee_customer_id`
+)
+
+// referralRefereeDiscountCap limits the referee's first-rental discount.
+var referralRefereeDiscountCap = models.Baht(500, 0)
+
+func generateReferralCode() (string, error) {
+	code := make([]byte, referralCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(referralCodeAlphabet))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate referral code: %w", err)
+		}
+		code[i] = referralCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// assignReferralCode gives a customer a referral code unless they have one, and returns it.
+func assignReferralCode(e sqlx.Ext, customerID int) (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := generateReferralCode()
+		if err != nil {
+			return "", err
+		}
+		result, err := e.Exec(`UPDATE customers SET referral_code = $1
+			WHERE id = $2 AND referral_code IS NULL AND NOT EXISTS (SELECT 1 FROM customers WHERE referral_code = $1)`, code, customerID)
+		if err != nil {
+			return "", fmt.Errorf("failed to assign referral code: %w", err)
+		}
+		if assigned, _ := result.RowsAffected(); assigned == 1 {
+			return code, nil
+		}
+		// Either they already have a code, or this one is taken and another is tried.
+		var existing *string
+		if err := sqlx.Get(e, &existing, "SELECT referral_code FROM customers WHERE id = $1", customerID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return "", errors.New("customer not found")
+			}
+			return "", fmt.Errorf("failed to fetch referral code: %w", err)
+		}
+		if existing != nil {
+			return *existing, nil
+		}
+	}
+	return "", errors.New("failed to find an unused referral code")
+}
+
+// referrerForCode finds the customer a referral code belongs to. The code of an account merged
+// into another refers to the surviving account.
+func referrerForCode(q sqlx.Queryer, code string) (int, error) {
+	var referrerID int
+	err := sqlx.Get(q, &referrerID, `SELECT COALESCE(merged_into_customer_id, id) FROM customers
+		WHERE referral_code = $1 AND erased_at IS NULL AND (deleted_at IS NULL OR merged_into_customer_id IS NOT NULL)`,
+		strings.ToUpper(strings.TrimSpace(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidReferralCode
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up referral code: %w", err)
+	}
+	return referrerID, nil
+}
+
+// customerPhone returns a customer's phone number in plaintext, or "" if they have none.
+func customerPhone(q sqlx.Queryer, customerID int) (string, error) {
+	var stored *string
+	if err := sqlx.Get(q, &stored, "SELECT phone FROM customers WHERE id = $1", customerID); err != nil {
+		return "", fmt.Errorf("failed to fetch customer phone: %w", err)
+	}
+	phone, err := decryptPIIPtr(piiCustomerPhone, stored)
+	if err != nil || phone == nil {
+		return "", err
+	}
+	return normalisePII(piiCustomerPhone, *phone), nil
+}
+
+// referralFraudReason runs the fraud checks on a referral and returns why it should be rejected,
+// or "" if it passes: the referrer must still be in good standing, the two accounts must not share
+// a phone number, and the referee must not use the referrer's device (the same address and
+// browser) or a device another of their referees registered from.
+func referralFraudReason(q sqlx.Queryer, referral models.Referral) (string, error) {
+	if err := checkCustomerNotBlacklisted(q, referral.ReferrerCustomerID); err != nil {
+		if errors.Is(err, ErrCustomerBlacklisted) {
+			return "the referrer is blacklisted", nil
+		}
+		return "", err
+	}
+	var referrerClosed bool
+	if err := sqlx.Get(q, &referrerClosed, "SELECT deleted_at IS NOT NULL OR erased_at IS NOT NULL FROM customers WHERE id = $1", referral.ReferrerCustomerID); err != nil {
+		return "", fmt.Errorf("failed to fetch referrer: %w", err)
+	}
+	if referrerClosed {
+		return "the referrer's account is closed", nil
+	}
+
+	referrerPhone, err := customerPhone(q, referral.ReferrerCustomerID)
+	if err != nil {
+		return "", err
+	}
+	refereePhone, err := customerPhone(q, referral.RefereeCustomerID)
+	if err != nil {
+		return "", err
+	}
+	if referrerPhone != "" && referrerPhone == refereePhone {
+		return "the referee has the referrer's phone number", nil
+	}
+
+	checks := []struct {
+		reason string
+		query  string
+		args   []interface{}
+		// Checks on the registration device are skipped when its address is unknown, rather than
+		// matching every other unknown one.
+		usesRegistration bool
+	}{
+		{"the referee registered from the referrer's device",
+			"SELECT EXISTS(SELECT 1 FROM sessions WHERE customer_id = $1 AND ip_address = $2 AND user_agent = $3)",
+			[]interface{}{referral.ReferrerCustomerID, referral.RegistrationIP, referral.RegistrationUserAgent}, true},
+		{"another customer referred by the same referrer registered from this device",
+			`SELECT EXISTS(SELECT 1 FROM referrals WHERE referrer_customer_id = $1 AND referee_customer_id <> $2
+				AND registration_ip = $3 AND registration_user_agent = $4)`,
+			[]interface{}{referral.ReferrerCustomerID, referral.RefereeCustomerID, referral.RegistrationIP, referral.RegistrationUserAgent}, true},
+		{"the referee has signed in from the referrer's device",
+			`SELECT EXISTS(SELECT 1 FROM sessions a JOIN sessions b ON a.ip_address = b.ip_address AND a.user_agent = b.user_agent
+				WHERE a.customer_id = $1 AND b.customer_id = $2 AND a.ip_address <> '')`,
+			[]interface{}{referral.RefereeCustomerID, referral.ReferrerCustomerID}, false},
+	}
+	for _, check := range checks {
+		if check.usesRegistration && referral.RegistrationIP == "" {
+			continue
+		}
+		var matched bool
+		if err := sqlx.Get(q, &matched, check.query, check.args...); err != nil {
+			return "", fmt.Errorf("failed to run referral checks: %w", err)
+		}
+		if matched {
+			return check.reason, nil
+		}
+	}
+	return "", nil
+}
+
+// recordReferral records that a newly registered customer was referred by referrerID, rejecting
+// the referral straight away if it fails the fraud checks. Registration goes ahead either way.
+func recordReferral(tx *sqlx.Tx, refereeID, referrerID int, code string, client models.ClientInfo) error {
+	userAgent := client.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	referral := models.Referral{
+		ReferrerCustomerID:    referrerID,
+		RefereeCustomerID:     refereeID,
+		ReferralCode:          strings.ToUpper(strings.TrimSpace(code)),
+		Status:                "pending",
+		RegistrationIP:        client.IP,
+		RegistrationUserAgent: userAgent,
+	}
+	reason, err := referralFraudReason(tx, referral)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		referral.Status = "rejected"
+		referral.RejectionReason = &reason
+		log.Printf("🚩 Referral of customer %d by customer %d rejected: %s", refereeID, referrerID, reason)
+	}
+	_, err = tx.Exec(`INSERT INTO referrals (referrer_customer_id, referee_customer_id, referral_code, status, rejection_reason,
+			registration_ip, registration_user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		referral.ReferrerCustomerID, referral.RefereeCustomerID, referral.ReferralCode, referral.Status, referral.RejectionReason,
+		referral.RegistrationIP, referral.RegistrationUserAgent)
+	if err != nil {
+		return fmt.Errorf("failed to record referral: %w", err)
+	}
+	return nil
+}
+
+// referralBookingDiscount is the referee's discount off net, the price before VAT, if this is
+// their first rental and their referral is still pending. Cancelled and failed rentals do not
+// count, so the discount carries over to the next booking.
+func referralBookingDiscount(tx *sqlx.Tx, customerID int, net models.Money) (models.Money, error) {
+	none := models.NewMoney(0, net.Currency)
+	// Locking the referral stops two bookings at once both getting the discount.
+	var referralID int
+	err := tx.Get(&referralID, "SELECT id FROM referrals WHERE referee_customer_id = $1 AND status = 'pending' FOR UPDATE", customerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return none, nil
+	}
+	if err != nil {
+		return none, fmt.Errorf("failed to fetch referral: %w", err)
+	}
+	var hasRentals bool
+	err = tx.Get(&hasRentals, "SELECT EXISTS(SELECT 1 FROM rentals WHERE customer_id = $1 AND status NOT IN ('Cancelled', 'Failed'))", customerID)
+	if err != nil {
+		return none, fmt.Errorf("failed to check earlier rentals: %w", err)
+	}
+	if hasRentals {
+		return none, nil
+	}
+	discount := net.MulRatio(referralRefereeDiscountPercent, 100, models.RoundDown)
+	if discount.Cmp(referralRefereeDiscountCap) > 0 {
+		discount = referralRefereeDiscountCap
+	}
+	return discount, nil
+}
+
+// convertReferral credits the referrer once a referee's rental is returned, if the referee was
+// referred and the referral is still pending. The fraud checks are run again first, as the
+// referee has had time to sign in and change their details since registering.
+func convertReferral(tx *sqlx.Tx, rentalID int) error {
+	var referral models.Referral
+	err := tx.Get(&referral, `SELECT f.id, f.referrer_customer_id, f.referee_customer_id, f.referral_code, f.status,
+			f.registration_ip, f.registration_user_agent, f.reward_points, f.created_at
+		FROM referrals f JOIN rentals r ON r.customer_id = f.referee_customer_id
+		WHERE r.id = $1 AND f.status = 'pending' FOR UPDATE OF f`, rentalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch referral: %w", err)
+	}
+	reason, err := referralFraudReason(tx, referral)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		if _, err := tx.Exec("UPDATE referrals SET status = 'rejected', rejection_reason = $1 WHERE id = $2", reason, referral.ID); err != nil {
+			return fmt.Errorf("failed to reject referral: %w", err)
+		}
+		log.Printf("🚩 Referral %d rejected on conversion: %s", referral.ID, reason)
+		return nil
+	}
+
+	_, err = tx.Exec(`INSERT INTO loyalty_transactions (customer_id, entry_type, points, remaining, expires_at, description)
+		VALUES ($1, 'referral', $2, $2, NOW() + $3::interval, $4)`,
+		referral.ReferrerCustomerID, referralRewardPoints, loyaltyPointValidity,
+		fmt.Sprintf("Referral credit: customer #%d completed their first rental", referral.RefereeCustomerID))
+	if err != nil {
+		return fmt.Errorf("failed to credit referrer: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE referrals SET status = 'converted', converted_rental_id = $1, reward_points = $2, converted_at = NOW()
+		WHERE id = $3`, rentalID, referralRewardPoints, referral.ID)
+	if err != nil {
+		return fmt.Errorf("failed to convert referral: %w", err)
+	}
+	log.Printf("🤝 Referral %d converted; customer %d credited %d points", referral.ID, referral.ReferrerCustomerID, referralRewardPoints)
+	return nil
+}
+
+// GetMyReferral returns a customer's referral code, giving them one if they have none yet, and
+// the people they have referred.
+func GetMyReferral(customerID int) (models.MyReferralSummary, error) {
+	if _, err := GetCustomerByID(customerID); err != nil {
+		return models.MyReferralSummary{}, err
+	}
+	code, err := assignReferralCode(config.DB, customerID)
+	if err != nil {
+		return models.MyReferralSummary{}, err
+	}
+	summary := models.MyReferralSummary{
+		ReferralCode:           code,
+		RefereeDiscountPercent: referralRefereeDiscountPercent,
+		RefereeDiscountCap:     referralRefereeDiscountCap,
+		RewardPoints:           referralRewardPoints,
+		Referrals:              []models.MyReferralItem{},
+	}
+	err = config.DB.Select(&summary.Referrals, `SELECT SPLIT_PART(c.name, ' ', 1) AS referee_name, f.status, f.reward_points, f.created_at, f.converted_at
+		FROM referrals f JOIN customers c ON c.id = f.referee_customer_id
+		WHERE f.referrer_customer_id = $1 ORDER BY f.created_at DESC`, customerID)
+	if err != nil {
+		return models.MyReferralSummary{}, fmt.Errorf("failed to fetch referrals: %w", err)
+	}
+	return summary, nil
+}
+
+// GetReferralReport reports on the referrals registered in [from, to): how many converted, what
+// they cost, who referred the most, and each referral with its fraud check outcome.
+func GetReferralReport(from, to time.Time) (models.ReferralReport, error) {
+	log.Printf("⚙️ Service: Building referral report for %s to %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	report := models.ReferralReport{From: from, To: to, TopReferrers: []models.ReferrerReportItem{}, Referrals: []models.Referral{}}
+
+	err := config.DB.Select(&report.Referrals, "SELECT "+referralSelectColumns+referralJoins+`
+		WHERE f.created_at >= $1 AND f.created_at < $2 ORDER BY f.created_at DESC`, from, to)
+	if err != nil {
+		return models.ReferralReport{}, fmt.Errorf("failed to fetch referrals: %w", err)
+	}
+	for _, referral := range report.Referrals {
+		report.Registered++
+		switch referral.Status {
+		case "pending":
+			report.Pending++
+		case "converted":
+			report.Converted++
+			report.PointsCredited += referral.RewardPoints
+		case "rejected":
+			report.Rejected++
+		}
+	}
+	if eligible := report.Registered - report.Rejected; eligible > 0 {
+		report.ConversionRate = float64(report.Converted) / float64(eligible)
+	}
+
+	err = config.DB.Get(&report.DiscountsGiven, `SELECT COALESCE(SUM(r.referral_discount), 0)
+		FROM rentals r JOIN referrals f ON f.referee_customer_id = r.customer_id
+		WHERE f.created_at >= $1 AND f.created_at < $2 AND r.status NOT IN ('Cancelled', 'Failed')`, from, to)
+	if err != nil {
+		return models.ReferralReport{}, fmt.Errorf("failed to sum referral discounts: %w", err)
+	}
+
+	err = config.DB.Select(&report.TopReferrers, `SELECT c.id AS customer_id, c.name, COUNT(*) AS registered,
+			COUNT(*) FILTER (WHERE f.status = 'converted') AS converted,
+			COUNT(*) FILTER (WHERE f.status = 'rejected') AS rejected
+		FROM referrals f JOIN customers c ON c.id = f.referrer_customer_id
+		WHERE f.created_at >= $1 AND f.created_at < $2
+		GROUP BY c.id, c.name
+		ORDER BY converted DESC, registered DESC, c.id
+		LIMIT 20`, from, to)
+	if err != nil {
+		return models.ReferralReport{}, fmt.Errorf("failed to fetch top referrers: %w", err)
+	}
+	return report, nil
+}
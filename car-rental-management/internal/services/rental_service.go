@@ -24,6 +24,11 @@ var (
 	ErrInvalidState    = errors.New("invalid operation for current rental/payment state")
 )
 
+// rentalPricingColumns are the price, loyalty and referral fields fixed when a rental aliased r was booked,
+// for selects into models.Rental.
+const rentalPricingColumns = "r.booked_price_per_day, r.booked_net, r.booked_vat, r.booked_total, " +
+	"r.loyalty_tier, r.loyalty_discount, r.points_redeemed, r.points_discount, r.loyalty_free_upgrade, r.referral_discount"
+
 func InitiateRentalBooking(customerID int, input models.InitiateRentalInput) (models.Rental, error) {
 	log.Printf("Service: Initiating rental for customer %d, car %d", customerID, input.CarID)
 	if customerID <= 0 {
@@ -60,8 +65,18 @@ func InitiateRentalBooking(customerID int, input models.InitiateRentalInput) (mo
 		}
 	}()
 
+	if finalErr = checkCustomerNotBlacklisted(tx, customerID); finalErr != nil {
+		return models.Rental{}, finalErr
+	}
+	if finalErr = checkCustomerEmailVerified(tx, customerID); finalErr != nil {
+		return models.Rental{}, finalErr
+	}
+	if finalErr = checkDriverLicence(tx, customerID, input.DropoffDatetime); finalErr != nil {
+		return models.Rental{}, finalErr
+	}
+
 	var car models.Car
-	errCar := tx.Get(&car, "SELECT id, availability, branch_id FROM cars WHERE id=$1 FOR UPDATE", input.CarID)
+	errCar := tx.Get(&car, "SELECT id, availability, branch_id, price_per_day FROM cars WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", input.CarID)
 	if errCar != nil {
 		if errors.Is(errCar, sql.ErrNoRows) {
 			finalErr = ErrCarNotFound // Use defined error
@@ -75,7 +90,7 @@ func InitiateRentalBooking(customerID int, input models.InitiateRentalInput) (mo
 	var overlapCount int
 	overlapQuery := `
             SELECT COUNT(*) FROM rentals
-            WHERE car_id = $1
+            WHERE car_id = $1 AND deleted_at IS NULL
               AND status IN ('Pending', 'Booked', 'Confirmed', 'Active', 'Pending Verification')
               AND (pickup_datetime < $3 AND dropoff_datetime > $2)`
 	errOverlap := tx.Get(&overlapCount, overlapQuery, input.CarID, input.PickupDatetime, input.DropoffDatetime)
@@ -90,14 +105,56 @@ func InitiateRentalBooking(customerID int, input models.InitiateRentalInput) (mo
 		return models.Rental{}, finalErr
 	}
 
+	// Rentals billed to a corporate account are invoiced monthly, so they must fit the member's limit
+	rentalNet, _, rentalTotal := rentalCharge(car.PricePerDay, billableRentalDays(input.PickupDatetime, input.DropoffDatetime))
+	if input.CorporateAccountID != nil {
+		if input.RedeemPoints > 0 {
+			finalErr = ErrLoyaltyCorporateBooking
+			return models.Rental{}, finalErr
+		}
+		finalErr = checkCorporateBooking(tx, *input.CorporateAccountID, customerID, input.PickupDatetime, rentalTotal)
+		if finalErr != nil {
+			log.Printf("❌ InitiateRentalBooking: Corporate booking rejected for customer %d on account %d: %v", customerID, *input.CorporateAccountID, finalErr)
+			return models.Rental{}, finalErr
+		}
+	}
+
+	// Loyalty and referral benefits are for personal bookings; the company pays for corporate ones.
+	// A referred customer's first-rental discount comes first, then loyalty applies to the rest.
+	loyalty := bookingLoyalty{TierDiscount: models.NewMoney(0, models.BaseCurrency), PointsDiscount: models.NewMoney(0, models.BaseCurrency)}
+	referralDiscount := models.NewMoney(0, models.BaseCurrency)
+	if input.CorporateAccountID == nil {
+		if referralDiscount, finalErr = referralBookingDiscount(tx, customerID, rentalNet); finalErr != nil {
+			return models.Rental{}, finalErr
+		}
+		if loyalty, finalErr = loyaltyBookingDiscount(tx, customerID, rentalNet.Sub(referralDiscount), input.RedeemPoints); finalErr != nil {
+			return models.Rental{}, finalErr
+		}
+	}
+
+	// The price is fixed now, so later changes to the car's daily rate do not reach this rental.
+	bookedNet, bookedVAT, bookedTotal := discountedRentalCharge(car.PricePerDay, billableRentalDays(input.PickupDatetime, input.DropoffDatetime),
+		loyalty.TierDiscount.Add(loyalty.PointsDiscount).Add(referralDiscount))
+
 	rental := models.Rental{
-		CustomerID:      customerID,
-		CarID:           input.CarID,
-		PickupDatetime:  input.PickupDatetime,
-		DropoffDatetime: input.DropoffDatetime,
-		PickupLocation:  input.PickupLocation,
-		Status:          "Pending",
-		BookingDate:     nil,
+		CustomerID:         customerID,
+		CarID:              input.CarID,
+		PickupDatetime:     input.PickupDatetime,
+		DropoffDatetime:    input.DropoffDatetime,
+		PickupLocation:     input.PickupLocation,
+		Status:             "Pending",
+		BookingDate:        nil,
+		CorporateAccountID: input.CorporateAccountID,
+		BookedPricePerDay:  car.PricePerDay,
+		BookedNet:          bookedNet,
+		BookedVAT:          bookedVAT,
+		BookedTotal:        bookedTotal,
+		LoyaltyTier:        loyalty.Tier,
+		LoyaltyDiscount:    loyalty.TierDiscount,
+		PointsRedeemed:     loyalty.PointsRedeemed,
+		PointsDiscount:     loyalty.PointsDiscount,
+		LoyaltyFreeUpgrade: loyalty.FreeUpgrade,
+		ReferralDiscount:   referralDiscount,
 	}
 
 	if rental.PickupLocation == nil || *rental.PickupLocation == "" {
@@ -115,13 +172,17 @@ func InitiateRentalBooking(customerID int, input models.InitiateRentalInput) (mo
 	}
 
 	insertQuery := `
-		INSERT INTO rentals (customer_id, car_id, pickup_datetime, dropoff_datetime, pickup_location, status, booking_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO rentals (customer_id, car_id, pickup_datetime, dropoff_datetime, pickup_location, status, booking_date, corporate_account_id,
+			booked_price_per_day, booked_net, booked_vat, booked_total,
+			loyalty_tier, loyalty_discount, points_redeemed, points_discount, loyalty_free_upgrade, referral_discount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
         RETURNING id, created_at, updated_at`
 
 	finalErr = tx.QueryRowx(
 		insertQuery,
-		rental.CustomerID, rental.CarID, rental.PickupDatetime, rental.DropoffDatetime, rental.PickupLocation, rental.Status, rental.BookingDate,
+		rental.CustomerID, rental.CarID, rental.PickupDatetime, rental.DropoffDatetime, rental.PickupLocation, rental.Status, rental.BookingDate, rental.CorporateAccountID,
+		rental.BookedPricePerDay, rental.BookedNet, rental.BookedVAT, rental.BookedTotal,
+		rental.LoyaltyTier, rental.LoyaltyDiscount, rental.PointsRedeemed, rental.PointsDiscount, rental.LoyaltyFreeUpgrade, rental.ReferralDiscount,
 	).Scan(&rental.ID, &rental.CreatedAt, &rental.UpdatedAt)
 
 	if finalErr != nil {
@@ -129,12 +190,39 @@ func InitiateRentalBooking(customerID int, input models.InitiateRentalInput) (mo
 		finalErr = fmt.Errorf("database error creating pending rental: %w", finalErr)
 		return models.Rental{}, finalErr
 	}
+	if rental.PointsRedeemed > 0 {
+		if finalErr = redeemLoyaltyPoints(tx, customerID, rental.ID, rental.PointsRedeemed); finalErr != nil {
+			return models.Rental{}, finalErr
+		}
+	}
+
+	// No slip step for corporate rentals: they are billed on the account's monthly invoice.
+	if rental.CorporateAccountID != nil {
+		booked, errStatus := UpdateRentalStatus(tx, rental.ID, "Booked", nil)
+		if errStatus != nil {
+			log.Printf("❌ InitiateRentalBooking: Error booking corporate rental %d: %v", rental.ID, errStatus)
+			finalErr = fmt.Errorf("failed to book corporate rental: %w", errStatus)
+			return models.Rental{}, finalErr
+		}
+		log.Printf("✅ Service: Corporate rental %d booked on account %d", rental.ID, *rental.CorporateAccountID)
+		return booked, nil
+	}
 
 	log.Printf("✅ Service: Pending rental created with ID: %d", rental.ID)
 	return rental, finalErr
 }
 
+// GetRentalByID returns a rental, unless it has been deleted.
 func GetRentalByID(id int) (models.Rental, error) {
+	return getRental(id, false)
+}
+
+// GetRentalByIDIncludingDeleted returns a rental even if it has been deleted.
+func GetRentalByIDIncludingDeleted(id int) (models.Rental, error) {
+	return getRental(id, true)
+}
+
+func getRental(id int, includeDeleted bool) (models.Rental, error) {
 	var rental models.Rental
 	log.Println("🔍 Service: Fetching rental by ID:", id)
 	if id <= 0 {
@@ -144,12 +232,16 @@ func GetRentalByID(id int) (models.Rental, error) {
 		SELECT
 			r.id, r.customer_id, r.car_id, r.booking_date,
 			r.pickup_datetime, r.dropoff_datetime, r.pickup_location,
-			r.status, r.created_at, r.updated_at,
+			r.status, r.corporate_account_id, r.created_at, r.updated_at, r.deleted_at,
+			` + rentalPricingColumns + `,
 			c.brand AS "car.brand",
 			c.model AS "car.model"
 		FROM rentals r
 		JOIN cars c ON r.car_id = c.id
 		WHERE r.id=$1`
+	if !includeDeleted {
+		query += " AND r.deleted_at IS NULL"
+	}
 	err := config.DB.Get(&rental, query, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -228,7 +320,7 @@ func UpdateRentalStatus(tx *sqlx.Tx, rentalID int, newStatus string, employeeID
 	var currentStatus string
 	var carID int
 	// Use currentTx for all DB operations within this function
-	query := "SELECT status, car_id FROM rentals WHERE id=$1 FOR UPDATE"
+	query := "SELECT status, car_id FROM rentals WHERE id=$1 AND deleted_at IS NULL FOR UPDATE"
 	err = currentTx.QueryRowx(query, rentalID).Scan(&currentStatus, &carID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -299,7 +391,7 @@ func UpdateRentalStatus(tx *sqlx.Tx, rentalID int, newStatus string, employeeID
 			var activeBookingCount int
 			// Check for other bookings that would keep the car unavailable
 			checkOverlapQuery := `SELECT COUNT(*) FROM rentals
-                                  WHERE car_id = $1 AND id != $2
+                                  WHERE car_id = $1 AND id != $2 AND deleted_at IS NULL
                                   AND status IN ('Booked', 'Confirmed', 'Active', 'Pending Verification')
                                   AND dropoff_datetime > NOW()` // Consider only future or ongoing bookings
 			countErr := currentTx.Get(&activeBookingCount, checkOverlapQuery, carID, rentalID)
@@ -328,6 +420,18 @@ func UpdateRentalStatus(tx *sqlx.Tx, rentalID int, newStatus string, employeeID
 		log.Printf("ℹ️ No car availability update needed for status change from '%s' to '%s'", currentStatus, newStatus)
 	}
 
+	switch newStatus {
+	case "Returned":
+		if err = awardLoyaltyPoints(currentTx, rentalID); err == nil {
+			err = convertReferral(currentTx, rentalID)
+		}
+	case "Cancelled", "Failed":
+		err = reinstateLoyaltyPoints(currentTx, rentalID)
+	}
+	if err != nil {
+		return
+	}
+
 	// Fetch the updated rental details (outside the transaction if this function manages its own)
 	// If called with an existing tx, this Get should also use that tx for consistency.
 	// However, GetRentalByID creates its own connection. For simplicity for this example, we fetch after commit.
@@ -342,7 +446,8 @@ func UpdateRentalStatus(tx *sqlx.Tx, rentalID int, newStatus string, employeeID
             SELECT
                 r.id, r.customer_id, r.car_id, r.booking_date,
                 r.pickup_datetime, r.dropoff_datetime, r.pickup_location,
-                r.status, r.created_at, r.updated_at,
+                r.status, r.corporate_account_id, r.created_at, r.updated_at,
+                ` + rentalPricingColumns + `,
                 c.brand AS "car.brand",
                 c.model AS "car.model"
             FROM rentals r
@@ -361,7 +466,8 @@ func UpdateRentalStatus(tx *sqlx.Tx, rentalID int, newStatus string, employeeID
 	return
 }
 
-func GetRentalsPaginated(filters models.RentalFiltersWithPagination) (models.PaginatedRentalsResponse, error) {
+// GetRentalsPaginated lists rentals matching filters, limited to rentals of cars in scope.
+func GetRentalsPaginated(filters models.RentalFiltersWithPagination, scope BranchScope) (models.PaginatedRentalsResponse, error) {
 	var response models.PaginatedRentalsResponse
 	response.Rentals = []models.Rental{}
 
@@ -374,7 +480,8 @@ func GetRentalsPaginated(filters models.RentalFiltersWithPagination) (models.Pag
 		SELECT
 			r.id, r.customer_id, r.car_id, r.booking_date,
 			r.pickup_datetime, r.dropoff_datetime, r.pickup_location,
-			r.status, r.created_at, r.updated_at,
+			r.status, r.corporate_account_id, r.created_at, r.updated_at, r.deleted_at,
+			` + rentalPricingColumns + `,
 			c.brand AS "car.brand",
 			c.model AS "car.model"
 		FROM rentals r
@@ -383,6 +490,9 @@ func GetRentalsPaginated(filters models.RentalFiltersWithPagination) (models.Pag
 	countQueryBuilder.WriteString("SELECT COUNT(r.id) FROM rentals r JOIN cars c ON r.car_id = c.id")
 
 	var conditions []string
+	if !filters.IncludeDeleted {
+		conditions = append(conditions, "r.deleted_at IS NULL")
+	}
 	if filters.RentalID != nil && *filters.RentalID > 0 {
 		conditions = append(conditions, fmt.Sprintf("r.id = $%d", paramCount))
 		args = append(args, *filters.RentalID)
@@ -408,6 +518,11 @@ func GetRentalsPaginated(filters models.RentalFiltersWithPagination) (models.Pag
 		args = append(args, *filters.PickupDateAfter)
 		paramCount++
 	}
+	if !scope.All {
+		conditions = append(conditions, fmt.Sprintf("c.branch_id = ANY($%d)", paramCount))
+		args = append(args, scope.filterArg())
+		paramCount++
+	}
 
 	if len(conditions) > 0 {
 		whereClause := " WHERE " + strings.Join(conditions, " AND ")
@@ -490,19 +605,20 @@ func GetRentals() ([]models.Rental, error) {
 		SortBy:        "id",
 		SortDirection: "DESC",
 	}
-	result, err := GetRentalsPaginated(paginatedFilters)
+	result, err := GetRentalsPaginated(paginatedFilters, AllBranches)
 	if err != nil {
 		return nil, err
 	}
 	return result.Rentals, nil
 }
 
+// DeleteRental soft-deletes a rental. A deleted rental no longer holds its car.
 func DeleteRental(rentalID int) error {
 	log.Println("🗑 Service: Deleting rental with ID:", rentalID)
 	if rentalID <= 0 {
 		return errors.New("invalid rental ID")
 	}
-	result, err := config.DB.Exec("DELETE FROM rentals WHERE id=$1", rentalID)
+	result, err := config.DB.Exec("UPDATE rentals SET deleted_at = NOW() WHERE id=$1 AND deleted_at IS NULL", rentalID)
 	if err != nil {
 		log.Printf("❌ Service: Error deleting rental %d: %v", rentalID, err)
 		return fmt.Errorf("failed to delete rental: %w", err)
@@ -515,6 +631,73 @@ func DeleteRental(rentalID int) error {
 	return nil
 }
 
+// RestoreRental undoes DeleteRental. Its car and customer must not be deleted, and a rental that
+// still holds its car must not overlap a booking made since.
+func RestoreRental(rentalID int) (err error) {
+	if rentalID <= 0 {
+		return errors.New("invalid rental ID")
+	}
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var row struct {
+		CarDeleted      bool      `db:"car_deleted"`
+		CustomerDeleted bool      `db:"customer_deleted"`
+		Holding         bool      `db:"holding"`
+		CarID           int       `db:"car_id"`
+		Pickup          time.Time `db:"pickup_datetime"`
+		Dropoff         time.Time `db:"dropoff_datetime"`
+	}
+	err = tx.Get(&row, `SELECT c.deleted_at IS NOT NULL AS car_deleted, cu.deleted_at IS NOT NULL AS customer_deleted,
+			r.status IN `+liveRentalStatuses+` AS holding, r.car_id, r.pickup_datetime, r.dropoff_datetime
+		FROM rentals r
+		JOIN cars c ON r.car_id = c.id
+		JOIN customers cu ON r.customer_id = cu.id
+		WHERE r.id = $1 AND r.deleted_at IS NOT NULL
+		FOR UPDATE OF r, c`, rentalID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNothingToRestore
+			return err
+		}
+		return fmt.Errorf("failed to fetch deleted rental: %w", err)
+	}
+	if row.CarDeleted || row.CustomerDeleted {
+		err = ErrRestoreParentFirst
+		return err
+	}
+	if row.Holding {
+		var overlapCount int
+		err = tx.Get(&overlapCount, `SELECT COUNT(*) FROM rentals
+			WHERE car_id = $1 AND id != $2 AND deleted_at IS NULL AND status IN `+liveRentalStatuses+`
+			  AND (pickup_datetime < $4 AND dropoff_datetime > $3)`, row.CarID, rentalID, row.Pickup, row.Dropoff)
+		if err != nil {
+			return fmt.Errorf("failed to verify car availability: %w", err)
+		}
+		if overlapCount > 0 {
+			err = ErrCarNotAvailable
+			return err
+		}
+	}
+	if _, err = tx.Exec("UPDATE rentals SET deleted_at = NULL WHERE id = $1", rentalID); err != nil {
+		return fmt.Errorf("failed to restore rental: %w", err)
+	}
+	log.Printf("♻️ Service: Rental %d restored", rentalID)
+	return nil
+}
+
 func GetRentalsByCustomerIDPaginated(customerID int, page int, limit int) (models.PaginatedRentalsResponse, error) {
 	if customerID <= 0 {
 		return models.PaginatedRentalsResponse{}, errors.New("invalid customer ID")
@@ -526,7 +709,7 @@ func GetRentalsByCustomerIDPaginated(customerID int, page int, limit int) (model
 		SortBy:        "pickup_datetime",
 		SortDirection: "DESC",
 	}
-	return GetRentalsPaginated(filters)
+	return GetRentalsPaginated(filters, AllBranches)
 }
 
 func GetRentalsByCustomerID(customerID int) ([]models.Rental, error) {
@@ -564,6 +747,35 @@ func CancelCustomerRental(rentalID int, customerID int) error {
 	return nil
 }
 
+// billableRentalDays counts started days: ceil to the next full day.
+// Example: 1 hour = 1 day, 25 hours = 2 days
+func billableRentalDays(pickup, dropoff time.Time) int {
+	hours := dropoff.Sub(pickup).Hours()
+	if hours <= 0 {
+		return 0
+	}
+	rentalDays := int(math.Ceil(hours / 24.0))
+	if rentalDays == 0 { // If duration is > 0 but < 24h, count as 1 day
+		rentalDays = 1
+	}
+	return rentalDays
+}
+
+// rentalCharge prices a rental: the daily rate times billable days, plus 7% VAT (see ledger_service.go).
+func rentalCharge(pricePerDay models.Money, rentalDays int) (base, vat, total models.Money) {
+	return discountedRentalCharge(pricePerDay, rentalDays, models.NewMoney(0, models.BaseCurrency))
+}
+
+// discountedRentalCharge is rentalCharge with a discount taken off the net price before VAT.
+func discountedRentalCharge(pricePerDay models.Money, rentalDays int, discount models.Money) (net, vat, total models.Money) {
+	net = pricePerDay.MulInt(int64(rentalDays)).Sub(discount)
+	if net.IsNegative() {
+		net = models.NewMoney(0, net.Currency)
+	}
+	vat = vatOnNet(net)
+	return net, vat, net.Add(vat)
+}
+
 func CalculateRentalCost(rentalID int) (models.Payment, error) {
 	log.Println("Calculating cost for rental ID:", rentalID)
 	if rentalID <= 0 {
@@ -571,17 +783,21 @@ func CalculateRentalCost(rentalID int) (models.Payment, error) {
 	}
 
 	var rentalData struct {
-		Pickup  time.Time `db:"pickup_datetime"`
-		Dropoff time.Time `db:"dropoff_datetime"`
-		Status  string    `db:"status"`
-		Price   float64   `db:"price_per_day"`
-		CarID   int       `db:"car_id"`
-	}
-
-	query := `SELECT r.pickup_datetime, r.dropoff_datetime, r.status, c.price_per_day, r.car_id
+		Pickup  time.Time    `db:"pickup_datetime"`
+		Dropoff time.Time    `db:"dropoff_datetime"`
+		Status  string       `db:"status"`
+		Price   models.Money `db:"booked_price_per_day"`
+		CarID   int          `db:"car_id"`
+		// Fixed at booking, after loyalty and referral discounts
+		Net   models.Money `db:"booked_net"`
+		VAT   models.Money `db:"booked_vat"`
+		Total models.Money `db:"booked_total"`
+	}
+
+	query := `SELECT r.pickup_datetime, r.dropoff_datetime, r.status, r.booked_price_per_day, r.car_id,
+				r.booked_net, r.booked_vat, r.booked_total
               FROM rentals r
-              JOIN cars c ON r.car_id = c.id
-              WHERE r.id=$1`
+              WHERE r.id=$1 AND r.deleted_at IS NULL`
 	err := config.DB.Get(&rentalData, query, rentalID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -595,8 +811,8 @@ func CalculateRentalCost(rentalID int) (models.Payment, error) {
 		log.Printf("❌ CalculateRentalCost: Invalid dates for rental %d: Pickup %v, Dropoff %v", rentalID, rentalData.Pickup, rentalData.Dropoff)
 		return models.Payment{}, ErrInvalidDates
 	}
-	if rentalData.Price <= 0 {
-		log.Printf("❌ CalculateRentalCost: Invalid car price (%.2f) for car %d", rentalData.Price, rentalData.CarID)
+	if !rentalData.Price.IsPositive() {
+		log.Printf("❌ CalculateRentalCost: Invalid car price (%s) for car %d", rentalData.Price, rentalData.CarID)
 		return models.Payment{}, errors.New("invalid car price")
 	}
 
@@ -606,22 +822,14 @@ func CalculateRentalCost(rentalID int) (models.Payment, error) {
 	if hours <= 0 { // Less than or equal to 0 hours means no rental period
 		log.Printf("⚠️ CalculateRentalCost: Duration is zero or negative for rental %d. Setting cost to 0.", rentalID)
 		// Create a payment object with 0 amount. Status might depend on business rules.
-		return models.Payment{RentalID: rentalID, Amount: 0, PaymentStatus: "Pending"}, nil // Or another appropriate status
+		return models.Payment{RentalID: rentalID, Amount: models.NewMoney(0, models.BaseCurrency), PaymentStatus: "Pending"}, nil // Or another appropriate status
 	}
 
-	// Calculate days: Ceil to the next full day.
-	// Example: 1 hour = 1 day, 25 hours = 2 days
-	rentalDays := int(math.Ceil(hours / 24.0))
-	if rentalDays == 0 && hours > 0 { // If duration is > 0 but < 24h, count as 1 day
-		rentalDays = 1
-	}
+	rentalDays := billableRentalDays(rentalData.Pickup, rentalData.Dropoff)
 
-	baseCost := float64(rentalDays) * rentalData.Price
-	vatRate := 0.07 // 7% VAT
-	vatAmount := baseCost * vatRate
-	totalCost := baseCost + vatAmount
+	baseCost, vatAmount, totalCost := rentalData.Net, rentalData.VAT, rentalData.Total
 
-	log.Printf("✅ Calculated cost for rental %d (%d days, %.2f hours): Total %.2f (Base: %.2f, VAT: %.2f)",
+	log.Printf("✅ Calculated cost for rental %d (%d days, %.2f hours): Total %s (Base: %s, VAT: %s)",
 		rentalID, rentalDays, hours, totalCost, baseCost, vatAmount)
 
 	return models.Payment{
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	ErrNothingToRestore   = errors.New("no deleted record with this ID")
+	ErrRestoreParentFirst = errors.New("this record belongs to one that is still deleted; restore that first")
+)
+
+// Rental statuses that still need the car and customer, so neither may be deleted while one exists.
+const liveRentalStatuses = `('Pending', 'Booked', 'Confirmed', 'Active', 'Pending Verification')`
+
+// PurgeResult counts the soft-deleted rows a purge removed for good.
+type PurgeResult struct {
+	Rentals           int64 `json:"rentals"`
+	Cars              int64 `json:"cars"`
+	CustomerDocuments int64 `json:"customer_documents"`
+	Customers         int64 `json:"customers"`
+	Branches          int64 `json:"branches"`
+	Employees         int64 `json:"employees"`
+}
+
+// PurgeDeletedRecords permanently removes rows soft-deleted more than retention ago. Rows that
+// something still refers to (a car with rentals) are kept until those go too. Rentals with any
+// accounting record (payments, ledger postings, a tax invoice or a corporate invoice line) are
+// never purged, since deleting them would cascade into the payments and orphan the books; nor,
+// in turn, are their car and customer. Purging a customer removes their uploaded documents.
+func PurgeDeletedRecords(retention time.Duration) (result PurgeResult, err error) {
+	cutoff := time.Now().Add(-retention)
+	var documentFiles []string
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return PurgeResult{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else if err = tx.Commit(); err == nil {
+			removeCustomerDocumentFiles(documentFiles)
+		}
+	}()
+
+	// Parents after children, so that rows freed by one step are purged by the next.
+	// A step with files set collects the file names its query returns.
+	steps := []struct {
+		count *int64
+		query string
+		files *[]string
+	}{
+		{&result.Rentals, `DELETE FROM rentals r WHERE r.deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.rental_id = r.id)
+			AND NOT EXISTS (SELECT 1 FROM ledger_transactions lt WHERE lt.rental_id = r.id)
+			AND NOT EXISTS (SELECT 1 FROM invoices i WHERE i.rental_id = r.id)
+			AND NOT EXISTS (SELECT 1 FROM corporate_invoice_items i WHERE i.rental_id = r.id)`, nil},
+		{&result.Cars, `DELETE FROM cars c WHERE c.deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM rentals r WHERE r.car_id = c.id)`, nil},
+		{&result.CustomerDocuments, `DELETE FROM customer_documents d USING customers cu
+			WHERE d.customer_id = cu.id AND cu.deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM rentals r WHERE r.customer_id = cu.id) RETURNING d.file_name`, &documentFiles},
+		{&result.Customers, `DELETE FROM customers cu WHERE cu.deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM rentals r WHERE r.customer_id = cu.id)`, nil},
+		{&result.Branches, `DELETE FROM branches b WHERE b.deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM cars c WHERE c.branch_id = b.id)`, nil},
+		{&result.Employees, `DELETE FROM employees WHERE deleted_at < $1`, nil},
+	}
+	for _, step := range steps {
+		if step.files != nil {
+			if errStep := tx.Select(step.files, step.query, cutoff); errStep != nil {
+				err = fmt.Errorf("failed to purge deleted records: %w", errStep)
+				return PurgeResult{}, err
+			}
+			*step.count = int64(len(*step.files))
+			continue
+		}
+		purged, errStep := tx.Exec(step.query, cutoff)
+		if errStep != nil {
+			err = fmt.Errorf("failed to purge deleted records: %w", errStep)
+			return PurgeResult{}, err
+		}
+		*step.count, _ = purged.RowsAffected()
+	}
+	return result, nil
+}
+
+// StartDeletedRecordPurge runs PurgeDeletedRecords once a day in the background, removing rows
+// that have been soft-deleted for longer than DELETED_RECORD_RETENTION.
+func StartDeletedRecordPurge() {
+	retention := config.DeletedRecordRetention()
+	log.Printf("🗑️ Soft-deleted records will be purged after %v", retention)
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			result, err := PurgeDeletedRecords(retention)
+			if err != nil {
+				log.Printf("❌ Deleted record purge failed: %v", err)
+			} else {
+				log.Printf("✅ Deleted record purge: %+v", result)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -79,6 +79,21 @@ func GetReviewByRental(rentalID int) (models.Review, error) {
 	return review, nil
 }
 
+func GetReviewByID(reviewID int) (models.Review, error) {
+	var review models.Review
+	query := `SELECT id, customer_id, rental_id, rating, comment, created_at, updated_at
+              FROM reviews WHERE id=$1`
+	err := config.DB.Get(&review, query, reviewID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Review{}, errors.New("review not found")
+		}
+		log.Printf("❌ Error fetching review %d: %v", reviewID, err)
+		return models.Review{}, errors.New("failed to fetch review")
+	}
+	return review, nil
+}
+
 func DeleteReview(reviewID int, actorID int, actorRole string) error {
 	log.Printf("Attempting to delete review %d by actor %d (role: %s)", reviewID, actorID, actorRole)
 	var reviewOwnerID int
@@ -91,10 +106,14 @@ func DeleteReview(reviewID int, actorID int, actorRole string) error {
 		return errors.New("failed to get review details")
 	}
 	allowed := false
-	if actorRole == "customer" && actorID == reviewOwnerID {
-		allowed = true
-	} else if actorRole == "admin" || actorRole == "manager" {
-		allowed = true
+	if actorRole == "customer" {
+		allowed = actorID == reviewOwnerID
+	} else {
+		allowed, err = RoleHasPermissions(actorRole, "reviews:moderate")
+		if err != nil {
+			log.Printf("❌ Error checking review permission for role %s: %v", actorRole, err)
+			return errors.New("failed to check permissions")
+		}
 	}
 	if !allowed {
 		log.Printf("❌ Permission denied: Actor %d (role %s) cannot delete review %d owned by %d", actorID, actorRole, reviewID, reviewOwnerID)
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleExists        = errors.New("a role with this name already exists")
+	ErrInvalidRoleName   = errors.New("role name must be 2-50 lowercase letters, digits, '-' or '_' and start with a letter")
+	ErrSystemRole        = errors.New("system roles cannot be changed or deleted")
+	ErrRoleInUse         = errors.New("role is still assigned to employees")
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// ensureRoleExists returns ErrRoleNotFound unless role is a defined role.
+func ensureRoleExists(q sqlx.Queryer, role string) error {
+	var exists bool
+	if err := sqlx.Get(q, &exists, "SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)", role); err != nil {
+		return fmt.Errorf("failed to check role: %w", err)
+	}
+	if !exists {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// RoleHasPermissions reports whether role holds every one of permissions.
+func RoleHasPermissions(role string, permissions ...string) (bool, error) {
+	if len(permissions) == 0 {
+		return true, nil
+	}
+	var held int
+	err := config.DB.Get(&held, "SELECT COUNT(DISTINCT permission) FROM role_permissions WHERE role = $1 AND permission = ANY($2)",
+		role, pq.Array(permissions))
+	if err != nil {
+		return false, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	return held == len(uniqueStrings(permissions)), nil
+}
+
+// GetRolePermissions lists the permissions held by role.
+func GetRolePermissions(role string) ([]string, error) {
+	permissions := []string{}
+	if err := config.DB.Select(&permissions, "SELECT permission FROM role_permissions WHERE role = $1 ORDER BY permission", role); err != nil {
+		return nil, fmt.Errorf("failed to fetch role permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// GetPermissions returns the permission catalogue.
+func GetPermissions() ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	if err := config.DB.Select(&permissions, "SELECT code, description FROM permissions ORDER BY code"); err != nil {
+		log.Println("❌ Error fetching permissions:", err)
+		return nil, fmt.Errorf("failed to fetch permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+const roleSelect = `SELECT r.name, r.description, r.is_system, r.created_at, r.updated_at,
+		(SELECT COUNT(*) FROM employees e WHERE e.role = r.name) AS employee_count
+	FROM roles r`
+
+// GetRoles lists every role with its permissions.
+func GetRoles() ([]models.Role, error) {
+	roles := []models.Role{}
+	if err := config.DB.Select(&roles, roleSelect+" ORDER BY r.name"); err != nil {
+		log.Println("❌ Error fetching roles:", err)
+		return nil, fmt.Errorf("failed to fetch roles: %w", err)
+	}
+
+	var grants []struct {
+		Role       string `db:"role"`
+		Permission string `db:"permission"`
+	}
+	if err := config.DB.Select(&grants, "SELECT role, permission FROM role_permissions ORDER BY role, permission"); err != nil {
+		log.Println("❌ Error fetching role permissions:", err)
+		return nil, fmt.Errorf("failed to fetch role permissions: %w", err)
+	}
+	byRole := make(map[string][]string)
+	for _, grant := range grants {
+		byRole[grant.Role] = append(byRole[grant.Role], grant.Permission)
+	}
+	for i := range roles {
+		roles[i].Permissions = byRole[roles[i].Name]
+		if roles[i].Permissions == nil {
+			roles[i].Permissions = []string{}
+		}
+	}
+	return roles, nil
+}
+
+// GetRoleByName returns one role with its permissions.
+func GetRoleByName(name string) (models.Role, error) {
+	var role models.Role
+	if err := config.DB.Get(&role, roleSelect+" WHERE r.name = $1", name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Role{}, ErrRoleNotFound
+		}
+		return models.Role{}, fmt.Errorf("failed to fetch role: %w", err)
+	}
+	permissions, err := GetRolePermissions(name)
+	if err != nil {
+		return models.Role{}, err
+	}
+	role.Permissions = permissions
+	return role, nil
+}
+
+// checkPermissionsExist returns ErrUnknownPermission naming any code not in the catalogue.
+func checkPermissionsExist(tx *sqlx.Tx, permissions []string) error {
+	var known []string
+	if err := tx.Select(&known, "SELECT code FROM permissions WHERE code = ANY($1)", pq.Array(permissions)); err != nil {
+		return fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if len(known) != len(permissions) {
+		knownSet := make(map[string]bool, len(known))
+		for _, code := range known {
+			knownSet[code] = true
+		}
+		var unknown []string
+		for _, code := range permissions {
+			if !knownSet[code] {
+				unknown = append(unknown, code)
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrUnknownPermission, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// setRolePermissions replaces role's permissions with permissions, rejecting unknown codes.
+func setRolePermissions(tx *sqlx.Tx, role string, permissions []string) error {
+	permissions = uniqueStrings(permissions)
+	if err := checkPermissionsExist(tx, permissions); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role = $1", role); err != nil {
+		return fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+	_, err := tx.Exec("INSERT INTO role_permissions (role, permission) SELECT $1, UNNEST($2::text[])", role, pq.Array(permissions))
+	if err != nil {
+		return fmt.Errorf("failed to grant role permissions: %w", err)
+	}
+	return nil
+}
+
+// CreateRole adds a new role with the given permissions and returns its name.
+func CreateRole(input models.CreateRoleInput) (name string, err error) {
+	name = strings.TrimSpace(input.Name)
+	if !roleNamePattern.MatchString(name) {
+		return "", ErrInvalidRoleName
+	}
+	if name == "customer" { // Customer tokens carry this role name
+		return "", ErrRoleExists
+	}
+
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return "", fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	_, err = tx.Exec("INSERT INTO roles (name, description) VALUES ($1, $2)", name, strings.TrimSpace(input.Description))
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return "", ErrRoleExists
+		}
+		return "", fmt.Errorf("failed to create role: %w", err)
+	}
+	if err = setRolePermissions(tx, name, input.Permissions); err != nil {
+		return "", err
+	}
+	log.Printf("✅ Service: Role %s created with %d permissions", name, len(input.Permissions))
+	return name, nil
+}
+
+// UpdateRole changes a role's description and replaces its permissions. Permission checks read
+// the database on every request, so the change applies to signed-in employees straight away.
+func UpdateRole(name string, input models.UpdateRoleInput) (err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	var isSystem bool
+	if err = tx.Get(&isSystem, "SELECT is_system FROM roles WHERE name = $1 FOR UPDATE", name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoleNotFound
+		}
+		return fmt.Errorf("failed to fetch role: %w", err)
+	}
+	if isSystem {
+		return ErrSystemRole
+	}
+	if _, err = tx.Exec("UPDATE roles SET description = $1 WHERE name = $2", strings.TrimSpace(input.Description), name); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if err = setRolePermissions(tx, name, input.Permissions); err != nil {
+		return err
+	}
+	log.Printf("✅ Service: Role %s updated, now %d permissions", name, len(uniqueStrings(input.Permissions)))
+	return nil
+}
+
+// DeleteRole removes a role that no employee holds.
+func DeleteRole(name string) error {
+	var isSystem bool
+	if err := config.DB.Get(&isSystem, "SELECT is_system FROM roles WHERE name = $1", name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoleNotFound
+		}
+		return fmt.Errorf("failed to fetch role: %w", err)
+	}
+	if isSystem {
+		return ErrSystemRole
+	}
+	if _, err := config.DB.Exec("DELETE FROM roles WHERE name = $1", name); err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" {
+			return ErrRoleInUse
+		}
+		log.Printf("❌ Service: Error deleting role %s: %v", name, err)
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	log.Printf("✅ Service: Role %s deleted", name)
+	return nil
+}
+
+// uniqueStrings drops duplicates and empty strings, keeping the first occurrence order.
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
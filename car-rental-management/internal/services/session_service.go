@@ -0,0 +1,191 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// last_seen_at is refreshed at most this often, so each request does not cost a write.
+const sessionLastSeenResolution = 1 * time.Minute
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has ended, please log in again")
+)
+
+// sessionOwnerColumn is the sessions column holding the id of a user of userType.
+func sessionOwnerColumn(userType string) (string, error) {
+	_, ownerColumn, err := userTable(userType)
+	return ownerColumn, err
+}
+
+// upsertSession records a new session, or refreshes the details of an existing one on token rotation.
+func upsertSession(db sqlx.Execer, sessionID string, user tokenUser, client models.ClientInfo, expiresAt time.Time) error {
+	ownerColumn, err := sessionOwnerColumn(user.UserType)
+	if err != nil {
+		return err
+	}
+	userAgent := client.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	_, err = db.Exec(`INSERT INTO sessions (id, user_type, `+ownerColumn+`, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET user_agent = EXCLUDED.user_agent, ip_address = EXCLUDED.ip_address,
+			last_seen_at = NOW(), expires_at = EXCLUDED.expires_at`,
+		sessionID, user.UserType, user.ID, userAgent, client.IP, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record session: %w", err)
+	}
+	return nil
+}
+
+// CheckSession confirms that an access token's session belongs to the user and is still open,
+// and notes that it was seen from ip.
+func CheckSession(sessionID, userType string, userID int, ip string) error {
+	ownerColumn, err := sessionOwnerColumn(userType)
+	if err != nil {
+		return err
+	}
+	var session struct {
+		LastSeenAt time.Time  `db:"last_seen_at"`
+		ExpiresAt  time.Time  `db:"expires_at"`
+		RevokedAt  *time.Time `db:"revoked_at"`
+	}
+	err = config.DB.Get(&session, "SELECT last_seen_at, expires_at, revoked_at FROM sessions WHERE id = $1 AND "+ownerColumn+" = $2",
+		sessionID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionRevoked
+		}
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return ErrSessionRevoked
+	}
+	if time.Since(session.LastSeenAt) > sessionLastSeenResolution {
+		if _, err := config.DB.Exec("UPDATE sessions SET last_seen_at = NOW(), ip_address = $1 WHERE id = $2", ip, sessionID); err != nil {
+			log.Printf("⚠️ Failed to update last seen for session %s: %v", sessionID, err)
+		}
+	}
+	return nil
+}
+
+// GetSessions lists a user's open sessions, most recently used first. currentSessionID is marked.
+func GetSessions(userType string, userID int, currentSessionID string) ([]models.Session, error) {
+	ownerColumn, err := sessionOwnerColumn(userType)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []models.Session{}
+	err = config.DB.Select(&sessions, `SELECT id, user_agent, ip_address, created_at, last_seen_at, expires_at
+		FROM sessions WHERE `+ownerColumn+` = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		log.Printf("❌ Service: Error fetching sessions for %s %d: %v", userType, userID, err)
+		return nil, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Device = describeDevice(sessions[i].UserAgent)
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions: its refresh tokens stop working and its access
+// tokens are refused from the next request.
+func RevokeSession(userType string, userID int, sessionID string) error {
+	ownerColumn, err := sessionOwnerColumn(userType)
+	if err != nil {
+		return err
+	}
+	var exists bool
+	err = config.DB.Get(&exists, "SELECT EXISTS(SELECT 1 FROM sessions WHERE id = $1 AND "+ownerColumn+" = $2 AND revoked_at IS NULL)",
+		sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to look up session: %w", err)
+	}
+	if !exists {
+		return ErrSessionNotFound
+	}
+	if err := revokeTokens("family_id", sessionID); err != nil {
+		log.Printf("❌ Service: Error revoking session %s for %s %d: %v", sessionID, userType, userID, err)
+		return err
+	}
+	log.Printf("✅ Service: Session %s of %s %d revoked", sessionID, userType, userID)
+	return nil
+}
+
+// RevokeAllSessions ends every open session of the user except keepSessionID (if not empty),
+// and returns how many were ended.
+func RevokeAllSessions(userType string, userID int, keepSessionID string) (int, error) {
+	ownerColumn, err := sessionOwnerColumn(userType)
+	if err != nil {
+		return 0, err
+	}
+	var sessionIDs []string
+	err = config.DB.Select(&sessionIDs, "SELECT id FROM sessions WHERE "+ownerColumn+" = $1 AND revoked_at IS NULL AND id <> $2",
+		userID, keepSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+	for _, sessionID := range sessionIDs {
+		if err := revokeTokens("family_id", sessionID); err != nil {
+			log.Printf("❌ Service: Error revoking session %s for %s %d: %v", sessionID, userType, userID, err)
+			return 0, err
+		}
+	}
+	log.Printf("✅ Service: Revoked %d sessions of %s %d", len(sessionIDs), userType, userID)
+	return len(sessionIDs), nil
+}
+
+// describeDevice turns a user agent into a short label such as "Firefox on Linux".
+// Anything it does not recognise is reported as-is by the user_agent field.
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	browser := ""
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "okhttp") || strings.Contains(ua, "dart") || strings.Contains(ua, "cfnetwork"):
+		browser = "Mobile app"
+	}
+	platform := ""
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ios"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os") || strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return "Unknown device"
+}
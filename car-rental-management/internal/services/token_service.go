@@ -0,0 +1,323 @@
+package services
+
+import (
+	"car-rental-management/internal/config"
+	"car-rental-management/internal/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used; all sessions from this login have been revoked")
+)
+
+// tokenUser identifies who a token pair is issued to.
+type tokenUser struct {
+	UserType string // "employee" or "customer"
+	ID       int
+	Email    string
+	Role     string // Employees only
+}
+
+// newTokenID returns a random identifier used as a jti or token family ID.
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashOpaqueToken is what is stored in place of a refresh or one-time token itself.
+func hashOpaqueToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokenPair signs an access token and stores a new refresh token for user. An empty
+// familyID starts a new family (a fresh login); rotation passes the existing family on.
+// The family is also the session the tokens belong to, recorded with the client's details.
+func issueTokenPair(db sqlx.Execer, user tokenUser, familyID string, client models.ClientInfo) (models.TokenPair, error) {
+	accessTTL, refreshTTL := config.TokenLifetimes()
+
+	var err error
+	if familyID == "" {
+		if familyID, err = newTokenID(); err != nil {
+			return models.TokenPair{}, err
+		}
+	}
+
+	var (
+		accessToken, jti string
+		accessExpiresAt  time.Time
+	)
+	var employeeID, customerID *int
+	switch user.UserType {
+	case "employee":
+		accessToken, jti, accessExpiresAt, err = generateEmployeeToken(user.ID, user.Email, user.Role, familyID, accessTTL)
+		employeeID = &user.ID
+	case "customer":
+		accessToken, jti, accessExpiresAt, err = generateCustomerToken(user.ID, user.Email, familyID, accessTTL)
+		customerID = &user.ID
+	default:
+		return models.TokenPair{}, fmt.Errorf("unknown user type '%s'", user.UserType)
+	}
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	if err = upsertSession(db, familyID, user, client, time.Now().Add(refreshTTL)); err != nil {
+		return models.TokenPair{}, err
+	}
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return models.TokenPair{}, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(buf)
+
+	query := `INSERT INTO refresh_tokens (token_hash, family_id, user_type, employee_id, customer_id, access_jti, access_expires_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = db.Exec(query, hashOpaqueToken(refreshToken), familyID, user.UserType, employeeID, customerID, jti, accessExpiresAt, time.Now().Add(refreshTTL))
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return models.TokenPair{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTTL.Seconds()),
+	}, nil
+}
+
+type refreshTokenRow struct {
+	ID         int        `db:"id"`
+	FamilyID   string     `db:"family_id"`
+	UserType   string     `db:"user_type"`
+	EmployeeID *int       `db:"employee_id"`
+	CustomerID *int       `db:"customer_id"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	UsedAt     *time.Time `db:"used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. Each refresh token works once;
+// presenting one that was already rotated means it leaked, so its whole family is revoked.
+func RefreshTokens(rawToken string, client models.ClientInfo) (models.TokenPair, error) {
+	tokens, reusedFamily, err := rotateRefreshToken(rawToken, client)
+	if reusedFamily != "" {
+		log.Printf("🚨 RefreshTokens: Reuse of a rotated refresh token detected, revoking family %s", reusedFamily)
+		if errRevoke := revokeTokens("family_id", reusedFamily); errRevoke != nil {
+			log.Printf("❌ RefreshTokens: Failed to revoke token family %s: %v", reusedFamily, errRevoke)
+			return models.TokenPair{}, errRevoke
+		}
+	}
+	return tokens, err
+}
+
+// rotateRefreshToken does the transactional part of RefreshTokens. It returns the token's family
+// when reuse is detected, so the caller can revoke it outside the rolled-back transaction.
+func rotateRefreshToken(rawToken string, client models.ClientInfo) (tokens models.TokenPair, reusedFamily string, err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.TokenPair{}, "", fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+			if err != nil {
+				tokens = models.TokenPair{}
+			}
+		}
+	}()
+
+	// Locking the row means two concurrent refreshes with the same token cannot both succeed.
+	var row refreshTokenRow
+	err = tx.Get(&row, `SELECT id, family_id, user_type, employee_id, customer_id, expires_at, used_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, hashOpaqueToken(rawToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TokenPair{}, "", ErrInvalidRefreshToken
+		}
+		return models.TokenPair{}, "", fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+	if row.RevokedAt != nil {
+		return models.TokenPair{}, "", ErrInvalidRefreshToken
+	}
+	if row.UsedAt != nil {
+		return models.TokenPair{}, row.FamilyID, ErrRefreshTokenReused
+	}
+	if time.Now().After(row.ExpiresAt) {
+		return models.TokenPair{}, "", ErrInvalidRefreshToken
+	}
+
+	// Reload the user so role changes and deletions take effect at the next refresh.
+	user := tokenUser{UserType: row.UserType}
+	if row.UserType == "employee" && row.EmployeeID != nil {
+		user.ID = *row.EmployeeID
+		err = tx.QueryRowx("SELECT email, role FROM employees WHERE id = $1 AND deleted_at IS NULL", user.ID).Scan(&user.Email, &user.Role)
+	} else if row.UserType == "customer" && row.CustomerID != nil {
+		user.ID = *row.CustomerID
+		err = tx.Get(&user.Email, "SELECT email FROM customers WHERE id = $1 AND deleted_at IS NULL", user.ID)
+		if err == nil {
+			user.Email, err = decryptPII(piiCustomerEmail, user.Email)
+		}
+	} else {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TokenPair{}, "", ErrInvalidRefreshToken
+		}
+		return models.TokenPair{}, "", fmt.Errorf("failed to load token owner: %w", err)
+	}
+
+	if _, err = tx.Exec("UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1", row.ID); err != nil {
+		return models.TokenPair{}, "", fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	tokens, err = issueTokenPair(tx, user, row.FamilyID, client)
+	if err != nil {
+		return models.TokenPair{}, "", err
+	}
+	log.Printf("✅ Service: Rotated refresh token for %s %d", user.UserType, user.ID)
+	return tokens, "", nil
+}
+
+// revokeTokens revokes every refresh token matching column = value, together with the access
+// tokens issued alongside them that have not yet expired and the sessions they belong to.
+// column must be a trusted identifier.
+func revokeTokens(column string, value interface{}) (err error) {
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	return revokeTokensTx(tx, column, value)
+}
+
+// revokeTokensTx is revokeTokens within the caller's transaction.
+func revokeTokensTx(tx *sqlx.Tx, column string, value interface{}) error {
+	_, err := tx.Exec(`INSERT INTO revoked_tokens (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_tokens
+		WHERE `+column+` = $1 AND access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING`, value)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE `+column+` = $1 AND revoked_at IS NULL`, value)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	sessionColumn := column
+	if column == "family_id" { // A session's id is its family_id
+		sessionColumn = "id"
+	}
+	_, err = tx.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE `+sessionColumn+` = $1 AND revoked_at IS NULL`, value)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// RevokeEmployeeTokens logs an employee out everywhere, effective immediately.
+func RevokeEmployeeTokens(employeeID int) error {
+	if err := revokeTokens("employee_id", employeeID); err != nil {
+		log.Printf("❌ Service: Error revoking tokens for employee %d: %v", employeeID, err)
+		return err
+	}
+	log.Printf("✅ Service: Revoked all tokens for employee %d", employeeID)
+	return nil
+}
+
+// Logout revokes the caller's access token and ends its session. A refresh token, when given,
+// has its family revoked too.
+func Logout(userType string, userID int, sessionID, accessJTI string, accessExpiresAt time.Time, rawRefreshToken string) error {
+	if accessJTI != "" {
+		_, err := config.DB.Exec(`INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`, accessJTI, accessExpiresAt)
+		if err != nil {
+			log.Printf("❌ Service: Error revoking access token for %s %d: %v", userType, userID, err)
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+
+	if sessionID != "" {
+		if err := RevokeSession(userType, userID, sessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+
+	if rawRefreshToken != "" {
+		var row refreshTokenRow
+		err := config.DB.Get(&row, `SELECT id, family_id, user_type, employee_id, customer_id, expires_at, used_at, revoked_at
+			FROM refresh_tokens WHERE token_hash = $1`, hashOpaqueToken(rawRefreshToken))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidRefreshToken
+			}
+			return fmt.Errorf("failed to look up refresh token: %w", err)
+		}
+		owner := row.CustomerID
+		if row.UserType == "employee" {
+			owner = row.EmployeeID
+		}
+		if row.UserType != userType || owner == nil || *owner != userID {
+			return ErrInvalidRefreshToken
+		}
+		if err = revokeTokens("family_id", row.FamilyID); err != nil {
+			log.Printf("❌ Service: Error revoking token family for %s %d: %v", userType, userID, err)
+			return err
+		}
+	}
+
+	purgeExpiredTokens()
+	log.Printf("✅ Service: %s %d logged out", userType, userID)
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token's jti is on the revocation list.
+func IsAccessTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := config.DB.Get(&revoked, "SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// purgeExpiredTokens drops rows that can no longer matter because the tokens they describe have expired.
+func purgeExpiredTokens() {
+	if _, err := config.DB.Exec("DELETE FROM revoked_tokens WHERE expires_at < NOW()"); err != nil {
+		log.Printf("⚠️ Service: Failed to purge expired revoked tokens: %v", err)
+	}
+	if _, err := config.DB.Exec("DELETE FROM refresh_tokens WHERE expires_at < NOW()"); err != nil {
+		log.Printf("⚠️ Service: Failed to purge expired refresh tokens: %v", err)
+	}
+	if _, err := config.DB.Exec("DELETE FROM sessions WHERE expires_at < NOW()"); err != nil {
+		log.Printf("⚠️ Service: Failed to purge expired sessions: %v", err)
+	}
+}
@@ -13,11 +13,16 @@ import (
 	"github.com/lib/pq"
 )
 
-func GetUsers() ([]models.Employee, error) {
+// GetUsers lists employees; deleted ones only when includeDeleted is set.
+func GetUsers(includeDeleted bool) ([]models.Employee, error) {
 	var users []models.Employee
 	log.Println("🔍 Fetching users (employees)...")
 
-	query := "SELECT id, name, email, role, created_at, updated_at FROM employees ORDER BY name"
+	query := "SELECT id, name, email, role, created_at, updated_at, deleted_at FROM employees"
+	if !includeDeleted {
+		query += " WHERE deleted_at IS NULL"
+	}
+	query += " ORDER BY name"
 	err := config.DB.Select(&users, query)
 	if err != nil {
 		log.Println("❌ Error fetching users (employees):", err)
@@ -28,7 +33,33 @@ func GetUsers() ([]models.Employee, error) {
 	return users, nil
 }
 
-func CreateEmployeeByAdmin(input models.CreateEmployeeInput) (models.Employee, error) {
+// GetUserByID returns an employee, unless they have been deleted.
+func GetUserByID(id int) (models.Employee, error) {
+	return getUser(id, false)
+}
+
+// GetUserByIDIncludingDeleted returns an employee even if they have been deleted.
+func GetUserByIDIncludingDeleted(id int) (models.Employee, error) {
+	return getUser(id, true)
+}
+
+func getUser(id int, includeDeleted bool) (models.Employee, error) {
+	var user models.Employee
+	query := "SELECT id, name, email, role, created_at, updated_at, deleted_at FROM employees WHERE id = $1"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	err := config.DB.Get(&user, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Employee{}, errors.New("employee not found")
+		}
+		return models.Employee{}, fmt.Errorf("failed to fetch employee: %w", err)
+	}
+	return user, nil
+}
+
+func CreateEmployeeByAdmin(input models.CreateEmployeeInput) (createdEmployee models.Employee, err error) {
 	log.Println("⚙️ Service: Admin creating employee:", input.Email)
 
 	if strings.TrimSpace(input.Name) == "" {
@@ -43,7 +74,7 @@ func CreateEmployeeByAdmin(input models.CreateEmployeeInput) (models.Employee, e
 
 	var count int
 	countQuery := "SELECT COUNT(*) FROM employees WHERE email=$1"
-	err := config.DB.Get(&count, countQuery, input.Email)
+	err = config.DB.Get(&count, countQuery, input.Email)
 
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		log.Printf("❌ Service: DB error checking employee email existence for %s: %v", input.Email, err)
@@ -52,6 +83,9 @@ func CreateEmployeeByAdmin(input models.CreateEmployeeInput) (models.Employee, e
 	if count > 0 {
 		return models.Employee{}, errors.New("employee email already exists")
 	}
+	if err := ensureRoleExists(config.DB, input.Role); err != nil {
+		return models.Employee{}, err
+	}
 
 	hashedPassword, err := utils.HashPassword(input.Password)
 	if err != nil {
@@ -59,18 +93,37 @@ func CreateEmployeeByAdmin(input models.CreateEmployeeInput) (models.Employee, e
 		return models.Employee{}, errors.New("failed to secure password")
 	}
 
-	var createdEmployee models.Employee
+	tx, err := config.DB.Beginx()
+	if err != nil {
+		return models.Employee{}, fmt.Errorf("database transaction error: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
 	insertQuery := `
 		INSERT INTO employees (name, email, password, role)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id, name, email, role, created_at, updated_at
 	`
-	err = config.DB.QueryRowx(insertQuery, input.Name, input.Email, hashedPassword, input.Role).StructScan(&createdEmployee)
+	err = tx.QueryRowx(insertQuery, input.Name, input.Email, hashedPassword, input.Role).StructScan(&createdEmployee)
 	if err != nil {
 		log.Println("❌ Service: Error inserting employee:", err)
 
 		return models.Employee{}, fmt.Errorf("database error creating employee: %w", err)
 	}
+	if len(input.BranchIDs) > 0 {
+		if err = setEmployeeBranches(tx, createdEmployee.ID, input.BranchIDs); err != nil {
+			return models.Employee{}, err
+		}
+	}
 
 	log.Printf("✅ Service: Employee created successfully by admin with ID: %d", createdEmployee.ID)
 	return createdEmployee, nil
@@ -90,9 +143,22 @@ func UpdateEmployeeByAdmin(id int, input models.UpdateEmployeeInput) (models.Emp
 		return models.Employee{}, errors.New("invalid email format")
 	}
 
+	if err := ensureRoleExists(config.DB, input.Role); err != nil {
+		return models.Employee{}, err
+	}
+
+	var previousRole string
+	if err := config.DB.Get(&previousRole, "SELECT role FROM employees WHERE id=$1 AND deleted_at IS NULL", id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Employee{}, errors.New("employee not found for update")
+		}
+		return models.Employee{}, fmt.Errorf("database error fetching employee: %w", err)
+	}
+
 	query := `
-		UPDATE employees SET name=$1, email=$2, role=$3
-		WHERE id=$4
+		UPDATE employees SET name=$1, email=$2, role=$3,
+			email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END
+		WHERE id=$4 AND deleted_at IS NULL
 		RETURNING id, name, email, role, created_at, updated_at
 	`
 	var updatedEmployee models.Employee
@@ -113,6 +179,13 @@ func UpdateEmployeeByAdmin(id int, input models.UpdateEmployeeInput) (models.Emp
 		return models.Employee{}, fmt.Errorf("database error updating employee: %w", err)
 	}
 
+	// Existing tokens still carry the old role, so make the employee log in again.
+	if previousRole != updatedEmployee.Role {
+		if err := RevokeEmployeeTokens(id); err != nil {
+			return models.Employee{}, fmt.Errorf("employee updated but failed to revoke old tokens: %w", err)
+		}
+	}
+
 	log.Printf("✅ Service: Employee %d updated successfully by admin.", id)
 	return updatedEmployee, nil
 }
@@ -124,7 +197,13 @@ func DeleteEmployeeByAdmin(id int) error {
 		return errors.New("invalid employee ID")
 	}
 
-	result, err := config.DB.Exec("DELETE FROM employees WHERE id=$1", id)
+	// Revoke first: once purged, the employee's refresh tokens go with them, and those are
+	// needed to find the access tokens that are still live.
+	if err := RevokeEmployeeTokens(id); err != nil {
+		return fmt.Errorf("failed to revoke employee tokens: %w", err)
+	}
+
+	result, err := config.DB.Exec("UPDATE employees SET deleted_at = NOW() WHERE id=$1 AND deleted_at IS NULL", id)
 	if err != nil {
 		log.Printf("❌ Service: Error deleting employee %d: %v", id, err)
 
@@ -143,3 +222,21 @@ func DeleteEmployeeByAdmin(id int) error {
 	log.Printf("✅ Service: Employee %d deleted successfully by admin.", id)
 	return nil
 }
+
+// RestoreEmployee undoes DeleteEmployeeByAdmin. The employee has to log in again.
+func RestoreEmployee(id int) (models.Employee, error) {
+	if id <= 0 {
+		return models.Employee{}, errors.New("invalid employee ID")
+	}
+	result, err := config.DB.Exec("UPDATE employees SET deleted_at = NULL WHERE id=$1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		log.Printf("❌ Service: Error restoring employee %d: %v", id, err)
+		return models.Employee{}, fmt.Errorf("database error restoring employee: %w", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.Employee{}, ErrNothingToRestore
+	}
+	log.Printf("♻️ Service: Employee %d restored by admin.", id)
+	return GetUserByID(id)
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"sync"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,3 +20,17 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil // Returns true if password matches hash
 }
+
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// DummyPasswordCheck spends the same time as CheckPasswordHash, for logins to unknown
+// accounts, so response times do not reveal whether an email is registered.
+func DummyPasswordCheck(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("timing-equaliser"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
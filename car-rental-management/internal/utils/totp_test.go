@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// The RFC 6238 Appendix B SHA-1 seed, and its base32 encoding as stored in employee_mfa.
+const (
+	rfc6238Seed   = "12345678901234567890"
+	rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+)
+
+// The Appendix B SHA-1 vectors. The RFC prints 8 digits; a 6-digit code is the last six.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, tt := range rfc6238Vectors {
+		if got := totpCode([]byte(rfc6238Seed), tt.unix/totpPeriod); got != tt.code {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTPRFC6238(t *testing.T) {
+	for _, tt := range rfc6238Vectors {
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s, T=%d) = %d, %v; want %d, true", tt.code, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	const unix = 1111111111 // Mid-step, so ±30s lands in the neighbouring steps
+	step := int64(unix / totpPeriod)
+	code := totpCode([]byte(rfc6238Seed), step)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{"same step", 0, true},
+		{"one step later", totpPeriod * time.Second, true},
+		{"one step earlier", -totpPeriod * time.Second, true},
+		{"two steps later", 2 * totpPeriod * time.Second, false},
+		{"two steps earlier", -2 * totpPeriod * time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(rfc6238Secret, code, time.Unix(unix, 0).Add(tt.offset))
+			if ok != tt.want {
+				t.Fatalf("ValidateTOTP at %v = %v, want %v", tt.offset, ok, tt.want)
+			}
+			// The step returned is the one the code was generated for, not the current one.
+			if ok && gotStep != step {
+				t.Errorf("ValidateTOTP step = %d, want %d", gotStep, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	at := time.Unix(59, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"empty", rfc6238Secret, ""},
+		{"too short", rfc6238Secret, "28708"},
+		{"too long", rfc6238Secret, "2870820"},
+		{"8-digit RFC code", rfc6238Secret, "94287082"},
+		{"wrong code", rfc6238Secret, "287083"},
+		{"bad secret", "not base32!", "287082"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, at); ok {
+				t.Errorf("ValidateTOTP(%q, %q) accepted", tt.secret, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPTolerantInput(t *testing.T) {
+	at := time.Unix(59, 0)
+	for _, secret := range []string{rfc6238Secret, "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", rfc6238Secret + "===="} {
+		if _, ok := ValidateTOTP(secret, " 287082 ", at); !ok {
+			t.Errorf("ValidateTOTP(%q, padded code) rejected", secret)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
 
 // Consider adding more complex validation logic if needed (e.g., stronger password checks)
 
@@ -14,3 +18,34 @@ func IsValidEmail(email string) bool {
 	}
 	return emailRegex.MatchString(email)
 }
+
+// NormaliseEmail trims an email address and lowercases it, so the same address is always stored
+// the same way however it was typed.
+func NormaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalisePhone keeps only the digits of a phone number and a leading +, dropping the spaces,
+// dashes, dots and brackets people format numbers with.
+func NormalisePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	var b strings.Builder
+	for i, r := range phone {
+		if unicode.IsDigit(r) || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// NormalisePhonePtr is NormalisePhone for an optional number; a number with no digits becomes nil.
+func NormalisePhonePtr(phone *string) *string {
+	if phone == nil {
+		return nil
+	}
+	normalised := NormalisePhone(*phone)
+	if normalised == "" || normalised == "+" {
+		return nil
+	}
+	return &normalised
+}